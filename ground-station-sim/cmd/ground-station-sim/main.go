@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,7 +11,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"actinspace.org/internal/reqsign"
 )
 
 // CommandRequest 定義要發送的指令格式。
@@ -17,6 +23,7 @@ type CommandRequest struct {
 	Command     string                 `json:"command"`
 	Params      map[string]interface{} `json:"params,omitempty"`
 	SatelliteID string                 `json:"satelliteId,omitempty"`
+	ApprovalID  string                 `json:"approvalId,omitempty"`
 }
 
 // CommandResponse 是 gateway 的回應格式。
@@ -26,6 +33,19 @@ type CommandResponse struct {
 	Decision    string `json:"decision"`
 	Reason      string `json:"reason,omitempty"`
 	ProcessedAt string `json:"processedAt"`
+
+	RequiresApproval  bool   `json:"requiresApproval,omitempty"`
+	ApprovalID        string `json:"approvalId,omitempty"`
+	ApprovalExpiresAt string `json:"approvalExpiresAt,omitempty"`
+}
+
+// DualAuthRequest 是 gateway 雙人授權請求的狀態。
+type DualAuthRequest struct {
+	ID          string `json:"id"`
+	Command     string `json:"command"`
+	SatelliteID string `json:"satelliteId,omitempty"`
+	Status      string `json:"status"`
+	ExpiresAt   string `json:"expiresAt"`
 }
 
 func main() {
@@ -33,10 +53,12 @@ func main() {
 	command := flag.String("cmd", "", "指令名稱（必填）")
 	token := flag.String("token", "operator-token", "認證 token（預設: operator-token）")
 	satelliteID := flag.String("satellite", "", "衛星 ID（選填）")
+	approvalID := flag.String("approval", "", "已核准的雙人授權請求 ID，重送指令時使用（選填）")
+	approve := flag.String("approve", "", "以目前 token 核准另一位操作員的雙人授權請求 ID")
 	flag.Parse()
 
-	if *command == "" {
-		fmt.Fprintf(os.Stderr, "錯誤: 必須指定指令 (-cmd)\n")
+	if *command == "" && *approve == "" {
+		fmt.Fprintf(os.Stderr, "錯誤: 必須指定指令 (-cmd) 或要核准的請求 (-approve)\n")
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -90,9 +112,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *approve != "" {
+		approveDualAuth(gatewayURLStr, *token, *approve)
+		return
+	}
+
 	req := CommandRequest{
 		Command:     *command,
 		SatelliteID: *satelliteID,
+		ApprovalID:  *approvalID,
 	}
 
 	reqBody, err := json.Marshal(req)
@@ -110,6 +138,14 @@ func main() {
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+*token)
 
+	// 重放保護所需的 nonce、時間戳記與簽章（REPLAY_SIGNING_SECRET 需與 gateway 相同；gateway 未啟用時會忽略）
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: 無法產生 nonce: %v\n", err)
+		os.Exit(1)
+	}
+	reqsign.SetHeaders(httpReq, []byte(os.Getenv("REPLAY_SIGNING_SECRET")), hex.EncodeToString(nonce), strconv.FormatInt(time.Now().Unix(), 10), reqBody)
+
 	client := &http.Client{}
 	resp, err := client.Do(httpReq)
 	if err != nil {
@@ -124,6 +160,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	if resp.StatusCode == http.StatusAccepted {
+		var pending CommandResponse
+		if err := json.Unmarshal(body, &pending); err == nil && pending.RequiresApproval {
+			fmt.Printf("指令需要第二位操作員核准（雙人授權）\n")
+			fmt.Printf("請求 ID: %s\n", pending.ApprovalID)
+			fmt.Printf("核准期限: %s\n", pending.ApprovalExpiresAt)
+			fmt.Printf("第二位操作員核准: ground-station-sim -approve %s -token <自己的 token>\n", pending.ApprovalID)
+			resubmit := fmt.Sprintf("-cmd %s -approval %s", req.Command, pending.ApprovalID)
+			if req.SatelliteID != "" {
+				resubmit += " -satellite " + req.SatelliteID
+			}
+			fmt.Printf("核准後重送: ground-station-sim %s -token <原本的 token>\n", resubmit)
+			return
+		}
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		fmt.Fprintf(os.Stderr, "錯誤: Gateway 回應狀態碼 %d\n", resp.StatusCode)
 		fmt.Fprintf(os.Stderr, "回應內容: %s\n", string(body))
@@ -146,3 +198,39 @@ func main() {
 	fmt.Printf("處理時間: %s\n", cmdResp.ProcessedAt)
 }
 
+// approveDualAuth 以目前 token 核准另一位操作員的雙人授權請求。
+func approveDualAuth(gatewayURL, token, id string) {
+	httpReq, err := http.NewRequest("POST", gatewayURL+"/dual-auth/"+url.PathEscape(id)+"/approve", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: 無法建立請求: %v\n", err)
+		os.Exit(1)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: 無法發送請求: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: 無法讀取回應: %v\n", err)
+		os.Exit(1)
+	}
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "錯誤: Gateway 回應狀態碼 %d\n", resp.StatusCode)
+		fmt.Fprintf(os.Stderr, "回應內容: %s\n", string(body))
+		os.Exit(1)
+	}
+
+	var approved DualAuthRequest
+	if err := json.Unmarshal(body, &approved); err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: 無法解析回應: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已核准雙人授權請求 %s\n", approved.ID)
+	fmt.Printf("指令: %s\n", approved.Command)
+	fmt.Printf("申請者須在 %s 前以 -approval %s 重送指令\n", approved.ExpiresAt, approved.ID)
+}
@@ -0,0 +1,95 @@
+// Package database 提供各服務共用的資料庫連線設定。
+package database
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 預設的連線池設定：上限低於 PostgreSQL 預設的 max_connections（100），讓數個副本與 pooler 可以共存；
+// 定期汰換連線，避免 pooler 或負載平衡器無聲關閉的連線被重複使用。
+const (
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 10
+	DefaultConnMaxLifetime = 30 * time.Minute
+	DefaultConnMaxIdleTime = 5 * time.Minute
+)
+
+// PoolConfig 是套用到底層 *sql.DB 的連線池設定；0 表示不限制。
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// PoolConfigFromEnv 讀取連線池設定，無效或未設定的值使用預設值：
+//   - DB_MAX_OPEN_CONNS: 最多同時開啟的連線數（預設 25，0 表示不限制）
+//   - DB_MAX_IDLE_CONNS: 最多保留的閒置連線數（預設 10，超過 DB_MAX_OPEN_CONNS 時以其為準）
+//   - DB_CONN_MAX_LIFETIME: 連線最長使用時間（預設 30m，0 表示不限制）
+//   - DB_CONN_MAX_IDLE_TIME: 連線最長閒置時間（預設 5m，0 表示不限制）
+func PoolConfigFromEnv() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    intFromEnv("DB_MAX_OPEN_CONNS", DefaultMaxOpenConns),
+		MaxIdleConns:    intFromEnv("DB_MAX_IDLE_CONNS", DefaultMaxIdleConns),
+		ConnMaxLifetime: durationFromEnv("DB_CONN_MAX_LIFETIME", DefaultConnMaxLifetime),
+		ConnMaxIdleTime: durationFromEnv("DB_CONN_MAX_IDLE_TIME", DefaultConnMaxIdleTime),
+	}
+}
+
+// ConfigurePool 將 PoolConfigFromEnv 的設定套用到 gorm 底層的 *sql.DB，應在 gorm.Open 之後立即呼叫。
+func ConfigurePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	config := PoolConfigFromEnv()
+	if config.MaxOpenConns > 0 && config.MaxIdleConns > config.MaxOpenConns {
+		config.MaxIdleConns = config.MaxOpenConns
+	}
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	slog.Info("資料庫連線池設定",
+		"maxOpenConns", config.MaxOpenConns,
+		"maxIdleConns", config.MaxIdleConns,
+		"connMaxLifetime", config.ConnMaxLifetime,
+		"connMaxIdleTime", config.ConnMaxIdleTime,
+	)
+	return nil
+}
+
+// intFromEnv 讀取非負整數設定，無效或未設定時使用預設值。
+func intFromEnv(name string, def int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		slog.Warn("無效的資料庫連線池設定，使用預設值", "name", name, "value", raw, "default", def)
+		return def
+	}
+	return v
+}
+
+// durationFromEnv 讀取非負時間長度設定（例如 "30m"；"0" 表示不限制），無效或未設定時使用預設值。
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil || v < 0 {
+		slog.Warn("無效的資料庫連線池設定，使用預設值", "name", name, "value", raw, "default", def)
+		return def
+	}
+	return v
+}
@@ -0,0 +1,179 @@
+// Package health 提供各服務共用的就緒檢查（readiness）：逐一探測實際依賴（資料庫、下游服務），
+// 任一依賴無法使用時回傳 503 與各依賴的狀態，讓編排系統停止把流量導向壞掉的實例。
+// /health 仍只表示程序存活（liveness），不應檢查依賴，以免下游故障時服務被連鎖重啟。
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 預設的探測逾時與結果快取時間（READINESS_PROBE_TIMEOUT / READINESS_CACHE_TTL）。
+const (
+	DefaultProbeTimeout = 2 * time.Second
+	DefaultCacheTTL     = 5 * time.Second
+)
+
+// CheckFunc 檢查單一依賴，無法使用時回傳錯誤；ctx 帶有探測逾時。
+type CheckFunc func(ctx context.Context) error
+
+// DependencyStatus 是單一依賴的檢查結果。
+type DependencyStatus struct {
+	Status    string `json:"status"` // "up" or "down"
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// check 是已註冊的依賴檢查。
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker 並行執行已註冊的依賴檢查，結果在 ttl 內重複使用，避免每次就緒檢查都打到依賴。
+type Checker struct {
+	checks  []check
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	results   map[string]DependencyStatus
+}
+
+// NewChecker 建立就緒檢查器：
+//   - READINESS_PROBE_TIMEOUT: 每個依賴的探測逾時（預設 2s）
+//   - READINESS_CACHE_TTL: 探測結果快取時間（預設 5s）
+func NewChecker() *Checker {
+	return &Checker{
+		timeout: durationFromEnv("READINESS_PROBE_TIMEOUT", DefaultProbeTimeout),
+		ttl:     durationFromEnv("READINESS_CACHE_TTL", DefaultCacheTTL),
+	}
+}
+
+// durationFromEnv 讀取時間長度設定，無效或未設定時使用預設值。
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil || parsed <= 0 {
+		slog.Warn("無效的就緒檢查設定，使用預設值", "name", name, "value", v, "default", def)
+		return def
+	}
+	return parsed
+}
+
+// Add 註冊名為 name 的依賴檢查；應在開始處理請求前完成註冊。
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Check 回傳各依賴的檢查結果；快取過期時重新檢查（同時間只有一個請求會實際檢查）。
+func (c *Checker) Check(ctx context.Context) (map[string]DependencyStatus, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.results != nil && time.Since(c.checkedAt) < c.ttl {
+		return c.results, c.checkedAt
+	}
+
+	statuses := make([]DependencyStatus, len(c.checks))
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func(i int, chk check) {
+			defer wg.Done()
+			statuses[i] = c.run(ctx, chk)
+		}(i, chk)
+	}
+	wg.Wait()
+
+	results := make(map[string]DependencyStatus, len(c.checks))
+	for i, chk := range c.checks {
+		results[chk.name] = statuses[i]
+	}
+	c.results = results
+	c.checkedAt = time.Now().UTC()
+	return c.results, c.checkedAt
+}
+
+// run 在探測逾時內執行單一檢查。檢查不受觸發它的請求取消影響，確保快取的是完整結果。
+func (c *Checker) run(ctx context.Context, chk check) DependencyStatus {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := chk.fn(ctx)
+	status := DependencyStatus{Status: "up", LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		status.Status = "down"
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Handler 在所有依賴可用時回傳 200，否則回傳 503；兩者都附上各依賴的狀態，並在 failed 列出無法使用的依賴。
+func (c *Checker) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		results, checkedAt := c.Check(ctx.Request.Context())
+
+		code, status := http.StatusOK, "ready"
+		failed := []string{}
+		for _, chk := range c.checks {
+			if results[chk.name].Status != "up" {
+				failed = append(failed, chk.name)
+			}
+		}
+		if len(failed) > 0 {
+			code, status = http.StatusServiceUnavailable, "unavailable"
+		}
+
+		ctx.JSON(code, gin.H{
+			"status":       status,
+			"dependencies": results,
+			"failed":       failed,
+			"checkedAt":    checkedAt,
+		})
+	}
+}
+
+// HTTPCheck 回傳探測下游服務 baseURL+"/health" 的檢查，2xx 視為可用。
+func HTTPCheck(client *http.Client, baseURL string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/health", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// SQLCheck 回傳以 PingContext 檢查資料庫連線的檢查；getDB 通常為 gorm 的 db.DB。
+func SQLCheck(getDB func() (*sql.DB, error)) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := getDB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
@@ -0,0 +1,175 @@
+// Package logging 提供各服務共用的 slog 設定、請求 ID 關聯與業務事件記錄。
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 是請求 ID 的 header；未帶入時沿用 traceparent 的 trace-id 或自動產生。
+const RequestIDHeader = "X-Request-ID"
+
+type (
+	contextKey   struct{}
+	requestIDKey struct{}
+)
+
+// service 是 Setup 設定的服務名稱，作為事件 component 欄位的預設值。
+var service string
+
+// ParseLevel 解析 LOG_LEVEL（debug、info、warn、error），無法辨識時回傳 info。
+func ParseLevel(value string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Setup 依 LOG_LEVEL 與 LOG_FORMAT（json 預設、text）建立 logger 並設為預設值；
+// 標準 log 套件的輸出也會經由此 logger，以 info 等級記錄。
+func Setup(component string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(os.Getenv("LOG_LEVEL"))}
+
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "text") {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+
+	service = component
+	logger := slog.New(handler).With("service", component)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// WithLogger 將 logger 放入 context。
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext 取得 context 中帶有 requestId 的 logger，沒有時回傳預設 logger。
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// RequestID 回傳 Middleware 放入 context 的 request ID，供轉發到下游服務時帶上。
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDFrom 依序使用 X-Request-ID、W3C traceparent 的 trace-id，最後隨機產生。
+func requestIDFrom(c *gin.Context) string {
+	if id := strings.TrimSpace(c.GetHeader(RequestIDHeader)); id != "" && len(id) <= 128 {
+		return id
+	}
+	// traceparent: version-traceid-parentid-flags
+	if parts := strings.Split(c.GetHeader("traceparent"), "-"); len(parts) == 4 && len(parts[1]) == 32 {
+		return parts[1]
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Middleware 取代 gin 預設的 access log：為每個請求指定 request ID（回寫到回應 header），
+// 將帶有 requestId 的 logger 放入 request context，並在請求結束時記錄一筆 access log。
+func Middleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := requestIDFrom(c)
+		c.Header(RequestIDHeader, requestID)
+		c.Set("requestId", requestID)
+
+		reqLogger := logger.With("requestId", requestID)
+		ctx := context.WithValue(c.Request.Context(), requestIDKey{}, requestID)
+		c.Request = c.Request.WithContext(WithLogger(ctx, reqLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		// 健康檢查太頻繁，降為 debug
+		if path := c.FullPath(); path == "/health" || path == "/ready" || path == "/readyz" {
+			level = slog.LevelDebug
+		}
+
+		reqLogger.LogAttrs(c.Request.Context(), level, "http_request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("clientIp", c.ClientIP()),
+		)
+	}
+}
+
+// severityLevel 將事件的 severity 欄位對應到 log 等級。
+func severityLevel(severity interface{}) slog.Level {
+	switch severity {
+	case "critical":
+		return slog.LevelError
+	case "high":
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Event 以結構化欄位記錄業務事件（event 名稱加上呼叫端提供的欄位，component 預設為服務名稱、severity 預設為 info），
+// 等級依 severity 欄位決定，並帶上 context 中的 requestId；時間由 slog 的 time 欄位記錄。
+func Event(ctx context.Context, eventType string, fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(fields)+3)
+	attrs = append(attrs, slog.String("event", eventType))
+	if _, ok := fields["component"]; !ok {
+		attrs = append(attrs, slog.String("component", service))
+	}
+	if _, ok := fields["severity"]; !ok {
+		attrs = append(attrs, slog.String("severity", "info"))
+	}
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	FromContext(ctx).LogAttrs(ctx, severityLevel(fields["severity"]), eventType, attrs...)
+}
@@ -0,0 +1,64 @@
+// Package middleware 提供各服務共用的 gin middleware。
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxBodyBytes 是未設定 MAX_BODY_BYTES 時的請求 body 上限（256 KiB）。
+const DefaultMaxBodyBytes int64 = 256 << 10
+
+// MaxBodyBytesFromEnv 讀取 MAX_BODY_BYTES，無效或未設定時使用 DefaultMaxBodyBytes。
+func MaxBodyBytesFromEnv() int64 {
+	raw := os.Getenv("MAX_BODY_BYTES")
+	if raw == "" {
+		return DefaultMaxBodyBytes
+	}
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit <= 0 {
+		slog.Warn("無效的 MAX_BODY_BYTES，使用預設值", "value", raw, "default", DefaultMaxBodyBytes)
+		return DefaultMaxBodyBytes
+	}
+	return limit
+}
+
+// MaxBodyBytes 在 JSON 解析前拒絕超過 limit 的請求 body（413）。
+// Content-Length 已超過時直接拒絕；chunked 或未宣告長度的 body 則最多讀取 limit+1 bytes 判斷。
+func MaxBodyBytes(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			rejectTooLarge(c, limit)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+		c.Request.Body.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		if int64(len(body)) > limit {
+			rejectTooLarge(c, limit)
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func rejectTooLarge(c *gin.Context, limit int64) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":        "request body too large",
+		"maxBodyBytes": limit,
+	})
+	c.Abort()
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newLimitedRouter(limit int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(MaxBodyBytes(limit))
+	r.POST("/", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	})
+	return r
+}
+
+func TestMaxBodyBytesRejectsOversizedPayload(t *testing.T) {
+	r := newLimitedRouter(16)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", 17))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	if !strings.Contains(w.Body.String(), `"maxBodyBytes":16`) {
+		t.Fatalf("response should report the limit, got %s", w.Body.String())
+	}
+}
+
+func TestMaxBodyBytesRejectsOversizedChunkedPayload(t *testing.T) {
+	r := newLimitedRouter(16)
+
+	// No Content-Length: the limit is enforced while reading the body
+	req := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(strings.NewReader(strings.Repeat("x", 10)), strings.NewReader(strings.Repeat("y", 10))))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestMaxBodyBytesPassesPayloadAtLimit(t *testing.T) {
+	r := newLimitedRouter(16)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bytes.Repeat([]byte("x"), 16))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w.Body.String() != "16" {
+		t.Fatalf("handler read %s bytes, want the full 16", w.Body.String())
+	}
+}
+
+func TestMaxBodyBytesFromEnv(t *testing.T) {
+	t.Setenv("MAX_BODY_BYTES", "1024")
+	if got := MaxBodyBytesFromEnv(); got != 1024 {
+		t.Fatalf("MaxBodyBytesFromEnv() = %d, want 1024", got)
+	}
+
+	t.Setenv("MAX_BODY_BYTES", "-1")
+	if got := MaxBodyBytesFromEnv(); got != DefaultMaxBodyBytes {
+		t.Fatalf("invalid value should fall back to the default, got %d", got)
+	}
+}
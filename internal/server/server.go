@@ -0,0 +1,104 @@
+// Package server 提供各服務共用的 HTTP 伺服器啟動與優雅關閉（SIGINT / SIGTERM）。
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout 是未設定 SHUTDOWN_TIMEOUT 時的關閉期限，
+// 小於 Kubernetes 預設的 terminationGracePeriodSeconds（30s），確保在 SIGKILL 前完成。
+const DefaultShutdownTimeout = 25 * time.Second
+
+// ShutdownTimeoutFromEnv 讀取 SHUTDOWN_TIMEOUT（例如 "25s"），無效或未設定時使用 DefaultShutdownTimeout。
+func ShutdownTimeoutFromEnv() time.Duration {
+	raw := os.Getenv("SHUTDOWN_TIMEOUT")
+	if raw == "" {
+		return DefaultShutdownTimeout
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil || timeout <= 0 {
+		slog.Warn("無效的 SHUTDOWN_TIMEOUT，使用預設值", "value", raw, "default", DefaultShutdownTimeout)
+		return DefaultShutdownTimeout
+	}
+	return timeout
+}
+
+// cleanup 是 HTTP 伺服器停止後執行的收尾工作。
+type cleanup struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Server 包裝 http.Server：收到 SIGINT / SIGTERM 後停止接受新連線，在期限內等待處理中的請求完成，
+// 再依註冊順序執行收尾工作（例如停止 webhook worker、Kafka consumer、寫入狀態）。
+type Server struct {
+	http     *http.Server
+	timeout  time.Duration
+	cleanups []cleanup
+}
+
+// New 建立在 addr 提供 handler 的伺服器，關閉期限取自 SHUTDOWN_TIMEOUT。
+func New(addr string, handler http.Handler) *Server {
+	return &Server{
+		http:    &http.Server{Addr: addr, Handler: handler},
+		timeout: ShutdownTimeoutFromEnv(),
+	}
+}
+
+// OnShutdown 註冊開始關閉時立即呼叫的函式，用於結束 SSE / WebSocket 等長連線；
+// 否則 Shutdown 會一直等到期限才結束（WebSocket 已被 hijack，Shutdown 不會等待也不會關閉它們）。
+func (s *Server) OnShutdown(fn func()) {
+	s.http.RegisterOnShutdown(fn)
+}
+
+// AddCleanup 註冊 HTTP 伺服器停止後執行的收尾工作，與 HTTP 關閉共用同一個期限。
+func (s *Server) AddCleanup(name string, fn func(ctx context.Context) error) {
+	s.cleanups = append(s.cleanups, cleanup{name: name, fn: fn})
+}
+
+// Run 啟動伺服器並阻塞到收到 SIGINT / SIGTERM 且完成關閉為止。只有伺服器無法啟動（例如埠已被占用）時回傳錯誤；
+// 關閉過程中的錯誤只記錄日誌。收到第一個訊號後恢復預設處理，再按一次 Ctrl-C 會立即結束。
+func (s *Server) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("收到關閉訊號，停止接受新請求", "timeout", s.timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("等待處理中的請求逾時，強制關閉連線", "error", err)
+		s.http.Close()
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("HTTP 伺服器異常結束", "error", err)
+	}
+
+	for _, c := range s.cleanups {
+		if err := c.fn(shutdownCtx); err != nil {
+			slog.Error("關閉時收尾失敗", "cleanup", c.name, "error", err)
+		}
+	}
+	slog.Info("服務已關閉", "elapsed", time.Since(start))
+	return nil
+}
@@ -0,0 +1,107 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Custom 表示以 SetCustomCondition 設定的自訂網路狀況（例如實測的地面站過境鏈路）。
+const Custom NetworkCondition = "custom"
+
+// ParseNetworkCondition 解析網路狀況名稱（五種預設或 custom）。
+func ParseNetworkCondition(name string) (NetworkCondition, error) {
+	switch condition := NetworkCondition(name); condition {
+	case LEO, MEO, GEO, DeepSpace, Degraded, Custom:
+		return condition, nil
+	default:
+		return "", fmt.Errorf("unknown network condition %q (supported: leo, meo, geo, deep_space, degraded, custom)", name)
+	}
+}
+
+// SetCustomCondition 以自訂參數取代預設狀況：延遲範圍、jitter、掉包率（0–1）與頻寬（KB/s）。
+// 延遲分佈維持 uniform，可再以 SetLatencyDistribution 覆寫；鏈路預算沿用 LEO 的軌道參數。
+func (ns *NetworkSimulator) SetCustomCondition(latencyMin, latencyMax, jitter time.Duration, lossRate float64, bandwidthKBs int) error {
+	if latencyMin < 0 || jitter < 0 {
+		return fmt.Errorf("latency and jitter must not be negative")
+	}
+	if latencyMax < latencyMin {
+		return fmt.Errorf("latencyMax (%v) must be at least latencyMin (%v)", latencyMax, latencyMin)
+	}
+	if lossRate < 0 || lossRate > 1 {
+		return fmt.Errorf("loss rate must be within 0-1, got %v", lossRate)
+	}
+	if bandwidthKBs <= 0 {
+		return fmt.Errorf("bandwidth must be positive, got %d KB/s", bandwidthKBs)
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.condition = Custom
+	ns.latencyMin = latencyMin
+	ns.latencyMax = latencyMax
+	ns.distribution = Uniform
+	ns.jitterRange = jitter
+	ns.packetLossRate = lossRate
+	ns.bandwidthLimitKBs = bandwidthKBs
+
+	if ns.link.enabled {
+		ns.link.rateRefTime = time.Time{}
+		ns.setElevationLocked(ns.link.elevationDeg, time.Now())
+	}
+	return nil
+}
+
+// CustomCondition 是自訂網路狀況的 JSON 格式，時間為 Go duration 字串：
+//
+//	{"latencyMin": "120ms", "latencyMax": "180ms", "jitter": "15ms", "lossRate": 0.03, "bandwidthKBs": 512, "distribution": "lognormal"}
+type CustomCondition struct {
+	LatencyMin   string              `json:"latencyMin"`
+	LatencyMax   string              `json:"latencyMax"`
+	Jitter       string              `json:"jitter,omitempty"`
+	LossRate     float64             `json:"lossRate"`
+	BandwidthKBs int                 `json:"bandwidthKBs"`
+	Distribution LatencyDistribution `json:"distribution,omitempty"`
+}
+
+// LoadCustomCondition 從 JSON 檔讀取自訂網路狀況。
+func LoadCustomCondition(path string) (CustomCondition, error) {
+	var custom CustomCondition
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return custom, err
+	}
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return custom, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return custom, nil
+}
+
+// Apply 驗證並套用自訂網路狀況。
+func (c CustomCondition) Apply(ns *NetworkSimulator) error {
+	durations := make([]time.Duration, 3)
+	for i, field := range []struct{ name, value string }{
+		{"latencyMin", c.LatencyMin},
+		{"latencyMax", c.LatencyMax},
+		{"jitter", c.Jitter},
+	} {
+		if field.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", field.name, err)
+		}
+		durations[i] = d
+	}
+
+	if err := ns.SetCustomCondition(durations[0], durations[1], durations[2], c.LossRate, c.BandwidthKBs); err != nil {
+		return err
+	}
+	if c.Distribution != "" {
+		return ns.SetLatencyDistribution(c.Distribution)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// LatencyDistribution 是基礎延遲的機率分佈；jitter 另外疊加在抽樣結果上。
+type LatencyDistribution string
+
+const (
+	// Uniform 在 [latencyMin, latencyMax) 間均勻分佈
+	Uniform LatencyDistribution = "uniform"
+	// Normal 以範圍中點為平均、範圍的 1/4 為標準差（約 95% 落在範圍內），低於 latencyMin 時截斷
+	Normal LatencyDistribution = "normal"
+	// LogNormal 為 latencyMin 加上中位數為半個範圍、σ=0.6 的對數常態分佈，右偏且偶有長尾
+	LogNormal LatencyDistribution = "lognormal"
+	// Pareto 為 latencyMin 加上 α=2.5 的 Lomax（Pareto II）分佈，平均為半個範圍，尾部最重
+	Pareto LatencyDistribution = "pareto"
+)
+
+const (
+	logNormalSigma = 0.6
+	paretoAlpha    = 2.5
+	// maxLatencyFactor 限制長尾抽樣不超過 latencyMax 的倍數，避免 SimulateDelay 無限期阻塞
+	maxLatencyFactor = 10
+)
+
+// ParseLatencyDistribution 解析分佈名稱（空字串視為 uniform）。
+func ParseLatencyDistribution(name string) (LatencyDistribution, error) {
+	switch dist := LatencyDistribution(name); dist {
+	case "":
+		return Uniform, nil
+	case Uniform, Normal, LogNormal, Pareto:
+		return dist, nil
+	default:
+		return "", fmt.Errorf("unknown latency distribution %q (supported: uniform, normal, lognormal, pareto)", name)
+	}
+}
+
+// SetLatencyDistribution 覆寫目前軌道預設的延遲分佈（下次 SetCondition 時回到預設）。
+func (ns *NetworkSimulator) SetLatencyDistribution(dist LatencyDistribution) error {
+	if _, err := ParseLatencyDistribution(string(dist)); err != nil {
+		return err
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.distribution = dist
+	return nil
+}
+
+// sampleLatency 依目前分佈抽樣基礎延遲（不含 jitter 與鏈路預算延遲；呼叫端需持有鎖）。
+func (ns *NetworkSimulator) sampleLatency() time.Duration {
+	return sampleLatency(ns.distribution, ns.latencyMin, ns.latencyMax, rand.Float64, rand.NormFloat64)
+}
+
+// sampleLatency 依分佈在 [min, max] 定義的範圍抽樣；uniform 與 normal 以外的分佈為 min 加上右偏的額外延遲。
+func sampleLatency(dist LatencyDistribution, min, max time.Duration, uniform, normal func() float64) time.Duration {
+	span := float64(max - min)
+	if span <= 0 {
+		return min
+	}
+
+	var latency float64
+	switch dist {
+	case Normal:
+		latency = math.Max(float64(min), float64(min)+span/2+normal()*span/4)
+	case LogNormal:
+		latency = float64(min) + span/2*math.Exp(logNormalSigma*normal())
+	case Pareto:
+		// Lomax 的平均為 scale/(α-1)，取 scale 使平均為半個範圍
+		scale := span / 2 * (paretoAlpha - 1)
+		latency = float64(min) + scale*(math.Pow(1-uniform(), -1/paretoAlpha)-1)
+	default:
+		latency = float64(min) + uniform()*span
+	}
+
+	return time.Duration(math.Min(latency, float64(max)*maxLatencyFactor))
+}
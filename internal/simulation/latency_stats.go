@@ -0,0 +1,116 @@
+package simulation
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// latencyReservoirSize 是每個延遲樣本池保留的樣本數（Algorithm R 均勻抽樣）
+	latencyReservoirSize = 1024
+	// maxTrackedCommands 限制個別統計的指令類型數，超過的指令併入 otherCommands
+	maxTrackedCommands = 64
+	otherCommands      = "_other"
+)
+
+// LatencyPercentiles 是成功送達封包的端到端延遲分佈（含傳輸時間與亂序延後）。
+type LatencyPercentiles struct {
+	Samples int64
+	P50Ms   float64
+	P95Ms   float64
+	P99Ms   float64
+	MaxMs   float64
+}
+
+// latencyReservoir 以固定大小的樣本池估計延遲百分位數。
+type latencyReservoir struct {
+	samples []float64 // 毫秒
+	seen    int64
+	maxMs   float64
+}
+
+func newLatencyReservoir() *latencyReservoir {
+	return &latencyReservoir{samples: make([]float64, 0, latencyReservoirSize)}
+}
+
+// add 加入一筆延遲樣本；樣本池滿後以 size/seen 的機率取代既有樣本。
+func (r *latencyReservoir) add(latency time.Duration) {
+	ms := float64(latency) / float64(time.Millisecond)
+	r.seen++
+	if ms > r.maxMs {
+		r.maxMs = ms
+	}
+	if len(r.samples) < latencyReservoirSize {
+		r.samples = append(r.samples, ms)
+		return
+	}
+	if i := rand.Int63n(r.seen); i < latencyReservoirSize {
+		r.samples[i] = ms
+	}
+}
+
+// percentiles 計算目前樣本的 p50 / p95 / p99（nearest-rank）。
+func (r *latencyReservoir) percentiles() LatencyPercentiles {
+	result := LatencyPercentiles{Samples: r.seen, MaxMs: r.maxMs}
+	if len(r.samples) == 0 {
+		return result
+	}
+
+	sorted := append([]float64(nil), r.samples...)
+	sort.Float64s(sorted)
+	rank := func(p float64) float64 {
+		i := int(p*float64(len(sorted))+0.5) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(sorted) {
+			i = len(sorted) - 1
+		}
+		return sorted[i]
+	}
+	result.P50Ms = rank(0.50)
+	result.P95Ms = rank(0.95)
+	result.P99Ms = rank(0.99)
+	return result
+}
+
+// recordLatency 將延遲計入整體與指令類型的樣本池（呼叫端需持有寫鎖）。
+func (ns *NetworkSimulator) recordLatency(command string, latency time.Duration) {
+	if ns.latency == nil {
+		ns.latency = newLatencyReservoir()
+	}
+	ns.latency.add(latency)
+
+	if command == "" {
+		return
+	}
+	if ns.commandLatency == nil {
+		ns.commandLatency = make(map[string]*latencyReservoir)
+	}
+	reservoir, ok := ns.commandLatency[command]
+	if !ok {
+		if len(ns.commandLatency) >= maxTrackedCommands {
+			command = otherCommands
+			reservoir = ns.commandLatency[command]
+		}
+		if reservoir == nil {
+			reservoir = newLatencyReservoir()
+			ns.commandLatency[command] = reservoir
+		}
+	}
+	reservoir.add(latency)
+}
+
+// latencySnapshot 回傳整體與各指令類型的延遲百分位數（呼叫端需持有鎖）。
+func (ns *NetworkSimulator) latencySnapshot() (LatencyPercentiles, map[string]LatencyPercentiles) {
+	var overall LatencyPercentiles
+	if ns.latency != nil {
+		overall = ns.latency.percentiles()
+	}
+	byCommand := make(map[string]LatencyPercentiles, len(ns.commandLatency))
+	for command, reservoir := range ns.commandLatency {
+		byCommand[command] = reservoir.percentiles()
+	}
+	return overall, byCommand
+}
@@ -0,0 +1,89 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// sampleMs 以固定種子抽樣 n 筆延遲（毫秒）並排序
+func sampleMs(dist LatencyDistribution, min, max time.Duration, n int) []float64 {
+	rng := rand.New(rand.NewSource(42))
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = float64(sampleLatency(dist, min, max, rng.Float64, rng.NormFloat64)) / float64(time.Millisecond)
+	}
+	sort.Float64s(samples)
+	return samples
+}
+
+func mean(samples []float64) float64 {
+	total := 0.0
+	for _, s := range samples {
+		total += s
+	}
+	return total / float64(len(samples))
+}
+
+func TestSampleLatencyMatchesDistribution(t *testing.T) {
+	const (
+		min = 100 * time.Millisecond
+		max = 200 * time.Millisecond
+		n   = 200000
+	)
+	// 理論值（毫秒）：範圍 100ms，z(0.99) = 2.3263
+	z99 := 2.3263
+	tests := []struct {
+		dist     LatencyDistribution
+		wantMean float64
+		wantP99  float64
+	}{
+		{Uniform, 150, 199},
+		{Normal, 150, 150 + z99*25},
+		{LogNormal, 100 + 50*math.Exp(logNormalSigma*logNormalSigma/2), 100 + 50*math.Exp(logNormalSigma*z99)},
+		{Pareto, 150, 100 + 50*(paretoAlpha-1)*(math.Pow(0.01, -1/paretoAlpha)-1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dist), func(t *testing.T) {
+			samples := sampleMs(tt.dist, min, max, n)
+
+			if got := mean(samples); math.Abs(got-tt.wantMean)/tt.wantMean > 0.01 {
+				t.Errorf("mean = %.2fms, want %.2fms ±1%%", got, tt.wantMean)
+			}
+			if got := samples[int(0.99*n)]; math.Abs(got-tt.wantP99)/tt.wantP99 > 0.03 {
+				t.Errorf("p99 = %.2fms, want %.2fms ±3%%", got, tt.wantP99)
+			}
+			if samples[0] < float64(min/time.Millisecond) {
+				t.Errorf("sample %.2fms below latencyMin", samples[0])
+			}
+			if limit := float64(max/time.Millisecond) * maxLatencyFactor; samples[n-1] > limit {
+				t.Errorf("sample %.2fms above the %.0fms cap", samples[n-1], limit)
+			}
+		})
+	}
+}
+
+func TestSampleLatencyTailOrdering(t *testing.T) {
+	// 相同範圍下，尾部由輕到重應為 uniform < normal < lognormal < pareto
+	const n = 50000
+	var previous float64
+	for _, dist := range []LatencyDistribution{Uniform, Normal, LogNormal, Pareto} {
+		samples := sampleMs(dist, 100*time.Millisecond, 200*time.Millisecond, n)
+		p999 := samples[int(0.999*n)]
+		if p999 <= previous {
+			t.Fatalf("%s p99.9 = %.2fms, want heavier than the previous distribution (%.2fms)", dist, p999, previous)
+		}
+		previous = p999
+	}
+}
+
+func TestSampleLatencyEmptyRange(t *testing.T) {
+	for _, dist := range []LatencyDistribution{Uniform, Normal, LogNormal, Pareto} {
+		if got := sampleLatency(dist, 50*time.Millisecond, 50*time.Millisecond, rand.Float64, rand.NormFloat64); got != 50*time.Millisecond {
+			t.Fatalf("%s with min == max = %v, want 50ms", dist, got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package simulation
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	earthRadiusKm   = 6371.0
+	speedOfLightKmS = 299792.458
+	// carrierFrequencyHz 是用於計算都卜勒頻移的 S-band 載波頻率
+	carrierFrequencyHz = 2.2e9
+	// minElevationDeg 以下視為在地平線下，鏈路中斷
+	minElevationDeg = 0.0
+	// snrKneeDB 以下開始額外掉包
+	snrKneeDB = 10.0
+	// rangeRateSampleInterval 是估算斜距變化率的最短取樣間隔，避免連續呼叫造成數值爆衝
+	rangeRateSampleInterval = time.Second
+)
+
+// linkProfile 是各軌道預設的簡化鏈路預算參數。
+type linkProfile struct {
+	altitudeKm  float64 // 衛星高度
+	zenithSNRdB float64 // 天頂（仰角 90°）時的訊噪比
+}
+
+var linkProfiles = map[NetworkCondition]linkProfile{
+	LEO:       {altitudeKm: 550, zenithSNRdB: 20},
+	MEO:       {altitudeKm: 20200, zenithSNRdB: 15},
+	GEO:       {altitudeKm: 35786, zenithSNRdB: 12},
+	DeepSpace: {altitudeKm: 2.25e8, zenithSNRdB: 6}, // 約地球到火星的平均距離
+	Degraded:  {altitudeKm: 550, zenithSNRdB: 8},    // LEO 軌道但受太陽風暴影響
+}
+
+// linkState 是目前仰角下的鏈路狀態。
+type linkState struct {
+	enabled        bool
+	elevationDeg   float64
+	slantRangeKm   float64
+	snrDB          float64
+	rangeRateKmS   float64 // 正值表示遠離
+	rateRefRangeKm float64 // 上次估算變化率時的斜距
+	rateRefTime    time.Time
+	bandwidthScale float64 // 相對天頂頻寬的比例（Shannon capacity）
+	extraLossRate  float64 // SNR 低於門檻時增加的掉包率
+	extraLatency   time.Duration
+}
+
+// slantRangeKm 計算地面站到衛星的斜距。
+func slantRangeKm(altitudeKm, elevationDeg float64) float64 {
+	e := elevationDeg * math.Pi / 180
+	r := earthRadiusKm + altitudeKm
+	return math.Sqrt(r*r-math.Pow(earthRadiusKm*math.Cos(e), 2)) - earthRadiusKm*math.Sin(e)
+}
+
+// atmosphericLossDB 以 airmass（1/sin(仰角)）近似低仰角的大氣衰減。
+func atmosphericLossDB(elevationDeg float64) float64 {
+	e := math.Max(elevationDeg, 5) * math.Pi / 180
+	return 0.5 * (1/math.Sin(e) - 1)
+}
+
+// computeLink 依仰角計算 SNR、頻寬比例與額外掉包/延遲。
+func computeLink(profile linkProfile, elevationDeg float64) (rangeKm, snrDB, bandwidthScale, extraLoss float64, extraLatency time.Duration) {
+	rangeKm = slantRangeKm(profile.altitudeKm, elevationDeg)
+	zenithRange := profile.altitudeKm
+
+	// 自由空間路徑損耗與距離平方成正比：相對天頂的差值為 20·log10(range/zenith)
+	snrDB = profile.zenithSNRdB - 20*math.Log10(rangeKm/zenithRange) - atmosphericLossDB(elevationDeg)
+
+	if elevationDeg < minElevationDeg {
+		return rangeKm, snrDB, 0, 1, 0
+	}
+
+	snrLinear := math.Pow(10, snrDB/10)
+	zenithLinear := math.Pow(10, profile.zenithSNRdB/10)
+	bandwidthScale = math.Log2(1+snrLinear) / math.Log2(1+zenithLinear)
+
+	if snrDB < snrKneeDB {
+		extraLoss = math.Min((snrKneeDB-snrDB)/snrKneeDB*0.5, 0.95)
+	}
+
+	// 低仰角時斜距較長，額外的單程傳播延遲
+	extraLatency = time.Duration((rangeKm - zenithRange) / speedOfLightKmS * float64(time.Second))
+
+	return rangeKm, snrDB, bandwidthScale, extraLoss, extraLatency
+}
+
+// SetElevation 設定目前衛星仰角（度）並啟用鏈路預算模型；
+// 連續呼叫之間的斜距變化用來估算都卜勒效應（延遲的時間導數）。
+func (ns *NetworkSimulator) SetElevation(elevationDeg float64) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.setElevationLocked(elevationDeg, time.Now())
+}
+
+// setElevationLocked 更新鏈路狀態（呼叫端需持有寫鎖）。
+func (ns *NetworkSimulator) setElevationLocked(elevationDeg float64, now time.Time) {
+	profile, ok := linkProfiles[ns.condition]
+	if !ok {
+		profile = linkProfiles[LEO]
+	}
+
+	rangeKm, snr, bwScale, extraLoss, extraLatency := computeLink(profile, elevationDeg)
+
+	// 每隔至少 rangeRateSampleInterval 以斜距差估算變化率；首次呼叫只建立參考點
+	if !ns.link.enabled || ns.link.rateRefTime.IsZero() {
+		ns.link.rateRefRangeKm = rangeKm
+		ns.link.rateRefTime = now
+		ns.link.rangeRateKmS = 0
+	} else if dt := now.Sub(ns.link.rateRefTime); dt >= rangeRateSampleInterval {
+		ns.link.rangeRateKmS = (rangeKm - ns.link.rateRefRangeKm) / dt.Seconds()
+		ns.link.rateRefRangeKm = rangeKm
+		ns.link.rateRefTime = now
+	}
+
+	ns.link.enabled = true
+	ns.link.elevationDeg = elevationDeg
+	ns.link.slantRangeKm = rangeKm
+	ns.link.snrDB = snr
+	ns.link.bandwidthScale = bwScale
+	ns.link.extraLossRate = extraLoss
+	ns.link.extraLatency = extraLatency
+
+	ns.stats.ElevationDeg = elevationDeg
+	ns.stats.SlantRangeKm = rangeKm
+	ns.stats.SNRdB = snr
+	// 延遲導數 = 斜距變化率 / 光速（ms/s）；頻移 = -f·v/c
+	ns.stats.LatencyRateMsPerSec = ns.link.rangeRateKmS / speedOfLightKmS * 1000
+	ns.stats.DopplerShiftHz = -carrierFrequencyHz * ns.link.rangeRateKmS / speedOfLightKmS
+}
+
+// DisableLinkBudget 停用鏈路預算模型（含進行中的過境），回到預設的固定參數。
+func (ns *NetworkSimulator) DisableLinkBudget() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.link = linkState{}
+	ns.pass = passState{}
+	ns.stats.ElevationDeg = 0
+	ns.stats.SlantRangeKm = 0
+	ns.stats.SNRdB = 0
+	ns.stats.LatencyRateMsPerSec = 0
+	ns.stats.DopplerShiftHz = 0
+}
+
+// effectiveLinkParams 回傳套用鏈路預算後的掉包率、頻寬與額外延遲（呼叫端需持有鎖）。
+func (ns *NetworkSimulator) effectiveLinkParams() (float64, int, time.Duration) {
+	if !ns.link.enabled {
+		return ns.packetLossRate, ns.bandwidthLimitKBs, 0
+	}
+
+	loss := math.Min(ns.packetLossRate+ns.link.extraLossRate, 1)
+	bandwidth := int(float64(ns.bandwidthLimitKBs) * ns.link.bandwidthScale)
+	if bandwidth < 1 {
+		bandwidth = 1
+	}
+
+	return loss, bandwidth, ns.link.extraLatency
+}
@@ -0,0 +1,360 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// NetworkSimulator simulates realistic network conditions for space communications
+type NetworkSimulator struct {
+	mu                sync.RWMutex
+	enabled           bool
+	latencyMin        time.Duration
+	latencyMax        time.Duration
+	distribution      LatencyDistribution // shape of the base latency (see latency.go)
+	packetLossRate    float64             // 0.0 to 1.0
+	jitterRange       time.Duration
+	bandwidthLimitKBs int // KB/s
+	condition         NetworkCondition
+	link              linkState    // elevation-driven link budget (see link_budget.go)
+	reorder           reorderState // out-of-order delivery (see reorder.go)
+	pass              passState    // time-varying elevation over a pass (see pass.go)
+	stats             NetworkStats
+	latency           *latencyReservoir            // end-to-end delay samples (see latency_stats.go)
+	commandLatency    map[string]*latencyReservoir // per command type
+}
+
+// NetworkStats tracks network simulation statistics
+type NetworkStats struct {
+	TotalPackets     int64
+	DroppedPackets   int64
+	ReorderedPackets int64 // delivered before a packet sent earlier
+	AverageLatencyMs float64
+	MaxLatencyMs     float64
+	BytesTransferred int64
+
+	// Delay percentiles of delivered packets, overall and per command type
+	// (only commands sent through SimulateCommandPacket are broken down)
+	Latency        LatencyPercentiles
+	CommandLatency map[string]LatencyPercentiles
+
+	// Link budget (only populated once SetElevation has been called)
+	ElevationDeg        float64
+	SlantRangeKm        float64
+	SNRdB               float64
+	LatencyRateMsPerSec float64 // Doppler: time derivative of one-way latency
+	DopplerShiftHz      float64
+
+	// Fraction of the current pass elapsed, 0-1 (0 when no pass was started)
+	PassProgress float64
+}
+
+// NetworkCondition represents different network condition presets
+type NetworkCondition string
+
+const (
+	// LEO - Low Earth Orbit (typical: 550-2000 km altitude)
+	LEO NetworkCondition = "leo"
+	// MEO - Medium Earth Orbit (typical: 2000-35786 km altitude)
+	MEO NetworkCondition = "meo"
+	// GEO - Geostationary Orbit (35786 km altitude)
+	GEO NetworkCondition = "geo"
+	// DeepSpace - Beyond Earth orbit
+	DeepSpace NetworkCondition = "deep_space"
+	// Degraded - Simulates adverse conditions
+	Degraded NetworkCondition = "degraded"
+)
+
+// NewNetworkSimulator creates a new network simulator
+func NewNetworkSimulator() *NetworkSimulator {
+	return &NetworkSimulator{
+		enabled:           false,
+		condition:         LEO,
+		latencyMin:        10 * time.Millisecond,
+		latencyMax:        50 * time.Millisecond,
+		distribution:      Uniform,
+		packetLossRate:    0.01, // 1%
+		jitterRange:       5 * time.Millisecond,
+		bandwidthLimitKBs: 1024, // 1 MB/s
+	}
+}
+
+// SetCondition sets the network condition to a preset
+func (ns *NetworkSimulator) SetCondition(condition NetworkCondition) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.condition = condition
+
+	switch condition {
+	case LEO:
+		// LEO: 20-40ms latency, 0.5% packet loss
+		ns.latencyMin = 20 * time.Millisecond
+		ns.latencyMax = 40 * time.Millisecond
+		ns.distribution = Uniform
+		ns.packetLossRate = 0.005
+		ns.jitterRange = 5 * time.Millisecond
+		ns.bandwidthLimitKBs = 10240 // 10 MB/s
+
+	case MEO:
+		// MEO: 50-100ms latency, 1% packet loss
+		ns.latencyMin = 50 * time.Millisecond
+		ns.latencyMax = 100 * time.Millisecond
+		ns.distribution = Normal
+		ns.packetLossRate = 0.01
+		ns.jitterRange = 10 * time.Millisecond
+		ns.bandwidthLimitKBs = 5120 // 5 MB/s
+
+	case GEO:
+		// GEO: 240-280ms latency (round-trip ~500ms), log-normal tail, 2% packet loss
+		ns.latencyMin = 240 * time.Millisecond
+		ns.latencyMax = 280 * time.Millisecond
+		ns.distribution = LogNormal
+		ns.packetLossRate = 0.02
+		ns.jitterRange = 20 * time.Millisecond
+		ns.bandwidthLimitKBs = 2048 // 2 MB/s
+
+	case DeepSpace:
+		// Deep Space: seconds to minutes of latency, heavy Pareto tail
+		ns.latencyMin = 2 * time.Second
+		ns.latencyMax = 5 * time.Second
+		ns.distribution = Pareto
+		ns.packetLossRate = 0.05
+		ns.jitterRange = 500 * time.Millisecond
+		ns.bandwidthLimitKBs = 128 // 128 KB/s
+
+	case Degraded:
+		// Degraded: High latency, high packet loss (e.g., during solar storm)
+		ns.latencyMin = 100 * time.Millisecond
+		ns.latencyMax = 500 * time.Millisecond
+		ns.distribution = LogNormal
+		ns.packetLossRate = 0.15 // 15%
+		ns.jitterRange = 100 * time.Millisecond
+		ns.bandwidthLimitKBs = 256 // 256 KB/s
+	}
+
+	// Re-evaluate the link budget against the new orbit profile; the range
+	// jump between profiles is not motion, so restart the Doppler estimate
+	if ns.link.enabled {
+		ns.link.rateRefTime = time.Time{}
+		ns.setElevationLocked(ns.link.elevationDeg, time.Now())
+	}
+}
+
+// Enable enables network simulation
+func (ns *NetworkSimulator) Enable() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.enabled = true
+}
+
+// Disable disables network simulation
+func (ns *NetworkSimulator) Disable() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.enabled = false
+}
+
+// SimulatePacket simulates sending a packet through the network
+// Returns (success, latency, error)
+func (ns *NetworkSimulator) SimulatePacket(sizeBytes int) (bool, time.Duration, error) {
+	return ns.SimulateCommandPacket("", sizeBytes)
+}
+
+// SimulateCommandPacket simulates sending a packet carrying the given command type,
+// so its delay is also tracked in the per-command latency breakdown
+func (ns *NetworkSimulator) SimulateCommandPacket(command string, sizeBytes int) (bool, time.Duration, error) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if !ns.enabled {
+		return true, 0, nil
+	}
+
+	ns.stats.TotalPackets++
+	ns.stats.BytesTransferred += int64(sizeBytes)
+
+	// During a pass the elevation follows elapsed time; the link budget then adjusts
+	// loss/bandwidth/latency by elevation when enabled
+	ns.updatePass(time.Now())
+	lossRate, bandwidthKBs, extraLatency := ns.effectiveLinkParams()
+
+	// Simulate packet loss
+	if rand.Float64() < lossRate {
+		ns.stats.DroppedPackets++
+		return false, 0, fmt.Errorf("packet dropped (simulated loss)")
+	}
+
+	// Calculate latency with jitter
+	baseLatency := ns.sampleLatency()
+	jitter := time.Duration(0)
+	if ns.jitterRange > 0 {
+		jitter = time.Duration(rand.Int63n(int64(ns.jitterRange))) - ns.jitterRange/2
+	}
+	latency := baseLatency + jitter + extraLatency
+
+	// Update stats
+	latencyMs := float64(latency.Milliseconds())
+	if latencyMs > ns.stats.MaxLatencyMs {
+		ns.stats.MaxLatencyMs = latencyMs
+	}
+	// Running average
+	totalPackets := float64(ns.stats.TotalPackets - ns.stats.DroppedPackets)
+	ns.stats.AverageLatencyMs = (ns.stats.AverageLatencyMs*(totalPackets-1) + latencyMs) / totalPackets
+
+	// Simulate bandwidth limit
+	totalDelay := latency + transmissionTime(sizeBytes, bandwidthKBs)
+
+	// Hold some packets back so later packets can overtake them
+	hold := ns.reorderDelay()
+	totalDelay += hold
+	ns.trackDelivery(time.Now(), totalDelay, hold > 0)
+	ns.recordLatency(command, totalDelay)
+
+	return true, totalDelay, nil
+}
+
+// transmissionTime returns how long sizeBytes takes to serialize onto a link of bandwidthKBs
+// KB/s (1 KB = 1024 bytes), e.g. 1 MB over a 128 KB/s deep-space link takes 8s
+func transmissionTime(sizeBytes, bandwidthKBs int) time.Duration {
+	if sizeBytes <= 0 || bandwidthKBs <= 0 {
+		return 0
+	}
+	seconds := float64(sizeBytes) / (float64(bandwidthKBs) * 1024)
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// SimulateDelay simulates network delay (blocking)
+func (ns *NetworkSimulator) SimulateDelay(sizeBytes int) error {
+	return ns.SimulateCommandDelay("", sizeBytes)
+}
+
+// SimulateCommandDelay simulates network delay for a command (blocking)
+func (ns *NetworkSimulator) SimulateCommandDelay(command string, sizeBytes int) error {
+	return ns.SimulateCommandDelayContext(context.Background(), command, sizeBytes)
+}
+
+// SimulateCommandDelayContext simulates network delay for a command, blocking until the
+// delay elapses or ctx is done (returning ctx.Err())
+func (ns *NetworkSimulator) SimulateCommandDelayContext(ctx context.Context, command string, sizeBytes int) error {
+	success, delay, err := ns.SimulateCommandPacket(command, sizeBytes)
+	if !success {
+		return err
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return nil
+}
+
+// MaxDelay returns an upper bound on the delay SimulateCommandDelay adds for a packet of
+// sizeBytes under the current condition: capped base latency, jitter, link-budget latency,
+// transmission time and reorder hold. It is 0 while simulation is disabled.
+func (ns *NetworkSimulator) MaxDelay(sizeBytes int) time.Duration {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	if !ns.enabled {
+		return 0
+	}
+	_, bandwidthKBs, extraLatency := ns.effectiveLinkParams()
+	hold := time.Duration(0)
+	if ns.reorder.probability > 0 {
+		hold = ns.reorder.maxHold
+		if hold <= 0 {
+			hold = ns.latencyMax - ns.latencyMin + ns.jitterRange // reorderDelay's default
+		}
+	}
+	return ns.latencyMax*maxLatencyFactor + ns.jitterRange/2 + extraLatency + transmissionTime(sizeBytes, bandwidthKBs) + hold
+}
+
+// GetStats returns current network statistics
+func (ns *NetworkSimulator) GetStats() NetworkStats {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	stats := ns.stats
+	stats.Latency, stats.CommandLatency = ns.latencySnapshot()
+	stats.PassProgress = ns.passProgress(time.Now())
+	return stats
+}
+
+// ResetStats resets network statistics
+func (ns *NetworkSimulator) ResetStats() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.stats = NetworkStats{}
+	ns.reorder.heldUntil = time.Time{}
+	ns.latency = nil
+	ns.commandLatency = nil
+}
+
+// IsEnabled returns whether network simulation is enabled
+func (ns *NetworkSimulator) IsEnabled() bool {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	return ns.enabled
+}
+
+// GetPacketLossRate returns the current packet loss rate
+func (ns *NetworkSimulator) GetPacketLossRate() float64 {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	if ns.stats.TotalPackets == 0 {
+		return 0
+	}
+
+	return float64(ns.stats.DroppedPackets) / float64(ns.stats.TotalPackets)
+}
+
+// NetworkConfig is a read-only snapshot of the simulator's active parameters
+type NetworkConfig struct {
+	Enabled           bool                `json:"enabled"`
+	Condition         NetworkCondition    `json:"condition"`
+	LatencyMin        string              `json:"latencyMin"`
+	LatencyMax        string              `json:"latencyMax"`
+	Distribution      LatencyDistribution `json:"distribution"`
+	Jitter            string              `json:"jitter"`
+	PacketLossRate    float64             `json:"packetLossRate"`
+	BandwidthLimitKBs int                 `json:"bandwidthLimitKBs"`
+	LinkBudget        bool                `json:"linkBudget"`
+	ReorderRate       float64             `json:"reorderRate"`
+	ReorderMaxHold    string              `json:"reorderMaxHold,omitempty"`
+}
+
+// Config returns the currently active simulation parameters
+func (ns *NetworkSimulator) Config() NetworkConfig {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	config := NetworkConfig{
+		Enabled:           ns.enabled,
+		Condition:         ns.condition,
+		LatencyMin:        ns.latencyMin.String(),
+		LatencyMax:        ns.latencyMax.String(),
+		Distribution:      ns.distribution,
+		Jitter:            ns.jitterRange.String(),
+		PacketLossRate:    ns.packetLossRate,
+		BandwidthLimitKBs: ns.bandwidthLimitKBs,
+		LinkBudget:        ns.link.enabled,
+		ReorderRate:       ns.reorder.probability,
+	}
+	if ns.reorder.maxHold > 0 {
+		config.ReorderMaxHold = ns.reorder.maxHold.String()
+	}
+	return config
+}
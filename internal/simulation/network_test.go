@@ -0,0 +1,90 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransmissionTime(t *testing.T) {
+	tests := []struct {
+		name         string
+		sizeBytes    int
+		bandwidthKBs int
+		want         time.Duration
+	}{
+		{"empty payload", 0, 1024, 0},
+		{"no bandwidth configured", 1024, 0, 0},
+		{"1 KB at 1 MB/s", 1024, 1024, time.Second / 1024},
+		{"command packet over LEO", 512, 10240, 48828 * time.Nanosecond},
+		{"1 MB over LEO", 1 << 20, 10240, 100 * time.Millisecond},
+		{"1 MB over MEO", 1 << 20, 5120, 200 * time.Millisecond},
+		{"1 MB over GEO", 1 << 20, 2048, 500 * time.Millisecond},
+		{"1 MB over degraded link", 1 << 20, 256, 4 * time.Second},
+		{"1 MB over deep space", 1 << 20, 128, 8 * time.Second},
+		{"100 MB firmware over deep space", 100 << 20, 128, 800 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transmissionTime(tt.sizeBytes, tt.bandwidthKBs); got != tt.want {
+				t.Fatalf("transmissionTime(%d, %d) = %v, want %v", tt.sizeBytes, tt.bandwidthKBs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulatePacketDelayIsTransmissionTime(t *testing.T) {
+	// With no latency, jitter or loss the only delay left is serialization onto the link
+	ns := NewNetworkSimulator()
+	if err := ns.SetCustomCondition(0, 0, 0, 0, 256); err != nil {
+		t.Fatalf("SetCustomCondition: %v", err)
+	}
+	ns.Enable()
+
+	for _, size := range []int{64, 4096, 256 << 10, 1 << 20} {
+		ok, delay, err := ns.SimulatePacket(size)
+		if !ok || err != nil {
+			t.Fatalf("packet of %d bytes dropped: %v", size, err)
+		}
+		if want := transmissionTime(size, 256); delay != want {
+			t.Fatalf("delay for %d bytes = %v, want %v", size, delay, want)
+		}
+	}
+}
+
+func TestTransmissionTimeFollowsPresetAndLinkBudget(t *testing.T) {
+	const size = 1 << 20
+	tests := []struct {
+		condition    NetworkCondition
+		elevationDeg float64 // 0 leaves the link budget disabled
+		wantZenith   time.Duration
+	}{
+		{LEO, 0, 100 * time.Millisecond},
+		{GEO, 0, 500 * time.Millisecond},
+		{DeepSpace, 0, 8 * time.Second},
+		{LEO, 90, 100 * time.Millisecond},
+		{LEO, 10, 100 * time.Millisecond},
+		{Degraded, 15, 4 * time.Second},
+	}
+
+	for _, tt := range tests {
+		ns := NewNetworkSimulator()
+		ns.SetCondition(tt.condition)
+		want := tt.wantZenith
+		if tt.elevationDeg > 0 {
+			ns.SetElevation(tt.elevationDeg)
+			// Low elevations shrink the Shannon capacity relative to zenith, stretching the transfer
+			_, _, scale, _, _ := computeLink(linkProfiles[tt.condition], tt.elevationDeg)
+			want = transmissionTime(size, int(float64(ns.bandwidthLimitKBs)*scale))
+			if tt.elevationDeg < 90 && want <= tt.wantZenith {
+				t.Fatalf("%s at %.0f°: transfer %v should be slower than at zenith (%v)", tt.condition, tt.elevationDeg, want, tt.wantZenith)
+			}
+		}
+		ns.Enable()
+
+		// MaxDelay differs between payload sizes only by the transmission time
+		if got := ns.MaxDelay(size) - ns.MaxDelay(0); got != want {
+			t.Fatalf("%s at %.0f°: transfer of 1 MB = %v, want %v", tt.condition, tt.elevationDeg, got, want)
+		}
+	}
+}
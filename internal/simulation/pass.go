@@ -0,0 +1,67 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// passState 是衛星過境模擬的狀態：仰角隨過境時間變化，鏈路參數跟著改變。
+type passState struct {
+	start           time.Time
+	duration        time.Duration
+	maxElevationDeg float64
+}
+
+// passMaxElevationDeg 是 StartPass 的最高仰角（天頂過境）。
+const passMaxElevationDeg = 90.0
+
+// passEndElevationDeg 是過境結束後使用的仰角；低於地平線，鏈路中斷、封包全數掉包。
+const passEndElevationDeg = minElevationDeg - 1
+
+// StartPass 開始一次長度為 duration 的天頂過境：仰角從地平線依正弦曲線升到天頂（過境中點）再落回地平線，
+// 之後每個封包依當下仰角套用鏈路預算（低仰角延遲長、SNR 低、掉包多）。過境結束後衛星在地平線下，
+// 封包全數掉包，直到再次呼叫 StartPass 或 StopPass。
+func (ns *NetworkSimulator) StartPass(duration time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("pass duration must be positive, got %v", duration)
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	now := time.Now()
+	ns.pass = passState{start: now, duration: duration, maxElevationDeg: passMaxElevationDeg}
+	ns.link.rateRefTime = time.Time{}
+	ns.setElevationLocked(ns.passElevation(now), now)
+	return nil
+}
+
+// StopPass 結束過境模擬並停用鏈路預算，回到固定參數。
+func (ns *NetworkSimulator) StopPass() {
+	ns.DisableLinkBudget()
+}
+
+// passElevation 回傳過境中 now 時的仰角（呼叫端需持有鎖）。
+func (ns *NetworkSimulator) passElevation(now time.Time) float64 {
+	progress := ns.passProgress(now)
+	if progress >= 1 {
+		return passEndElevationDeg
+	}
+	return ns.pass.maxElevationDeg * math.Sin(math.Pi*progress)
+}
+
+// passProgress 回傳過境已經過的比例（0–1）；未啟用過境時為 0（呼叫端需持有鎖）。
+func (ns *NetworkSimulator) passProgress(now time.Time) float64 {
+	if ns.pass.duration <= 0 {
+		return 0
+	}
+	return math.Min(math.Max(float64(now.Sub(ns.pass.start))/float64(ns.pass.duration), 0), 1)
+}
+
+// updatePass 依目前時間更新過境中的仰角（呼叫端需持有寫鎖）。
+func (ns *NetworkSimulator) updatePass(now time.Time) {
+	if ns.pass.duration > 0 {
+		ns.setElevationLocked(ns.passElevation(now), now)
+	}
+}
@@ -0,0 +1,59 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// reorderState 是封包亂序模擬的設定與狀態。
+type reorderState struct {
+	probability float64       // 封包被額外延後的機率（0 表示停用）
+	maxHold     time.Duration // 被延後的封包最多額外延遲的時間
+	heldUntil   time.Time     // 被延後封包中最晚的抵達時間
+}
+
+// SetReordering 啟用封包亂序：每個封包有 probability 的機率額外延後 (0, maxHold] 的時間，
+// 讓之後送出的封包可能先抵達。maxHold 為 0 時使用目前延遲範圍加上 jitter；probability 為 0 即停用。
+func (ns *NetworkSimulator) SetReordering(probability float64, maxHold time.Duration) error {
+	if probability < 0 || probability > 1 {
+		return fmt.Errorf("reorder probability must be between 0 and 1, got %v", probability)
+	}
+	if maxHold < 0 {
+		return fmt.Errorf("reorder hold must not be negative, got %v", maxHold)
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.reorder.probability = probability
+	ns.reorder.maxHold = maxHold
+	return nil
+}
+
+// reorderDelay 回傳封包被亂序時額外延後的時間，未被選中時回傳 0（呼叫端需持有寫鎖）。
+func (ns *NetworkSimulator) reorderDelay() time.Duration {
+	if ns.reorder.probability <= 0 || rand.Float64() >= ns.reorder.probability {
+		return 0
+	}
+	maxHold := ns.reorder.maxHold
+	if maxHold <= 0 {
+		maxHold = ns.latencyMax - ns.latencyMin + ns.jitterRange
+	}
+	if maxHold <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(maxHold))) + 1
+}
+
+// trackDelivery 記錄封包的抵達時間；搶在先前被延後的封包之前抵達時計為亂序（呼叫端需持有寫鎖）。
+// 只計算由亂序模擬造成的超車，一般延遲 jitter 造成的先後差異不計入。
+func (ns *NetworkSimulator) trackDelivery(sentAt time.Time, delay time.Duration, held bool) {
+	delivery := sentAt.Add(delay)
+	if delivery.Before(ns.reorder.heldUntil) {
+		ns.stats.ReorderedPackets++
+	}
+	if held && delivery.After(ns.reorder.heldUntil) {
+		ns.reorder.heldUntil = delivery
+	}
+}
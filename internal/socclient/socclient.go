@@ -0,0 +1,399 @@
+// Package socclient 提供各服務將安全事件送往 Space-SOC 的共用傳送器。
+// 事件在背景送出，失敗時以指數退避（含 jitter）重試；重試後仍失敗的事件寫入本機 spool 目錄，
+// 待 SOC 恢復後依序重送，避免 SOC 短暫中斷就永久遺失異常與策略事件。
+package socclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 預設設定（可由環境變數覆寫，見 New）。
+const (
+	DefaultMaxAttempts    = 4
+	DefaultAttemptTimeout = 5 * time.Second
+	DefaultSpoolDir       = "soc-spool"
+	DefaultSpoolMaxFiles  = 10000
+	DefaultReplayInterval = 30 * time.Second
+)
+
+const (
+	// baseBackoff / maxBackoff 是重試間隔的起始值與上限：0.5s、1s、2s ... 最多 8s，每次取 [d/2, d] 的隨機值
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 8 * time.Second
+	// queueSize 是等待送出的事件數上限；佇列已滿時事件直接寫入 spool，不阻塞呼叫端
+	queueSize = 1000
+	// spoolDisabled 是 SOC_SPOOL_DIR 停用 spool 的值
+	spoolDisabled = "off"
+)
+
+// errPermanent 表示 SOC 拒絕事件（例如 400 驗證失敗），重試也不會成功。
+var errPermanent = errors.New("rejected by Space-SOC")
+
+// envelope 是一筆待送出的事件，也是 spool 檔案的內容。不包含 API key：每次送出時才從環境變數讀取。
+type envelope struct {
+	URL      string          `json:"url"`
+	OrgID    string          `json:"orgId,omitempty"`
+	Body     json.RawMessage `json:"body"`
+	QueuedAt time.Time       `json:"queuedAt"`
+}
+
+// Sender 以單一背景 worker 依序送出事件，並定期重送 spool 中的事件。
+// spool 中仍有事件時，新事件直接排在 spool 後面，確保 SOC 收到的事件維持原本順序。
+type Sender struct {
+	client         *http.Client
+	maxAttempts    int
+	attemptTimeout time.Duration
+	spoolDir       string
+	spoolMaxFiles  int
+	replayInterval time.Duration
+
+	queue  chan envelope
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+
+	spoolMu sync.Mutex
+	pending atomic.Int64
+	seq     atomic.Uint64
+}
+
+// New 建立傳送器並啟動背景 worker：
+//   - SOC_SEND_MAX_ATTEMPTS: 每筆事件的嘗試次數（預設 4）
+//   - SOC_SEND_TIMEOUT: 每次嘗試的逾時（預設 5s）
+//   - SOC_SPOOL_DIR: 重試後仍失敗的事件寫入的目錄（預設 soc-spool，設為 off 停用）
+//   - SOC_SPOOL_MAX_FILES: spool 最多保留的事件數（預設 10000，超過時丟棄新事件並記錄錯誤）
+//   - SOC_SPOOL_REPLAY_INTERVAL: 重送 spool 的間隔（預設 30s）
+func New() *Sender {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sender{
+		client:         &http.Client{},
+		maxAttempts:    intFromEnv("SOC_SEND_MAX_ATTEMPTS", DefaultMaxAttempts),
+		attemptTimeout: durationFromEnv("SOC_SEND_TIMEOUT", DefaultAttemptTimeout),
+		spoolMaxFiles:  intFromEnv("SOC_SPOOL_MAX_FILES", DefaultSpoolMaxFiles),
+		replayInterval: durationFromEnv("SOC_SPOOL_REPLAY_INTERVAL", DefaultReplayInterval),
+		queue:          make(chan envelope, queueSize),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	s.spoolDir = os.Getenv("SOC_SPOOL_DIR")
+	if s.spoolDir == "" {
+		s.spoolDir = DefaultSpoolDir
+	}
+	if s.spoolDir == spoolDisabled {
+		s.spoolDir = ""
+	} else if err := os.MkdirAll(s.spoolDir, 0o700); err != nil {
+		slog.Warn("無法建立 SOC 事件 spool 目錄，重試失敗的事件將被丟棄", "dir", s.spoolDir, "error", err)
+		s.spoolDir = ""
+	} else if files, err := s.spoolFiles(); err == nil && len(files) > 0 {
+		s.pending.Store(int64(len(files)))
+		slog.Info("發現尚未送出的 SOC 事件，將在背景重送", "dir", s.spoolDir, "pending", len(files))
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	if s.spoolDir != "" {
+		s.wg.Add(1)
+		go s.replayLoop()
+	}
+	return s
+}
+
+// intFromEnv 讀取正整數設定，無效或未設定時使用預設值。
+func intFromEnv(name string, def int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		slog.Warn("無效的 SOC 傳送設定，使用預設值", "name", name, "value", raw, "default", def)
+		return def
+	}
+	return v
+}
+
+// durationFromEnv 讀取時間長度設定，無效或未設定時使用預設值。
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil || v <= 0 {
+		slog.Warn("無效的 SOC 傳送設定，使用預設值", "name", name, "value", raw, "default", def)
+		return def
+	}
+	return v
+}
+
+// Send 將事件 POST 到 url 的工作排入背景佇列，不會阻塞呼叫端。orgID 非空時以 X-Org-ID header 指定組織。
+// 佇列已滿或傳送器已關閉時，事件直接寫入 spool。
+func (s *Sender) Send(url string, body []byte, orgID string) {
+	env := envelope{URL: url, OrgID: orgID, Body: body, QueuedAt: time.Now().UTC()}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.closed {
+		select {
+		case s.queue <- env:
+			return
+		default:
+			slog.Warn("SOC 事件佇列已滿，事件寫入 spool", "queueSize", queueSize)
+		}
+	}
+	s.spool(env)
+}
+
+// Close 停止背景 worker：正在重試的事件與佇列中尚未送出的事件寫入 spool，於下次啟動時重送。
+func (s *Sender) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 依序送出佇列中的事件；關閉時將剩餘事件寫入 spool。
+func (s *Sender) run() {
+	defer s.wg.Done()
+	for {
+		select {
+		case env := <-s.queue:
+			s.handle(env)
+		case <-s.ctx.Done():
+			for {
+				select {
+				case env := <-s.queue:
+					s.spool(env)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// handle 送出單一事件。spool 中已有事件時（SOC 尚未恢復）直接排入 spool，維持順序並避免每筆事件都等待重試。
+func (s *Sender) handle(env envelope) {
+	if s.spoolDir != "" && s.pending.Load() > 0 {
+		s.spool(env)
+		return
+	}
+
+	err := s.deliverWithRetry(env)
+	switch {
+	case err == nil:
+	case errors.Is(err, errPermanent):
+		slog.Error("Space-SOC 拒絕事件，不再重試", "url", env.URL, "error", err)
+	default:
+		slog.Warn("無法發送事件到 Space-SOC，事件寫入 spool", "url", env.URL, "attempts", s.maxAttempts, "error", err)
+		s.spool(env)
+	}
+}
+
+// deliverWithRetry 最多嘗試 maxAttempts 次，每次失敗後等待指數退避的時間；傳送器關閉時立即停止。
+func (s *Sender) deliverWithRetry(env envelope) error {
+	var err error
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		if err = s.deliver(env); err == nil || errors.Is(err, errPermanent) {
+			return err
+		}
+		if attempt == s.maxAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff(attempt)):
+		case <-s.ctx.Done():
+			return err
+		}
+	}
+	return err
+}
+
+// backoff 回傳第 attempt 次失敗後的等待時間：baseBackoff * 2^(attempt-1)（上限 maxBackoff），取 [d/2, d] 的隨機值，
+// 避免多個實例在 SOC 恢復時同時重送。
+func backoff(attempt int) time.Duration {
+	d := maxBackoff
+	if shift := attempt - 1; shift < 5 {
+		d = min(baseBackoff<<shift, maxBackoff)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// deliver 嘗試送出一次。網路錯誤、408、429 與 5xx 可重試；其他非 2xx 回應包裝 errPermanent。
+func (s *Sender) deliver(env envelope) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, env.URL, bytes.NewReader(env.Body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// 多租戶部署時，以此 key 決定事件寫入的組織
+	if apiKey := os.Getenv("SPACE_SOC_API_KEY"); apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	if env.OrgID != "" {
+		req.Header.Set("X-Org-ID", env.OrgID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("Space-SOC responded with status %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: status %d", errPermanent, resp.StatusCode)
+	}
+}
+
+// spool 將事件寫入 spool 目錄；檔名依寫入順序排序。spool 停用或已滿時記錄錯誤並丟棄事件。
+func (s *Sender) spool(env envelope) {
+	if s.spoolDir == "" {
+		slog.Error("無法發送事件到 Space-SOC，事件已丟棄（spool 已停用）", "url", env.URL)
+		return
+	}
+	if s.pending.Load() >= int64(s.spoolMaxFiles) {
+		slog.Error("SOC 事件 spool 已滿，事件已丟棄", "dir", s.spoolDir, "maxFiles", s.spoolMaxFiles)
+		return
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		slog.Error("無法序列化 SOC 事件，事件已丟棄", "error", err)
+		return
+	}
+
+	s.spoolMu.Lock()
+	defer s.spoolMu.Unlock()
+	name := fmt.Sprintf("%020d-%010d.json", time.Now().UnixNano(), s.seq.Add(1))
+	path := filepath.Join(s.spoolDir, name)
+	// 先寫入暫存檔再改名，避免重送時讀到寫到一半的檔案
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		slog.Error("無法寫入 SOC 事件 spool，事件已丟棄", "path", path, "error", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		slog.Error("無法寫入 SOC 事件 spool，事件已丟棄", "path", path, "error", err)
+		return
+	}
+	s.pending.Add(1)
+}
+
+// spoolFiles 回傳 spool 目錄中的事件檔，依寫入順序排序。
+func (s *Sender) spoolFiles() ([]string, error) {
+	entries, err := os.ReadDir(s.spoolDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// replayLoop 啟動時與每隔 replayInterval 重送 spool 中的事件。
+func (s *Sender) replayLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.replayInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.pending.Load() > 0 {
+			s.replay()
+		}
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// replay 依序重送 spool 中的事件（每筆只嘗試一次），遇到可重試的失敗即停止，等待下一輪以維持順序。
+func (s *Sender) replay() {
+	files, err := s.spoolFiles()
+	if err != nil {
+		slog.Error("無法讀取 SOC 事件 spool", "dir", s.spoolDir, "error", err)
+		return
+	}
+
+	sent := 0
+	for _, name := range files {
+		if s.ctx.Err() != nil {
+			break
+		}
+		path := filepath.Join(s.spoolDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.Error("無法讀取 SOC 事件 spool 檔案", "path", path, "error", err)
+			break
+		}
+
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			slog.Error("SOC 事件 spool 檔案格式錯誤，已刪除", "path", path, "error", err)
+		} else if err := s.deliver(env); errors.Is(err, errPermanent) {
+			slog.Error("Space-SOC 拒絕 spool 中的事件，已刪除", "path", path, "error", err)
+		} else if err != nil {
+			slog.Warn("Space-SOC 仍無法接收事件，稍後重送", "pending", s.pending.Load(), "error", err)
+			break
+		} else {
+			sent++
+		}
+
+		if err := os.Remove(path); err != nil {
+			slog.Error("無法刪除已送出的 SOC 事件 spool 檔案", "path", path, "error", err)
+			break
+		}
+		s.pending.Add(-1)
+	}
+	if sent > 0 {
+		slog.Info("已重送 spool 中的 SOC 事件", "sent", sent, "pending", s.pending.Load())
+	}
+}
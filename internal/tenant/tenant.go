@@ -0,0 +1,121 @@
+// Package tenant 提供 Space-SOC 與 OTA controller 共用的多租戶 API key 解析與 gin middleware。
+package tenant
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultOrgID 是單租戶模式（未設定 TENANT_API_KEYS）下所有資料所屬的組織。
+	DefaultOrgID = "default"
+
+	// ServiceOrgWildcard 標記服務金鑰：持有者可透過 X-Org-ID 代表任一組織寫入（例如 OTA controller、CI pipeline）。
+	ServiceOrgWildcard = "*"
+
+	orgContextKey     = "orgID"
+	serviceContextKey = "tenantServiceKey"
+)
+
+// Keys 將 API key 對應到組織 ID；為空時代表單租戶模式。
+type Keys map[string]string
+
+// LoadKeys 解析 TENANT_API_KEYS（格式: "key1=org-a,key2=org-b,svc-key=*"）。
+func LoadKeys() Keys {
+	keys := make(Keys)
+	raw := os.Getenv("TENANT_API_KEYS")
+	if raw == "" {
+		return keys
+	}
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			slog.Warn("忽略無效的 TENANT_API_KEYS 項目", "entry", entry)
+			continue
+		}
+		keys[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return keys
+}
+
+// MultiTenant 回傳是否設定了任何 API key。
+func (k Keys) MultiTenant() bool {
+	return len(k) > 0
+}
+
+// APIKey 從 X-API-Key 或 Authorization: Bearer 取得 API key。
+func APIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// Middleware 依 API key 解析請求所屬組織，並放入 gin context。
+// 未設定任何 key 時，所有請求都屬於 DefaultOrgID，以維持既有單租戶部署的行為。
+func (k Keys) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !k.MultiTenant() {
+			c.Set(orgContextKey, DefaultOrgID)
+			c.Next()
+			return
+		}
+
+		orgID, ok := k[APIKey(c)]
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid API key"})
+			c.Abort()
+			return
+		}
+
+		// 服務金鑰必須明確指定要代表的組織
+		if orgID == ServiceOrgWildcard {
+			orgID = strings.TrimSpace(c.GetHeader("X-Org-ID"))
+			if orgID == "" || orgID == ServiceOrgWildcard {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "service key requires X-Org-ID header"})
+				c.Abort()
+				return
+			}
+			c.Set(serviceContextKey, true)
+		}
+
+		c.Set(orgContextKey, orgID)
+		c.Next()
+	}
+}
+
+// RequireServiceKey 保護影響所有組織的管理端點（須在 Middleware 之後）：多租戶模式下只允許服務金鑰，
+// 租戶自己的 API key 回傳 403。單租戶模式下只有一個組織，不另外限制。
+func (k Keys) RequireServiceKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if k.MultiTenant() && !IsServiceKey(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint requires a service key"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// OrgID 回傳 Middleware 解析出的組織 ID。
+func OrgID(c *gin.Context) string {
+	return c.GetString(orgContextKey)
+}
+
+// IsServiceKey 回傳請求是否以服務金鑰驗證。
+func IsServiceKey(c *gin.Context) bool {
+	return c.GetBool(serviceContextKey)
+}
@@ -6,6 +6,9 @@ import (
 	"os"
 	"time"
 
+	"actinspace.org/internal/logging"
+	"actinspace.org/internal/server"
+	"actinspace.org/internal/simulation"
 	"github.com/gin-gonic/gin"
 	"actinspace.org/satellite-sim/internal/ota"
 )
@@ -23,8 +26,44 @@ type CommandResponse struct {
 	ReceivedAt time.Time `json:"receivedAt"`
 }
 
+// newNetworkSimulator 依 NETWORK_CONDITION（leo、meo、geo、deep_space、degraded，或 custom 搭配
+// NETWORK_CONDITION_FILE）建立並啟用網路模擬器；未設定時回傳 nil，指令立即回應。
+func newNetworkSimulator() *simulation.NetworkSimulator {
+	name := os.Getenv("NETWORK_CONDITION")
+	if name == "" {
+		return nil
+	}
+	condition, err := simulation.ParseNetworkCondition(name)
+	if err != nil {
+		log.Fatalf("無效的 NETWORK_CONDITION: %v", err)
+	}
+
+	sim := simulation.NewNetworkSimulator()
+	if condition == simulation.Custom {
+		path := os.Getenv("NETWORK_CONDITION_FILE")
+		if path == "" {
+			log.Fatalf("NETWORK_CONDITION=custom 需要設定 NETWORK_CONDITION_FILE")
+		}
+		custom, err := simulation.LoadCustomCondition(path)
+		if err != nil {
+			log.Fatalf("無法載入自訂網路狀況: %v", err)
+		}
+		if err := custom.Apply(sim); err != nil {
+			log.Fatalf("無效的自訂網路狀況: %v", err)
+		}
+	} else {
+		sim.SetCondition(condition)
+	}
+	sim.Enable()
+	log.Printf("網路模擬已啟用: %s", condition)
+	return sim
+}
+
 func main() {
-	r := gin.Default()
+	logger := logging.Setup("satellite-sim")
+
+	r := gin.New()
+	r.Use(gin.Recovery(), logging.Middleware(logger))
 
 	// 啟動 OTA client（如果配置了 OTA controller URL）
 	otaControllerURL := os.Getenv("OTA_CONTROLLER_URL")
@@ -34,7 +73,10 @@ func main() {
 			version = "v1.0.0"
 		}
 
-		otaClient := ota.NewClient(otaControllerURL, "satellite-sim", version)
+		otaClient, err := ota.NewClient(otaControllerURL, "satellite-sim", version)
+		if err != nil {
+			log.Fatalf("無法建立 OTA client: %v", err)
+		}
 		go otaClient.StartUpdateLoop(30 * time.Second) // 每 30 秒檢查一次
 		log.Printf("OTA client 已啟動，連接到: %s", otaControllerURL)
 	}
@@ -43,6 +85,51 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	networkSim := newNetworkSimulator()
+
+	// 網路模擬統計：封包數、掉包、延遲百分位數（未啟用時為 {"enabled": false}）
+	r.GET("/network/stats", func(c *gin.Context) {
+		if networkSim == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":   true,
+			"condition": networkSim.Config().Condition,
+			"stats":     networkSim.GetStats(),
+		})
+	})
+
+	// 過境模擬：仰角隨時間從地平線升到天頂再落下，結束後鏈路中斷（需已設定 NETWORK_CONDITION）
+	r.POST("/network/pass", func(c *gin.Context) {
+		if networkSim == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "network simulation is disabled"})
+			return
+		}
+		var req struct {
+			Duration string `json:"duration" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		duration, err := time.ParseDuration(req.Duration)
+		if err == nil {
+			err = networkSim.StartPass(duration)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"duration": duration.String(), "endsAt": time.Now().Add(duration).UTC()})
+	})
+	r.DELETE("/network/pass", func(c *gin.Context) {
+		if networkSim != nil {
+			networkSim.StopPass()
+		}
+		c.Status(http.StatusNoContent)
+	})
+
 	r.POST("/command", func(c *gin.Context) {
 		var req CommandRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -50,7 +137,19 @@ func main() {
 			return
 		}
 
-		log.Printf(`{"component":"satellite-sim","event":"command_received","command":"%s"}`, req.Command)
+		logging.Event(c.Request.Context(), "command_received", map[string]interface{}{"command": req.Command})
+
+		// 依目前軌道模擬往返延遲；模擬掉包時回傳 503
+		if networkSim != nil {
+			size := int(c.Request.ContentLength)
+			if size < 0 {
+				size = 0
+			}
+			if err := networkSim.SimulateCommandDelayContext(c.Request.Context(), req.Command, size); err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+				return
+			}
+		}
 
 		resp := CommandResponse{
 			Status:     "accepted",
@@ -65,7 +164,8 @@ func main() {
 		port = "8082"
 	}
 
-	if err := r.Run(":" + port); err != nil {
+	// 收到 SIGTERM 時等待處理中的指令（包含模擬的鏈路延遲）完成後再結束
+	if err := server.New(":"+port, r).Run(); err != nil {
 		log.Fatalf("satellite-sim server failed: %v", err)
 	}
 }
@@ -2,23 +2,28 @@ package ota
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
+
+	"actinspace.org/internal/logging"
+	"actinspace.org/supply-chain/signing-service/signer"
 )
 
 // UpdateResponse 定義 OTA controller 的回應。
 type UpdateResponse struct {
 	Available     bool      `json:"available"`
+	ReleaseID     uint      `json:"releaseId,omitempty"`
+	Channel       string    `json:"channel,omitempty"`
 	Version       string    `json:"version,omitempty"`
 	ImageDigest   string    `json:"imageDigest,omitempty"`
 	SBOMURL       string    `json:"sbomUrl,omitempty"`
 	Attestation   string    `json:"attestation,omitempty"`
+	RollbackTo    string    `json:"rollbackTo,omitempty"` // 目前版本已撤銷時建議回滾的版本
 	Message       string    `json:"message"`
 	UpdateAllowed bool      `json:"updateAllowed"`
 	DenialReason  string    `json:"denialReason,omitempty"`
@@ -30,22 +35,32 @@ type Client struct {
 	controllerURL  string
 	component      string
 	currentVersion string
-	signingSecret  string
+	verifier       *signer.Verifier // 依 attestation 的簽章版本驗證（Ed25519 公鑰或舊版共享 secret）
+	apiKey         string           // OTA controller 的租戶 API key（選填）
+	satelliteID    string           // 回報下載 / 套用狀態時使用的衛星 ID
+	channel        string           // 訂閱的發布通道（beta / stable）
 }
 
-// NewClient 創建新的 OTA 客戶端。
-func NewClient(controllerURL, component, currentVersion string) *Client {
-	secret := os.Getenv("SIGNING_SECRET")
-	if secret == "" {
-		secret = "dev-secret"
+// NewClient 創建新的 OTA 客戶端；簽章驗證設定（SIGNING_PUBLIC_KEY_FILE 等）無效時回傳錯誤。
+func NewClient(controllerURL, component, currentVersion string) (*Client, error) {
+	verifier, err := signer.LoadVerifier()
+	if err != nil {
+		return nil, err
+	}
+	satelliteID := os.Getenv("SATELLITE_ID")
+	if satelliteID == "" {
+		satelliteID = "SAT-001"
 	}
 
 	return &Client{
 		controllerURL:  controllerURL,
 		component:      component,
 		currentVersion: currentVersion,
-		signingSecret:  secret,
-	}
+		verifier:       verifier,
+		apiKey:         os.Getenv("OTA_API_KEY"),
+		satelliteID:    satelliteID,
+		channel:        os.Getenv("OTA_CHANNEL"),
+	}, nil
 }
 
 // CheckForUpdates 檢查是否有可用更新。
@@ -53,12 +68,23 @@ func (c *Client) CheckForUpdates() (*UpdateResponse, error) {
 	reqBody, err := json.Marshal(map[string]interface{}{
 		"component":      c.component,
 		"currentVersion": c.currentVersion,
+		"satelliteId":    c.satelliteID,
+		"channel":        c.channel,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.Post(c.controllerURL+"/api/v1/updates/check", "application/json", bytes.NewBuffer(reqBody))
+	req, err := http.NewRequest(http.MethodPost, c.controllerURL+"/api/v1/updates/check", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -72,12 +98,50 @@ func (c *Client) CheckForUpdates() (*UpdateResponse, error) {
 	return &updateResp, nil
 }
 
-// VerifySignature 驗證簽章。
+// ReportStatus 回報 release 的下載 / 套用狀態（"downloaded", "applied", "failed"），供 controller 判斷通道推進並更新衛星群狀態。
+func (c *Client) ReportStatus(releaseID uint, status, message string) error {
+	if releaseID == 0 {
+		return nil
+	}
+
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"satelliteId": c.satelliteID,
+		"status":      status,
+		"message":     message,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/v1/releases/%d/reports", c.controllerURL, releaseID), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// VerifySignature 依 attestation 的 version 驗證簽章（Ed25519 公鑰，或舊版共享 secret）。
 func (c *Client) VerifySignature(imageDigest, attestation string) (bool, error) {
 	// 解析 attestation（簡化版）
 	var meta struct {
-		Digest    string `json:"digest"`
-		Signature string `json:"signature"`
+		Version    int    `json:"version"`
+		Digest     string `json:"digest"`
+		SBOMDigest string `json:"sbomDigest"`
+		Signature  string `json:"signature"`
 	}
 
 	if err := json.Unmarshal([]byte(attestation), &meta); err != nil {
@@ -89,12 +153,9 @@ func (c *Client) VerifySignature(imageDigest, attestation string) (bool, error)
 		return false, fmt.Errorf("digest mismatch")
 	}
 
-	// 重新計算簽章
-	sigBytes := sha256.Sum256([]byte(meta.Digest + ":" + c.signingSecret))
-	expectedSignature := hex.EncodeToString(sigBytes[:])
-
-	if meta.Signature != expectedSignature {
-		return false, fmt.Errorf("signature verification failed")
+	// 依簽章版本驗證（Ed25519 以公鑰驗證，舊版重新計算共享 secret 簽章）；附 SBOM 時簽章涵蓋 SBOM digest
+	if err := c.verifier.Verify(meta.Version, signer.Payload(meta.Digest, meta.SBOMDigest), meta.Signature); err != nil {
+		return false, err
 	}
 
 	return true, nil
@@ -102,7 +163,7 @@ func (c *Client) VerifySignature(imageDigest, attestation string) (bool, error)
 
 // ApplyUpdate 應用更新（模擬）。
 func (c *Client) ApplyUpdate(updateResp *UpdateResponse) error {
-	log.Printf("開始應用更新: %s -> %s", c.currentVersion, updateResp.Version)
+	slog.Info("開始應用更新", "from", c.currentVersion, "to", updateResp.Version)
 
 	// 驗證簽章
 	if updateResp.Attestation != "" {
@@ -110,19 +171,20 @@ func (c *Client) ApplyUpdate(updateResp *UpdateResponse) error {
 		if err != nil || !valid {
 			return fmt.Errorf("簽章驗證失敗: %v", err)
 		}
-		log.Println("✅ 簽章驗證通過")
+		slog.Info("簽章驗證通過", "imageDigest", updateResp.ImageDigest)
 	}
 
 	// 模擬下載和應用更新
-	log.Printf("下載映像檔: %s", updateResp.ImageDigest)
+	slog.Info("下載映像檔", "imageDigest", updateResp.ImageDigest)
 	time.Sleep(1 * time.Second) // 模擬下載時間
+	c.report(updateResp.ReleaseID, "downloaded", "")
 
 	// 實際環境中，這裡會：
 	// 1. 下載新映像檔
 	// 2. 驗證 SBOM policy
 	// 3. 重啟服務或熱更新
 
-	log.Println("✅ 更新應用成功")
+	slog.Info("更新應用成功", "version", updateResp.Version)
 	c.currentVersion = updateResp.Version
 
 	return nil
@@ -133,33 +195,63 @@ func (c *Client) StartUpdateLoop(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	log.Printf("OTA client 已啟動，每 %v 檢查一次更新", interval)
+	slog.Info("OTA client 已啟動", "interval", interval, "satelliteId", c.satelliteID)
 
+	ctx := context.Background()
 	for range ticker.C {
 		updateResp, err := c.CheckForUpdates()
 		if err != nil {
-			log.Printf("檢查更新失敗: %v", err)
+			slog.Warn("檢查更新失敗", "error", err)
 			continue
 		}
 
+		// 沒有更新是常態，只在 debug 等級記錄
 		if !updateResp.Available {
-			log.Printf("無可用更新: %s", updateResp.Message)
+			slog.Debug("無可用更新", "version", c.currentVersion, "message", updateResp.Message)
 			continue
 		}
 
 		if !updateResp.UpdateAllowed {
-			log.Printf("更新被拒絕: %s", updateResp.DenialReason)
+			logging.Event(ctx, "ota_update_denied", map[string]interface{}{
+				"satelliteId": c.satelliteID,
+				"version":     updateResp.Version,
+				"reason":      updateResp.DenialReason,
+				"severity":    "medium",
+			})
 			continue
 		}
 
-		log.Printf("發現新版本: %s", updateResp.Version)
+		if updateResp.RollbackTo != "" {
+			slog.Warn("目前版本已撤銷，回滾", "version", c.currentVersion, "rollbackTo", updateResp.RollbackTo)
+		} else {
+			slog.Info("發現新版本", "version", updateResp.Version)
+		}
 
+		previous := c.currentVersion
 		if err := c.ApplyUpdate(updateResp); err != nil {
-			log.Printf("應用更新失敗: %v", err)
+			logging.Event(ctx, "ota_update_failed", map[string]interface{}{
+				"satelliteId": c.satelliteID,
+				"version":     updateResp.Version,
+				"error":       err.Error(),
+				"severity":    "high",
+			})
+			c.report(updateResp.ReleaseID, "failed", err.Error())
 			continue
 		}
 
-		log.Printf("成功更新到版本: %s", updateResp.Version)
+		logging.Event(ctx, "ota_update_applied", map[string]interface{}{
+			"satelliteId":     c.satelliteID,
+			"previousVersion": previous,
+			"version":         updateResp.Version,
+		})
+		c.report(updateResp.ReleaseID, "applied", "")
 	}
 }
 
+
+// report 回報狀態，失敗只記錄日誌，不影響更新流程。
+func (c *Client) report(releaseID uint, status, message string) {
+	if err := c.ReportStatus(releaseID, status, message); err != nil {
+		slog.Warn("回報狀態失敗", "status", status, "releaseId", releaseID, "error", err)
+	}
+}
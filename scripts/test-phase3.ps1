@@ -31,7 +31,7 @@ Write-Host ""
 Write-Host "2. 測試 SBOM Policy 檢查..." -ForegroundColor Yellow
 if (Test-Path "supply-chain/sbom/examples/satellite-sim-v1.0.0.cdx.json") {
     try {
-        go run supply-chain/sbom/cmd/check-sbom/main.go `
+        go run ./supply-chain/sbom/cmd/check-sbom `
             -sbom supply-chain/sbom/examples/satellite-sim-v1.0.0.cdx.json 2>&1 | Out-Null
         Write-Host "   ✅ SBOM Policy 檢查通過" -ForegroundColor Green
     } catch {
@@ -17,7 +17,7 @@ echo ""
 # 測試 SBOM policy 檢查
 echo "2. 測試 SBOM Policy 檢查..."
 if [ -f "supply-chain/sbom/examples/satellite-sim-v1.0.0.cdx.json" ]; then
-    go run supply-chain/sbom/cmd/check-sbom/main.go \
+    go run ./supply-chain/sbom/cmd/check-sbom \
         -sbom supply-chain/sbom/examples/satellite-sim-v1.0.0.cdx.json && \
         echo "   ✅ SBOM Policy 檢查通過" || echo "   ⚠️  SBOM Policy 有違規"
 else
@@ -27,14 +27,17 @@ echo ""
 
 # 註冊新版本
 echo "3. 註冊新版本到 OTA Controller..."
+# OTA Controller 會在註冊時驗證 attestation 簽章（此處使用舊版 sha256(digest:SIGNING_SECRET)，未設定 SIGNING_PUBLIC_KEY 時接受）
+IMAGE_DIGEST="sha256:131705145af9efa445b30033e041d53385aac048f8fc261a3a5fc3d9a0a71998"
+SIGNATURE=$(printf '%s:%s' "$IMAGE_DIGEST" "${SIGNING_SECRET:-dev-secret}" | sha256sum | cut -d' ' -f1)
 RELEASE_RESPONSE=$(curl -s -X POST http://localhost:8084/api/v1/releases \
     -H "Content-Type: application/json" \
     -d '{
         "component": "satellite-sim",
         "version": "v1.1.0",
-        "imageDigest": "sha256:test123456",
+        "imageDigest": "'"$IMAGE_DIGEST"'",
         "sbomUrl": "http://example.com/sbom.json",
-        "attestation": "{\"digest\":\"sha256:test123456\",\"signature\":\"test_sig\"}"
+        "attestation": "{\"digest\":\"'"$IMAGE_DIGEST"'\",\"signature\":\"'"$SIGNATURE"'\"}"
     }')
 
 RELEASE_ID=$(echo $RELEASE_RESPONSE | jq -r '.id')
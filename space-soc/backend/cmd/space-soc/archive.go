@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// registerArchiveRoutes 註冊封存 incident 的端點。
+// 封存只設定 ArchivedAt，不刪除資料，incident、事件與狀態變更紀錄仍保留供稽核。
+func registerArchiveRoutes(r *gin.Engine) {
+	// 封存已結案的 incident，預設列表不再顯示（includeArchived=true 可查回）
+	r.DELETE("/api/v1/incidents/:id", func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid incident ID"})
+			return
+		}
+
+		var incident Incident
+		orgID := tenant.OrgID(c)
+		if err := db.Where("org_id = ?", orgID).First(&incident, uint(id)).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+			return
+		}
+		if incident.ArchivedAt != nil {
+			c.JSON(http.StatusOK, incident)
+			return
+		}
+		if !isClosedStatus(incident.Status) {
+			c.JSON(http.StatusConflict, gin.H{"error": "only resolved or closed incidents can be archived", "status": incident.Status})
+			return
+		}
+
+		// 以目前狀態為條件更新，避免封存同時被重新開啟的 incident
+		now := time.Now().UTC()
+		result := db.Model(&Incident{}).
+			Where("id = ? AND org_id = ? AND status = ?", incident.ID, orgID, incident.Status).
+			Update("archived_at", now)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法封存 incident"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "incident status changed concurrently"})
+			return
+		}
+
+		incident.ArchivedAt = &now
+		c.JSON(http.StatusOK, incident)
+	})
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"actinspace.org/space-soc/backend/internal/audit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// auditEventTypes 是納入稽核紀錄的事件類型（核准、權限變更與各種人為覆寫）。
+var auditEventTypes = []string{
+	"release_approved",
+	"rbac_role_changed",
+	"dual_auth_requested",
+	"dual_auth_approved",
+	"mission_phase_changed", // 任務階段的執行期覆寫
+	"ml_model_changed",
+}
+
+// errAuditAppendOnly 在嘗試修改或刪除稽核紀錄時回傳。
+var errAuditAppendOnly = errors.New("audit records are append-only")
+
+// AuditRecord 是寫入時即串鏈的稽核紀錄（append-only）：hash 與 prevHash 在來源資料寫入的同一個 transaction 中計算並保存，
+// 匯出與驗證只讀取這張表，事後修改 events / incident_status_changes 不會影響既有的鏈。
+type AuditRecord struct {
+	ID         uint      `gorm:"primaryKey"`
+	OrgID      string    `gorm:"not null;uniqueIndex:idx_audit_org_seq"`
+	Seq        int       `gorm:"not null;uniqueIndex:idx_audit_org_seq"`
+	RecordedAt time.Time `gorm:"not null;index"` // 稽核項目的時間（計入 hash）
+	Type       string    `gorm:"not null;index"`
+	SourceID   uint
+	Data       string `gorm:"type:text"` // JSON
+	PrevHash   string `gorm:"not null"`
+	Hash       string `gorm:"not null"`
+}
+
+// BeforeUpdate 拒絕透過 GORM 修改稽核紀錄。
+func (AuditRecord) BeforeUpdate(*gorm.DB) error { return errAuditAppendOnly }
+
+// BeforeDelete 拒絕透過 GORM 刪除稽核紀錄。
+func (AuditRecord) BeforeDelete(*gorm.DB) error { return errAuditAppendOnly }
+
+// record 轉回 audit.Record。
+func (r AuditRecord) record() audit.Record {
+	data := map[string]interface{}{}
+	if r.Data != "" {
+		json.Unmarshal([]byte(r.Data), &data)
+	}
+	return audit.Record{
+		Seq:       r.Seq,
+		Timestamp: r.RecordedAt.UTC().Format(time.RFC3339Nano),
+		Type:      r.Type,
+		SourceID:  r.SourceID,
+		Data:      data,
+		PrevHash:  r.PrevHash,
+		Hash:      r.Hash,
+	}
+}
+
+// migrateAuditLog 在資料庫層以 trigger 禁止修改或刪除稽核紀錄，並在表為空時以既有資料建立初始鏈。
+func migrateAuditLog(db *gorm.DB) error {
+	var statements []string
+	switch db.Dialector.Name() {
+	case "postgres":
+		statements = []string{
+			`CREATE OR REPLACE FUNCTION audit_records_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_records is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+			`DROP TRIGGER IF EXISTS audit_records_append_only ON audit_records`,
+			`CREATE TRIGGER audit_records_append_only BEFORE UPDATE OR DELETE ON audit_records
+	FOR EACH ROW EXECUTE FUNCTION audit_records_append_only()`,
+		}
+	case "sqlite":
+		statements = []string{
+			`CREATE TRIGGER IF NOT EXISTS audit_records_no_update BEFORE UPDATE ON audit_records
+	BEGIN SELECT RAISE(ABORT, 'audit_records is append-only'); END`,
+			`CREATE TRIGGER IF NOT EXISTS audit_records_no_delete BEFORE DELETE ON audit_records
+	BEGIN SELECT RAISE(ABORT, 'audit_records is append-only'); END`,
+		}
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	return backfillAuditLog(db)
+}
+
+// backfillAuditLog 在稽核表仍為空時，依時間順序將既有的狀態變更與稽核事件串成各組織的初始鏈。
+func backfillAuditLog(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&AuditRecord{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+
+	var changes []IncidentStatusChange
+	if err := db.Order("changed_at ASC, id ASC").Find(&changes).Error; err != nil {
+		return err
+	}
+	var events []Event
+	if err := db.Where("event_type IN ?", auditEventTypes).Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		return err
+	}
+
+	type orgEntry struct {
+		orgID string
+		entry audit.Entry
+	}
+	pending := make([]orgEntry, 0, len(changes)+len(events))
+	for _, ch := range changes {
+		pending = append(pending, orgEntry{ch.OrgID, statusChangeAuditEntry(ch)})
+	}
+	for _, ev := range events {
+		pending = append(pending, orgEntry{ev.OrgID, eventAuditEntry(ev)})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].entry.Timestamp.Before(pending[j].entry.Timestamp)
+	})
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range pending {
+			if err := appendAuditRecord(tx, p.orgID, p.entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		log.Printf("已將 %d 筆既有稽核項目寫入稽核鏈", len(pending))
+	}
+	return err
+}
+
+// isAuditEventType 判斷事件類型是否需寫入稽核鏈。
+func isAuditEventType(eventType string) bool {
+	return slices.Contains(auditEventTypes, eventType)
+}
+
+// statusChangeAuditEntry 將 incident 狀態變更轉為稽核項目。
+func statusChangeAuditEntry(ch IncidentStatusChange) audit.Entry {
+	data := map[string]interface{}{
+		"incidentId": ch.IncidentID,
+		"oldStatus":  ch.OldStatus,
+		"newStatus":  ch.NewStatus,
+		"changedBy":  ch.ChangedBy,
+	}
+	if ch.Reason != "" {
+		data["reason"] = ch.Reason
+	}
+	return audit.Entry{
+		Timestamp: ch.ChangedAt,
+		Type:      "incident_status_change",
+		SourceID:  ch.ID,
+		Data:      data,
+	}
+}
+
+// eventAuditEntry 將稽核類型的事件轉為稽核項目。
+func eventAuditEntry(ev Event) audit.Entry {
+	data := map[string]interface{}{
+		"component":    ev.Component,
+		"operatorRole": ev.OperatorRole,
+		"message":      ev.Message,
+		"severity":     ev.Severity,
+	}
+	if ev.OperatorID != "" {
+		data["operatorId"] = ev.OperatorID
+	}
+	if ev.Metadata != "" {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(ev.Metadata), &metadata); err == nil {
+			data["metadata"] = metadata
+		}
+	}
+	return audit.Entry{
+		Timestamp: ev.CreatedAt,
+		Type:      ev.EventType,
+		SourceID:  ev.ID,
+		Data:      data,
+	}
+}
+
+// appendAuditRecord 在 tx 中將 entry 接到組織稽核鏈的尾端。PostgreSQL 上以 advisory lock 序列化同一組織的寫入
+// （跨實例有效，直到 transaction 結束）；(org_id, seq) 唯一索引確保鏈不會分岔。
+func appendAuditRecord(tx *gorm.DB, orgID string, entry audit.Entry) error {
+	if tx.Dialector.Name() == "postgres" {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "audit:"+orgID).Error; err != nil {
+			return err
+		}
+	}
+
+	// 時間取到微秒（PostgreSQL 的精度），資料先經過一次 JSON 往返，讓讀回的內容與計算 hash 時完全相同
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Microsecond)
+	raw, err := json.Marshal(entry.Data)
+	if err != nil {
+		return err
+	}
+	entry.Data = map[string]interface{}{}
+	json.Unmarshal(raw, &entry.Data)
+
+	var last AuditRecord
+	if err := tx.Where("org_id = ?", orgID).Order("seq DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+	prevHash := last.Hash
+	if last.ID == 0 {
+		prevHash = audit.GenesisHash
+	}
+
+	record := audit.Append(last.Seq, prevHash, entry)
+	return tx.Create(&AuditRecord{
+		OrgID:      orgID,
+		Seq:        record.Seq,
+		RecordedAt: entry.Timestamp,
+		Type:       record.Type,
+		SourceID:   record.SourceID,
+		Data:       string(raw),
+		PrevHash:   record.PrevHash,
+		Hash:       record.Hash,
+	}).Error
+}
+
+// parseTimeRange 解析 from/to（RFC3339）查詢參數；未帶的一端為零值。
+func parseTimeRange(c *gin.Context) (from, to time.Time, err error) {
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// loadAuditRecords 讀取組織在時間範圍內的稽核紀錄。範圍以序號界定（第一筆到最後一筆之間全部匯出），
+// 確保匯出的是鏈上連續的一段。
+func loadAuditRecords(orgID string, from, to time.Time) ([]audit.Record, error) {
+	bounds := db.Model(&AuditRecord{}).Where("org_id = ?", orgID)
+	if !from.IsZero() {
+		bounds = bounds.Where("recorded_at >= ?", from.UTC())
+	}
+	if !to.IsZero() {
+		bounds = bounds.Where("recorded_at <= ?", to.UTC())
+	}
+	var seqRange struct {
+		MinSeq *int
+		MaxSeq *int
+	}
+	if err := bounds.Select("MIN(seq) AS min_seq, MAX(seq) AS max_seq").Scan(&seqRange).Error; err != nil {
+		return nil, err
+	}
+	if seqRange.MinSeq == nil {
+		return nil, nil
+	}
+
+	var stored []AuditRecord
+	if err := db.Where("org_id = ? AND seq BETWEEN ? AND ?", orgID, *seqRange.MinSeq, *seqRange.MaxSeq).
+		Order("seq ASC").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	records := make([]audit.Record, 0, len(stored))
+	for _, r := range stored {
+		records = append(records, r.record())
+	}
+	return records, nil
+}
+
+// compareWithStored 確認匯出文件中的每筆紀錄與資料庫中同序號的紀錄 hash 相同。
+func compareWithStored(orgID string, export audit.Export) error {
+	if len(export.Records) == 0 {
+		return nil
+	}
+	first, last := export.Records[0].Seq, export.Records[len(export.Records)-1].Seq
+
+	var stored []AuditRecord
+	if err := db.Where("org_id = ? AND seq BETWEEN ? AND ?", orgID, first, last).Find(&stored).Error; err != nil {
+		return err
+	}
+	hashes := make(map[int]string, len(stored))
+	for _, r := range stored {
+		hashes[r.Seq] = r.Hash
+	}
+	for _, record := range export.Records {
+		if hashes[record.Seq] != record.Hash {
+			return fmt.Errorf("record %d does not match the stored audit log", record.Seq)
+		}
+	}
+	return nil
+}
+
+// registerAuditRoutes 註冊稽核匯出與驗證端點。
+func registerAuditRoutes(r *gin.Engine) {
+	// 匯出 hash-chained 稽核紀錄；sign=true 時以 SIGNING_SECRET 簽章鏈頭
+	r.GET("/api/v1/audit/export", func(c *gin.Context) {
+		from, to, err := parseTimeRange(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from/to must be RFC3339 timestamps"})
+			return
+		}
+
+		secret := os.Getenv("SIGNING_SECRET")
+		sign := c.Query("sign") == "true"
+		if sign && secret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "signing requested but SIGNING_SECRET is not configured"})
+			return
+		}
+
+		orgID := tenant.OrgID(c)
+		records, err := loadAuditRecords(orgID, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢稽核紀錄"})
+			return
+		}
+
+		export := audit.FromRecords(orgID, records)
+		export.From = c.Query("from")
+		export.To = c.Query("to")
+
+		// 資料庫中的鏈本身已被改動時不匯出（也不簽章）
+		if err := audit.Verify(export, ""); err != nil {
+			log.Printf("稽核鏈完整性檢查失敗 (org=%s): %v", orgID, err)
+			c.JSON(http.StatusConflict, gin.H{"error": "stored audit chain failed integrity check: " + err.Error()})
+			return
+		}
+		if sign {
+			export.Sign(secret, "space-soc")
+		}
+
+		c.JSON(http.StatusOK, export)
+	})
+
+	// 驗證匯出文件是否遭竄改（插入、刪除或修改任一紀錄都會失敗），並與資料庫中保存的鏈比對
+	r.POST("/api/v1/audit/verify", func(c *gin.Context) {
+		var export audit.Export
+		if err := c.ShouldBindJSON(&export); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		secret := ""
+		if export.Signature != "" {
+			secret = os.Getenv("SIGNING_SECRET")
+		}
+		if err := audit.Verify(export, secret); err != nil {
+			c.JSON(http.StatusOK, gin.H{"valid": false, "error": err.Error()})
+			return
+		}
+		if orgID := tenant.OrgID(c); export.OrgID != orgID {
+			c.JSON(http.StatusOK, gin.H{"valid": false, "error": "export belongs to a different organization"})
+			return
+		}
+		if err := compareWithStored(export.OrgID, export); err != nil {
+			c.JSON(http.StatusOK, gin.H{"valid": false, "error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"valid":             true,
+			"records":           len(export.Records),
+			"headHash":          export.HeadHash,
+			"signatureVerified": secret != "",
+		})
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// maxEventBatchSize 是單次批次寫入的事件上限。
+const maxEventBatchSize = 500
+
+// BatchItemResult 是批次寫入中單一事件的結果：成功時帶事件 ID（及觸發的 incident），失敗時帶錯誤訊息。
+type BatchItemResult struct {
+	Index    int              `json:"index"`
+	ID       uint             `json:"id,omitempty"`
+	Incident *IncidentSummary `json:"incident,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// registerBatchRoutes 註冊批次事件寫入端點。
+func registerBatchRoutes(r *gin.Engine, maxBody gin.HandlerFunc) {
+	// 以單一交易寫入多筆事件；每筆使用獨立 savepoint，個別失敗不影響其他事件
+	r.POST("/api/v1/events/batch", maxBody, func(c *gin.Context) {
+		var items []json.RawMessage
+		if err := c.ShouldBindJSON(&items); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "batch is empty"})
+			return
+		}
+		if len(items) > maxEventBatchSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("batch size %d exceeds limit %d", len(items), maxEventBatchSize)})
+			return
+		}
+
+		orgID := tenant.OrgID(c)
+		results := make([]BatchItemResult, len(items))
+		created := 0
+		var stored []*Event
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for i, raw := range items {
+				results[i].Index = i
+
+				var req IngestRequest
+				if err := json.Unmarshal(raw, &req); err != nil {
+					results[i].Error = err.Error()
+					continue
+				}
+				if err := binding.Validator.ValidateStruct(&req); err != nil {
+					results[i].Error = err.Error()
+					continue
+				}
+
+				var event *Event
+				var incidentSummary *IncidentSummary
+				err := tx.Transaction(func(itemTx *gorm.DB) error {
+					var err error
+					event, incidentSummary, err = ingestEvent(itemTx, req, orgID)
+					return err
+				})
+				if err != nil {
+					results[i].Error = "無法儲存事件"
+					continue
+				}
+				results[i].ID = event.ID
+				stored = append(stored, event)
+				results[i].Incident = incidentSummary
+				created++
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法儲存事件"})
+			return
+		}
+		// 交易提交後才計數與推送，避免訂閱者看到被回滾的 incident
+		for _, event := range stored {
+			countIngestedEvent("batch", event)
+		}
+		for _, result := range results {
+			publishIngestedIncident(orgID, result.Incident)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"results": results,
+			"created": created,
+			"failed":  len(items) - created,
+		})
+	})
+}
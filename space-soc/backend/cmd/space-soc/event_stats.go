@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultEventStatsWindow 是未指定 from / to 時統計的時間範圍（最近一小時）。
+const defaultEventStatsWindow = time.Hour
+
+// eventGroupColumns 是 groupBy 允許的維度與對應的欄位（皆有索引）；欄位名稱只來自此白名單，不會拼接用戶輸入。
+var eventGroupColumns = map[string]string{
+	"component":   "component",
+	"eventType":   "event_type",
+	"severity":    "severity",
+	"ruleID":      "rule_id",
+	"anomalyType": "anomaly_type",
+}
+
+// EventGroupCount 是單一群組的事件數量；Value 為空字串代表事件未設定該欄位。
+type EventGroupCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// eventGroupNames 回傳排序後的可用 groupBy 維度，用於錯誤訊息。
+func eventGroupNames() string {
+	names := make([]string, 0, len(eventGroupColumns))
+	for name := range eventGroupColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// registerEventStatsRoutes 註冊事件彙總端點，供時間序列面板取得各群組的事件數量而不需下載原始事件。
+func registerEventStatsRoutes(r *gin.Engine) {
+	// 依 groupBy 維度統計事件數量，支援與 GET /api/v1/events 相同的篩選參數
+	r.GET("/api/v1/events/stats", func(c *gin.Context) {
+		groupBy := c.Query("groupBy")
+		column, ok := eventGroupColumns[groupBy]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "groupBy must be one of " + eventGroupNames()})
+			return
+		}
+
+		from, to, err := parseTimeRange(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from/to must be RFC3339 timestamps"})
+			return
+		}
+		// 未指定範圍時只統計最近一小時，避免掃描整個事件表
+		if from.IsZero() && to.IsZero() {
+			to = time.Now().UTC()
+			from = to.Add(-defaultEventStatsWindow)
+		}
+
+		query := db.Model(&Event{}).Where("org_id = ?", tenant.OrgID(c))
+		query, err = applyEventFilters(c, query, from, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		groups := []EventGroupCount{}
+		err = query.
+			Select(column + " AS value, COUNT(*) AS count").
+			Group(column).
+			Order("count DESC, value ASC").
+			Scan(&groups).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法統計事件"})
+			return
+		}
+
+		var total int64
+		for _, group := range groups {
+			total += group.Count
+		}
+		response := gin.H{"groupBy": groupBy, "groups": groups, "total": total}
+		if !from.IsZero() {
+			response["from"] = from.UTC()
+		}
+		if !to.IsZero() {
+			response["to"] = to.UTC()
+		}
+		c.JSON(http.StatusOK, response)
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/internal/tenant"
+	"actinspace.org/space-soc/backend/internal/integrations"
+)
+
+// kafkaConsumer 從 Kafka topic 接收事件；未設定 KAFKA_CONSUMER_FILE 時為 nil。
+var kafkaConsumer *integrations.KafkaConsumer
+
+// loadKafkaConsumer 從 KAFKA_CONSUMER_FILE（KafkaConsumerConfig 的 JSON）建立 consumer。
+func loadKafkaConsumer() (*integrations.KafkaConsumer, error) {
+	path := os.Getenv("KAFKA_CONSUMER_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config integrations.KafkaConsumerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return integrations.NewKafkaConsumer(config, ingestConsumedEvent)
+}
+
+// ingestConsumedEvent 以與 POST /api/v1/events 相同的流程儲存 Kafka 事件並關聯 incident。
+// 訊息未指定組織時歸入預設組織。
+func ingestConsumedEvent(ctx context.Context, event integrations.ConsumedEvent) error {
+	orgID := event.OrgID
+	if orgID == "" {
+		orgID = tenant.DefaultOrgID
+	}
+	req := IngestRequest{
+		Component:    event.Component,
+		EventType:    event.EventType,
+		Command:      event.Command,
+		OperatorRole: event.OperatorRole,
+		OperatorID:   event.OperatorID,
+		Decision:     event.Decision,
+		Reason:       event.Reason,
+		Status:       event.Status,
+		Message:      event.Message,
+		Severity:     event.Severity,
+		RuleID:       event.RuleID,
+		AnomalyType:  event.AnomalyType,
+		ScenarioID:   event.ScenarioID,
+		Metadata:     event.Metadata,
+	}
+
+	stored, incidentSummary, err := ingestEvent(db.WithContext(ctx), req, orgID)
+	if err != nil {
+		return err
+	}
+	countIngestedEvent("kafka", stored)
+	publishIngestedIncident(orgID, incidentSummary)
+	return nil
+}
+
+// registerKafkaConsumerRoutes 註冊 Kafka consumer 的狀態與啟停端點；啟停影響所有組織的事件接收，需要服務金鑰。
+func registerKafkaConsumerRoutes(r *gin.Engine) {
+	requireService := tenantKeys.RequireServiceKey()
+
+	r.GET("/api/v1/kafka/consumer", func(c *gin.Context) {
+		if kafkaConsumer == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": kafkaConsumer.GetStats()})
+	})
+
+	r.POST("/api/v1/kafka/consumer/start", requireService, func(c *gin.Context) {
+		if kafkaConsumer == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "kafka consumer is not configured"})
+			return
+		}
+		kafkaConsumer.Start()
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": kafkaConsumer.GetStats()})
+	})
+
+	r.POST("/api/v1/kafka/consumer/stop", requireService, func(c *gin.Context) {
+		if kafkaConsumer == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "kafka consumer is not configured"})
+			return
+		}
+		if err := kafkaConsumer.Stop(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": kafkaConsumer.GetStats()})
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"actinspace.org/space-soc/backend/internal/integrations"
+)
+
+// kafkaProducer 將 incident 變更發布到 Kafka topic；未設定 KAFKA_PRODUCER_FILE 時為 nil。
+var kafkaProducer *integrations.KafkaProducer
+
+// loadKafkaProducer 從 KAFKA_PRODUCER_FILE（KafkaConfig 的 JSON）建立 producer。
+// 沒有 brokers 時使用只計數的 mock backend；發布的 topic 不可與 consumer 相同，否則 SOC 會重新接收自己發出的訊息。
+func loadKafkaProducer(consumerTopic string) (*integrations.KafkaProducer, error) {
+	path := os.Getenv("KAFKA_PRODUCER_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config integrations.KafkaConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if consumerTopic != "" && config.Topic == consumerTopic {
+		return nil, fmt.Errorf("producer topic %q must differ from the consumer topic", config.Topic)
+	}
+	return integrations.NewKafkaProducer(config)
+}
+
+// notifyKafka 將 incident 變更以 "incident.created" / "incident.status_changed" 事件類型發布，payload 與 webhook 相同。
+func notifyKafka(update IncidentUpdate) {
+	if kafkaProducer == nil {
+		return
+	}
+	payload := map[string]interface{}{
+		"type":       update.Type,
+		"orgId":      update.OrgID,
+		"incidentId": update.IncidentID,
+		"title":      update.Title,
+		"severity":   update.Severity,
+		"status":     update.Status,
+		"timestamp":  update.Timestamp,
+	}
+	if update.OldStatus != "" {
+		payload["oldStatus"] = update.OldStatus
+	}
+	if err := kafkaProducer.SendEvent("incident."+update.Type, payload); err != nil {
+		log.Printf("Kafka 發布 incident 變更失敗（incident %d）: %v", update.IncidentID, err)
+	}
+}
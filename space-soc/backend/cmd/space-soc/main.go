@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,6 +11,12 @@ import (
 	"strconv"
 	"time"
 
+	"actinspace.org/internal/database"
+	"actinspace.org/internal/health"
+	"actinspace.org/internal/logging"
+	"actinspace.org/internal/middleware"
+	"actinspace.org/internal/server"
+	"actinspace.org/internal/tenant"
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -17,41 +25,62 @@ import (
 
 // Event 定義 Space-SOC 儲存的事件格式。
 type Event struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Component    string    `gorm:"not null;index" json:"component"`
-	EventType    string    `gorm:"not null;index" json:"eventType"`
-	Command      string    `gorm:"index" json:"command,omitempty"`
-	OperatorRole string    `gorm:"index" json:"operatorRole,omitempty"`
-	Decision     string    `json:"decision,omitempty"`
-	Reason       string    `json:"reason,omitempty"`
-	Status       string    `json:"status,omitempty"`
-	Message      string    `json:"message,omitempty"`
-	Severity     string    `gorm:"index" json:"severity,omitempty"` // "low", "medium", "high", "critical"
-	RuleID       string    `json:"ruleID,omitempty"`
-	AnomalyType  string    `json:"anomalyType,omitempty"`
-	ScenarioID   string    `gorm:"index" json:"scenarioID,omitempty"` // 關聯的威脅場景
-	IncidentID   *uint     `gorm:"index" json:"incidentID,omitempty"` // 關聯的 incident
-	Metadata     string    `gorm:"type:text" json:"metadata,omitempty"` // JSON string
-	CreatedAt    time.Time `gorm:"index" json:"createdAt"`
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	OrgID        string        `gorm:"not null;index;default:default" json:"orgId"` // 所屬組織（租戶）
+	Component    string        `gorm:"not null;index" json:"component"`
+	EventType    string        `gorm:"not null;index" json:"eventType"`
+	Command      string        `gorm:"index" json:"command,omitempty"`
+	OperatorRole string        `gorm:"index" json:"operatorRole,omitempty"`
+	OperatorID   string        `gorm:"index" json:"operatorId,omitempty"` // 發出指令的操作員（JWT sub）
+	Decision     string        `json:"decision,omitempty"`
+	Reason       string        `json:"reason,omitempty"`
+	Status       string        `json:"status,omitempty"`
+	Message      string        `json:"message,omitempty"`
+	Severity     string        `gorm:"index" json:"severity,omitempty"` // "low", "medium", "high", "critical"
+	RuleID       string        `gorm:"index" json:"ruleID,omitempty"`
+	AnomalyType  string        `gorm:"index" json:"anomalyType,omitempty"`
+	ScenarioID   string        `gorm:"index" json:"scenarioID,omitempty"` // 關聯的威脅場景
+	IncidentID   *uint         `gorm:"index" json:"incidentID,omitempty"` // 關聯的 incident
+	Metadata     EventMetadata `json:"metadata,omitempty"`                // JSON 字串（PostgreSQL 上為 jsonb，見 metadata.go）
+	CreatedAt    time.Time     `gorm:"index" json:"createdAt"`
 }
 
 // Incident 定義安全事件。
 type Incident struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Title       string    `gorm:"not null" json:"title"`
-	Description string    `gorm:"type:text" json:"description"`
-	Severity    string    `gorm:"not null;index" json:"severity"`            // "low", "medium", "high", "critical"
-	Status      string    `gorm:"not null;index;default:open" json:"status"` // "open", "investigating", "resolved", "closed"
-	ScenarioID  string    `gorm:"index" json:"scenarioID,omitempty"`         // 關聯的威脅場景
-	Events      []Event   `gorm:"foreignKey:IncidentID" json:"events,omitempty"`
-	CreatedAt   time.Time `gorm:"index" json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	OrgID       string     `gorm:"not null;index;default:default" json:"orgId"` // 所屬組織（租戶）
+	Title       string     `gorm:"not null" json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	Severity    string     `gorm:"not null;index" json:"severity"`            // "low", "medium", "high", "critical"
+	Status      string     `gorm:"not null;index;default:open" json:"status"` // "open", "investigating", "resolved", "closed"
+	ScenarioID  string     `gorm:"index" json:"scenarioID,omitempty"`         // 關聯的威脅場景
+	TemplateKey string     `gorm:"index" json:"templateKey,omitempty"`        // 產生標題/描述的 incident template
+	PlaybookKey string     `gorm:"index" json:"playbookKey,omitempty"`        // 關聯的處置 playbook
+	Playbook    *Playbook  `gorm:"-" json:"playbook,omitempty"`               // 查詢單一 incident 時附上
+	Events      []Event    `gorm:"foreignKey:IncidentID" json:"events,omitempty"`
+	CreatedAt   time.Time  `gorm:"index" json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	ResolvedAt  *time.Time `json:"resolvedAt,omitempty"`              // 進入 resolved / closed 的時間，重新開啟時清除
+	ArchivedAt  *time.Time `gorm:"index" json:"archivedAt,omitempty"` // 封存時間（DELETE /api/v1/incidents/:id），回到處理中狀態時清除
 }
 
-// SoftwarePosture 定義組件的軟體姿態。
+// IncidentStatusChange 記錄 incident 的每次狀態轉換（供稽核使用）。
+type IncidentStatusChange struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	OrgID      string    `gorm:"not null;index;default:default" json:"orgId"`
+	IncidentID uint      `gorm:"not null;index" json:"incidentId"`
+	OldStatus  string    `json:"oldStatus"`
+	NewStatus  string    `gorm:"not null" json:"newStatus"`
+	ChangedBy  string    `json:"changedBy,omitempty"` // "api"、"auto-correlation" 或重新開啟的操作者
+	Reason     string    `gorm:"type:text" json:"reason,omitempty"`
+	ChangedAt  time.Time `gorm:"index" json:"changedAt"`
+}
+
+// SoftwarePosture 定義組件的軟體姿態（每個組織各自一份）。
 type SoftwarePosture struct {
 	ID              uint      `gorm:"primaryKey" json:"id"`
-	Component       string    `gorm:"not null;uniqueIndex" json:"component"` // satellite-sim, ttc-gateway, etc.
+	OrgID           string    `gorm:"not null;uniqueIndex:idx_posture_org_component;default:default" json:"orgId"` // 所屬組織（租戶）
+	Component       string    `gorm:"not null;uniqueIndex:idx_posture_org_component" json:"component"`             // satellite-sim, ttc-gateway, etc.
 	CurrentVersion  string    `gorm:"not null" json:"currentVersion"`
 	LatestVersion   string    `json:"latestVersion,omitempty"`
 	ImageDigest     string    `json:"imageDigest,omitempty"`
@@ -70,6 +99,7 @@ type IngestRequest struct {
 	EventType    string                 `json:"eventType" binding:"required"`
 	Command      string                 `json:"command,omitempty"`
 	OperatorRole string                 `json:"operatorRole,omitempty"`
+	OperatorID   string                 `json:"operatorId,omitempty"`
 	Decision     string                 `json:"decision,omitempty"`
 	Reason       string                 `json:"reason,omitempty"`
 	Status       string                 `json:"status,omitempty"`
@@ -83,6 +113,9 @@ type IngestRequest struct {
 
 var db *gorm.DB
 
+// tenantKeys 將 API key 對應到組織 ID；為空時代表單租戶模式。
+var tenantKeys tenant.Keys
+
 func initDB() {
 	var err error
 	var dialector gorm.Dialector
@@ -100,20 +133,72 @@ func initDB() {
 	if err != nil {
 		log.Fatalf("無法連接到資料庫: %v", err)
 	}
+	if err := database.ConfigurePool(db); err != nil {
+		log.Fatalf("無法設定資料庫連線池: %v", err)
+	}
 
-	// 自動遷移
-	if err := db.AutoMigrate(&Event{}, &Incident{}, &SoftwarePosture{}); err != nil {
+	// 自動遷移（metadata 欄位需先轉為 jsonb）
+	if err := migrateEventMetadata(db); err != nil {
+		log.Fatalf("無法遷移事件 metadata 欄位: %v", err)
+	}
+	if err := db.AutoMigrate(&Event{}, &Incident{}, &IncidentStatusChange{}, &IncidentTemplate{}, &Playbook{}, &SoftwarePosture{}, &AuditRecord{}); err != nil {
 		log.Fatalf("資料庫遷移失敗: %v", err)
 	}
+	// 軟體姿態改為依組織區分，移除舊的 component 全域唯一索引
+	if db.Migrator().HasIndex(&SoftwarePosture{}, "idx_software_postures_component") {
+		if err := db.Migrator().DropIndex(&SoftwarePosture{}, "idx_software_postures_component"); err != nil {
+			log.Fatalf("無法移除舊的軟體姿態索引: %v", err)
+		}
+	}
+	if err := migrateAuditLog(db); err != nil {
+		log.Fatalf("無法初始化稽核紀錄: %v", err)
+	}
+	if err := configureEventSearch(db); err != nil {
+		log.Fatalf("無效的事件搜尋設定: %v", err)
+	}
 
 	log.Println("資料庫初始化完成")
 }
 
-// createOrUpdateIncident 根據事件創建或更新 incident。
-func createOrUpdateIncident(req IngestRequest, db *gorm.DB) *Incident {
+// correlationWindow 是關聯新事件時可重用的 incident 最後更新時間範圍（INCIDENT_CORRELATION_WINDOW，預設 1h）；
+// 0 代表不限時間。
+var correlationWindow = time.Hour
+
+// loadCorrelationWindow 讀取 INCIDENT_CORRELATION_WINDOW（Go duration，例如 "30m"、"2h"）。
+func loadCorrelationWindow() (time.Duration, error) {
+	v := os.Getenv("INCIDENT_CORRELATION_WINDOW")
+	if v == "" {
+		return time.Hour, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid INCIDENT_CORRELATION_WINDOW %q", v)
+	}
+	return d, nil
+}
+
+// IncidentSummary 是事件觸發 incident 時隨 POST /api/v1/events 回應附上的精簡資訊。
+type IncidentSummary struct {
+	ID             uint   `json:"id"`
+	Title          string `json:"title"`
+	Severity       string `json:"severity"`
+	Status         string `json:"status"`
+	Created        bool   `json:"created"`                  // 此事件是否建立了新的 incident（false 代表關聯到既有 incident）
+	PreviousStatus string `json:"previousStatus,omitempty"` // 此事件使既有 incident 自動升級時的原狀態
+}
+
+// createOrUpdateIncident 根據事件創建或更新 incident（僅在同一組織內關聯），並回傳是否為新建立的 incident，
+// 以及既有 incident 因此自動升級時的原狀態（未變更時為空字串）。
+// 只有在關聯時間窗內更新過的 incident 會被重用，較舊的 incident 保持不變並另建新的 incident。
+func createOrUpdateIncident(req IngestRequest, orgID string, db *gorm.DB) (*Incident, bool, string) {
+	now := time.Now().UTC()
+
 	// 查找是否有相關的開放 incident
 	var existingIncident Incident
-	query := db.Where("status IN ?", []string{"open", "investigating"})
+	query := db.Where("org_id = ? AND status IN ?", orgID, []string{"open", "investigating"})
+	if correlationWindow > 0 {
+		query = query.Where("updated_at >= ?", now.Add(-correlationWindow))
+	}
 
 	if req.ScenarioID != "" {
 		query = query.Where("scenario_id = ?", req.ScenarioID)
@@ -122,52 +207,172 @@ func createOrUpdateIncident(req IngestRequest, db *gorm.DB) *Incident {
 		query = query.Where("severity = ?", req.Severity)
 	}
 
-	query.First(&existingIncident)
-
-	now := time.Now().UTC()
+	query.Order("updated_at DESC").First(&existingIncident)
 
 	if existingIncident.ID == 0 {
-		// 創建新 incident
+		// 創建新 incident（有對應 template 時使用 template 的標題與描述）
 		title := fmt.Sprintf("Security Incident: %s", req.EventType)
 		if req.Severity == "critical" {
 			title = fmt.Sprintf("CRITICAL: %s", req.EventType)
 		}
+		description := fmt.Sprintf("Detected %s event from %s. %s", req.EventType, req.Component, req.Message)
+		severity := req.Severity
+		templateKey := ""
+
+		if tmpl := findIncidentTemplate(db, orgID, req); tmpl != nil {
+			title, description = tmpl.render(req)
+			if severity == "" {
+				severity = tmpl.DefaultSeverity
+			}
+			templateKey = tmpl.Key
+		}
 
 		incident := Incident{
+			OrgID:       orgID,
 			Title:       title,
-			Description: fmt.Sprintf("Detected %s event from %s. %s", req.EventType, req.Component, req.Message),
-			Severity:    req.Severity,
+			Description: description,
+			Severity:    severity,
 			Status:      "open",
 			ScenarioID:  req.ScenarioID,
+			TemplateKey: templateKey,
 			CreatedAt:   now,
 			UpdatedAt:   now,
 		}
+		if playbook := findPlaybook(db, orgID, req); playbook != nil {
+			incident.PlaybookKey = playbook.Key
+		}
 
 		if err := db.Create(&incident).Error; err != nil {
 			log.Printf("無法創建 incident: %v", err)
-			return nil
+			return nil, false, ""
 		}
 
-		return &incident
+		return &incident, true, ""
 	} else {
-		// 更新現有 incident
+		// 更新現有 incident（尚未關聯 playbook 時依新事件補上）
 		existingIncident.UpdatedAt = now
+		if existingIncident.PlaybookKey == "" {
+			if playbook := findPlaybook(db, orgID, req); playbook != nil {
+				existingIncident.PlaybookKey = playbook.Key
+			}
+		}
+		previousStatus := ""
 		if existingIncident.Status == "open" && req.Severity == "critical" {
+			previousStatus = existingIncident.Status
 			existingIncident.Status = "investigating"
+			recordStatusChange(db, existingIncident, previousStatus, "auto-correlation", "")
 		}
 		db.Save(&existingIncident)
-		return &existingIncident
+		return &existingIncident, false, previousStatus
+	}
+}
+
+// ingestEvent 儲存單一事件：高嚴重性事件自動創建或更新 incident，OTA 事件更新軟體姿態。
+// 觸發 incident 時回傳 incident 摘要，否則為 nil。
+func ingestEvent(db *gorm.DB, req IngestRequest, orgID string) (*Event, *IncidentSummary, error) {
+	// 將 metadata 轉換為 JSON 字串
+	var metadataJSON string
+	if req.Metadata != nil {
+		metadataBytes, _ := json.Marshal(req.Metadata)
+		metadataJSON = string(metadataBytes)
+	}
+
+	event := Event{
+		OrgID:        orgID,
+		Component:    req.Component,
+		EventType:    req.EventType,
+		Command:      req.Command,
+		OperatorRole: req.OperatorRole,
+		OperatorID:   req.OperatorID,
+		Decision:     req.Decision,
+		Reason:       req.Reason,
+		Status:       req.Status,
+		Message:      req.Message,
+		Severity:     req.Severity,
+		RuleID:       req.RuleID,
+		AnomalyType:  req.AnomalyType,
+		ScenarioID:   req.ScenarioID,
+		Metadata:     EventMetadata(metadataJSON),
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	// 如果是高嚴重性事件，自動創建或更新 incident
+	var incidentSummary *IncidentSummary
+	if req.Severity == "high" || req.Severity == "critical" {
+		incident, created, previousStatus := createOrUpdateIncident(req, orgID, db)
+		if incident != nil {
+			event.IncidentID = &incident.ID
+			incidentSummary = &IncidentSummary{
+				ID:             incident.ID,
+				Title:          incident.Title,
+				Severity:       incident.Severity,
+				Status:         incident.Status,
+				Created:        created,
+				PreviousStatus: previousStatus,
+			}
+		}
+	}
+
+	// 如果是 OTA 相關事件，更新軟體姿態
+	if req.EventType == "release_approved" || req.EventType == "update_applied" {
+		if component, ok := req.Metadata["component"].(string); ok {
+			if version, ok := req.Metadata["version"].(string); ok {
+				imageDigest := ""
+				if digest, ok := req.Metadata["imageDigest"].(string); ok {
+					imageDigest = digest
+				}
+				updateSoftwarePosture(orgID, component, version, imageDigest, db)
+			}
+		}
+	}
+
+	// 稽核類型的事件與其稽核紀錄在同一個 transaction 中寫入
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		if isAuditEventType(event.EventType) {
+			return appendAuditRecord(tx, orgID, eventAuditEntry(event))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
+	return &event, incidentSummary, nil
 }
 
-// updateSoftwarePosture 更新組件的軟體姿態。
-func updateSoftwarePosture(component, version, imageDigest string, db *gorm.DB) {
+// recordStatusChange 寫入 incident 狀態轉換紀錄，並同時接上稽核鏈。
+func recordStatusChange(db *gorm.DB, incident Incident, oldStatus, changedBy, reason string) {
+	change := IncidentStatusChange{
+		OrgID:      incident.OrgID,
+		IncidentID: incident.ID,
+		OldStatus:  oldStatus,
+		NewStatus:  incident.Status,
+		ChangedBy:  changedBy,
+		Reason:     reason,
+		ChangedAt:  time.Now().UTC(),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&change).Error; err != nil {
+			return err
+		}
+		return appendAuditRecord(tx, change.OrgID, statusChangeAuditEntry(change))
+	})
+	if err != nil {
+		log.Printf("無法記錄 incident 狀態變更: %v", err)
+	}
+}
+
+// updateSoftwarePosture 更新組織內組件的軟體姿態。
+func updateSoftwarePosture(orgID, component, version, imageDigest string, db *gorm.DB) {
 	var posture SoftwarePosture
 
-	err := db.Where("component = ?", component).First(&posture).Error
+	err := db.Where("org_id = ? AND component = ?", orgID, component).First(&posture).Error
 	if err != nil {
 		// 創建新記錄
 		posture = SoftwarePosture{
+			OrgID:          orgID,
 			Component:      component,
 			CurrentVersion: version,
 			ImageDigest:    imageDigest,
@@ -186,16 +391,121 @@ func updateSoftwarePosture(component, version, imageDigest string, db *gorm.DB)
 	}
 }
 
+// applyEventFilters 套用事件查詢共用的篩選參數（component、eventType、command、operatorId、q、metadata.<key>、severity、minSeverity）
+// 與時間範圍；參數無效時回傳可直接回應給用戶端的錯誤。
+func applyEventFilters(c *gin.Context, query *gorm.DB, from, to time.Time) (*gorm.DB, error) {
+	if component := c.Query("component"); component != "" {
+		query = query.Where("component = ?", component)
+	}
+	if eventType := c.Query("eventType"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if command := c.Query("command"); command != "" {
+		query = query.Where("command = ?", command)
+	}
+	if operatorID := c.Query("operatorId"); operatorID != "" {
+		query = query.Where("operator_id = ?", operatorID)
+	}
+	// 全文搜尋：q 比對 message、reason、command（見 search.go）
+	query, err := applyEventSearch(query, c.Query("q"))
+	if err != nil {
+		return nil, err
+	}
+	// metadata.<key>=<value>（見 metadata.go）
+	if query, err = applyMetadataFilters(query, c.Request.URL.Query()); err != nil {
+		return nil, err
+	}
+
+	// 嚴重性：severity 完全符合，minSeverity 為該等級以上（low < medium < high < critical）
+	if severity := c.Query("severity"); severity != "" {
+		if !validSeverities[severity] {
+			return nil, errors.New("severity must be one of low, medium, high, critical")
+		}
+		query = query.Where("severity = ?", severity)
+	}
+	if minSeverity := c.Query("minSeverity"); minSeverity != "" {
+		severities, ok := severitiesAtLeast(minSeverity)
+		if !ok {
+			return nil, errors.New("minSeverity must be one of low, medium, high, critical")
+		}
+		query = query.Where("severity IN ?", severities)
+	}
+
+	// 時間範圍（轉為 UTC 與儲存的 created_at 比較；兩端皆包含）
+	switch {
+	case !from.IsZero() && !to.IsZero():
+		if to.Before(from) {
+			return nil, errors.New("to must not be before from")
+		}
+		query = query.Where("created_at BETWEEN ? AND ?", from.UTC(), to.UTC())
+	case !from.IsZero():
+		query = query.Where("created_at >= ?", from.UTC())
+	case !to.IsZero():
+		query = query.Where("created_at <= ?", to.UTC())
+	}
+	return query, nil
+}
+
 func main() {
+	logger := logging.Setup("space-soc")
+
 	initDB()
 
-	r := gin.Default()
+	retention, err := loadRetentionPolicy()
+	if err != nil {
+		log.Fatalf("無效的事件保留設定: %v", err)
+	}
+	if retention != nil {
+		startRetentionJob(db, retention)
+	}
+
+	correlationWindow, err = loadCorrelationWindow()
+	if err != nil {
+		log.Fatalf("無效的 incident 關聯設定: %v", err)
+	}
+
+	webhookManager, err = loadWebhookManager()
+	if err != nil {
+		log.Fatalf("無效的 webhook 設定: %v", err)
+	}
+	if webhookManager != nil {
+		log.Printf("已載入 %d 個 webhook", len(webhookManager.GetWebhooks()))
+	}
+
+	kafkaConsumer, err = loadKafkaConsumer()
+	if err != nil {
+		log.Fatalf("無效的 Kafka consumer 設定: %v", err)
+	}
+	if kafkaConsumer != nil {
+		kafkaConsumer.Start()
+		log.Printf("Kafka consumer 已啟動（topic %s）", kafkaConsumer.GetStats().Topic)
+	}
+
+	consumerTopic := ""
+	if kafkaConsumer != nil {
+		consumerTopic = kafkaConsumer.GetStats().Topic
+	}
+	kafkaProducer, err = loadKafkaProducer(consumerTopic)
+	if err != nil {
+		log.Fatalf("無效的 Kafka producer 設定: %v", err)
+	}
+	if kafkaProducer != nil {
+		log.Printf("Kafka producer 已載入（backend %s，enabled=%t）", kafkaProducer.GetStats().Backend, kafkaProducer.IsEnabled())
+	}
+
+	tenantKeys = tenant.LoadKeys()
+	if tenantKeys.MultiTenant() {
+		log.Printf("多租戶模式已啟用（%d 個 API key）", len(tenantKeys))
+	}
+
+	r := gin.New()
+	r.Use(gin.Recovery(), logging.Middleware(logger))
 
 	// CORS 設定（允許 frontend 存取）
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Org-ID, X-Request-ID")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -207,81 +517,63 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// 就緒檢查：確認資料庫可連線（結果短暫快取）
+	readiness := health.NewChecker()
+	readiness.Add("database", health.SQLCheck(db.DB))
+	r.GET("/ready", readiness.Handler())
+
+	// Prometheus 指標
+	r.GET("/metrics", metricsHandler())
+
+	// 以下 API 皆依 API key 解析租戶，所有查詢都限定在該組織內
+	r.Use(tenantKeys.Middleware())
+
+	// 寫入端點在解析 JSON 前限制 body 大小（MAX_BODY_BYTES，預設 256 KiB）
+	maxBody := middleware.MaxBodyBytes(middleware.MaxBodyBytesFromEnv())
+
 	// 事件接收端點
-	r.POST("/api/v1/events", func(c *gin.Context) {
+	r.POST("/api/v1/events", maxBody, func(c *gin.Context) {
 		var req IngestRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		// 將 metadata 轉換為 JSON 字串
-		var metadataJSON string
-		if req.Metadata != nil {
-			metadataBytes, _ := json.Marshal(req.Metadata)
-			metadataJSON = string(metadataBytes)
-		}
-
-		event := Event{
-			Component:    req.Component,
-			EventType:    req.EventType,
-			Command:      req.Command,
-			OperatorRole: req.OperatorRole,
-			Decision:     req.Decision,
-			Reason:       req.Reason,
-			Status:       req.Status,
-			Message:      req.Message,
-			Severity:     req.Severity,
-			RuleID:       req.RuleID,
-			AnomalyType:  req.AnomalyType,
-			ScenarioID:   req.ScenarioID,
-			Metadata:     metadataJSON,
-			CreatedAt:    time.Now().UTC(),
-		}
-
-		// 如果是高嚴重性事件，自動創建或更新 incident
-		if req.Severity == "high" || req.Severity == "critical" {
-			incident := createOrUpdateIncident(req, db)
-			if incident != nil {
-				event.IncidentID = &incident.ID
-			}
-		}
-
-		// 如果是 OTA 相關事件，更新軟體姿態
-		if req.EventType == "release_approved" || req.EventType == "update_applied" {
-			if component, ok := req.Metadata["component"].(string); ok {
-				if version, ok := req.Metadata["version"].(string); ok {
-					imageDigest := ""
-					if digest, ok := req.Metadata["imageDigest"].(string); ok {
-						imageDigest = digest
-					}
-					updateSoftwarePosture(component, version, imageDigest, db)
-				}
-			}
-		}
-
-		if err := db.Create(&event).Error; err != nil {
+		orgID := tenant.OrgID(c)
+		event, incidentSummary, err := ingestEvent(db, req, orgID)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法儲存事件"})
 			return
 		}
+		countIngestedEvent("api", event)
+		publishIngestedIncident(orgID, incidentSummary)
 
-		c.JSON(http.StatusCreated, event)
+		if incidentSummary == nil {
+			c.JSON(http.StatusCreated, event)
+			return
+		}
+		// 觸發 incident 時在事件欄位之外附上 incident 摘要，省去額外查詢
+		c.JSON(http.StatusCreated, struct {
+			Event
+			Incident *IncidentSummary `json:"incident"`
+		}{*event, incidentSummary})
 	})
 
 	// 查詢事件端點
 	r.GET("/api/v1/events", func(c *gin.Context) {
 		var events []Event
-		query := db.Model(&Event{})
+		query := db.Model(&Event{}).Where("org_id = ?", tenant.OrgID(c))
 
 		// 可選的篩選參數
-		if component := c.Query("component"); component != "" {
-			query = query.Where("component = ?", component)
-		}
-		if eventType := c.Query("eventType"); eventType != "" {
-			query = query.Where("event_type = ?", eventType)
+		from, to, err := parseTimeRange(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from/to must be RFC3339 timestamps"})
+			return
 		}
-		if command := c.Query("command"); command != "" {
-			query = query.Where("command = ?", command)
+		query, err = applyEventFilters(c, query, from, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
 		// 限制結果數量（預設 100）
@@ -291,24 +583,67 @@ func main() {
 				limit = parsedLimit
 			}
 		}
-		query = query.Limit(limit).Order("created_at DESC")
 
-		if err := query.Find(&events).Error; err != nil {
+		// 以事件 ID 為游標分頁：before 往較舊的事件翻頁（由新到舊），
+		// 只帶 after 時往較新的事件翻頁（由舊到新）；ID 單調遞增，新事件寫入不影響既有游標
+		cursor := func(name string) (uint64, bool) {
+			v := c.Query(name)
+			if v == "" {
+				return 0, true
+			}
+			id, err := strconv.ParseUint(v, 10, 64)
+			return id, err == nil
+		}
+		before, okBefore := cursor("before")
+		after, okAfter := cursor("after")
+		if !okBefore || !okAfter {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		if before > 0 {
+			query = query.Where("id < ?", before)
+		}
+		if after > 0 {
+			query = query.Where("id > ?", after)
+		}
+		forward := after > 0 && before == 0
+		if forward {
+			query = query.Order("id ASC")
+		} else {
+			query = query.Order("id DESC")
+		}
+
+		// 多取一筆判斷是否還有下一頁
+		if err := query.Limit(limit + 1).Find(&events).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢事件"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"events": events, "count": len(events)})
+		response := gin.H{"events": events, "count": len(events)}
+		if len(events) > limit {
+			events = events[:limit]
+			response["events"], response["count"] = events, len(events)
+			response["nextCursor"] = strconv.FormatUint(uint64(events[len(events)-1].ID), 10)
+			// cursorParam 指出 nextCursor 要帶入的參數：由舊到新的頁面接續 after，由新到舊的頁面接續 before
+			if forward {
+				response["cursorParam"] = "after"
+			} else {
+				response["cursorParam"] = "before"
+			}
+		}
+
+		c.JSON(http.StatusOK, response)
 	})
 
 	// Incident API（必須在 events/scenario 之前註冊，避免路由衝突）
 	// 創建 incident
-	r.POST("/api/v1/incidents", func(c *gin.Context) {
+	r.POST("/api/v1/incidents", maxBody, func(c *gin.Context) {
 		var req struct {
-			Title       string `json:"title" binding:"required"`
+			Title       string `json:"title"`
 			Description string `json:"description"`
-			Severity    string `json:"severity" binding:"required"`
+			Severity    string `json:"severity"`
 			ScenarioID  string `json:"scenarioID,omitempty"`
+			TemplateKey string `json:"templateKey,omitempty"` // 未填的標題/描述/嚴重性由 template 補上
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -316,12 +651,37 @@ func main() {
 			return
 		}
 
+		orgID := tenant.OrgID(c)
+		if req.TemplateKey != "" {
+			tmpl := getIncidentTemplate(db, orgID, req.TemplateKey)
+			if tmpl == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown templateKey"})
+				return
+			}
+			title, description := tmpl.render(IngestRequest{ScenarioID: req.ScenarioID})
+			if req.Title == "" {
+				req.Title = title
+			}
+			if req.Description == "" {
+				req.Description = description
+			}
+			if req.Severity == "" {
+				req.Severity = tmpl.DefaultSeverity
+			}
+		}
+		if req.Title == "" || req.Severity == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "title and severity are required (directly or via templateKey)"})
+			return
+		}
+
 		incident := Incident{
+			OrgID:       orgID,
 			Title:       req.Title,
 			Description: req.Description,
 			Severity:    req.Severity,
 			Status:      "open",
 			ScenarioID:  req.ScenarioID,
+			TemplateKey: req.TemplateKey,
 			CreatedAt:   time.Now().UTC(),
 			UpdatedAt:   time.Now().UTC(),
 		}
@@ -330,6 +690,7 @@ func main() {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法創建 incident"})
 			return
 		}
+		publishIncidentUpdate(newIncidentUpdate(incident, ""))
 
 		c.JSON(http.StatusCreated, incident)
 	})
@@ -337,7 +698,7 @@ func main() {
 	// 查詢所有 incidents
 	r.GET("/api/v1/incidents", func(c *gin.Context) {
 		var incidents []Incident
-		query := db.Model(&Incident{})
+		query := db.Model(&Incident{}).Where("org_id = ?", tenant.OrgID(c))
 
 		if status := c.Query("status"); status != "" {
 			query = query.Where("status = ?", status)
@@ -348,6 +709,9 @@ func main() {
 		if scenarioID := c.Query("scenarioId"); scenarioID != "" {
 			query = query.Where("scenario_id = ?", scenarioID)
 		}
+		if c.Query("includeArchived") != "true" {
+			query = query.Where("archived_at IS NULL")
+		}
 
 		query = query.Preload("Events").Order("created_at DESC").Limit(100)
 
@@ -371,16 +735,21 @@ func main() {
 			return
 		}
 
-		if err := db.Preload("Events").First(&incident, uint(id)).Error; err != nil {
+		orgID := tenant.OrgID(c)
+		if err := db.Preload("Events").Where("org_id = ?", orgID).First(&incident, uint(id)).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
 			return
 		}
 
+		if incident.PlaybookKey != "" {
+			incident.Playbook = getPlaybook(db, orgID, incident.PlaybookKey)
+		}
+
 		c.JSON(http.StatusOK, incident)
 	})
 
 	// 更新 incident 狀態
-	r.PATCH("/api/v1/incidents/:id", func(c *gin.Context) {
+	r.PATCH("/api/v1/incidents/:id", maxBody, func(c *gin.Context) {
 		var incident Incident
 		idStr := c.Param("id")
 
@@ -391,7 +760,7 @@ func main() {
 			return
 		}
 
-		if err := db.First(&incident, uint(id)).Error; err != nil {
+		if err := db.Where("org_id = ?", tenant.OrgID(c)).First(&incident, uint(id)).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
 			return
 		}
@@ -405,25 +774,38 @@ func main() {
 			return
 		}
 
+		oldStatus := incident.Status
 		if req.Status != "" {
 			incident.Status = req.Status
 		}
 		incident.UpdatedAt = time.Now().UTC()
+		if isClosedStatus(incident.Status) && !isClosedStatus(oldStatus) {
+			resolvedAt := incident.UpdatedAt
+			incident.ResolvedAt = &resolvedAt
+		} else if !isClosedStatus(incident.Status) {
+			incident.ResolvedAt = nil
+			incident.ArchivedAt = nil
+		}
 
 		if err := db.Save(&incident).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法更新 incident"})
 			return
 		}
 
+		if incident.Status != oldStatus {
+			recordStatusChange(db, incident, oldStatus, "api", "")
+			publishIncidentUpdate(newIncidentUpdate(incident, oldStatus))
+		}
+
 		c.JSON(http.StatusOK, incident)
 	})
 
 	// Software Posture API
-	// 查詢所有組件的軟體姿態
+	// 查詢組織內所有組件的軟體姿態
 	r.GET("/api/v1/posture", func(c *gin.Context) {
 		var postures []SoftwarePosture
 
-		if err := db.Order("component ASC").Find(&postures).Error; err != nil {
+		if err := db.Where("org_id = ?", tenant.OrgID(c)).Order("component ASC").Find(&postures).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢軟體姿態"})
 			return
 		}
@@ -436,7 +818,7 @@ func main() {
 		component := c.Param("component")
 		var posture SoftwarePosture
 
-		if err := db.Where("component = ?", component).First(&posture).Error; err != nil {
+		if err := db.Where("org_id = ? AND component = ?", tenant.OrgID(c), component).First(&posture).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "component not found"})
 			return
 		}
@@ -445,7 +827,7 @@ func main() {
 	})
 
 	// 更新組件軟體姿態（由 OTA controller 或 CI 調用）
-	r.POST("/api/v1/posture", func(c *gin.Context) {
+	r.POST("/api/v1/posture", maxBody, func(c *gin.Context) {
 		var req struct {
 			Component       string    `json:"component" binding:"required"`
 			CurrentVersion  string    `json:"currentVersion" binding:"required"`
@@ -461,14 +843,16 @@ func main() {
 			return
 		}
 
+		orgID := tenant.OrgID(c)
 		var posture SoftwarePosture
-		err := db.Where("component = ?", req.Component).First(&posture).Error
+		err := db.Where("org_id = ? AND component = ?", orgID, req.Component).First(&posture).Error
 
 		now := time.Now().UTC()
 
 		if err != nil {
 			// 創建新記錄
 			posture = SoftwarePosture{
+				OrgID:           orgID,
 				Component:       req.Component,
 				CurrentVersion:  req.CurrentVersion,
 				ImageDigest:     req.ImageDigest,
@@ -498,12 +882,28 @@ func main() {
 		c.JSON(http.StatusOK, posture)
 	})
 
+	// Incident template 與 playbook 管理
+	registerTemplateRoutes(r, maxBody)
+	registerPlaybookRoutes(r, maxBody)
+	registerReopenRoutes(r, maxBody)
+	registerTimelineRoutes(r)
+	registerStatsRoutes(r)
+	registerEventStatsRoutes(r)
+	registerArchiveRoutes(r)
+	registerBatchRoutes(r, maxBody)
+	registerStreamRoutes(r)
+	registerWebhookRoutes(r)
+	registerKafkaConsumerRoutes(r)
+
+	// 稽核匯出與驗證
+	registerAuditRoutes(r)
+
 	// 查詢事件（依場景）- 放在 incidents 路由之後，避免路由衝突
 	r.GET("/api/v1/events/scenario/:scenarioId", func(c *gin.Context) {
 		scenarioID := c.Param("scenarioId")
 		var events []Event
 
-		if err := db.Where("scenario_id = ?", scenarioID).Order("created_at DESC").Find(&events).Error; err != nil {
+		if err := db.Where("org_id = ? AND scenario_id = ?", tenant.OrgID(c), scenarioID).Order("created_at DESC").Find(&events).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢事件"})
 			return
 		}
@@ -516,7 +916,36 @@ func main() {
 		port = "8080"
 	}
 
-	if err := r.Run(":" + port); err != nil {
+	// 收到 SIGTERM 時先結束 SSE 連線並等待處理中的請求，再停止 Kafka consumer（不再產生新通知）、
+	// 送完 webhook 佇列與 Kafka producer 緩衝區，最後關閉資料庫
+	srv := server.New(":"+port, r)
+	srv.OnShutdown(incidentUpdates.closeAll)
+	srv.AddCleanup("kafka_consumer", func(context.Context) error {
+		if kafkaConsumer == nil {
+			return nil
+		}
+		return kafkaConsumer.Stop()
+	})
+	srv.AddCleanup("webhooks", func(ctx context.Context) error {
+		if webhookManager == nil {
+			return nil
+		}
+		return webhookManager.Shutdown(ctx)
+	})
+	srv.AddCleanup("kafka_producer", func(context.Context) error {
+		if kafkaProducer == nil {
+			return nil
+		}
+		return kafkaProducer.Close()
+	})
+	srv.AddCleanup("database", func(context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+	if err := srv.Run(); err != nil {
 		log.Fatalf("space-soc backend server failed: %v", err)
 	}
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// metadataFilterPrefix 是以 metadata 欄位篩選事件的查詢參數前綴，例如 metadata.satelliteId=SAT-7。
+const metadataFilterPrefix = "metadata."
+
+// maxMetadataFilters 是單次查詢可使用的 metadata 篩選數量上限。
+const maxMetadataFilters = 5
+
+// metadataKeyPattern 限制可篩選的 metadata key；gateway 與 OTA controller 送出的 key 皆符合。
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// EventMetadata 是事件附帶的 metadata JSON 字串。PostgreSQL 上存為 jsonb 以便依 key 查詢，
+// SQLite 上維持 text；空字串存為 NULL（jsonb 不接受空字串）。
+type EventMetadata string
+
+// GormDBDataType 依資料庫決定欄位型別。
+func (EventMetadata) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "jsonb"
+	}
+	return "text"
+}
+
+// Value 實作 driver.Valuer。
+func (m EventMetadata) Value() (driver.Value, error) {
+	if m == "" {
+		return nil, nil
+	}
+	return string(m), nil
+}
+
+// Scan 實作 sql.Scanner；NULL 讀回空字串。
+func (m *EventMetadata) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = ""
+	case string:
+		*m = EventMetadata(v)
+	case []byte:
+		*m = EventMetadata(v)
+	default:
+		return fmt.Errorf("unsupported metadata type %T", value)
+	}
+	return nil
+}
+
+// migrateEventMetadata 在 PostgreSQL 上將既有 events.metadata 的 text 欄位轉為 jsonb，
+// 必須在 AutoMigrate 之前執行：空字串轉為 NULL，否則轉型會失敗。
+func migrateEventMetadata(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" || !db.Migrator().HasTable(&Event{}) {
+		return nil
+	}
+
+	var dataType string
+	err := db.Raw(
+		"SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'events' AND column_name = 'metadata'",
+	).Scan(&dataType).Error
+	if err != nil {
+		return err
+	}
+	if dataType == "" || dataType == "jsonb" {
+		return nil
+	}
+	return db.Exec("ALTER TABLE events ALTER COLUMN metadata TYPE jsonb USING NULLIF(metadata, '')::jsonb").Error
+}
+
+// metadataFilters 從查詢參數取出 metadata.<key>=<value> 篩選，依 key 排序。
+func metadataFilters(values url.Values) (map[string]string, []string, error) {
+	filters := map[string]string{}
+	for param, vals := range values {
+		key, ok := strings.CutPrefix(param, metadataFilterPrefix)
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if !metadataKeyPattern.MatchString(key) {
+			return nil, nil, fmt.Errorf("invalid metadata filter %q: keys may contain only letters, digits, '_' and '-'", param)
+		}
+		filters[key] = vals[0]
+	}
+	if len(filters) > maxMetadataFilters {
+		return nil, nil, fmt.Errorf("at most %d metadata filters are allowed", maxMetadataFilters)
+	}
+
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return filters, keys, nil
+}
+
+// applyMetadataFilters 套用 metadata.<key>=<value> 篩選（值以字串比較）。PostgreSQL 使用 jsonb 的 ->> 運算子；
+// SQLite 以 json_extract 比對 text 欄位，僅供開發使用（無索引、數值以文字比較）。
+func applyMetadataFilters(query *gorm.DB, values url.Values) (*gorm.DB, error) {
+	filters, keys, err := metadataFilters(values)
+	if err != nil {
+		return nil, err
+	}
+
+	isPostgres := query.Dialector.Name() == "postgres"
+	for _, key := range keys {
+		if isPostgres {
+			query = query.Where("metadata ->> ? = ?", key, filters[key])
+			continue
+		}
+		// key 已通過 metadataKeyPattern 驗證，可安全放入 JSON path；json_valid 避免舊資料的空字串造成錯誤
+		query = query.Where(
+			"CAST(CASE WHEN json_valid(metadata) THEN json_extract(metadata, ?) END AS TEXT) = ?",
+			`$."`+key+`"`, filters[key],
+		)
+	}
+	return query, nil
+}
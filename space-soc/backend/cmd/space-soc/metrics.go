@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsQueryTimeout 是每次抓取時查詢 incident 數量的時限。
+const metricsQueryTimeout = 5 * time.Second
+
+// eventsIngested 依來源（api、batch、kafka）、發出事件的組件與嚴重性計算已儲存的事件。
+var eventsIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "space_soc_events_ingested_total",
+	Help: "Events stored by ingest source (api, batch, kafka), component and severity.",
+}, []string{"source", "component", "severity"})
+
+// countIngestedEvent 記錄一筆已儲存的事件；未設定嚴重性的事件記為 "none"。
+func countIngestedEvent(source string, event *Event) {
+	severity := event.Severity
+	if severity == "" {
+		severity = "none"
+	}
+	eventsIngested.WithLabelValues(source, event.Component, severity).Inc()
+}
+
+var (
+	openIncidentsDesc = prometheus.NewDesc(
+		"space_soc_incidents_open",
+		"Incidents not yet resolved or closed, by severity (all organizations).",
+		[]string{"severity"}, nil)
+	webhookDeliveriesDesc = prometheus.NewDesc(
+		"space_soc_webhook_deliveries_total",
+		"Webhook delivery attempts by webhook and outcome (succeeded, failed, dead_lettered).",
+		[]string{"webhook", "outcome"}, nil)
+	kafkaMessagesDesc = prometheus.NewDesc(
+		"space_soc_kafka_consumer_messages_total",
+		"Messages read by the Kafka consumer by topic and outcome (consumed, decode_error, handler_error).",
+		[]string{"topic", "outcome"}, nil)
+	kafkaLagDesc = prometheus.NewDesc(
+		"space_soc_kafka_consumer_lag",
+		"Messages behind the partition head as last reported by the Kafka consumer.",
+		[]string{"topic"}, nil)
+	kafkaProducedDesc = prometheus.NewDesc(
+		"space_soc_kafka_producer_messages_total",
+		"Messages published by the Kafka producer by topic and outcome (sent, error).",
+		[]string{"topic", "outcome"}, nil)
+	kafkaBufferedDesc = prometheus.NewDesc(
+		"space_soc_kafka_producer_buffered",
+		"Messages waiting in the Kafka producer buffer for the next flush.",
+		[]string{"topic"}, nil)
+)
+
+// socCollector 在每次抓取時讀取目前狀態：incident 數量來自資料庫，
+// webhook 與 Kafka 的計數來自 integrations 已維護的統計，避免在多處重複計數。
+type socCollector struct{}
+
+func (socCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openIncidentsDesc
+	ch <- webhookDeliveriesDesc
+	ch <- kafkaMessagesDesc
+	ch <- kafkaLagDesc
+	ch <- kafkaProducedDesc
+	ch <- kafkaBufferedDesc
+}
+
+func (socCollector) Collect(ch chan<- prometheus.Metric) {
+	collectOpenIncidents(ch)
+
+	if webhookManager != nil {
+		for name, counts := range webhookManager.GetDeliveryCounts() {
+			ch <- prometheus.MustNewConstMetric(webhookDeliveriesDesc, prometheus.CounterValue, float64(counts.Succeeded), name, "succeeded")
+			ch <- prometheus.MustNewConstMetric(webhookDeliveriesDesc, prometheus.CounterValue, float64(counts.Failed), name, "failed")
+			ch <- prometheus.MustNewConstMetric(webhookDeliveriesDesc, prometheus.CounterValue, float64(counts.DeadLettered), name, "dead_lettered")
+		}
+	}
+
+	if kafkaConsumer != nil {
+		stats := kafkaConsumer.GetStats()
+		ch <- prometheus.MustNewConstMetric(kafkaMessagesDesc, prometheus.CounterValue, float64(stats.Consumed), stats.Topic, "consumed")
+		ch <- prometheus.MustNewConstMetric(kafkaMessagesDesc, prometheus.CounterValue, float64(stats.DecodeErrors), stats.Topic, "decode_error")
+		ch <- prometheus.MustNewConstMetric(kafkaMessagesDesc, prometheus.CounterValue, float64(stats.HandlerErrors), stats.Topic, "handler_error")
+		ch <- prometheus.MustNewConstMetric(kafkaLagDesc, prometheus.GaugeValue, float64(stats.Lag), stats.Topic)
+	}
+
+	if kafkaProducer != nil {
+		stats := kafkaProducer.GetStats()
+		ch <- prometheus.MustNewConstMetric(kafkaProducedDesc, prometheus.CounterValue, float64(stats.MessagesSent), stats.Topic, "sent")
+		ch <- prometheus.MustNewConstMetric(kafkaProducedDesc, prometheus.CounterValue, float64(stats.Errors), stats.Topic, "error")
+		ch <- prometheus.MustNewConstMetric(kafkaBufferedDesc, prometheus.GaugeValue, float64(stats.MessagesBuffered), stats.Topic)
+	}
+}
+
+// collectOpenIncidents 依嚴重性統計未結案的 incident；查詢失敗時略過此指標並記錄日誌。
+func collectOpenIncidents(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsQueryTimeout)
+	defer cancel()
+
+	var rows []struct {
+		Severity string
+		Count    int64
+	}
+	err := db.WithContext(ctx).Model(&Incident{}).
+		Select("severity, count(*) AS count").
+		Where("status NOT IN ?", closedStatuses).
+		Group("severity").
+		Scan(&rows).Error
+	if err != nil {
+		log.Printf("無法統計未結案 incident: %v", err)
+		return
+	}
+	for _, row := range rows {
+		ch <- prometheus.MustNewConstMetric(openIncidentsDesc, prometheus.GaugeValue, float64(row.Count), row.Severity)
+	}
+}
+
+// metricsHandler 註冊 socCollector 與資料庫連線池統計（go_sql_* 指標，db_name="space_soc"），
+// 並提供預設 registry 的指標（含 Go runtime 與 process 指標）。
+// 與 /health 一樣不經過租戶驗證，僅應開放給叢集內的 Prometheus 抓取。
+func metricsHandler() gin.HandlerFunc {
+	prometheus.MustRegister(socCollector{})
+	if sqlDB, err := db.DB(); err == nil {
+		prometheus.MustRegister(collectors.NewDBStatsCollector(sqlDB, "space_soc"))
+	}
+	return gin.WrapH(promhttp.Handler())
+}
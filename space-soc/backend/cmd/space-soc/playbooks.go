@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Playbook 是 incident 的處置手冊，以場景 ID 或規則 ID 為 key，內容可為 markdown 或步驟清單。
+type Playbook struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	OrgID     string    `gorm:"not null;uniqueIndex:idx_playbook_org_key;default:default" json:"orgId"`
+	Key       string    `gorm:"column:playbook_key;not null;uniqueIndex:idx_playbook_org_key" json:"key"`
+	Title     string    `gorm:"not null" json:"title"`
+	Markdown  string    `gorm:"type:text" json:"markdown,omitempty"`
+	Steps     []string  `gorm:"serializer:json;type:text" json:"steps,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// findPlaybook 依序以場景 ID、規則 ID 查找觸發事件對應的 playbook。
+func findPlaybook(db *gorm.DB, orgID string, req IngestRequest) *Playbook {
+	for _, key := range []string{req.ScenarioID, req.RuleID} {
+		if key == "" {
+			continue
+		}
+		if playbook := getPlaybook(db, orgID, key); playbook != nil {
+			return playbook
+		}
+	}
+	return nil
+}
+
+// getPlaybook 取得指定 key 的 playbook，不存在時回傳 nil。
+func getPlaybook(db *gorm.DB, orgID, key string) *Playbook {
+	var playbook Playbook
+	if err := db.Where("org_id = ? AND playbook_key = ?", orgID, key).First(&playbook).Error; err != nil {
+		return nil
+	}
+	return &playbook
+}
+
+// registerPlaybookRoutes 註冊 playbook 管理與手動關聯端點。
+func registerPlaybookRoutes(r *gin.Engine, maxBody gin.HandlerFunc) {
+	r.GET("/api/v1/playbooks", func(c *gin.Context) {
+		var playbooks []Playbook
+		if err := db.Where("org_id = ?", tenant.OrgID(c)).Order("playbook_key ASC").Find(&playbooks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢 playbooks"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"playbooks": playbooks, "count": len(playbooks)})
+	})
+
+	r.GET("/api/v1/playbooks/:key", func(c *gin.Context) {
+		playbook := getPlaybook(db, tenant.OrgID(c), c.Param("key"))
+		if playbook == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "playbook not found"})
+			return
+		}
+		c.JSON(http.StatusOK, playbook)
+	})
+
+	// 建立或更新 playbook（以 key 為識別）
+	r.PUT("/api/v1/playbooks/:key", maxBody, func(c *gin.Context) {
+		var req struct {
+			Title    string   `json:"title" binding:"required"`
+			Markdown string   `json:"markdown"`
+			Steps    []string `json:"steps"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Markdown == "" && len(req.Steps) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "markdown or steps is required"})
+			return
+		}
+
+		orgID := tenant.OrgID(c)
+		key := c.Param("key")
+		now := time.Now().UTC()
+
+		playbook := getPlaybook(db, orgID, key)
+		status := http.StatusOK
+		if playbook == nil {
+			playbook = &Playbook{OrgID: orgID, Key: key, CreatedAt: now}
+			status = http.StatusCreated
+		}
+		playbook.Title = req.Title
+		playbook.Markdown = req.Markdown
+		playbook.Steps = req.Steps
+		playbook.UpdatedAt = now
+
+		if err := db.Save(playbook).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法儲存 playbook"})
+			return
+		}
+		c.JSON(status, playbook)
+	})
+
+	r.DELETE("/api/v1/playbooks/:key", func(c *gin.Context) {
+		result := db.Where("org_id = ? AND playbook_key = ?", tenant.OrgID(c), c.Param("key")).Delete(&Playbook{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法刪除 playbook"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "playbook not found"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 手動將 playbook 關聯到 incident（key 為空字串時解除關聯）
+	r.PUT("/api/v1/incidents/:id/playbook", maxBody, func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid incident ID"})
+			return
+		}
+
+		var req struct {
+			Key string `json:"key"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		orgID := tenant.OrgID(c)
+		var incident Incident
+		if err := db.Where("org_id = ?", orgID).First(&incident, uint(id)).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+			return
+		}
+
+		if req.Key != "" {
+			incident.Playbook = getPlaybook(db, orgID, req.Key)
+			if incident.Playbook == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown playbook key"})
+				return
+			}
+		}
+		incident.PlaybookKey = req.Key
+		incident.UpdatedAt = time.Now().UTC()
+
+		if err := db.Save(&incident).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法更新 incident"})
+			return
+		}
+		c.JSON(http.StatusOK, incident)
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// closedStatuses 是可重新開啟的 incident 狀態。
+var closedStatuses = []string{"resolved", "closed"}
+
+// isClosedStatus 判斷 incident 狀態是否已結案。
+func isClosedStatus(status string) bool {
+	for _, s := range closedStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// registerReopenRoutes 註冊重新開啟已結案 incident 的端點。
+func registerReopenRoutes(r *gin.Engine, maxBody gin.HandlerFunc) {
+	// 將 resolved / closed 的 incident 轉回 investigating（並取消封存），之後符合條件的事件會再關聯到它
+	r.POST("/api/v1/incidents/:id/reopen", maxBody, func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid incident ID"})
+			return
+		}
+
+		var req struct {
+			Reason     string `json:"reason" binding:"required"`
+			ReopenedBy string `json:"reopenedBy"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+			return
+		}
+		if req.ReopenedBy == "" {
+			req.ReopenedBy = "api"
+		}
+
+		var incident Incident
+		orgID := tenant.OrgID(c)
+		if err := db.Where("org_id = ?", orgID).First(&incident, uint(id)).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+			return
+		}
+		if !isClosedStatus(incident.Status) {
+			c.JSON(http.StatusConflict, gin.H{"error": "incident is not resolved or closed", "status": incident.Status})
+			return
+		}
+
+		// 以目前狀態為條件更新，避免與同時進行的狀態變更互相覆蓋
+		oldStatus := incident.Status
+		now := time.Now().UTC()
+		result := db.Model(&Incident{}).
+			Where("id = ? AND org_id = ? AND status = ?", incident.ID, orgID, oldStatus).
+			Updates(map[string]interface{}{"status": "investigating", "resolved_at": nil, "archived_at": nil, "updated_at": now})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法重新開啟 incident"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "incident status changed concurrently"})
+			return
+		}
+
+		incident.Status = "investigating"
+		incident.ResolvedAt = nil
+		incident.ArchivedAt = nil
+		incident.UpdatedAt = now
+		recordStatusChange(db, incident, oldStatus, req.ReopenedBy, req.Reason)
+		publishIncidentUpdate(newIncidentUpdate(incident, oldStatus))
+
+		c.JSON(http.StatusOK, incident)
+	})
+}
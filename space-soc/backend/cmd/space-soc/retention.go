@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RetentionPolicy 定義事件保留期限：依嚴重性個別設定，未列出的嚴重性（含空值）使用 Default。
+// 期限為 0 代表永久保留。
+type RetentionPolicy struct {
+	Default    time.Duration
+	BySeverity map[string]time.Duration
+	Interval   time.Duration
+}
+
+// parseRetentionDuration 解析保留期限，除了 Go duration 外也接受天數（例如 "30d"）。
+func parseRetentionDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid retention %q", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention %q", value)
+	}
+	return d, nil
+}
+
+// loadRetentionPolicy 讀取保留設定，皆未設定時回傳 nil（不清除任何事件）：
+//   - EVENT_RETENTION: 預設保留期限（例如 "30d"）
+//   - EVENT_RETENTION_BY_SEVERITY: 依嚴重性的期限（例如 "low=7d,medium=30d,high=90d,critical=365d"）
+//   - RETENTION_PURGE_INTERVAL: 清除工作執行間隔（預設 1h）
+func loadRetentionPolicy() (*RetentionPolicy, error) {
+	policy := &RetentionPolicy{
+		BySeverity: make(map[string]time.Duration),
+		Interval:   time.Hour,
+	}
+
+	if v := os.Getenv("EVENT_RETENTION"); v != "" {
+		d, err := parseRetentionDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		policy.Default = d
+	}
+
+	if v := os.Getenv("EVENT_RETENTION_BY_SEVERITY"); v != "" {
+		for _, entry := range strings.Split(v, ",") {
+			parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+			if len(parts) != 2 || !validSeverities[strings.TrimSpace(parts[0])] {
+				return nil, fmt.Errorf("invalid EVENT_RETENTION_BY_SEVERITY entry %q", entry)
+			}
+			d, err := parseRetentionDuration(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			policy.BySeverity[strings.TrimSpace(parts[0])] = d
+		}
+	}
+
+	if v := os.Getenv("RETENTION_PURGE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid RETENTION_PURGE_INTERVAL %q", v)
+		}
+		policy.Interval = d
+	}
+
+	if policy.Default == 0 && len(policy.BySeverity) == 0 {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// purgeExpiredEvents 依各嚴重性的期限刪除過期事件，回傳各嚴重性的刪除數量
+// （未標示或非標準嚴重性的事件計入 "unspecified"）。
+// 關聯到仍在處理中（open / investigating）incident 的事件不論嚴重性都保留。
+func purgeExpiredEvents(db *gorm.DB, policy *RetentionPolicy, now time.Time) (map[string]int64, error) {
+	purged := make(map[string]int64)
+
+	activeIncidents := db.Model(&Incident{}).Select("id").Where("status IN ?", []string{"open", "investigating"})
+	notLinkedToActive := db.Where("incident_id IS NULL").Or("incident_id NOT IN (?)", activeIncidents)
+
+	known := make([]string, 0, len(validSeverities))
+	for severity := range validSeverities {
+		known = append(known, severity)
+		retention, ok := policy.BySeverity[severity]
+		if !ok {
+			retention = policy.Default
+		}
+		if retention == 0 {
+			continue
+		}
+		result := db.Where("severity = ? AND created_at < ?", severity, now.Add(-retention)).
+			Where(notLinkedToActive).
+			Delete(&Event{})
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged[severity] = result.RowsAffected
+	}
+
+	if policy.Default > 0 {
+		result := db.Where("created_at < ?", now.Add(-policy.Default)).
+			Where("(severity NOT IN ? OR severity IS NULL)", known).
+			Where(notLinkedToActive).
+			Delete(&Event{})
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged["unspecified"] = result.RowsAffected
+	}
+
+	return purged, nil
+}
+
+// startRetentionJob 定期執行事件清除，並記錄各嚴重性的刪除數量。
+func startRetentionJob(db *gorm.DB, policy *RetentionPolicy) {
+	run := func() {
+		purged, err := purgeExpiredEvents(db, policy, time.Now().UTC())
+		if err != nil {
+			slog.Error("事件保留清除失敗", "error", err)
+			return
+		}
+		var total int64
+		attrs := make([]any, 0, len(purged)*2+2)
+		for severity, count := range purged {
+			total += count
+			attrs = append(attrs, severity, count)
+		}
+		attrs = append(attrs, "total", total)
+		slog.Info("retention_purge", attrs...)
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(policy.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
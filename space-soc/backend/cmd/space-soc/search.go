@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// maxSearchQueryLength 是 q 參數的字元上限。
+const maxSearchQueryLength = 200
+
+// 事件全文搜尋模式（EVENT_SEARCH_MODE）。
+const (
+	// searchModeLike 以不分大小寫的子字串比對（LIKE），SQLite 與 PostgreSQL 皆可用，但無法使用索引
+	searchModeLike = "like"
+	// searchModeFullText 使用 PostgreSQL 的 to_tsvector GIN 索引，以詞（token）為單位比對片語
+	searchModeFullText = "fulltext"
+)
+
+// eventSearchVector 是全文搜尋索引與查詢共用的運算式；兩者必須完全相同，PostgreSQL 才會使用索引。
+// 使用 'simple' 設定（不做詞幹還原），讓指令名稱與識別碼依原樣比對。
+const eventSearchVector = "to_tsvector('simple', coalesce(message, '') || ' ' || coalesce(reason, '') || ' ' || coalesce(command, ''))"
+
+// eventSearchMode 是目前使用的搜尋模式，由 configureEventSearch 設定。
+var eventSearchMode = searchModeLike
+
+// configureEventSearch 讀取 EVENT_SEARCH_MODE（like 或 fulltext，預設 like）。fulltext 只支援 PostgreSQL，
+// 啟用時建立 GIN 索引；使用 SQLite 時記錄警告並改用 like。
+func configureEventSearch(db *gorm.DB) error {
+	mode := os.Getenv("EVENT_SEARCH_MODE")
+	switch mode {
+	case "", searchModeLike:
+		eventSearchMode = searchModeLike
+		return nil
+	case searchModeFullText:
+	default:
+		return fmt.Errorf("unknown EVENT_SEARCH_MODE %q (like or fulltext)", mode)
+	}
+
+	if db.Dialector.Name() != "postgres" {
+		log.Printf("EVENT_SEARCH_MODE=fulltext 需要 PostgreSQL，改用 LIKE 搜尋")
+		eventSearchMode = searchModeLike
+		return nil
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_events_search ON events USING GIN (" + eventSearchVector + ")").Error; err != nil {
+		return fmt.Errorf("create full-text index: %w", err)
+	}
+	eventSearchMode = searchModeFullText
+	return nil
+}
+
+// likePattern 將搜尋字串轉為小寫的 LIKE 子字串樣式，並跳脫 %、_ 與跳脫字元本身。
+func likePattern(q string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(q))
+	return "%" + escaped + "%"
+}
+
+// applyEventSearch 依 q 在 message、reason、command 中搜尋事件。
+func applyEventSearch(query *gorm.DB, q string) (*gorm.DB, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return query, nil
+	}
+	if utf8.RuneCountInString(q) > maxSearchQueryLength {
+		return nil, errors.New("q must be at most 200 characters")
+	}
+
+	if eventSearchMode == searchModeFullText {
+		return query.Where(eventSearchVector+" @@ phraseto_tsquery('simple', ?)", q), nil
+	}
+	pattern := likePattern(q)
+	return query.Where(
+		`(LOWER(message) LIKE ? ESCAPE '\' OR LOWER(reason) LIKE ? ESCAPE '\' OR LOWER(command) LIKE ? ESCAPE '\')`,
+		pattern, pattern, pattern,
+	), nil
+}
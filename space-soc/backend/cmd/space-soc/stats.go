@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IncidentStats 是 dashboard 總覽使用的 incident 統計（範圍為請求所屬組織，包含已封存的 incident）。
+type IncidentStats struct {
+	Total      int64            `json:"total"`
+	ByStatus   map[string]int64 `json:"byStatus"`
+	BySeverity map[string]int64 `json:"bySeverity"`
+	// OpenedLast24h / OpenedLast7d 是在該期間內建立的 incident 數量（不論目前狀態）
+	OpenedLast24h int64 `json:"openedLast24h"`
+	OpenedLast7d  int64 `json:"openedLast7d"`
+	// MeanTimeToResolutionSeconds 是已結案 incident 從建立到第一次進入 resolved / closed 的平均秒數，
+	// 沒有已結案的 incident 時為 null
+	MeanTimeToResolutionSeconds *float64  `json:"meanTimeToResolutionSeconds"`
+	ResolvedCount               int64     `json:"resolvedCount"`
+	GeneratedAt                 time.Time `json:"generatedAt"`
+}
+
+// resolutionSecondsExpr 回傳計算 resolved_at - created_at 秒數的 SQL；兩種資料庫的時間運算語法不同。
+func resolutionSecondsExpr(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "EXTRACT(EPOCH FROM (resolved_at - created_at))"
+	}
+	return "(julianday(resolved_at) - julianday(created_at)) * 86400.0"
+}
+
+// countByColumn 以 GROUP BY 統計 incident 在 column 上各值的數量。
+func countByColumn(query *gorm.DB, column string) (map[string]int64, error) {
+	var rows []struct {
+		Value string
+		Count int64
+	}
+	err := query.Session(&gorm.Session{}).
+		Select(column + " AS value, COUNT(*) AS count").
+		Group(column).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Value] = row.Count
+	}
+	return counts, nil
+}
+
+// computeIncidentStats 以彙總查詢計算組織的 incident 統計，不載入 incident 本身。
+func computeIncidentStats(db *gorm.DB, orgID string, now time.Time) (*IncidentStats, error) {
+	base := db.Model(&Incident{}).Where("org_id = ?", orgID)
+	stats := &IncidentStats{GeneratedAt: now}
+
+	var err error
+	if stats.ByStatus, err = countByColumn(base, "status"); err != nil {
+		return nil, err
+	}
+	if stats.BySeverity, err = countByColumn(base, "severity"); err != nil {
+		return nil, err
+	}
+	for _, count := range stats.ByStatus {
+		stats.Total += count
+	}
+
+	if err := base.Session(&gorm.Session{}).Where("created_at >= ?", now.Add(-24*time.Hour)).Count(&stats.OpenedLast24h).Error; err != nil {
+		return nil, err
+	}
+	if err := base.Session(&gorm.Session{}).Where("created_at >= ?", now.Add(-7*24*time.Hour)).Count(&stats.OpenedLast7d).Error; err != nil {
+		return nil, err
+	}
+
+	// ResolvedAt 在第一次進入 resolved / closed 時設定、重新開啟時清除，因此只統計目前已結案的 incident
+	var resolution struct {
+		Count       int64
+		MeanSeconds *float64
+	}
+	err = base.Session(&gorm.Session{}).
+		Select("COUNT(*) AS count, AVG(" + resolutionSecondsExpr(db) + ") AS mean_seconds").
+		Where("resolved_at IS NOT NULL").
+		Scan(&resolution).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.ResolvedCount = resolution.Count
+	stats.MeanTimeToResolutionSeconds = resolution.MeanSeconds
+
+	return stats, nil
+}
+
+// registerStatsRoutes 註冊 incident 統計端點。
+func registerStatsRoutes(r *gin.Engine) {
+	// dashboard 頂部的統計數字：依狀態與嚴重性的數量、近 24 小時 / 7 天新增數與平均處理時間
+	r.GET("/api/v1/incidents/stats", func(c *gin.Context) {
+		stats, err := computeIncidentStats(db, tenant.OrgID(c), time.Now().UTC())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法統計 incidents"})
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// streamHeartbeatInterval 是 SSE 心跳註解的間隔，避免代理伺服器關閉閒置連線。
+	streamHeartbeatInterval = 15 * time.Second
+	// streamClientBuffer 是每個訂閱者的訊息緩衝；緩衝滿時中斷該連線，由客戶端重新連線。
+	streamClientBuffer = 64
+)
+
+// IncidentUpdate 是推送給 SSE 訂閱者的 incident 變更訊息。
+type IncidentUpdate struct {
+	Type       string    `json:"type"` // "created", "status_changed"
+	OrgID      string    `json:"-"`
+	IncidentID uint      `json:"incidentId"`
+	Title      string    `json:"title"`
+	Severity   string    `json:"severity"`
+	Status     string    `json:"status"`
+	OldStatus  string    `json:"oldStatus,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// newIncidentUpdate 由 incident 建立變更訊息；oldStatus 為空代表新建立的 incident。
+func newIncidentUpdate(incident Incident, oldStatus string) IncidentUpdate {
+	update := IncidentUpdate{
+		Type:       "created",
+		OrgID:      incident.OrgID,
+		IncidentID: incident.ID,
+		Title:      incident.Title,
+		Severity:   incident.Severity,
+		Status:     incident.Status,
+		OldStatus:  oldStatus,
+		Timestamp:  time.Now().UTC(),
+	}
+	if oldStatus != "" {
+		update.Type = "status_changed"
+	}
+	return update
+}
+
+// incidentHub 是行程內的 incident 變更 pub/sub，每個 SSE 連線各自訂閱一個緩衝 channel。
+type incidentHub struct {
+	mu          sync.Mutex
+	subscribers map[chan IncidentUpdate]string // channel → 訂閱的組織
+}
+
+var incidentUpdates = &incidentHub{subscribers: make(map[chan IncidentUpdate]string)}
+
+// subscribe 註冊組織的訂閱者。
+func (h *incidentHub) subscribe(orgID string) chan IncidentUpdate {
+	ch := make(chan IncidentUpdate, streamClientBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = orgID
+	h.mu.Unlock()
+	return ch
+}
+
+// unsubscribe 移除訂閱者並關閉其 channel（已因緩衝滿而移除時不做事）。
+func (h *incidentHub) unsubscribe(ch chan IncidentUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// closeAll 關閉所有訂閱者的 channel，讓 SSE 連線結束（服務關閉時呼叫，客戶端會重新連線到其他實例）。
+func (h *incidentHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish 將訊息送給同組織的訂閱者，不會阻塞；跟不上的訂閱者會被移除並關閉連線。
+func (h *incidentHub) publish(update IncidentUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, orgID := range h.subscribers {
+		if orgID != update.OrgID {
+			continue
+		}
+		select {
+		case ch <- update:
+		default:
+			log.Printf("incident stream 訂閱者跟不上，中斷連線（org=%s）", orgID)
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publishIncidentUpdate 將 incident 變更推送給 SSE 訂閱者、設定的 webhook 與 Kafka producer。
+func publishIncidentUpdate(update IncidentUpdate) {
+	incidentUpdates.publish(update)
+	notifyWebhooks(update)
+	notifyKafka(update)
+}
+
+// publishIngestedIncident 依事件寫入結果推送 incident 建立或自動升級的狀態變更。
+func publishIngestedIncident(orgID string, summary *IncidentSummary) {
+	if summary == nil || (!summary.Created && summary.PreviousStatus == "") {
+		return
+	}
+	incident := Incident{
+		ID:       summary.ID,
+		OrgID:    orgID,
+		Title:    summary.Title,
+		Severity: summary.Severity,
+		Status:   summary.Status,
+	}
+	publishIncidentUpdate(newIncidentUpdate(incident, summary.PreviousStatus))
+}
+
+// registerStreamRoutes 註冊 incident 即時更新的 SSE 端點。
+func registerStreamRoutes(r *gin.Engine) {
+	r.GET("/api/v1/incidents/stream", func(c *gin.Context) {
+		ch := incidentUpdates.subscribe(tenant.OrgID(c))
+		defer incidentUpdates.unsubscribe(ch)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				return true
+			case update, ok := <-ch:
+				if !ok {
+					return false
+				}
+				c.SSEvent("incident", update)
+				return true
+			}
+		})
+	})
+}
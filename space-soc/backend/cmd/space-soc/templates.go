@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IncidentTemplate 定義常見攻擊類型的 incident 標題、描述與預設嚴重性。
+// Key 可為場景 ID、規則 ID、異常類型或事件類型；標題與描述支援
+// {eventType}、{component}、{message}、{scenarioID}、{ruleID}、{anomalyType}、{command} 佔位符。
+type IncidentTemplate struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	OrgID           string    `gorm:"not null;uniqueIndex:idx_template_org_key;default:default" json:"orgId"`
+	Key             string    `gorm:"column:template_key;not null;uniqueIndex:idx_template_org_key" json:"key"`
+	Title           string    `gorm:"not null" json:"title"`
+	Description     string    `gorm:"type:text" json:"description"`
+	DefaultSeverity string    `gorm:"not null" json:"defaultSeverity"` // "low", "medium", "high", "critical"
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+var validSeverities = map[string]bool{"low": true, "medium": true, "high": true, "critical": true}
+
+// severityRanks 是嚴重性由低到高的排序（low < medium < high < critical）。
+var severityRanks = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// severitiesAtLeast 回傳嚴重性不低於 min 的所有值；min 不是已知嚴重性時 ok 為 false。
+func severitiesAtLeast(min string) (severities []string, ok bool) {
+	minRank, ok := severityRanks[min]
+	if !ok {
+		return nil, false
+	}
+	for _, severity := range []string{"low", "medium", "high", "critical"} {
+		if severityRanks[severity] >= minRank {
+			severities = append(severities, severity)
+		}
+	}
+	return severities, true
+}
+
+// findIncidentTemplate 依序以場景 ID、規則 ID、異常類型、事件類型查找組織的 template。
+func findIncidentTemplate(db *gorm.DB, orgID string, req IngestRequest) *IncidentTemplate {
+	for _, key := range []string{req.ScenarioID, req.RuleID, req.AnomalyType, req.EventType} {
+		if key == "" {
+			continue
+		}
+		if tmpl := getIncidentTemplate(db, orgID, key); tmpl != nil {
+			return tmpl
+		}
+	}
+	return nil
+}
+
+// getIncidentTemplate 取得指定 key 的 template，不存在時回傳 nil。
+func getIncidentTemplate(db *gorm.DB, orgID, key string) *IncidentTemplate {
+	var tmpl IncidentTemplate
+	if err := db.Where("org_id = ? AND template_key = ?", orgID, key).First(&tmpl).Error; err != nil {
+		return nil
+	}
+	return &tmpl
+}
+
+// render 以事件欄位替換 template 中的佔位符。
+func (t *IncidentTemplate) render(req IngestRequest) (title, description string) {
+	replacer := strings.NewReplacer(
+		"{eventType}", req.EventType,
+		"{component}", req.Component,
+		"{message}", req.Message,
+		"{scenarioID}", req.ScenarioID,
+		"{ruleID}", req.RuleID,
+		"{anomalyType}", req.AnomalyType,
+		"{command}", req.Command,
+	)
+	return replacer.Replace(t.Title), replacer.Replace(t.Description)
+}
+
+// registerTemplateRoutes 註冊 incident template 管理端點。
+func registerTemplateRoutes(r *gin.Engine, maxBody gin.HandlerFunc) {
+	r.GET("/api/v1/incident-templates", func(c *gin.Context) {
+		var templates []IncidentTemplate
+		if err := db.Where("org_id = ?", tenant.OrgID(c)).Order("template_key ASC").Find(&templates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢 incident templates"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"templates": templates, "count": len(templates)})
+	})
+
+	r.GET("/api/v1/incident-templates/:key", func(c *gin.Context) {
+		tmpl := getIncidentTemplate(db, tenant.OrgID(c), c.Param("key"))
+		if tmpl == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+			return
+		}
+		c.JSON(http.StatusOK, tmpl)
+	})
+
+	// 建立或更新 template（以 key 為識別）
+	r.PUT("/api/v1/incident-templates/:key", maxBody, func(c *gin.Context) {
+		var req struct {
+			Title           string `json:"title" binding:"required"`
+			Description     string `json:"description"`
+			DefaultSeverity string `json:"defaultSeverity" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !validSeverities[req.DefaultSeverity] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid defaultSeverity %q", req.DefaultSeverity)})
+			return
+		}
+
+		orgID := tenant.OrgID(c)
+		key := c.Param("key")
+		now := time.Now().UTC()
+
+		tmpl := getIncidentTemplate(db, orgID, key)
+		status := http.StatusOK
+		if tmpl == nil {
+			tmpl = &IncidentTemplate{OrgID: orgID, Key: key, CreatedAt: now}
+			status = http.StatusCreated
+		}
+		tmpl.Title = req.Title
+		tmpl.Description = req.Description
+		tmpl.DefaultSeverity = req.DefaultSeverity
+		tmpl.UpdatedAt = now
+
+		if err := db.Save(tmpl).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法儲存 incident template"})
+			return
+		}
+		c.JSON(status, tmpl)
+	})
+
+	r.DELETE("/api/v1/incident-templates/:key", func(c *gin.Context) {
+		result := db.Where("org_id = ? AND template_key = ?", tenant.OrgID(c), c.Param("key")).Delete(&IncidentTemplate{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法刪除 incident template"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
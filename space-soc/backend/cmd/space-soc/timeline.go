@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// TimelineItem 是 incident 時間軸上的一筆紀錄：建立、狀態變更或關聯事件。
+type TimelineItem struct {
+	Type         string                `json:"type"` // "incident_created", "status_change", "event"
+	Timestamp    time.Time             `json:"timestamp"`
+	Summary      string                `json:"summary"`
+	StatusChange *IncidentStatusChange `json:"statusChange,omitempty"`
+	Event        *Event                `json:"event,omitempty"`
+}
+
+// buildTimeline 依時間排序合併 incident 建立、狀態變更與事件；同時間的紀錄維持建立 → 狀態變更 → 事件的順序。
+func buildTimeline(incident Incident, changes []IncidentStatusChange, events []Event) []TimelineItem {
+	items := make([]TimelineItem, 0, 1+len(changes)+len(events))
+	items = append(items, TimelineItem{
+		Type:      "incident_created",
+		Timestamp: incident.CreatedAt,
+		Summary:   fmt.Sprintf("incident created: %s (severity %s)", incident.Title, incident.Severity),
+	})
+	for i := range changes {
+		change := &changes[i]
+		summary := fmt.Sprintf("status %s -> %s", change.OldStatus, change.NewStatus)
+		if change.ChangedBy != "" {
+			summary += " by " + change.ChangedBy
+		}
+		if change.Reason != "" {
+			summary += ": " + change.Reason
+		}
+		items = append(items, TimelineItem{
+			Type:         "status_change",
+			Timestamp:    change.ChangedAt,
+			Summary:      summary,
+			StatusChange: change,
+		})
+	}
+	for i := range events {
+		event := &events[i]
+		summary := fmt.Sprintf("%s from %s", event.EventType, event.Component)
+		if event.Message != "" {
+			summary += ": " + event.Message
+		}
+		items = append(items, TimelineItem{
+			Type:      "event",
+			Timestamp: event.CreatedAt,
+			Summary:   summary,
+			Event:     event,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Timestamp.Before(items[j].Timestamp)
+	})
+	return items
+}
+
+// registerTimelineRoutes 註冊 incident 時間軸端點。
+func registerTimelineRoutes(r *gin.Engine) {
+	r.GET("/api/v1/incidents/:id/timeline", func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid incident ID"})
+			return
+		}
+
+		orgID := tenant.OrgID(c)
+		var incident Incident
+		if err := db.Where("org_id = ?", orgID).First(&incident, uint(id)).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+			return
+		}
+
+		var changes []IncidentStatusChange
+		if err := db.Where("org_id = ? AND incident_id = ?", orgID, incident.ID).Order("changed_at ASC, id ASC").Find(&changes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢狀態變更紀錄"})
+			return
+		}
+		var events []Event
+		if err := db.Where("org_id = ? AND incident_id = ?", orgID, incident.ID).Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢事件"})
+			return
+		}
+
+		c.JSON(http.StatusOK, buildTimeline(incident, changes, events))
+	})
+}
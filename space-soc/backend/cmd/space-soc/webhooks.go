@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/internal/tenant"
+	"actinspace.org/space-soc/backend/internal/integrations"
+)
+
+// webhookManager 將 incident 變更推送到外部 webhook；未設定 WEBHOOKS_FILE 時為 nil。
+var webhookManager *integrations.WebhookManager
+
+// loadWebhookManager 從 WEBHOOKS_FILE（WebhookConfig 的 JSON 陣列）建立 webhook manager。
+func loadWebhookManager() (*integrations.WebhookManager, error) {
+	path := os.Getenv("WEBHOOKS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []integrations.WebhookConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	manager := integrations.NewWebhookManager(2)
+	for _, config := range configs {
+		if err := manager.RegisterWebhook(config); err != nil {
+			return nil, err
+		}
+	}
+	return manager, nil
+}
+
+// notifyWebhooks 將 incident 變更送往訂閱該事件類型（"incident.created" / "incident.status_changed"）的 webhook。
+func notifyWebhooks(update IncidentUpdate) {
+	if webhookManager == nil {
+		return
+	}
+	payload := struct {
+		IncidentUpdate
+		OrgID string `json:"orgId"`
+	}{update, update.OrgID}
+	webhookManager.SendOrgEvent(update.OrgID, "incident."+update.Type, payload)
+}
+
+// webhookInfo 是列出 webhook 時回傳的資訊，不含 header（可能帶有認證資訊）。
+type webhookInfo struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	Enabled    bool     `json:"enabled"`
+	EventTypes []string `json:"eventTypes,omitempty"`
+
+	Breaker *integrations.BreakerStatus `json:"breaker,omitempty"`
+}
+
+// registerWebhookRoutes 註冊 webhook 列表與投遞紀錄端點。webhook 為全域設定（URL 與投遞結果涵蓋所有組織），
+// 列表、投遞紀錄與重新投遞需要服務金鑰；dead letter 只列出呼叫者所屬組織的紀錄。
+func registerWebhookRoutes(r *gin.Engine) {
+	requireService := tenantKeys.RequireServiceKey()
+
+	r.GET("/api/v1/webhooks", requireService, func(c *gin.Context) {
+		webhooks := []webhookInfo{}
+		if webhookManager != nil {
+			for name, config := range webhookManager.GetWebhooks() {
+				webhooks = append(webhooks, webhookInfo{
+					Name:       name,
+					URL:        config.URL,
+					Enabled:    config.Enabled,
+					EventTypes: config.EventTypes,
+					Breaker:    config.Breaker,
+				})
+			}
+		}
+		sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].Name < webhooks[j].Name })
+		c.JSON(http.StatusOK, gin.H{"webhooks": webhooks, "count": len(webhooks)})
+	})
+
+	// 最近的投遞結果（含重試），由新到舊
+	r.GET("/api/v1/webhooks/:name/deliveries", requireService, func(c *gin.Context) {
+		name := c.Param("name")
+		if webhookManager == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return
+		}
+		if _, ok := webhookManager.GetWebhooks()[name]; !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return
+		}
+
+		deliveries := webhookManager.GetDeliveryLog(name)
+		c.JSON(http.StatusOK, gin.H{"webhook": name, "deliveries": deliveries, "count": len(deliveries)})
+	})
+
+	// 重試用盡或無法排入佇列的投遞，保留最後一次結果供稽核與後續處理
+	r.GET("/api/v1/webhooks/dead-letters", func(c *gin.Context) {
+		letters := []integrations.DeadLetter{}
+		if webhookManager != nil {
+			letters = webhookManager.GetOrgDeadLetters(tenant.OrgID(c))
+		}
+		c.JSON(http.StatusOK, gin.H{"deadLetters": letters, "count": len(letters)})
+	})
+
+	// 手動重新投遞（服務金鑰，且只能重送 X-Org-ID 所屬組織的紀錄）；原紀錄保留並標記 redriven_at
+	r.POST("/api/v1/webhooks/dead-letters/:id/redrive", requireService, func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dead letter ID"})
+			return
+		}
+		if webhookManager == nil || !slices.ContainsFunc(webhookManager.GetOrgDeadLetters(tenant.OrgID(c)), func(letter integrations.DeadLetter) bool {
+			return letter.ID == id
+		}) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dead letter not found"})
+			return
+		}
+
+		letter, err := webhookManager.RedriveDeadLetter(id)
+		switch {
+		case errors.Is(err, integrations.ErrDeadLetterNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "deadLetter": letter})
+		default:
+			c.JSON(http.StatusAccepted, letter)
+		}
+	})
+}
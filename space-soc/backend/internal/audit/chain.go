@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"actinspace.org/supply-chain/signing-service/signer"
+)
+
+// GenesisHash 是鏈上第一筆紀錄的 prevHash。
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Entry 是尚未串鏈的稽核項目（由呼叫端在寫入時產生）。
+type Entry struct {
+	Timestamp time.Time
+	Type      string // "incident_status_change", "release_approved", "rbac_role_changed"...
+	SourceID  uint
+	Data      map[string]interface{}
+}
+
+// Record 是匯出中的單筆稽核紀錄，hash 覆蓋 seq/timestamp/type/sourceId/data 與前一筆的 hash。
+type Record struct {
+	Seq       int                    `json:"seq"`
+	Timestamp string                 `json:"timestamp"` // RFC3339Nano (UTC)
+	Type      string                 `json:"type"`
+	SourceID  uint                   `json:"sourceId"`
+	Data      map[string]interface{} `json:"data"`
+	PrevHash  string                 `json:"prevHash"`
+	Hash      string                 `json:"hash"`
+}
+
+// Export 是稽核匯出文件。依時間範圍匯出時只包含鏈的一段，BaseSeq / BaseHash 為該段之前最後一筆的序號與 hash
+// （從鏈頭開始匯出時為 0 與 GenesisHash）。
+type Export struct {
+	OrgID       string    `json:"orgId"`
+	From        string    `json:"from,omitempty"`
+	To          string    `json:"to,omitempty"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	Algorithm   string    `json:"algorithm"`
+	BaseSeq     int       `json:"baseSeq,omitempty"`
+	BaseHash    string    `json:"baseHash,omitempty"`
+	Records     []Record  `json:"records"`
+	HeadHash    string    `json:"headHash"`
+	Signature   string    `json:"signature,omitempty"` // signer.Sign(headHash, secret)
+	Signer      string    `json:"signer,omitempty"`
+}
+
+// Build 依時間排序 entries 並建立 hash chain。
+func Build(orgID string, entries []Entry) Export {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Timestamp.Equal(entries[j].Timestamp) {
+			return entries[i].Timestamp.Before(entries[j].Timestamp)
+		}
+		if entries[i].Type != entries[j].Type {
+			return entries[i].Type < entries[j].Type
+		}
+		return entries[i].SourceID < entries[j].SourceID
+	})
+
+	export := Export{
+		OrgID:       orgID,
+		GeneratedAt: time.Now().UTC(),
+		Algorithm:   "sha256",
+		Records:     make([]Record, 0, len(entries)),
+		HeadHash:    GenesisHash,
+	}
+
+	prev := Record{Hash: GenesisHash}
+	for _, entry := range entries {
+		prev = Append(prev.Seq, prev.Hash, entry)
+		export.Records = append(export.Records, prev)
+	}
+	export.HeadHash = prev.Hash
+
+	return export
+}
+
+// Append 建立接在 (prevSeq, prevHash) 之後的紀錄並計算其 hash，供寫入時逐筆串鏈。
+func Append(prevSeq int, prevHash string, entry Entry) Record {
+	data := entry.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	record := Record{
+		Seq:       prevSeq + 1,
+		Timestamp: entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		Type:      entry.Type,
+		SourceID:  entry.SourceID,
+		Data:      data,
+		PrevHash:  prevHash,
+	}
+	record.Hash = hashRecord(record)
+	return record
+}
+
+// FromRecords 以已儲存的連續紀錄建立匯出文件，鏈的起點取自第一筆的 prevHash。
+func FromRecords(orgID string, records []Record) Export {
+	export := Export{
+		OrgID:       orgID,
+		GeneratedAt: time.Now().UTC(),
+		Algorithm:   "sha256",
+		Records:     records,
+		HeadHash:    GenesisHash,
+	}
+	if len(records) > 0 {
+		export.BaseSeq = records[0].Seq - 1
+		export.BaseHash = records[0].PrevHash
+		export.HeadHash = records[len(records)-1].Hash
+	}
+	if export.Records == nil {
+		export.Records = []Record{}
+	}
+	return export
+}
+
+// Sign 以簽章服務的 secret 對鏈頭 hash 簽章。
+func (e *Export) Sign(secret, signerName string) {
+	e.Signature = signer.Sign(e.HeadHash, secret)
+	e.Signer = signerName
+}
+
+// Verify 重新計算（從 BaseHash 開始的）hash chain，偵測任何插入、刪除或修改；
+// secret 非空時同時驗證簽章。
+func Verify(export Export, secret string) error {
+	prev := export.BaseHash
+	if prev == "" {
+		prev = GenesisHash
+	}
+	for i, record := range export.Records {
+		if record.Seq != export.BaseSeq+i+1 {
+			return fmt.Errorf("record %d: unexpected sequence number %d", export.BaseSeq+i+1, record.Seq)
+		}
+		if record.PrevHash != prev {
+			return fmt.Errorf("record %d: prevHash does not match previous record", record.Seq)
+		}
+		if hashRecord(record) != record.Hash {
+			return fmt.Errorf("record %d: hash mismatch (record modified)", record.Seq)
+		}
+		prev = record.Hash
+	}
+
+	if export.HeadHash != prev {
+		return fmt.Errorf("headHash does not match last record (records truncated or appended)")
+	}
+
+	if secret != "" {
+		if export.Signature == "" {
+			return fmt.Errorf("export is not signed")
+		}
+		if !signer.Verify(export.HeadHash, export.Signature, secret) {
+			return fmt.Errorf("signature verification failed")
+		}
+	}
+
+	return nil
+}
+
+// hashRecord 計算 sha256(prevHash + canonical JSON)；map 由 encoding/json 依鍵排序，結果穩定。
+func hashRecord(record Record) string {
+	canonical, _ := json.Marshal(struct {
+		Seq       int                    `json:"seq"`
+		Timestamp string                 `json:"timestamp"`
+		Type      string                 `json:"type"`
+		SourceID  uint                   `json:"sourceId"`
+		Data      map[string]interface{} `json:"data"`
+	}{record.Seq, record.Timestamp, record.Type, record.SourceID, record.Data})
+
+	sum := sha256.Sum256(append([]byte(record.PrevHash), canonical...))
+	return hex.EncodeToString(sum[:])
+}
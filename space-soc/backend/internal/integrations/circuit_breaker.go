@@ -0,0 +1,119 @@
+package integrations
+
+import (
+	"fmt"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // deliveries flow normally
+	BreakerOpen     = "open"      // deliveries are skipped until the cooldown elapses
+	BreakerHalfOpen = "half-open" // a single probe delivery decides whether to close again
+)
+
+// Circuit breaker defaults applied by RegisterWebhook
+const (
+	DefaultBreakerThreshold    = 5
+	DefaultBreakerCooldownSecs = 60
+)
+
+// BreakerStatus is a snapshot of a webhook's circuit breaker
+type BreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// circuitBreaker tracks consecutive failures of one webhook endpoint
+type circuitBreaker struct {
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool // a half-open probe is in flight
+}
+
+func (b *circuitBreaker) status() BreakerStatus {
+	status := BreakerStatus{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// breakerFor returns the webhook's breaker, creating a closed one (breakerMu must be held)
+func (m *WebhookManager) breakerFor(name string) *circuitBreaker {
+	breaker, ok := m.breakers[name]
+	if !ok {
+		breaker = &circuitBreaker{state: BreakerClosed}
+		m.breakers[name] = breaker
+	}
+	return breaker
+}
+
+// allowDelivery reports whether a delivery may be attempted now. Once the cooldown of an
+// open breaker has elapsed, exactly one delivery is let through as a half-open probe.
+func (m *WebhookManager) allowDelivery(config *WebhookConfig, now time.Time) bool {
+	m.breakerMu.Lock()
+	defer m.breakerMu.Unlock()
+
+	breaker := m.breakerFor(config.Name)
+	switch breaker.state {
+	case BreakerOpen:
+		cooldown := time.Duration(config.BreakerCooldownSecs) * time.Second
+		if now.Sub(breaker.openedAt) < cooldown {
+			return false
+		}
+		breaker.state = BreakerHalfOpen
+		breaker.probing = true
+		return true
+	case BreakerHalfOpen:
+		if breaker.probing {
+			return false
+		}
+		breaker.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// recordBreakerResult updates the breaker after a delivery attempt
+func (m *WebhookManager) recordBreakerResult(config *WebhookConfig, success bool, now time.Time) {
+	m.breakerMu.Lock()
+	defer m.breakerMu.Unlock()
+
+	breaker := m.breakerFor(config.Name)
+	if success {
+		*breaker = circuitBreaker{state: BreakerClosed}
+		return
+	}
+
+	breaker.failures++
+	breaker.probing = false
+	if breaker.state == BreakerHalfOpen || breaker.failures >= config.BreakerThreshold {
+		if breaker.state != BreakerOpen {
+			fmt.Printf("Webhook circuit breaker opened for %s after %d consecutive failures\n", config.Name, breaker.failures)
+		}
+		breaker.state = BreakerOpen
+		breaker.openedAt = now
+	}
+}
+
+// releaseProbe lets another half-open probe through when the current one was cancelled
+// without reaching the endpoint
+func (m *WebhookManager) releaseProbe(config *WebhookConfig) {
+	m.breakerMu.Lock()
+	defer m.breakerMu.Unlock()
+
+	m.breakerFor(config.Name).probing = false
+}
+
+// breakerStatus returns the current breaker snapshot for a webhook
+func (m *WebhookManager) breakerStatus(name string) BreakerStatus {
+	m.breakerMu.Lock()
+	defer m.breakerMu.Unlock()
+
+	return m.breakerFor(name).status()
+}
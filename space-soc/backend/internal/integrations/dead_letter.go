@@ -0,0 +1,144 @@
+package integrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultDeadLetterCapacity is the number of dead letters kept before the oldest are evicted
+const DefaultDeadLetterCapacity = 1000
+
+var (
+	// ErrDeadLetterNotFound is returned when re-driving an unknown dead letter
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrDeadLetterRedriven is returned when a dead letter has already been re-driven
+	ErrDeadLetterRedriven = errors.New("dead letter already re-driven")
+)
+
+// DeadLetter is a webhook delivery that permanently failed: retries were exhausted,
+// or it could not be queued at all
+type DeadLetter struct {
+	ID          uint64        `json:"id"`
+	Webhook     string        `json:"webhook"`
+	OrgID       string        `json:"org_id,omitempty"`
+	EventType   string        `json:"event_type,omitempty"`
+	Payload     interface{}   `json:"payload"`
+	Attempts    int           `json:"attempts"`
+	FirstQueued time.Time     `json:"first_queued"`
+	FailedAt    time.Time     `json:"failed_at"`
+	LastResult  WebhookResult `json:"last_result"`
+	// Set once the delivery has been manually queued again; the entry is kept as a record
+	RedrivenAt *time.Time `json:"redriven_at,omitempty"`
+}
+
+// deadLetter records a delivery that will not be retried again
+func (m *WebhookManager) deadLetter(delivery WebhookDelivery, result WebhookResult) {
+	m.logMu.Lock()
+	m.countsLocked(delivery.Config.Name).DeadLettered++
+	m.logMu.Unlock()
+
+	m.deadMu.Lock()
+	defer m.deadMu.Unlock()
+
+	m.nextDeadID++
+	m.deadLetters = append(m.deadLetters, DeadLetter{
+		ID:          m.nextDeadID,
+		Webhook:     delivery.Config.Name,
+		OrgID:       delivery.OrgID,
+		EventType:   delivery.EventType,
+		Payload:     delivery.Payload,
+		Attempts:    delivery.Attempt + 1,
+		FirstQueued: delivery.Timestamp,
+		FailedAt:    time.Now(),
+		LastResult:  result,
+	})
+	if len(m.deadLetters) > DefaultDeadLetterCapacity {
+		m.deadLetters = m.deadLetters[len(m.deadLetters)-DefaultDeadLetterCapacity:]
+	}
+}
+
+// GetDeadLetters returns the recorded dead letters, oldest first
+func (m *WebhookManager) GetDeadLetters() []DeadLetter {
+	m.deadMu.Lock()
+	defer m.deadMu.Unlock()
+
+	letters := make([]DeadLetter, len(m.deadLetters))
+	copy(letters, m.deadLetters)
+	return letters
+}
+
+// GetOrgDeadLetters returns the dead letters of one organization, oldest first
+func (m *WebhookManager) GetOrgDeadLetters(orgID string) []DeadLetter {
+	m.deadMu.Lock()
+	defer m.deadMu.Unlock()
+
+	letters := []DeadLetter{}
+	for _, letter := range m.deadLetters {
+		if letter.OrgID == orgID {
+			letters = append(letters, letter)
+		}
+	}
+	return letters
+}
+
+// findDeadLetter returns the index of a dead letter (deadMu must be held)
+func (m *WebhookManager) findDeadLetter(id uint64) int {
+	for i := range m.deadLetters {
+		if m.deadLetters[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// RedriveDeadLetter queues a dead letter again as a fresh delivery (attempt 0) using the
+// webhook's current configuration. The dead letter is kept and marked as re-driven; if the
+// new delivery fails permanently it is recorded as a new dead letter.
+func (m *WebhookManager) RedriveDeadLetter(id uint64) (DeadLetter, error) {
+	// Look up the webhook without holding deadMu: SendEvent takes mu before deadMu
+	m.deadMu.Lock()
+	index := m.findDeadLetter(id)
+	var webhook string
+	if index >= 0 {
+		webhook = m.deadLetters[index].Webhook
+	}
+	m.deadMu.Unlock()
+	if index < 0 {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+
+	m.mu.RLock()
+	config, registered := m.webhooks[webhook]
+	m.mu.RUnlock()
+
+	m.deadMu.Lock()
+	defer m.deadMu.Unlock()
+
+	index = m.findDeadLetter(id)
+	if index < 0 {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+	letter := &m.deadLetters[index]
+	if letter.RedrivenAt != nil {
+		return *letter, ErrDeadLetterRedriven
+	}
+	if !registered {
+		return *letter, fmt.Errorf("webhook %s is no longer registered", webhook)
+	}
+
+	delivery := WebhookDelivery{
+		Config:    config,
+		OrgID:     letter.OrgID,
+		EventType: letter.EventType,
+		Payload:   letter.Payload,
+		Timestamp: time.Now(),
+	}
+	if err := m.enqueue(delivery); err != nil {
+		return *letter, err
+	}
+
+	now := time.Now()
+	letter.RedrivenAt = &now
+	return *letter, nil
+}
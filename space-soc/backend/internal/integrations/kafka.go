@@ -1,10 +1,13 @@
 package integrations
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/segmentio/kafka-go"
 )
 
 // KafkaConfig represents Kafka connection configuration
@@ -13,9 +16,10 @@ type KafkaConfig struct {
 	Topic         string            `json:"topic"`
 	ClientID      string            `json:"client_id"`
 	Enabled       bool              `json:"enabled"`
-	Compression   string            `json:"compression"` // none, gzip, snappy, lz4
+	Compression   string            `json:"compression"` // none, gzip, snappy, lz4, zstd
 	BatchSize     int               `json:"batch_size"`
 	FlushInterval int               `json:"flush_interval_ms"`
+	KeyStrategy   string            `json:"key_strategy"` // event_type (default), component, scenario, random
 	TLS           *TLSConfig        `json:"tls,omitempty"`
 	SASL          *SASLConfig       `json:"sasl,omitempty"`
 }
@@ -37,15 +41,18 @@ type SASLConfig struct {
 	Password  string `json:"password"`
 }
 
-// KafkaProducer manages Kafka event production
-// Note: This is a mock implementation. In production, use a real Kafka client library
-// such as github.com/segmentio/kafka-go or github.com/confluentinc/confluent-kafka-go
+// KafkaProducer manages Kafka event production. Messages are buffered and published in
+// batches through kafka-go; without brokers it falls back to a mock backend that only counts
 type KafkaProducer struct {
-	mu      sync.RWMutex
-	config  KafkaConfig
-	buffer  []KafkaMessage
-	enabled bool
-	stats   KafkaStats
+	mu        sync.RWMutex
+	config    KafkaConfig
+	transport *kafka.Transport // broker transport with TLS/SASL applied
+	backend   kafkaBackend     // kafka-go writer or mock (see kafka_writer.go)
+	buffer    []KafkaMessage
+	enabled   bool
+	stats     KafkaStats
+	stop      chan struct{} // closed by Close to end the flush loop
+	closed    bool
 }
 
 // KafkaMessage represents a message to be sent to Kafka
@@ -58,18 +65,19 @@ type KafkaMessage struct {
 
 // KafkaStats tracks Kafka producer statistics
 type KafkaStats struct {
+	Topic            string    `json:"topic"`
 	MessagesSent     int64     `json:"messages_sent"`
 	MessagesBuffered int       `json:"messages_buffered"`
 	BytesSent        int64     `json:"bytes_sent"`
 	Errors           int64     `json:"errors"`
 	LastSent         time.Time `json:"last_sent"`
+	LastError        string    `json:"last_error,omitempty"`
+	Backend          string    `json:"backend"` // "kafka" or "mock"
 }
 
-// NewKafkaProducer creates a new Kafka producer
+// NewKafkaProducer creates a new Kafka producer. With an empty broker list it uses the mock
+// backend, which logs and counts messages without a cluster.
 func NewKafkaProducer(config KafkaConfig) (*KafkaProducer, error) {
-	if len(config.Brokers) == 0 {
-		return nil, fmt.Errorf("at least one broker is required")
-	}
 	if config.Topic == "" {
 		return nil, fmt.Errorf("topic is required")
 	}
@@ -79,16 +87,41 @@ func NewKafkaProducer(config KafkaConfig) (*KafkaProducer, error) {
 	if config.FlushInterval == 0 {
 		config.FlushInterval = 1000 // 1 second
 	}
+	if config.KeyStrategy == "" {
+		config.KeyStrategy = KeyByEventType
+	}
+	if !validKeyStrategy(config.KeyStrategy) {
+		return nil, fmt.Errorf("unsupported key_strategy %q (supported: %s, %s, %s, %s)",
+			config.KeyStrategy, KeyByEventType, KeyByComponent, KeyByScenario, KeyRandom)
+	}
+
+	transport, err := newKafkaTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var backend kafkaBackend = &mockBackend{topic: config.Topic}
+	if len(config.Brokers) > 0 {
+		writer, err := newWriterBackend(config, transport)
+		if err != nil {
+			return nil, err
+		}
+		backend = writer
+	}
 
 	producer := &KafkaProducer{
-		config:  config,
-		buffer:  make([]KafkaMessage, 0, config.BatchSize),
-		enabled: config.Enabled,
+		config:    config,
+		transport: transport,
+		backend:   backend,
+		buffer:    make([]KafkaMessage, 0, config.BatchSize),
+		enabled:   config.Enabled,
+		stats:     KafkaStats{Topic: config.Topic, Backend: backend.name()},
+		stop:      make(chan struct{}),
 	}
 
 	// Start flush goroutine
 	if config.Enabled {
-		go producer.flushLoop()
+		go producer.flushLoop(producer.stop)
 	}
 
 	return producer, nil
@@ -104,7 +137,7 @@ func (p *KafkaProducer) SendEvent(eventType string, payload map[string]interface
 	defer p.mu.Unlock()
 
 	message := KafkaMessage{
-		Key:       eventType,
+		Key:       p.messageKey(eventType, payload),
 		Value:     payload,
 		Timestamp: time.Now(),
 		Headers: map[string]string{
@@ -124,45 +157,88 @@ func (p *KafkaProducer) SendEvent(eventType string, payload map[string]interface
 	return nil
 }
 
+// Partition key strategies for KafkaConfig.KeyStrategy
+const (
+	KeyByEventType = "event_type" // all events of one type share a partition
+	KeyByComponent = "component"  // payload "component"
+	KeyByScenario  = "scenario"   // payload "scenarioID", keeping a scenario's events in order
+	KeyRandom      = "random"     // no key: messages are spread round-robin
+)
+
+func validKeyStrategy(strategy string) bool {
+	switch strategy {
+	case KeyByEventType, KeyByComponent, KeyByScenario, KeyRandom:
+		return true
+	}
+	return false
+}
+
+// messageKey computes the partition key; component and scenario fall back to the
+// event type when the payload does not carry the field
+func (p *KafkaProducer) messageKey(eventType string, payload map[string]interface{}) string {
+	field := ""
+	switch p.config.KeyStrategy {
+	case KeyRandom:
+		return ""
+	case KeyByComponent:
+		field = "component"
+	case KeyByScenario:
+		field = "scenarioID"
+	default:
+		return eventType
+	}
+	if value, ok := payload[field].(string); ok && value != "" {
+		return value
+	}
+	return eventType
+}
+
 // flush sends buffered messages to Kafka
 func (p *KafkaProducer) flush() error {
 	if len(p.buffer) == 0 {
 		return nil
 	}
 
-	// In a real implementation, this would use a Kafka client library
-	// For now, we simulate the flush operation
-	fmt.Printf("[Kafka Mock] Flushing %d messages to topic %s\n", len(p.buffer), p.config.Topic)
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaWriteTimeout)
+	defer cancel()
 
-	// Simulate serialization
-	for _, msg := range p.buffer {
-		msgBytes, err := json.Marshal(msg)
-		if err != nil {
-			p.stats.Errors++
-			continue
-		}
-		p.stats.BytesSent += int64(len(msgBytes))
-		p.stats.MessagesSent++
+	// Failed messages are counted and dropped (the writer already retried them)
+	sent, bytes, err := p.backend.publish(ctx, p.buffer)
+	p.stats.MessagesSent += int64(sent)
+	p.stats.BytesSent += bytes
+	p.stats.Errors += int64(len(p.buffer) - sent)
+	if sent > 0 {
+		p.stats.LastSent = time.Now()
+	}
+	if err != nil {
+		p.stats.LastError = err.Error()
 	}
 
-	p.stats.LastSent = time.Now()
 	p.buffer = p.buffer[:0] // Clear buffer
 	p.stats.MessagesBuffered = 0
 
+	if err != nil {
+		return fmt.Errorf("kafka publish to %s: %w", p.config.Topic, err)
+	}
 	return nil
 }
 
-// flushLoop periodically flushes the buffer
-func (p *KafkaProducer) flushLoop() {
+// flushLoop periodically flushes the buffer until stop is closed
+func (p *KafkaProducer) flushLoop(stop <-chan struct{}) {
 	ticker := time.NewTicker(time.Duration(p.config.FlushInterval) * time.Millisecond)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		p.mu.Lock()
-		if err := p.flush(); err != nil {
-			fmt.Printf("[Kafka] Flush error: %v\n", err)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			if err := p.flush(); err != nil {
+				slog.Error("kafka flush failed", "topic", p.config.Topic, "error", err)
+			}
+			p.mu.Unlock()
 		}
-		p.mu.Unlock()
 	}
 }
 
@@ -174,18 +250,25 @@ func (p *KafkaProducer) GetStats() KafkaStats {
 	return p.stats
 }
 
-// Close closes the Kafka producer
+// Close flushes remaining messages and closes the Kafka producer
 func (p *KafkaProducer) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Flush remaining messages
-	if err := p.flush(); err != nil {
-		return err
+	if p.closed {
+		return nil
 	}
 
+	// Flush remaining messages
+	flushErr := p.flush()
+
 	p.enabled = false
-	return nil
+	p.closed = true
+	close(p.stop)
+	if err := p.backend.close(); err != nil && flushErr == nil {
+		return err
+	}
+	return flushErr
 }
 
 // Enable enables the Kafka producer
@@ -193,10 +276,13 @@ func (p *KafkaProducer) Enable() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if p.closed {
+		return
+	}
 	p.enabled = true
 	if !p.config.Enabled {
 		p.config.Enabled = true
-		go p.flushLoop()
+		go p.flushLoop(p.stop)
 	}
 }
 
@@ -208,3 +294,10 @@ func (p *KafkaProducer) Disable() {
 	p.enabled = false
 }
 
+// IsEnabled reports whether SendEvent currently accepts messages
+func (p *KafkaProducer) IsEnabled() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.enabled
+}
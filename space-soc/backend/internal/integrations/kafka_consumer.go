@@ -0,0 +1,276 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Handler retry policy: storage errors are retried before the message is skipped
+const (
+	consumerHandlerAttempts = 3
+	consumerRetryBackoff    = time.Second
+)
+
+// KafkaConsumerConfig represents configuration for consuming events from Kafka
+type KafkaConsumerConfig struct {
+	Brokers     []string    `json:"brokers"`
+	Topic       string      `json:"topic"`
+	GroupID     string      `json:"group_id"` // consumer group; instances sharing it split the partitions
+	ClientID    string      `json:"client_id"`
+	StartOffset string      `json:"start_offset"` // "earliest" or "latest" (default), for groups without committed offsets
+	TLS         *TLSConfig  `json:"tls,omitempty"`
+	SASL        *SASLConfig `json:"sasl,omitempty"`
+}
+
+// ConsumedEvent is an event decoded from a Kafka message; its JSON fields match the
+// HTTP ingestion request, plus an optional organization
+type ConsumedEvent struct {
+	OrgID        string                 `json:"orgId,omitempty"` // falls back to the "org_id" header
+	Component    string                 `json:"component"`
+	EventType    string                 `json:"eventType"`
+	Command      string                 `json:"command,omitempty"`
+	OperatorRole string                 `json:"operatorRole,omitempty"`
+	OperatorID   string                 `json:"operatorId,omitempty"`
+	Decision     string                 `json:"decision,omitempty"`
+	Reason       string                 `json:"reason,omitempty"`
+	Status       string                 `json:"status,omitempty"`
+	Message      string                 `json:"message,omitempty"`
+	Severity     string                 `json:"severity,omitempty"`
+	RuleID       string                 `json:"ruleID,omitempty"`
+	AnomalyType  string                 `json:"anomalyType,omitempty"`
+	ScenarioID   string                 `json:"scenarioID,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ConsumedEventHandler stores a consumed event; returning an error retries the message
+type ConsumedEventHandler func(ctx context.Context, event ConsumedEvent) error
+
+// KafkaConsumerStats tracks Kafka consumer statistics
+type KafkaConsumerStats struct {
+	Running       bool       `json:"running"`
+	Topic         string     `json:"topic"`
+	GroupID       string     `json:"group_id"`
+	Consumed      int64      `json:"consumed"`       // messages stored successfully
+	DecodeErrors  int64      `json:"decode_errors"`  // malformed messages, skipped
+	HandlerErrors int64      `json:"handler_errors"` // messages skipped after exhausting retries
+	Lag           int64      `json:"lag"`            // messages behind the partition head
+	LastMessage   *time.Time `json:"last_message,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// KafkaConsumer reads events from a topic as part of a consumer group and passes them
+// to a handler, committing offsets once each message has been handled
+type KafkaConsumer struct {
+	mu      sync.Mutex
+	config  KafkaConsumerConfig
+	dialer  *kafka.Dialer
+	handler ConsumedEventHandler
+	reader  *kafka.Reader
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stats   KafkaConsumerStats
+}
+
+// NewKafkaConsumer validates the configuration (including TLS/SASL) and creates a stopped consumer
+func NewKafkaConsumer(config KafkaConsumerConfig, handler ConsumedEventHandler) (*KafkaConsumer, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("at least one broker is required")
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+	if config.GroupID == "" {
+		return nil, fmt.Errorf("group_id is required")
+	}
+	if config.StartOffset != "" && config.StartOffset != "earliest" && config.StartOffset != "latest" {
+		return nil, fmt.Errorf("start_offset must be earliest or latest")
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("handler is required")
+	}
+
+	// Reuse the producer's security settings; the reader dials through a Dialer
+	transport, err := newKafkaTransport(KafkaConfig{ClientID: config.ClientID, TLS: config.TLS, SASL: config.SASL})
+	if err != nil {
+		return nil, err
+	}
+
+	return &KafkaConsumer{
+		config: config,
+		dialer: &kafka.Dialer{
+			ClientID:      config.ClientID,
+			Timeout:       10 * time.Second,
+			DualStack:     true,
+			TLS:           transport.TLS,
+			SASLMechanism: transport.SASL,
+		},
+		handler: handler,
+		stats:   KafkaConsumerStats{Topic: config.Topic, GroupID: config.GroupID},
+	}, nil
+}
+
+// Start begins consuming in the background; it is a no-op when already running
+func (c *KafkaConsumer) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reader != nil {
+		return
+	}
+
+	startOffset := kafka.LastOffset
+	if c.config.StartOffset == "earliest" {
+		startOffset = kafka.FirstOffset
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     c.config.Brokers,
+		Topic:       c.config.Topic,
+		GroupID:     c.config.GroupID,
+		Dialer:      c.dialer,
+		StartOffset: startOffset,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	c.reader = reader
+	c.cancel = cancel
+	c.done = done
+	c.stats.Running = true
+
+	go func() {
+		defer close(done)
+		c.run(ctx, reader)
+	}()
+}
+
+// Stop stops consuming, waits for the message in progress and leaves the consumer group
+func (c *KafkaConsumer) Stop() error {
+	c.mu.Lock()
+	reader, cancel, done := c.reader, c.cancel, c.done
+	c.reader = nil
+	c.stats.Running = false
+	c.mu.Unlock()
+
+	if reader == nil {
+		return nil
+	}
+	cancel()
+	<-done
+	return reader.Close()
+}
+
+// run fetches, handles and commits messages until ctx is cancelled
+func (c *KafkaConsumer) run(ctx context.Context, reader *kafka.Reader) {
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.recordError(nil, fmt.Errorf("fetch: %w", err))
+			slog.Warn("kafka consumer fetch failed", "topic", c.config.Topic, "error", err)
+			if !sleepCtx(ctx, consumerRetryBackoff) {
+				return
+			}
+			continue
+		}
+
+		event, err := decodeConsumedEvent(msg)
+		if err != nil {
+			c.recordError(&c.stats.DecodeErrors, err)
+			slog.Warn("kafka consumer skipped malformed message", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "error", err)
+		} else if err := c.handle(ctx, event); err != nil {
+			if ctx.Err() != nil {
+				return // not handled; redelivered after restart since the offset is not committed
+			}
+			c.recordError(&c.stats.HandlerErrors, err)
+			slog.Error("kafka consumer skipped message after retries", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "error", err)
+		} else {
+			c.mu.Lock()
+			c.stats.Consumed++
+			now := time.Now()
+			c.stats.LastMessage = &now
+			c.mu.Unlock()
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			slog.Warn("kafka consumer commit failed", "topic", msg.Topic, "offset", msg.Offset, "error", err)
+		}
+	}
+}
+
+// handle passes the event to the handler, retrying failures with a fixed backoff
+func (c *KafkaConsumer) handle(ctx context.Context, event ConsumedEvent) error {
+	var err error
+	for attempt := 1; attempt <= consumerHandlerAttempts; attempt++ {
+		if err = c.handler(ctx, event); err == nil {
+			return nil
+		}
+		if attempt < consumerHandlerAttempts && !sleepCtx(ctx, consumerRetryBackoff) {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// recordError remembers the last error and increments counter when given
+func (c *KafkaConsumer) recordError(counter *int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if counter != nil {
+		*counter++
+	}
+	c.stats.LastError = err.Error()
+}
+
+// decodeConsumedEvent parses a message value and applies the org_id header fallback
+func decodeConsumedEvent(msg kafka.Message) (ConsumedEvent, error) {
+	var event ConsumedEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return event, fmt.Errorf("decode message: %w", err)
+	}
+	if event.Component == "" || event.EventType == "" {
+		return event, errors.New("decode message: component and eventType are required")
+	}
+	if event.OrgID == "" {
+		for _, header := range msg.Headers {
+			if header.Key == "org_id" {
+				event.OrgID = string(header.Value)
+			}
+		}
+	}
+	return event, nil
+}
+
+// sleepCtx waits for d, returning false if ctx is cancelled first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// GetStats returns current consumer statistics, including the lag reported by the reader
+func (c *KafkaConsumer) GetStats() KafkaConsumerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	if c.reader != nil {
+		stats.Lag = c.reader.Stats().Lag
+	}
+	return stats
+}
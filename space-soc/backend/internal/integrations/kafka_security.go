@@ -0,0 +1,109 @@
+package integrations
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// supportedSASLMechanisms lists the SASL mechanisms accepted in SASLConfig.Mechanism
+var supportedSASLMechanisms = []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"}
+
+// buildTLSConfig loads the CA bundle and optional client certificate referenced by the config.
+// It returns nil when TLS is disabled.
+func buildTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		caPEM, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %q: %w", cfg.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("CA file %q contains no valid PEM certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.InsecureSkipVerify {
+		slog.Warn("kafka TLS certificate verification is disabled (insecure_skip_verify); do not use in production")
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return tlsConfig, nil
+}
+
+// buildSASLMechanism creates the SASL mechanism for the configured credentials.
+// It returns nil when SASL is disabled.
+func buildSASLMechanism(cfg *SASLConfig) (sasl.Mechanism, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Username == "" || cfg.Password == "" {
+		return nil, fmt.Errorf("SASL username and password are required")
+	}
+
+	// Accept "scram-sha-512" as well as the "SCRAM_SHA_512" spelling some providers use
+	mechanism := strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(cfg.Mechanism)), "_", "-")
+	if mechanism == "" {
+		return nil, fmt.Errorf("SASL mechanism is required (supported: %s)", strings.Join(supportedSASLMechanisms, ", "))
+	}
+
+	switch mechanism {
+	case "PLAIN":
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case "SCRAM-SHA-256":
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case "SCRAM-SHA-512":
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism %q (supported: %s)",
+			cfg.Mechanism, strings.Join(supportedSASLMechanisms, ", "))
+	}
+}
+
+// newKafkaTransport builds the broker transport with TLS and SASL applied, so that a
+// misconfigured secured cluster fails at construction time rather than on the first send.
+func newKafkaTransport(config KafkaConfig) (*kafka.Transport, error) {
+	tlsConfig, err := buildTLSConfig(config.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("kafka TLS config: %w", err)
+	}
+	mechanism, err := buildSASLMechanism(config.SASL)
+	if err != nil {
+		return nil, fmt.Errorf("kafka SASL config: %w", err)
+	}
+	if mechanism != nil && tlsConfig == nil && mechanism.Name() == "PLAIN" {
+		slog.Warn("kafka SASL/PLAIN is enabled without TLS; credentials are sent in cleartext")
+	}
+
+	return &kafka.Transport{
+		ClientID: config.ClientID,
+		TLS:      tlsConfig,
+		SASL:     mechanism,
+	}, nil
+}
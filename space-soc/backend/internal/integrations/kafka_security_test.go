@@ -0,0 +1,213 @@
+package integrations
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM files, usable both
+// as the CA bundle and as the client certificate
+func writeTestCertificate(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestBuildSASLMechanism(t *testing.T) {
+	tests := []struct {
+		mechanism string
+		want      string
+	}{
+		{"PLAIN", "PLAIN"},
+		{"plain", "PLAIN"},
+		{"SCRAM-SHA-256", "SCRAM-SHA-256"},
+		{"scram-sha-256", "SCRAM-SHA-256"},
+		{"SCRAM-SHA-512", "SCRAM-SHA-512"},
+		{" SCRAM_SHA_512 ", "SCRAM-SHA-512"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mechanism, func(t *testing.T) {
+			mechanism, err := buildSASLMechanism(&SASLConfig{Enabled: true, Mechanism: tt.mechanism, Username: "soc", Password: "secret"})
+			if err != nil {
+				t.Fatalf("buildSASLMechanism: %v", err)
+			}
+			if mechanism.Name() != tt.want {
+				t.Fatalf("mechanism = %s, want %s", mechanism.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSASLMechanismErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  SASLConfig
+	}{
+		{"missing mechanism", SASLConfig{Enabled: true, Username: "soc", Password: "secret"}},
+		{"unsupported mechanism", SASLConfig{Enabled: true, Mechanism: "GSSAPI", Username: "soc", Password: "secret"}},
+		{"missing username", SASLConfig{Enabled: true, Mechanism: "PLAIN", Password: "secret"}},
+		{"missing password", SASLConfig{Enabled: true, Mechanism: "SCRAM-SHA-256", Username: "soc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildSASLMechanism(&tt.cfg); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	for _, cfg := range []*SASLConfig{nil, {Enabled: false, Mechanism: "GSSAPI"}} {
+		if mechanism, err := buildSASLMechanism(cfg); mechanism != nil || err != nil {
+			t.Fatalf("disabled SASL should build nothing, got %v, %v", mechanism, err)
+		}
+	}
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	tlsConfig, err := buildTLSConfig(&TLSConfig{Enabled: true, CAFile: certFile, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("buildTLSConfig: %v", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, want TLS 1.2", tlsConfig.MinVersion)
+	}
+	if tlsConfig.RootCAs == nil {
+		t.Error("RootCAs not loaded from the CA file")
+	}
+	if len(tlsConfig.Certificates) != 1 {
+		t.Errorf("loaded %d client certificates, want 1", len(tlsConfig.Certificates))
+	}
+	if tlsConfig.InsecureSkipVerify {
+		t.Error("certificate verification should stay enabled")
+	}
+
+	insecure, err := buildTLSConfig(&TLSConfig{Enabled: true, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("buildTLSConfig: %v", err)
+	}
+	if !insecure.InsecureSkipVerify || insecure.RootCAs != nil || len(insecure.Certificates) != 0 {
+		t.Errorf("unexpected insecure config: %+v", insecure)
+	}
+
+	if tlsConfig, err := buildTLSConfig(&TLSConfig{Enabled: false, CAFile: "/missing"}); tlsConfig != nil || err != nil {
+		t.Fatalf("disabled TLS should build nothing, got %v, %v", tlsConfig, err)
+	}
+}
+
+func TestBuildTLSConfigErrors(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	notPEM := filepath.Join(t.TempDir(), "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  TLSConfig
+	}{
+		{"missing CA file", TLSConfig{Enabled: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{"CA file without certificates", TLSConfig{Enabled: true, CAFile: notPEM}},
+		{"certificate without key", TLSConfig{Enabled: true, CertFile: certFile}},
+		{"key without certificate", TLSConfig{Enabled: true, KeyFile: keyFile}},
+		{"key does not match", TLSConfig{Enabled: true, CertFile: certFile, KeyFile: certFile}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildTLSConfig(&tt.cfg); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestSecurityAppliedToProducerAndConsumer(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	tlsCfg := &TLSConfig{Enabled: true, CAFile: certFile, CertFile: certFile, KeyFile: keyFile}
+	saslCfg := &SASLConfig{Enabled: true, Mechanism: "scram-sha-512", Username: "soc", Password: "secret"}
+
+	transport, err := newKafkaTransport(KafkaConfig{ClientID: "space-soc", TLS: tlsCfg, SASL: saslCfg})
+	if err != nil {
+		t.Fatalf("newKafkaTransport: %v", err)
+	}
+	if transport.ClientID != "space-soc" || transport.TLS == nil || transport.SASL == nil || transport.SASL.Name() != "SCRAM-SHA-512" {
+		t.Fatalf("transport missing security settings: %+v", transport)
+	}
+
+	producer, err := NewKafkaProducer(KafkaConfig{Topic: "events", TLS: tlsCfg, SASL: saslCfg})
+	if err != nil {
+		t.Fatalf("NewKafkaProducer: %v", err)
+	}
+	if producer.transport.TLS == nil || producer.transport.SASL == nil || producer.transport.SASL.Name() != "SCRAM-SHA-512" {
+		t.Fatalf("producer transport missing security settings: %+v", producer.transport)
+	}
+
+	consumer, err := NewKafkaConsumer(KafkaConsumerConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "events",
+		GroupID: "space-soc",
+		TLS:     tlsCfg,
+		SASL:    saslCfg,
+	}, func(context.Context, ConsumedEvent) error { return nil })
+	if err != nil {
+		t.Fatalf("NewKafkaConsumer: %v", err)
+	}
+	if consumer.dialer.TLS == nil || len(consumer.dialer.TLS.Certificates) != 1 {
+		t.Fatalf("consumer dialer missing TLS client certificate: %+v", consumer.dialer.TLS)
+	}
+	if consumer.dialer.SASLMechanism == nil || consumer.dialer.SASLMechanism.Name() != "SCRAM-SHA-512" {
+		t.Fatalf("consumer dialer missing SASL mechanism: %+v", consumer.dialer.SASLMechanism)
+	}
+
+	// A bad mechanism fails at construction instead of on the first send
+	if _, err := NewKafkaProducer(KafkaConfig{Topic: "events", SASL: &SASLConfig{Enabled: true, Mechanism: "GSSAPI", Username: "soc", Password: "secret"}}); err == nil {
+		t.Fatal("NewKafkaProducer should reject an unsupported SASL mechanism")
+	}
+}
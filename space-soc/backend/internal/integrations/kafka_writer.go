@@ -0,0 +1,156 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/compress"
+)
+
+// kafkaWriteTimeout bounds a single flush against the brokers
+const kafkaWriteTimeout = 10 * time.Second
+
+// kafkaBackend publishes a batch of buffered messages
+type kafkaBackend interface {
+	// publish sends the batch and reports how many messages and bytes were delivered;
+	// a partial failure returns the delivered counts together with the error
+	publish(ctx context.Context, messages []KafkaMessage) (sent int, bytes int64, err error)
+	close() error
+	name() string
+}
+
+// parseCompression maps KafkaConfig.Compression to a kafka-go codec (0 means uncompressed)
+func parseCompression(value string) (compress.Compression, error) {
+	switch strings.ToLower(value) {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	default:
+		return 0, fmt.Errorf("unsupported compression %q (supported: none, gzip, snappy, lz4, zstd)", value)
+	}
+}
+
+// writerBackend publishes to real brokers through a kafka-go Writer
+type writerBackend struct {
+	writer *kafka.Writer
+}
+
+func newWriterBackend(config KafkaConfig, transport *kafka.Transport) (*writerBackend, error) {
+	compression, err := parseCompression(config.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writerBackend{writer: &kafka.Writer{
+		Addr:         kafka.TCP(config.Brokers...),
+		Topic:        config.Topic,
+		Transport:    transport,
+		Balancer:     &kafka.Hash{},
+		Compression:  compression,
+		BatchSize:    config.BatchSize,
+		BatchTimeout: time.Duration(config.FlushInterval) * time.Millisecond,
+		RequiredAcks: kafka.RequireOne,
+	}}, nil
+}
+
+func (b *writerBackend) publish(ctx context.Context, messages []KafkaMessage) (int, int64, error) {
+	records := make([]kafka.Message, 0, len(messages))
+	sizes := make([]int64, 0, len(messages))
+	var encodeErr error
+	for _, msg := range messages {
+		value, err := json.Marshal(msg.Value)
+		if err != nil {
+			encodeErr = errors.Join(encodeErr, err)
+			continue
+		}
+		record := kafka.Message{Value: value, Time: msg.Timestamp}
+		if msg.Key != "" {
+			record.Key = []byte(msg.Key) // a nil key makes the Hash balancer fall back to round-robin
+		}
+		for key, v := range msg.Headers {
+			record.Headers = append(record.Headers, kafka.Header{Key: key, Value: []byte(v)})
+		}
+		records = append(records, record)
+		sizes = append(sizes, int64(len(record.Key)+len(record.Value)))
+	}
+	if len(records) == 0 {
+		return 0, 0, encodeErr
+	}
+
+	err := b.writer.WriteMessages(ctx, records...)
+	if err == nil {
+		var total int64
+		for _, size := range sizes {
+			total += size
+		}
+		return len(records), total, encodeErr
+	}
+
+	// Count the messages that made it when only some of the batch failed
+	var writeErrs kafka.WriteErrors
+	if !errors.As(err, &writeErrs) {
+		return 0, 0, errors.Join(encodeErr, err)
+	}
+	sent := 0
+	var total int64
+	for i, writeErr := range writeErrs {
+		if writeErr == nil {
+			sent++
+			total += sizes[i]
+		}
+	}
+	return sent, total, errors.Join(encodeErr, err)
+}
+
+func (b *writerBackend) close() error {
+	return b.writer.Close()
+}
+
+func (b *writerBackend) name() string {
+	return "kafka"
+}
+
+// mockBackend only serializes and counts messages; used when no brokers are configured
+// so local development and tests work without a cluster
+type mockBackend struct {
+	topic string
+}
+
+func (b *mockBackend) publish(_ context.Context, messages []KafkaMessage) (int, int64, error) {
+	slog.Debug("kafka mock backend flushing messages", "topic", b.topic, "count", len(messages))
+
+	sent := 0
+	var total int64
+	var encodeErr error
+	for _, msg := range messages {
+		msgBytes, err := json.Marshal(msg)
+		if err != nil {
+			encodeErr = errors.Join(encodeErr, err)
+			continue
+		}
+		total += int64(len(msgBytes))
+		sent++
+	}
+	return sent, total, encodeErr
+}
+
+func (b *mockBackend) close() error {
+	return nil
+}
+
+func (b *mockBackend) name() string {
+	return "mock"
+}
@@ -2,6 +2,10 @@ package integrations
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -19,8 +23,31 @@ type WebhookConfig struct {
 	EventTypes  []string          `json:"event_types"` // Filter by event types
 	RetryCount  int               `json:"retry_count"`
 	TimeoutSecs int               `json:"timeout_secs"`
+	Secret      string            `json:"secret,omitempty"` // Signs the body into SignatureHeader when set
+
+	// Circuit breaker: open after BreakerThreshold consecutive failures, probe again after the cooldown
+	BreakerThreshold    int `json:"breaker_threshold"`
+	BreakerCooldownSecs int `json:"breaker_cooldown_secs"`
+
+	// Breaker is filled in by GetWebhooks with the current circuit breaker state
+	Breaker *BreakerStatus `json:"breaker,omitempty"`
+}
+
+// SignatureHeader carries the HMAC-SHA256 of the request body for webhooks with a Secret
+const SignatureHeader = "X-Space-SOC-Signature"
+
+// SignPayload returns the SignatureHeader value for a body: "sha256=" followed by the
+// hex-encoded HMAC-SHA256 of the exact body bytes, keyed with the webhook secret.
+// Receivers recompute it over the raw request body and compare with hmac.Equal.
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
 }
 
+// DefaultDeliveryLogSize is the number of recent delivery results kept per webhook
+const DefaultDeliveryLogSize = 50
+
 // WebhookManager manages webhook integrations
 type WebhookManager struct {
 	mu       sync.RWMutex
@@ -28,14 +55,37 @@ type WebhookManager struct {
 	client   *http.Client
 	queue    chan WebhookDelivery
 	workers  int
+
+	// Shutdown: closed stops enqueueing (guarded by mu), stop tells workers to drain and exit,
+	// and cancelling ctx aborts in-flight requests
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	logMu       sync.Mutex
+	logSize     int
+	deliveryLog map[string]*deliveryLog // recent results per webhook name
+	counts      map[string]*WebhookDeliveryCounts // cumulative outcomes per webhook name
+
+	deadMu      sync.Mutex
+	deadLetters []DeadLetter // permanently failed deliveries (see dead_letter.go)
+	nextDeadID  uint64
+
+	breakerMu sync.Mutex
+	breakers  map[string]*circuitBreaker // per webhook name (see circuit_breaker.go)
 }
 
 // WebhookDelivery represents a webhook delivery attempt
 type WebhookDelivery struct {
 	Config    *WebhookConfig
+	OrgID     string // tenant the payload belongs to; empty for events not tied to an organization
+	EventType string
 	Payload   interface{}
 	Timestamp time.Time
 	Attempt   int
+	Context   context.Context // caller context from SendEventCtx; cancels the delivery and its retries
 }
 
 // WebhookResult represents the result of a webhook delivery
@@ -45,21 +95,62 @@ type WebhookResult struct {
 	Error      string    `json:"error,omitempty"`
 	Duration   float64   `json:"duration_ms"`
 	Timestamp  time.Time `json:"timestamp"`
+	EventType  string    `json:"event_type,omitempty"`
+	Attempt    int       `json:"attempt"` // 0 for the first try, incremented on each retry
+}
+
+// WebhookDeliveryCounts are cumulative delivery outcomes for one webhook since startup
+type WebhookDeliveryCounts struct {
+	Succeeded    int64 `json:"succeeded"`
+	Failed       int64 `json:"failed"` // failed attempts, including ones that were retried
+	DeadLettered int64 `json:"dead_lettered"`
+}
+
+// deliveryLog is a fixed-size ring buffer of delivery results
+type deliveryLog struct {
+	entries []WebhookResult
+	next    int
+}
+
+func (l *deliveryLog) add(result WebhookResult, size int) {
+	if len(l.entries) < size {
+		l.entries = append(l.entries, result)
+		return
+	}
+	l.entries[l.next] = result
+	l.next = (l.next + 1) % size
+}
+
+// recent returns the entries newest first
+func (l *deliveryLog) recent() []WebhookResult {
+	results := make([]WebhookResult, 0, len(l.entries))
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		results = append(results, l.entries[(l.next+i)%len(l.entries)])
+	}
+	return results
 }
 
 // NewWebhookManager creates a new webhook manager
 func NewWebhookManager(workers int) *WebhookManager {
+	ctx, cancel := context.WithCancel(context.Background())
 	manager := &WebhookManager{
 		webhooks: make(map[string]*WebhookConfig),
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		queue:   make(chan WebhookDelivery, 1000),
-		workers: workers,
+		// Per-webhook timeouts are applied through the request context
+		client:      &http.Client{},
+		queue:       make(chan WebhookDelivery, 1000),
+		workers:     workers,
+		stop:        make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		logSize:     DefaultDeliveryLogSize,
+		deliveryLog: make(map[string]*deliveryLog),
+		counts:      make(map[string]*WebhookDeliveryCounts),
+		breakers:    make(map[string]*circuitBreaker),
 	}
 
 	// Start worker goroutines
 	for i := 0; i < workers; i++ {
+		manager.wg.Add(1)
 		go manager.worker()
 	}
 
@@ -86,6 +177,13 @@ func (m *WebhookManager) RegisterWebhook(config WebhookConfig) error {
 	if config.TimeoutSecs == 0 {
 		config.TimeoutSecs = 10
 	}
+	if config.BreakerThreshold == 0 {
+		config.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if config.BreakerCooldownSecs == 0 {
+		config.BreakerCooldownSecs = DefaultBreakerCooldownSecs
+	}
+	config.Breaker = nil
 
 	m.webhooks[config.Name] = &config
 	return nil
@@ -97,13 +195,37 @@ func (m *WebhookManager) UnregisterWebhook(name string) {
 	defer m.mu.Unlock()
 
 	delete(m.webhooks, name)
+
+	m.logMu.Lock()
+	delete(m.deliveryLog, name)
+	m.logMu.Unlock()
+
+	m.breakerMu.Lock()
+	delete(m.breakers, name)
+	m.breakerMu.Unlock()
 }
 
 // SendEvent sends an event to all registered webhooks
 func (m *WebhookManager) SendEvent(eventType string, payload interface{}) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.send(context.Background(), "", eventType, payload)
+}
+
+// SendOrgEvent sends an event that belongs to an organization; the org is kept on its
+// deliveries and dead letters so they can be filtered per tenant
+func (m *WebhookManager) SendOrgEvent(orgID, eventType string, payload interface{}) {
+	m.send(context.Background(), orgID, eventType, payload)
+}
 
+// SendEventCtx sends an event to all registered webhooks; cancelling ctx (or its deadline
+// expiring) aborts deliveries still queued or in flight, and they are kept as dead letters.
+// Do not pass a request-scoped context unless deliveries should stop when the request ends.
+func (m *WebhookManager) SendEventCtx(ctx context.Context, eventType string, payload interface{}) {
+	m.send(ctx, "", eventType, payload)
+}
+
+func (m *WebhookManager) send(ctx context.Context, orgID, eventType string, payload interface{}) {
+	m.mu.RLock()
+	var targets []*WebhookConfig
 	for _, config := range m.webhooks {
 		if !config.Enabled {
 			continue
@@ -123,51 +245,180 @@ func (m *WebhookManager) SendEvent(eventType string, payload interface{}) {
 			}
 		}
 
+		targets = append(targets, config)
+	}
+	m.mu.RUnlock()
+
+	for _, config := range targets {
 		// Queue delivery
 		delivery := WebhookDelivery{
 			Config:    config,
+			OrgID:     orgID,
+			EventType: eventType,
 			Payload:   payload,
 			Timestamp: time.Now(),
 			Attempt:   0,
+			Context:   ctx,
 		}
 
-		select {
-		case m.queue <- delivery:
-			// Queued successfully
-		default:
-			// Queue full, log error
-			fmt.Printf("Webhook queue full, dropping event for %s\n", config.Name)
+		if err := m.enqueue(delivery); err != nil {
+			// Keep a record instead of losing the event
+			fmt.Printf("Cannot queue webhook delivery for %s (%v), dead-lettering\n", config.Name, err)
+			m.deadLetter(delivery, WebhookResult{Error: err.Error(), Timestamp: time.Now(), EventType: eventType})
 		}
 	}
 }
 
-// worker processes webhook deliveries from the queue
+// enqueue queues a delivery without blocking; it fails when the queue is full or the
+// manager is shutting down
+func (m *WebhookManager) enqueue(delivery WebhookDelivery) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return fmt.Errorf("webhook manager is shutting down")
+	}
+	select {
+	case m.queue <- delivery:
+		return nil
+	default:
+		return fmt.Errorf("webhook queue full")
+	}
+}
+
+// Shutdown stops accepting new deliveries, lets the workers drain the queue and waits for
+// them to finish. If ctx expires first, in-flight and remaining deliveries are cancelled
+// (and kept as dead letters) and ctx's error is returned.
+func (m *WebhookManager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.stop)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		return ctx.Err()
+	}
+}
+
+// worker processes webhook deliveries from the queue until Shutdown, then drains what is left
 func (m *WebhookManager) worker() {
-	for delivery := range m.queue {
-		result := m.deliver(delivery)
-
-		// Retry on failure
-		if !result.Success && delivery.Attempt < delivery.Config.RetryCount {
-			delivery.Attempt++
-			// Exponential backoff
-			backoff := time.Duration(1<<uint(delivery.Attempt)) * time.Second
-			time.Sleep(backoff)
-
-			select {
-			case m.queue <- delivery:
-				// Requeued for retry
-			default:
-				fmt.Printf("Failed to requeue webhook delivery for %s\n", delivery.Config.Name)
+	defer m.wg.Done()
+
+	for {
+		select {
+		case delivery := <-m.queue:
+			m.process(delivery)
+		case <-m.stop:
+			for {
+				select {
+				case delivery := <-m.queue:
+					m.process(delivery)
+				default:
+					return
+				}
 			}
 		}
 	}
 }
 
-// deliver performs the actual HTTP request to the webhook endpoint
+// canceled returns the cancellation error of a delivery: its caller context or the manager's
+func (m *WebhookManager) canceled(delivery WebhookDelivery) error {
+	if delivery.Context != nil && delivery.Context.Err() != nil {
+		return delivery.Context.Err()
+	}
+	return m.ctx.Err()
+}
+
+// process attempts one delivery and schedules a retry or dead-letters it on failure
+func (m *WebhookManager) process(delivery WebhookDelivery) {
+	// Skip deliveries while the endpoint's breaker is open; they are kept as dead letters
+	if !m.allowDelivery(delivery.Config, time.Now()) {
+		result := WebhookResult{
+			Error:     "circuit breaker open",
+			Timestamp: time.Now(),
+			EventType: delivery.EventType,
+			Attempt:   delivery.Attempt,
+		}
+		m.recordResult(delivery.Config.Name, result)
+		m.deadLetter(delivery, result)
+		return
+	}
+
+	result := m.deliver(delivery)
+	m.recordResult(delivery.Config.Name, result)
+
+	// A cancelled delivery says nothing about the endpoint: no breaker update, no retry
+	if err := m.canceled(delivery); err != nil && !result.Success {
+		m.releaseProbe(delivery.Config)
+		m.deadLetter(delivery, result)
+		return
+	}
+	m.recordBreakerResult(delivery.Config, result.Success, time.Now())
+
+	if result.Success {
+		return
+	}
+	// Retries exhausted: keep the delivery as a dead letter
+	if delivery.Attempt >= delivery.Config.RetryCount {
+		m.deadLetter(delivery, result)
+		return
+	}
+
+	// Retry on failure with exponential backoff, unless cancelled or shutting down meanwhile
+	backoff := time.Duration(1<<uint(delivery.Attempt+1)) * time.Second
+	select {
+	case <-time.After(backoff):
+	case <-m.stop:
+	case <-m.ctx.Done():
+	case <-contextDone(delivery.Context):
+	}
+
+	delivery.Attempt++
+	if err := m.enqueue(delivery); err != nil {
+		fmt.Printf("Failed to requeue webhook delivery for %s: %v\n", delivery.Config.Name, err)
+		delivery.Attempt--
+		m.deadLetter(delivery, result)
+	}
+}
+
+// contextDone returns ctx.Done(), or nil (blocks forever in a select) for a nil context
+func contextDone(ctx context.Context) <-chan struct{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Done()
+}
+
+// deliver performs the actual HTTP request to the webhook endpoint, bounded by the webhook
+// timeout, the delivery's caller context and the manager's shutdown
 func (m *WebhookManager) deliver(delivery WebhookDelivery) WebhookResult {
+	parent := delivery.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, time.Duration(delivery.Config.TimeoutSecs)*time.Second)
+	defer cancel()
+	stop := context.AfterFunc(m.ctx, cancel)
+	defer stop()
+
 	start := time.Now()
 	result := WebhookResult{
 		Timestamp: start,
+		EventType: delivery.EventType,
+		Attempt:   delivery.Attempt,
 	}
 
 	// Prepare payload
@@ -178,7 +429,7 @@ func (m *WebhookManager) deliver(delivery WebhookDelivery) WebhookResult {
 	}
 
 	// Create request
-	req, err := http.NewRequest(delivery.Config.Method, delivery.Config.URL, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, delivery.Config.Method, delivery.Config.URL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		result.Error = fmt.Sprintf("failed to create request: %v", err)
 		return result
@@ -190,14 +441,12 @@ func (m *WebhookManager) deliver(delivery WebhookDelivery) WebhookResult {
 	for key, value := range delivery.Config.Headers {
 		req.Header.Set(key, value)
 	}
-
-	// Set timeout
-	client := &http.Client{
-		Timeout: time.Duration(delivery.Config.TimeoutSecs) * time.Second,
+	if delivery.Config.Secret != "" {
+		req.Header.Set(SignatureHeader, SignPayload(delivery.Config.Secret, payloadBytes))
 	}
 
 	// Send request
-	resp, err := client.Do(req)
+	resp, err := m.client.Do(req)
 	if err != nil {
 		result.Error = fmt.Sprintf("request failed: %v", err)
 		result.Duration = time.Since(start).Seconds() * 1000
@@ -218,18 +467,77 @@ func (m *WebhookManager) deliver(delivery WebhookDelivery) WebhookResult {
 	return result
 }
 
-// GetWebhooks returns all registered webhooks
+// GetWebhooks returns copies of all registered webhooks, including their circuit breaker state
 func (m *WebhookManager) GetWebhooks() map[string]*WebhookConfig {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	webhooks := make(map[string]*WebhookConfig)
 	for name, config := range m.webhooks {
-		webhooks[name] = config
+		webhook := *config
+		breaker := m.breakerStatus(name)
+		webhook.Breaker = &breaker
+		webhooks[name] = &webhook
 	}
 	return webhooks
 }
 
+// recordResult appends a delivery result to the webhook's delivery log and counts it
+func (m *WebhookManager) recordResult(name string, result WebhookResult) {
+	m.logMu.Lock()
+	defer m.logMu.Unlock()
+
+	log, ok := m.deliveryLog[name]
+	if !ok {
+		log = &deliveryLog{}
+		m.deliveryLog[name] = log
+	}
+	log.add(result, m.logSize)
+
+	counts := m.countsLocked(name)
+	if result.Success {
+		counts.Succeeded++
+	} else {
+		counts.Failed++
+	}
+}
+
+// countsLocked returns the webhook's counters, creating them on first use (logMu must be held)
+func (m *WebhookManager) countsLocked(name string) *WebhookDeliveryCounts {
+	counts, ok := m.counts[name]
+	if !ok {
+		counts = &WebhookDeliveryCounts{}
+		m.counts[name] = counts
+	}
+	return counts
+}
+
+// GetDeliveryCounts returns cumulative delivery outcomes per webhook name; unlike the delivery
+// log the counts are never truncated, so they can be exported as monotonic counters
+func (m *WebhookManager) GetDeliveryCounts() map[string]WebhookDeliveryCounts {
+	m.logMu.Lock()
+	defer m.logMu.Unlock()
+
+	counts := make(map[string]WebhookDeliveryCounts, len(m.counts))
+	for name, c := range m.counts {
+		counts[name] = *c
+	}
+	return counts
+}
+
+// GetDeliveryLog returns the most recent delivery attempts for a webhook, newest first
+// (at most DefaultDeliveryLogSize entries, including retries)
+func (m *WebhookManager) GetDeliveryLog(name string) []WebhookResult {
+	m.logMu.Lock()
+	defer m.logMu.Unlock()
+
+	log, ok := m.deliveryLog[name]
+	if !ok {
+		return []WebhookResult{}
+	}
+	return log.recent()
+}
+
 // GetQueueSize returns the current queue size
 func (m *WebhookManager) GetQueueSize() int {
 	return len(m.queue)
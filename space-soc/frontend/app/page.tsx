@@ -9,6 +9,7 @@ interface Event {
   eventType: string
   command?: string
   operatorRole?: string
+  operatorId?: string
   decision?: string
   reason?: string
   status?: string
@@ -221,6 +222,9 @@ export default function Home() {
                           </td>
                           <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-700">
                             {event.operatorRole || '-'}
+                            {event.operatorId && (
+                              <div className="text-xs text-gray-500">{event.operatorId}</div>
+                            )}
                           </td>
                           <td className={`px-4 py-3 whitespace-nowrap text-sm font-semibold ${getDecisionColor(event.decision)}`}>
                             {event.decision || '-'}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// digestPattern 是合法的映像檔 digest 格式（小寫 sha256 十六進位）。
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// validateImageDigest 正規化並驗證 digest 格式，回傳正規化後的 digest。
+func validateImageDigest(digest string) (string, error) {
+	normalized := normalizeDigest(digest)
+	if !digestPattern.MatchString(normalized) {
+		return "", fmt.Errorf("imageDigest %q is not a valid sha256 digest (expected sha256:<64 hex>)", digest)
+	}
+	return normalized, nil
+}
+
+// ArtifactFetcher 在註冊時下載 artifact 以確認 digest，限制時間、大小與可連線的位址：
+//   - ARTIFACT_FETCH_TIMEOUT: 下載逾時（預設 60s）
+//   - ARTIFACT_MAX_BYTES: artifact 大小上限（預設 1 GiB）
+//   - ARTIFACT_ALLOW_PRIVATE: 設為 true 時允許連到私有 / loopback 位址（僅限開發環境）
+type ArtifactFetcher struct {
+	Timeout      time.Duration
+	MaxBytes     int64
+	AllowPrivate bool
+}
+
+func loadArtifactFetcher() *ArtifactFetcher {
+	fetcher := &ArtifactFetcher{
+		Timeout:      60 * time.Second,
+		MaxBytes:     1 << 30,
+		AllowPrivate: os.Getenv("ARTIFACT_ALLOW_PRIVATE") == "true",
+	}
+	if v := os.Getenv("ARTIFACT_FETCH_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			fetcher.Timeout = d
+		} else {
+			log.Printf("無效的 ARTIFACT_FETCH_TIMEOUT %q，使用預設值 %v", v, fetcher.Timeout)
+		}
+	}
+	if v := os.Getenv("ARTIFACT_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			fetcher.MaxBytes = n
+		} else {
+			log.Printf("無效的 ARTIFACT_MAX_BYTES %q，使用預設值 %d", v, fetcher.MaxBytes)
+		}
+	}
+	return fetcher
+}
+
+var errArtifactMismatch = errors.New("artifact digest mismatch")
+
+// isPublicIP 判斷位址是否可作為外部下載目標（排除 loopback、私有、link-local 等）。
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// client 建立下載用的 HTTP client；連線時（含 redirect）檢查實際連線的 IP，避免 DNS rebinding 繞過。
+func (f *ArtifactFetcher) client() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if f.AllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("artifact host %s is not a public address", host)
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 15 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// Verify 下載 artifact 並計算 sha256，與 expectedDigest 不符時回傳 errArtifactMismatch。
+// 回傳實際大小（bytes）與計算出的 digest。
+func (f *ArtifactFetcher) Verify(ctx context.Context, artifactURL, expectedDigest string) (int64, string, error) {
+	u, err := url.Parse(artifactURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return 0, "", fmt.Errorf("artifactUrl must be an absolute http(s) URL")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to fetch artifact: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", fmt.Errorf("failed to fetch artifact: unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > f.MaxBytes {
+		return 0, "", fmt.Errorf("artifact size %d exceeds limit %d", resp.ContentLength, f.MaxBytes)
+	}
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, io.LimitReader(resp.Body, f.MaxBytes+1))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read artifact: %w", err)
+	}
+	if size > f.MaxBytes {
+		return 0, "", fmt.Errorf("artifact exceeds limit %d bytes", f.MaxBytes)
+	}
+
+	computed := "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+	if computed != expectedDigest {
+		return size, computed, errArtifactMismatch
+	}
+	return size, computed, nil
+}
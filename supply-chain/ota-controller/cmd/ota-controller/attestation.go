@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"actinspace.org/supply-chain/signing-service/signer"
+)
+
+// errUnsignedRelease 表示 release 沒有任何可驗證的簽章資料。
+var errUnsignedRelease = errors.New("release must include a signed attestation or a cosign bundle")
+
+// attestationVerifier 依簽章版本驗證 attestation（Ed25519 公鑰或舊版共享 secret），與 satellite-sim 使用相同設定。
+var attestationVerifier *signer.Verifier
+
+// allowUnsignedReleases 回傳是否允許沒有簽章的 release（ALLOW_UNSIGNED_RELEASES=true，僅限開發環境）。
+func allowUnsignedReleases() bool {
+	return os.Getenv("ALLOW_UNSIGNED_RELEASES") == "true"
+}
+
+// verifyAttestation 以與 satellite-sim 相同的方式驗證 attestation：digest 必須等於 imageDigest，
+// 簽章依 version 以 Ed25519 公鑰或舊版共享 secret 驗證。回傳簽章涵蓋的 SBOM digest（沒有時為空字串）。
+func verifyAttestation(attestation, imageDigest string) (string, error) {
+	var meta struct {
+		Version    int    `json:"version"`
+		Digest     string `json:"digest"`
+		SBOMDigest string `json:"sbomDigest"`
+		Signature  string `json:"signature"`
+	}
+	if err := json.Unmarshal([]byte(attestation), &meta); err != nil {
+		return "", fmt.Errorf("attestation is not valid JSON: %w", err)
+	}
+	if meta.Digest != imageDigest {
+		return "", fmt.Errorf("attestation digest %q does not match imageDigest %q", meta.Digest, imageDigest)
+	}
+	if meta.SBOMDigest != "" && !digestPattern.MatchString(meta.SBOMDigest) {
+		return "", fmt.Errorf("attestation sbomDigest %q is not a valid sha256 digest", meta.SBOMDigest)
+	}
+	payload := signer.Payload(meta.Digest, meta.SBOMDigest)
+	if err := attestationVerifier.Verify(meta.Version, payload, meta.Signature); err != nil {
+		return "", fmt.Errorf("attestation signature is invalid: %w", err)
+	}
+	return meta.SBOMDigest, nil
+}
+
+// checkReleaseSignature 在註冊時檢查簽章：有 attestation 時必須驗證通過；沒有 attestation 時
+// 必須附上 cosign bundle（且已啟用 cosign 驗證，批准時才會驗證），除非允許未簽章的 release。
+// 回傳 attestation 簽章涵蓋的 SBOM digest。
+func checkReleaseSignature(attestation string, hasCosignBundle bool, imageDigest string) (string, error) {
+	if attestation != "" {
+		return verifyAttestation(attestation, imageDigest)
+	}
+	if (hasCosignBundle && cosignPolicy != nil) || allowUnsignedReleases() {
+		return "", nil
+	}
+	return "", errUnsignedRelease
+}
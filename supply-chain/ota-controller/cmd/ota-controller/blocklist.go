@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlockedDigest 是已知遭入侵的映像檔 digest；列入後不論哪個組織的 release 都不能批准或下發。
+type BlockedDigest struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ImageDigest string    `gorm:"not null;uniqueIndex" json:"imageDigest"`
+	Reason      string    `gorm:"type:text;not null" json:"reason"`
+	Source      string    `gorm:"not null" json:"source"` // 例如 "incident-response", "vendor-advisory", "manual"
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// normalizeDigest 統一 digest 格式，避免大小寫或空白造成比對遺漏。
+func normalizeDigest(digest string) string {
+	return strings.ToLower(strings.TrimSpace(digest))
+}
+
+// findBlockedDigest 查詢 digest 是否在封鎖清單中。
+func findBlockedDigest(digest string) (*BlockedDigest, bool) {
+	var blocked BlockedDigest
+	if err := db.Where("image_digest = ?", normalizeDigest(digest)).First(&blocked).Error; err != nil {
+		return nil, false
+	}
+	return &blocked, true
+}
+
+// requireAdmin 保護管理端點：必須設定 OTA_ADMIN_TOKEN 並帶相符的 X-Admin-Token。
+// 未設定時一律拒絕（fail closed），否則任何人都能解除封鎖遭入侵的 digest。
+func requireAdmin(c *gin.Context) {
+	token := os.Getenv("OTA_ADMIN_TOKEN")
+	if token == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin endpoints are disabled: OTA_ADMIN_TOKEN is not configured"})
+		c.Abort()
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), []byte(token)) != 1 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin token required"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// registerBlocklistRoutes 註冊 digest 封鎖清單的管理端點（全域，不分組織）。
+func registerBlocklistRoutes(r *gin.Engine) {
+	admin := r.Group("/api/v1/blocklist", requireAdmin)
+
+	admin.GET("", func(c *gin.Context) {
+		var entries []BlockedDigest
+		if err := db.Order("created_at DESC").Find(&entries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢封鎖清單"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+	})
+
+	admin.POST("", func(c *gin.Context) {
+		var req struct {
+			ImageDigest string `json:"imageDigest" binding:"required"`
+			Reason      string `json:"reason" binding:"required"`
+			Source      string `json:"source"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Source == "" {
+			req.Source = "manual"
+		}
+
+		entry := BlockedDigest{
+			ImageDigest: normalizeDigest(req.ImageDigest),
+			Reason:      req.Reason,
+			Source:      req.Source,
+			CreatedAt:   time.Now().UTC(),
+		}
+		if _, exists := findBlockedDigest(entry.ImageDigest); exists {
+			c.JSON(http.StatusConflict, gin.H{"error": "digest already blocklisted"})
+			return
+		}
+		if err := db.Create(&entry).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法新增封鎖項目"})
+			return
+		}
+
+		logEvent(c.Request.Context(), "digest_blocklisted", map[string]interface{}{
+			"imageDigest": entry.ImageDigest,
+			"reason":      entry.Reason,
+			"source":      entry.Source,
+			"severity":    "high",
+		})
+
+		// 已批准的 release 會在下一次更新檢查時立即停止下發；逐一通知受影響的組織
+		var affected []Release
+		db.Where("image_digest = ? AND status = ?", entry.ImageDigest, "approved").Find(&affected)
+		for _, release := range affected {
+			logEvent(c.Request.Context(), "approved_release_blocklisted", map[string]interface{}{
+				"releaseId":   release.ID,
+				"component":   release.Component,
+				"version":     release.Version,
+				"imageDigest": release.ImageDigest,
+				"reason":      entry.Reason,
+				"source":      entry.Source,
+				"severity":    "critical",
+				"orgId":       release.OrgID,
+			})
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"entry": entry, "affectedReleases": len(affected)})
+	})
+
+	admin.DELETE("/:id", func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid blocklist entry ID"})
+			return
+		}
+
+		var entry BlockedDigest
+		if err := db.First(&entry, uint(id)).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "blocklist entry not found"})
+			return
+		}
+		if err := db.Delete(&entry).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法移除封鎖項目"})
+			return
+		}
+
+		logEvent(c.Request.Context(), "digest_unblocklisted", map[string]interface{}{
+			"imageDigest": entry.ImageDigest,
+			"reason":      entry.Reason,
+			"source":      entry.Source,
+			"severity":    "high",
+		})
+
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"actinspace.org/supply-chain/signing-service/cosign"
+)
+
+// 驗證方式（記錄在 Release.VerificationMethod 供稽核）
+const (
+	verificationCosign      = "cosign"      // Sigstore/cosign keyless bundle 驗證通過
+	verificationAttestation = "attestation" // 簽章 attestation 驗證通過（衛星端會再驗證一次）
+	verificationNone        = "none"        // 沒有任何簽章資料，僅人工批准（需 ALLOW_UNSIGNED_RELEASES=true）
+)
+
+// cosignPolicy 在 COSIGN_VERIFY=true 時載入；nil 表示停用 cosign 驗證。
+var cosignPolicy *cosign.Policy
+
+// loadCosignPolicy 依環境變數載入 keyless 驗證設定：
+//   - COSIGN_TRUSTED_ROOT: Fulcio root 憑證（PEM）
+//   - COSIGN_IDENTITY / COSIGN_ISSUER: 受信任的簽署身分與 OIDC issuer
+//   - COSIGN_REKOR_PUBLIC_KEY: Rekor 公鑰，驗證 SET 與 checkpoint 簽章（必填）
+func loadCosignPolicy() *cosign.Policy {
+	if os.Getenv("COSIGN_VERIFY") != "true" {
+		return nil
+	}
+
+	policy, err := cosign.LoadPolicy(
+		os.Getenv("COSIGN_TRUSTED_ROOT"),
+		os.Getenv("COSIGN_IDENTITY"),
+		os.Getenv("COSIGN_ISSUER"),
+		os.Getenv("COSIGN_REKOR_PUBLIC_KEY"),
+	)
+	if err != nil {
+		log.Fatalf("無法載入 cosign 驗證設定: %v", err)
+	}
+
+	return policy
+}
+
+// verifyReleaseForApproval 決定 release 的驗證方式；cosign 啟用且 release 帶有 bundle 時必須驗證通過，
+// 否則重新驗證 attestation 簽章（secret 可能已輪替，或 release 在註冊驗證之前建立）。
+// 沒有任何簽章資料時只在允許未簽章 release 時通過。
+func verifyReleaseForApproval(release *Release) (string, error) {
+	if cosignPolicy != nil && release.CosignBundle != "" {
+		result, err := cosignPolicy.Verify([]byte(release.CosignBundle), release.ImageDigest)
+		if err != nil {
+			return verificationCosign, fmt.Errorf("cosign verification failed: %w", err)
+		}
+		log.Printf("cosign 驗證通過: %s@%s（identity=%s, rekor logIndex=%d）",
+			release.Component, release.Version, result.Identity, result.LogIndex)
+		return verificationCosign, nil
+	}
+
+	if release.Attestation != "" {
+		if _, err := verifyAttestation(release.Attestation, release.ImageDigest); err != nil {
+			return verificationAttestation, err
+		}
+		return verificationAttestation, nil
+	}
+	if !allowUnsignedReleases() {
+		return verificationNone, errUnsignedRelease
+	}
+	return verificationNone, nil
+}
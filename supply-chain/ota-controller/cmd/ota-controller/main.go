@@ -1,31 +1,56 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
+	"actinspace.org/internal/database"
+	"actinspace.org/internal/health"
+	"actinspace.org/internal/logging"
+	"actinspace.org/internal/middleware"
+	"actinspace.org/internal/server"
+	"actinspace.org/internal/socclient"
+	"actinspace.org/internal/tenant"
+	"actinspace.org/supply-chain/signing-service/signer"
 	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 // Release 定義一個軟體發布版本。
 type Release struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Component   string    `gorm:"not null;index" json:"component"` // satellite-sim, ttc-gateway, etc.
-	Version     string    `gorm:"not null" json:"version"`
-	ImageDigest string    `gorm:"not null" json:"imageDigest"`
-	SBOMURL     string    `json:"sbomUrl,omitempty"`
-	Attestation string    `gorm:"type:text" json:"attestation"` // JSON string
-	Status      string    `gorm:"not null;index" json:"status"` // "pending", "approved", "rejected"
-	ApprovedBy  string    `json:"approvedBy,omitempty"`
-	CreatedAt   time.Time `gorm:"index" json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	OrgID              string     `gorm:"not null;index;default:default" json:"orgId"` // 所屬組織（租戶）
+	Component          string     `gorm:"not null;index" json:"component"`             // satellite-sim, ttc-gateway, etc.
+	Version            string     `gorm:"not null" json:"version"`
+	ImageDigest        string     `gorm:"not null" json:"imageDigest"`
+	SBOMURL            string     `json:"sbomUrl,omitempty"`
+	SBOMDigest         string     `json:"sbomDigest,omitempty"` // attestation 簽章涵蓋的 SBOM digest（sha256:<hex>）
+	ArtifactURL        string     `json:"artifactUrl,omitempty"`
+	ArtifactSize       int64      `json:"artifactSize,omitempty"`                       // 註冊時下載驗證得到的大小（bytes），未驗證時為 0
+	Attestation        string     `gorm:"type:text" json:"attestation"`                 // JSON string
+	CosignBundle       string     `gorm:"type:text" json:"cosignBundle,omitempty"`      // Sigstore bundle JSON（可選）
+	VerificationMethod string     `json:"verificationMethod,omitempty"`                 // 批准時使用的驗證方式: "cosign", "attestation", "none"
+	Status             string     `gorm:"not null;index" json:"status"`                 // "pending", "approved", "rejected", "revoked"
+	Channel            string     `gorm:"not null;index;default:stable" json:"channel"` // 發布通道: "beta", "stable"
+	SBOMCheck          string     `json:"sbomCheck,omitempty"`                          // SBOM policy 檢查結果: "passed", "failed"
+	SBOMCheckedAt      *time.Time `json:"sbomCheckedAt,omitempty"`
+	RolloutPercent     int        `json:"rolloutPercent"`                                     // 分階段推出的百分比（0–100），100 為全面推出
+	RolloutSatellites  []string   `gorm:"serializer:json" json:"rolloutSatellites,omitempty"` // 不論百分比都納入推出範圍的衛星 ID
+	ApprovedBy         string     `json:"approvedBy,omitempty"`
+	RevokedBy          string     `json:"revokedBy,omitempty"`
+	RevocationReason   string     `json:"revocationReason,omitempty"`
+	RevokedAt          *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt          time.Time  `gorm:"index" json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
 }
 
 // UpdateRequest 定義衛星請求更新的格式。
@@ -33,37 +58,66 @@ type UpdateRequest struct {
 	Component      string `json:"component" binding:"required"`
 	CurrentVersion string `json:"currentVersion"`
 	SatelliteID    string `json:"satelliteId,omitempty"`
+	Channel        string `json:"channel,omitempty"` // 訂閱的發布通道（預設 stable）
 }
 
 // UpdateResponse 定義 OTA controller 的回應。
 type UpdateResponse struct {
-	Available      bool      `json:"available"`
-	Version        string    `json:"version,omitempty"`
-	ImageDigest    string    `json:"imageDigest,omitempty"`
-	SBOMURL        string    `json:"sbomUrl,omitempty"`
-	Attestation    string    `json:"attestation,omitempty"`
-	Message        string    `json:"message"`
-	UpdateAllowed  bool      `json:"updateAllowed"`
-	DenialReason   string    `json:"denialReason,omitempty"`
-	Timestamp      time.Time `json:"timestamp"`
+	Available     bool      `json:"available"`
+	ReleaseID     uint      `json:"releaseId,omitempty"`
+	Channel       string    `json:"channel,omitempty"`
+	Version       string    `json:"version,omitempty"`
+	ImageDigest   string    `json:"imageDigest,omitempty"`
+	SBOMURL       string    `json:"sbomUrl,omitempty"`
+	Attestation   string    `json:"attestation,omitempty"`
+	RollbackTo    string    `json:"rollbackTo,omitempty"` // 目前版本已撤銷時建議回滾的版本
+	Message       string    `json:"message"`
+	UpdateAllowed bool      `json:"updateAllowed"`
+	DenialReason  string    `json:"denialReason,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
 }
 
 var db *gorm.DB
 
+// tenantKeys 將 API key 對應到組織 ID；為空時代表單租戶模式。
+var tenantKeys tenant.Keys
+
+// socSender 在背景將事件送往 Space-SOC（重試與 spool，見 internal/socclient）
+var socSender *socclient.Sender
+
+// artifactFetcher 用於註冊時下載 artifact 驗證 digest。
+var artifactFetcher *ArtifactFetcher
+
 func initDB() {
 	var err error
-	dbPath := os.Getenv("DATABASE_PATH")
-	if dbPath == "" {
-		dbPath = "ota-controller.db"
+	var dialector gorm.Dialector
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		// 預設使用 SQLite（開發環境）；單一檔案無法讓多個 controller 副本共用
+		dbPath := os.Getenv("DATABASE_PATH")
+		if dbPath == "" {
+			dbPath = "ota-controller.db"
+		}
+		dialector = sqlite.Open(dbPath)
+	} else {
+		// 使用 PostgreSQL（生產環境）
+		dialector = postgres.Open(dbURL)
 	}
 
-	db, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err = gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		log.Fatalf("無法連接到資料庫: %v", err)
 	}
+	if err := database.ConfigurePool(db); err != nil {
+		log.Fatalf("無法設定資料庫連線池: %v", err)
+	}
 
 	// 自動遷移
-	if err := db.AutoMigrate(&Release{}); err != nil {
+	if err := db.AutoMigrate(&Release{}, &BlockedDigest{}, &ReleaseReport{}, &ReleasePromotion{}, &SatelliteState{}); err != nil {
+		log.Fatalf("資料庫遷移失敗: %v", err)
+	}
+	if err := migrateRolloutPercent(); err != nil {
 		log.Fatalf("資料庫遷移失敗: %v", err)
 	}
 
@@ -71,109 +125,207 @@ func initDB() {
 }
 
 func main() {
+	logger := logging.Setup("ota-controller")
+	socSender = socclient.New()
+
 	initDB()
 
-	r := gin.Default()
+	cosignPolicy = loadCosignPolicy()
+	if cosignPolicy != nil {
+		log.Printf("cosign keyless 驗證已啟用（identity=%s, issuer=%s）", cosignPolicy.Identity, cosignPolicy.Issuer)
+	}
+
+	artifactFetcher = loadArtifactFetcher()
+
+	verifier, err := signer.LoadVerifier()
+	if err != nil {
+		log.Fatalf("無效的簽章驗證設定: %v", err)
+	}
+	attestationVerifier = verifier
+	if verifier.PublicKey != nil {
+		log.Printf("Ed25519 attestation 驗證已啟用（keyId=%s, 接受舊版簽章=%t）", signer.KeyID(verifier.PublicKey), verifier.AllowLegacy)
+	}
+
+	tenantKeys = tenant.LoadKeys()
+	if tenantKeys.MultiTenant() {
+		log.Printf("多租戶模式已啟用（%d 個 API key）", len(tenantKeys))
+	}
+
+	r := gin.New()
+	r.Use(gin.Recovery(), logging.Middleware(logger))
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// 就緒檢查：確認資料庫可連線（結果短暫快取）
+	readiness := health.NewChecker()
+	readiness.Add("database", health.SQLCheck(db.DB))
+	r.GET("/ready", readiness.Handler())
+
+	// 全域 digest 封鎖清單（管理端點，不分組織）
+	registerBlocklistRoutes(r)
+
+	// 以下 API 皆依 API key 解析租戶，release 僅對所屬組織可見
+	r.Use(tenantKeys.Middleware())
+
+	// 寫入端點在解析 JSON 前限制 body 大小（MAX_BODY_BYTES，預設 256 KiB）
+	maxBody := middleware.MaxBodyBytes(middleware.MaxBodyBytesFromEnv())
+
 	// 查詢可用更新
-	r.POST("/api/v1/updates/check", func(c *gin.Context) {
+	r.POST("/api/v1/updates/check", maxBody, func(c *gin.Context) {
 		var req UpdateRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		// 查找最新的已批准版本
-		var latestRelease Release
-		err := db.Where("component = ? AND status = ?", req.Component, "approved").
-			Order("created_at DESC").
-			First(&latestRelease).Error
+		orgID := tenant.OrgID(c)
 
-		if err != nil {
-			// 沒有可用更新
-			c.JSON(http.StatusOK, UpdateResponse{
-				Available:     false,
-				Message:       "no approved updates available",
-				UpdateAllowed: false,
-				Timestamp:     time.Now().UTC(),
-			})
-			return
-		}
-
-		// 檢查是否為新版本
-		if latestRelease.Version == req.CurrentVersion {
-			c.JSON(http.StatusOK, UpdateResponse{
-				Available:     false,
-				Message:       "already on latest version",
-				UpdateAllowed: false,
-				Timestamp:     time.Now().UTC(),
-			})
-			return
+		if req.Channel == "" {
+			req.Channel = defaultChannel
 		}
-
-		// 檢查任務政策（例如：關鍵階段禁止更新）
-		missionPhase := os.Getenv("MISSION_PHASE")
-		if missionPhase == "critical" {
-			c.JSON(http.StatusOK, UpdateResponse{
-				Available:     true,
-				Version:       latestRelease.Version,
-				UpdateAllowed: false,
-				DenialReason:  "updates blocked during critical mission phase",
-				Timestamp:     time.Now().UTC(),
-			})
+		channels := visibleChannels(req.Channel)
+		if channels == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown channel %q", req.Channel)})
 			return
 		}
 
-		// 允許更新
-		c.JSON(http.StatusOK, UpdateResponse{
-			Available:     true,
-			Version:       latestRelease.Version,
-			ImageDigest:   latestRelease.ImageDigest,
-			SBOMURL:       latestRelease.SBOMURL,
-			Attestation:   latestRelease.Attestation,
-			Message:       "update available",
-			UpdateAllowed: true,
-			Timestamp:     time.Now().UTC(),
-		})
-
-		// 記錄更新檢查事件
-		logEvent("update_check", map[string]interface{}{
-			"component":      req.Component,
-			"currentVersion": req.CurrentVersion,
-			"latestVersion":  latestRelease.Version,
-			"satelliteId":    req.SatelliteID,
-			"updateAllowed":  true,
-		})
+		resp := resolveUpdate(c, orgID, req, channels)
+		recordSatelliteCheck(orgID, req, resp)
+		c.JSON(http.StatusOK, resp)
 	})
 
 	// 註冊新版本（由 CI pipeline 調用）
-	r.POST("/api/v1/releases", func(c *gin.Context) {
+	r.POST("/api/v1/releases", maxBody, func(c *gin.Context) {
 		var req struct {
-			Component   string `json:"component" binding:"required"`
-			Version     string `json:"version" binding:"required"`
-			ImageDigest string `json:"imageDigest" binding:"required"`
-			SBOMURL     string `json:"sbomUrl,omitempty"`
-			Attestation string `json:"attestation,omitempty"`
+			Component    string          `json:"component" binding:"required"`
+			Version      string          `json:"version" binding:"required"`
+			ImageDigest  string          `json:"imageDigest" binding:"required"`
+			SBOMURL      string          `json:"sbomUrl,omitempty"`
+			ArtifactURL  string          `json:"artifactUrl,omitempty"`
+			Attestation  string          `json:"attestation,omitempty"`
+			CosignBundle json.RawMessage `json:"cosignBundle,omitempty"`
+			Channel      string          `json:"channel,omitempty"`
+			// 分階段推出（可選）：未指定時全面推出，之後可透過 /rollout 逐步調整
+			RolloutPercent    *int     `json:"rolloutPercent,omitempty"`
+			RolloutSatellites []string `json:"rolloutSatellites,omitempty"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if req.Channel == "" {
+			req.Channel = defaultChannel
+		}
+		if channelIndex(req.Channel) < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown channel %q", req.Channel)})
+			return
+		}
+
+		digest, err := validateImageDigest(req.ImageDigest)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rolloutPercent := fullRollout
+		if req.RolloutPercent != nil {
+			rolloutPercent = *req.RolloutPercent
+		}
+		rolloutSatellites, err := normalizeRollout(rolloutPercent, req.RolloutSatellites)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 未簽章或簽章不符的 release 不會進入批准佇列
+		sbomDigest, err := checkReleaseSignature(req.Attestation, len(req.CosignBundle) > 0, digest)
+		if err != nil {
+			logEvent(c.Request.Context(), "release_signature_rejected", map[string]interface{}{
+				"component":   req.Component,
+				"version":     req.Version,
+				"imageDigest": digest,
+				"reason":      err.Error(),
+				"severity":    "high",
+				"orgId":       tenant.OrgID(c),
+			})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 提供 artifactUrl 時下載並確認 digest，避免註冊無法在衛星端通過驗證的版本
+		var artifactSize int64
+		if req.ArtifactURL != "" {
+			size, computed, err := artifactFetcher.Verify(c.Request.Context(), req.ArtifactURL, digest)
+			if err != nil {
+				if errors.Is(err, errArtifactMismatch) {
+					logEvent(c.Request.Context(), "artifact_digest_mismatch", map[string]interface{}{
+						"component":      req.Component,
+						"version":        req.Version,
+						"imageDigest":    digest,
+						"computedDigest": computed,
+						"artifactUrl":    req.ArtifactURL,
+						"severity":       "high",
+						"orgId":          tenant.OrgID(c),
+					})
+					c.JSON(http.StatusUnprocessableEntity, gin.H{
+						"error":          "artifact digest does not match imageDigest",
+						"imageDigest":    digest,
+						"computedDigest": computed,
+					})
+					return
+				}
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+				return
+			}
+			artifactSize = size
+		}
+
+		// 簽章涵蓋 SBOM 時確認 sbomUrl 提供的 SBOM 就是簽章時的那一份
+		if sbomDigest != "" && req.SBOMURL != "" {
+			if _, computed, err := artifactFetcher.Verify(c.Request.Context(), req.SBOMURL, sbomDigest); err != nil {
+				if errors.Is(err, errArtifactMismatch) {
+					logEvent(c.Request.Context(), "sbom_digest_mismatch", map[string]interface{}{
+						"component":      req.Component,
+						"version":        req.Version,
+						"sbomUrl":        req.SBOMURL,
+						"sbomDigest":     sbomDigest,
+						"computedDigest": computed,
+						"severity":       "high",
+						"orgId":          tenant.OrgID(c),
+					})
+					c.JSON(http.StatusUnprocessableEntity, gin.H{
+						"error":          "SBOM at sbomUrl does not match the signed sbomDigest",
+						"sbomDigest":     sbomDigest,
+						"computedDigest": computed,
+					})
+					return
+				}
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("sbomUrl: %v", err)})
+				return
+			}
+		}
 
 		release := Release{
-			Component:   req.Component,
-			Version:     req.Version,
-			ImageDigest: req.ImageDigest,
-			SBOMURL:     req.SBOMURL,
-			Attestation: req.Attestation,
-			Status:      "pending", // 需要人工批准
-			CreatedAt:   time.Now().UTC(),
-			UpdatedAt:   time.Now().UTC(),
+			OrgID:        tenant.OrgID(c),
+			Component:    req.Component,
+			Version:      req.Version,
+			ImageDigest:  digest,
+			SBOMURL:      req.SBOMURL,
+			SBOMDigest:   sbomDigest,
+			ArtifactURL:  req.ArtifactURL,
+			ArtifactSize: artifactSize,
+			Attestation:  req.Attestation,
+			CosignBundle: string(req.CosignBundle),
+			Channel:      req.Channel,
+			Status:       "pending", // 需要人工批准
+			CreatedAt:    time.Now().UTC(),
+			UpdatedAt:    time.Now().UTC(),
+
+			RolloutPercent:    rolloutPercent,
+			RolloutSatellites: rolloutSatellites,
 		}
 
 		if err := db.Create(&release).Error; err != nil {
@@ -181,11 +333,14 @@ func main() {
 			return
 		}
 
-		logEvent("release_registered", map[string]interface{}{
+		logEvent(c.Request.Context(), "release_registered", map[string]interface{}{
 			"component":   req.Component,
 			"version":     req.Version,
-			"imageDigest": req.ImageDigest,
+			"imageDigest": release.ImageDigest,
 			"status":      "pending",
+			"channel":     release.Channel,
+			"rollout":     release.RolloutPercent,
+			"orgId":       release.OrgID,
 		})
 
 		c.JSON(http.StatusCreated, release)
@@ -203,12 +358,43 @@ func main() {
 			return
 		}
 
-		if err := db.First(&release, uint(id)).Error; err != nil {
+		if err := db.Where("org_id = ?", tenant.OrgID(c)).First(&release, uint(id)).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "release not found"})
 			return
 		}
 
+		if blocked, ok := findBlockedDigest(release.ImageDigest); ok {
+			logEvent(c.Request.Context(), "blocked_digest_denied", map[string]interface{}{
+				"releaseId":   release.ID,
+				"component":   release.Component,
+				"version":     release.Version,
+				"imageDigest": release.ImageDigest,
+				"reason":      blocked.Reason,
+				"source":      blocked.Source,
+				"severity":    "critical",
+				"orgId":       release.OrgID,
+			})
+			c.JSON(http.StatusForbidden, gin.H{"error": "image digest is blocklisted", "reason": blocked.Reason})
+			return
+		}
+
+		method, err := verifyReleaseForApproval(&release)
+		if err != nil {
+			logEvent(c.Request.Context(), "release_verification_failed", map[string]interface{}{
+				"component":          release.Component,
+				"version":            release.Version,
+				"imageDigest":        release.ImageDigest,
+				"verificationMethod": method,
+				"reason":             err.Error(),
+				"severity":           "high",
+				"orgId":              release.OrgID,
+			})
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "verificationMethod": method})
+			return
+		}
+
 		release.Status = "approved"
+		release.VerificationMethod = method
 		release.ApprovedBy = "admin" // 實際應從認證 token 取得
 		release.UpdatedAt = time.Now().UTC()
 
@@ -217,19 +403,33 @@ func main() {
 			return
 		}
 
-		logEvent("release_approved", map[string]interface{}{
-			"component":  release.Component,
-			"version":    release.Version,
-			"approvedBy": release.ApprovedBy,
+		logEvent(c.Request.Context(), "release_approved", map[string]interface{}{
+			"component":          release.Component,
+			"version":            release.Version,
+			"approvedBy":         release.ApprovedBy,
+			"verificationMethod": release.VerificationMethod,
+			"orgId":              release.OrgID,
 		})
 
 		c.JSON(http.StatusOK, release)
 	})
 
+	// 衛星回報、SBOM 檢查與通道推進
+	registerPromotionRoutes(r, maxBody)
+
+	// 撤銷已批准的版本（發現漏洞時）
+	registerRevocationRoutes(r, maxBody)
+
+	// 分階段（canary）推出
+	registerRolloutRoutes(r, maxBody)
+
+	// 衛星群更新狀態
+	registerSatelliteRoutes(r)
+
 	// 查詢所有 releases
 	r.GET("/api/v1/releases", func(c *gin.Context) {
 		var releases []Release
-		query := db.Model(&Release{})
+		query := db.Model(&Release{}).Where("org_id = ?", tenant.OrgID(c))
 
 		if component := c.Query("component"); component != "" {
 			query = query.Where("component = ?", component)
@@ -237,6 +437,9 @@ func main() {
 		if status := c.Query("status"); status != "" {
 			query = query.Where("status = ?", status)
 		}
+		if channel := c.Query("channel"); channel != "" {
+			query = query.Where("channel = ?", channel)
+		}
 
 		query = query.Order("created_at DESC").Limit(100)
 
@@ -253,13 +456,129 @@ func main() {
 		port = "8084"
 	}
 
-	if err := r.Run(":" + port); err != nil {
+	// 收到 SIGTERM 時等待處理中的註冊、artifact 驗證與衛星回報完成後再關閉資料庫，尚未送出的 SOC 事件寫入 spool
+	srv := server.New(":"+port, r)
+	srv.AddCleanup("database", func(context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+	srv.AddCleanup("soc_sender", socSender.Close)
+	if err := srv.Run(); err != nil {
 		log.Fatalf("ota-controller server failed: %v", err)
 	}
 }
 
-// logEvent 記錄結構化日誌。
-func logEvent(eventType string, data map[string]interface{}) {
+// resolveUpdate 決定要回應衛星的更新：撤銷回滾、分階段推出、封鎖清單、版本比較與任務政策。
+func resolveUpdate(c *gin.Context, orgID string, req UpdateRequest, channels []string) UpdateResponse {
+	// 衛星執行的版本已撤銷時建議回滾到最後一個安全的已批准版本
+	if req.CurrentVersion != "" {
+		if revoked, ok := findRevokedRelease(orgID, req.Component, req.CurrentVersion); ok {
+			return rollbackResponse(c, orgID, req, revoked, channels)
+		}
+	}
+
+	// 查找訂閱通道中版本最高的已批准版本（已撤銷的版本不會下發）
+	var candidates []Release
+	err := db.Where("org_id = ? AND component = ? AND status = ? AND channel IN ?", orgID, req.Component, "approved", channels).
+		Order("created_at DESC").
+		Limit(100).
+		Find(&candidates).Error
+	if err == nil {
+		// 分階段推出：只考慮這顆衛星在推出範圍內的版本
+		candidates = filterRolloutCohort(candidates, req.SatelliteID)
+	}
+
+	if err != nil || len(candidates) == 0 {
+		// 沒有可用更新
+		return UpdateResponse{
+			Available:     false,
+			Message:       "no approved updates available",
+			UpdateAllowed: false,
+			Timestamp:     time.Now().UTC(),
+		}
+	}
+	latestRelease := newestRelease(candidates)
+
+	// 封鎖清單中的 digest 永遠不下發，即使 release 已批准
+	if blocked, ok := findBlockedDigest(latestRelease.ImageDigest); ok {
+		logEvent(c.Request.Context(), "blocked_digest_denied", map[string]interface{}{
+			"component":   req.Component,
+			"version":     latestRelease.Version,
+			"imageDigest": latestRelease.ImageDigest,
+			"satelliteId": req.SatelliteID,
+			"reason":      blocked.Reason,
+			"source":      blocked.Source,
+			"severity":    "critical",
+			"orgId":       orgID,
+		})
+		return UpdateResponse{
+			Available:     false,
+			Message:       "latest release is blocked",
+			UpdateAllowed: false,
+			DenialReason:  "image digest is blocklisted",
+			Timestamp:     time.Now().UTC(),
+		}
+	}
+
+	// 只提供比目前版本更新的版本，避免降級
+	if !isNewerVersion(latestRelease.Version, req.CurrentVersion) {
+		return UpdateResponse{
+			Available:     false,
+			Message:       "already on latest or newer version",
+			UpdateAllowed: false,
+			Timestamp:     time.Now().UTC(),
+		}
+	}
+
+	// 檢查任務政策（例如：關鍵階段禁止更新）
+	if missionPhaseBlocksUpdates() {
+		return UpdateResponse{
+			Available:     true,
+			Version:       latestRelease.Version,
+			UpdateAllowed: false,
+			DenialReason:  "updates blocked during critical mission phase",
+			Timestamp:     time.Now().UTC(),
+		}
+	}
+
+	// 記錄更新檢查事件
+	logEvent(c.Request.Context(), "update_check", map[string]interface{}{
+		"component":      req.Component,
+		"currentVersion": req.CurrentVersion,
+		"latestVersion":  latestRelease.Version,
+		"satelliteId":    req.SatelliteID,
+		"channel":        latestRelease.Channel,
+		"updateAllowed":  true,
+		"orgId":          orgID,
+	})
+
+	// 允許更新
+	return UpdateResponse{
+		Available:     true,
+		ReleaseID:     latestRelease.ID,
+		Channel:       latestRelease.Channel,
+		Version:       latestRelease.Version,
+		ImageDigest:   latestRelease.ImageDigest,
+		SBOMURL:       latestRelease.SBOMURL,
+		Attestation:   latestRelease.Attestation,
+		Message:       "update available",
+		UpdateAllowed: true,
+		Timestamp:     time.Now().UTC(),
+	}
+}
+
+// missionPhaseBlocksUpdates 回傳目前任務階段（MISSION_PHASE）是否禁止更新。
+func missionPhaseBlocksUpdates() bool {
+	return os.Getenv("MISSION_PHASE") == "critical"
+}
+
+// logEvent 記錄結構化日誌（帶上請求的 requestId）。
+func logEvent(ctx context.Context, eventType string, data map[string]interface{}) {
+	logging.Event(ctx, eventType, data)
+
 	logData := map[string]interface{}{
 		"component": "ota-controller",
 		"event":     eventType,
@@ -268,8 +587,6 @@ func logEvent(eventType string, data map[string]interface{}) {
 	for k, v := range data {
 		logData[k] = v
 	}
-	jsonData, _ := json.Marshal(logData)
-	log.Println(string(jsonData))
 
 	// 發送到 Space-SOC（如果配置）
 	socURL := os.Getenv("SPACE_SOC_URL")
@@ -278,7 +595,8 @@ func logEvent(eventType string, data map[string]interface{}) {
 	}
 }
 
-// sendEventToSOC 發送事件到 Space-SOC。
+// sendEventToSOC 發送事件到 Space-SOC：由 socSender 在背景送出，失敗時重試，仍失敗則寫入 spool 待 SOC 恢復後重送。
+// 事件中的 orgId 不放入 payload，而是透過 X-Org-ID header 指定，搭配 SPACE_SOC_API_KEY（服務金鑰）寫入對應組織。
 func sendEventToSOC(socURL string, event map[string]interface{}) {
 	// 轉換為 Space-SOC 格式
 	socEvent := map[string]interface{}{
@@ -286,17 +604,16 @@ func sendEventToSOC(socURL string, event map[string]interface{}) {
 		"eventType": event["event"],
 	}
 	for k, v := range event {
-		if k != "component" && k != "event" && k != "timestamp" {
+		if k != "component" && k != "event" && k != "timestamp" && k != "orgId" {
 			socEvent[k] = v
 		}
 	}
 
-	eventData, _ := json.Marshal(socEvent)
-	resp, err := http.Post(socURL+"/api/v1/events", "application/json", bytes.NewBuffer(eventData))
+	eventData, err := json.Marshal(socEvent)
 	if err != nil {
-		log.Printf("無法發送事件到 Space-SOC: %v", err)
+		log.Printf("無法序列化事件: %v", err)
 		return
 	}
-	defer resp.Body.Close()
+	orgID, _ := event["orgId"].(string)
+	socSender.Send(socURL+"/api/v1/events", eventData, orgID)
 }
-
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"actinspace.org/supply-chain/sbom"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// releaseChannels 是發布通道的推進順序；訂閱較前面通道的衛星也會收到後面通道的版本。
+var releaseChannels = []string{"beta", "stable"}
+
+// defaultChannel 是未指定通道時 release 與衛星使用的通道。
+const defaultChannel = "stable"
+
+// ReleaseReport 是衛星回報的下載與套用狀態（promotion 的遙測依據）。
+type ReleaseReport struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	OrgID       string    `gorm:"not null;index;default:default" json:"orgId"`
+	ReleaseID   uint      `gorm:"not null;index" json:"releaseId"`
+	SatelliteID string    `gorm:"not null" json:"satelliteId"`
+	Status      string    `gorm:"not null" json:"status"` // "downloaded", "applied", "failed"
+	Message     string    `json:"message,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ReleasePromotion 記錄一次通道推進，保留執行者與時間。
+type ReleasePromotion struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	OrgID       string    `gorm:"not null;index;default:default" json:"orgId"`
+	ReleaseID   uint      `gorm:"not null;index" json:"releaseId"`
+	FromChannel string    `gorm:"not null" json:"fromChannel"`
+	ToChannel   string    `gorm:"not null" json:"toChannel"`
+	PromotedBy  string    `gorm:"not null" json:"promotedBy"`
+	PromotedAt  time.Time `json:"promotedAt"`
+}
+
+var validReportStatuses = map[string]bool{"downloaded": true, "applied": true, "failed": true}
+
+// PromotionCriteria 是推進到下一個通道前必須滿足的條件：
+//   - PROMOTION_MIN_DOWNLOADS: 最少下載回報數（預設 1）
+//   - PROMOTION_MIN_SUCCESS_RATE: 套用成功數 / 下載數的下限（預設 0.95）
+//
+// 另外固定要求沒有任何失敗回報，且 SBOM policy 檢查已通過。
+type PromotionCriteria struct {
+	MinDownloads   int64
+	MinSuccessRate float64
+}
+
+// PromotionStats 是 release 目前的遙測統計。
+type PromotionStats struct {
+	Downloads   int64   `json:"downloads"`
+	Applied     int64   `json:"applied"`
+	Failed      int64   `json:"failed"`
+	SuccessRate float64 `json:"successRate"`
+	SBOMCheck   string  `json:"sbomCheck"`
+}
+
+func loadPromotionCriteria() PromotionCriteria {
+	criteria := PromotionCriteria{MinDownloads: 1, MinSuccessRate: 0.95}
+	if v := os.Getenv("PROMOTION_MIN_DOWNLOADS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			criteria.MinDownloads = n
+		} else {
+			log.Printf("無效的 PROMOTION_MIN_DOWNLOADS %q，使用預設值 %d", v, criteria.MinDownloads)
+		}
+	}
+	if v := os.Getenv("PROMOTION_MIN_SUCCESS_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil && rate >= 0 && rate <= 1 {
+			criteria.MinSuccessRate = rate
+		} else {
+			log.Printf("無效的 PROMOTION_MIN_SUCCESS_RATE %q，使用預設值 %.2f", v, criteria.MinSuccessRate)
+		}
+	}
+	return criteria
+}
+
+// channelIndex 回傳通道在推進順序中的位置，未知通道回傳 -1。
+func channelIndex(channel string) int {
+	for i, ch := range releaseChannels {
+		if ch == channel {
+			return i
+		}
+	}
+	return -1
+}
+
+// visibleChannels 回傳訂閱指定通道的衛星可以收到的通道（自身及之後較穩定的通道）。
+func visibleChannels(channel string) []string {
+	idx := channelIndex(channel)
+	if idx < 0 {
+		return nil
+	}
+	return releaseChannels[idx:]
+}
+
+// releaseStats 彙整 release 的下載與套用回報。
+func releaseStats(release *Release) (PromotionStats, error) {
+	stats := PromotionStats{SBOMCheck: release.SBOMCheck}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&ReleaseReport{}).
+		Select("status, COUNT(*) AS count").
+		Where("org_id = ? AND release_id = ?", release.OrgID, release.ID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return stats, err
+	}
+	for _, row := range rows {
+		switch row.Status {
+		case "downloaded":
+			stats.Downloads = row.Count
+		case "applied":
+			stats.Applied = row.Count
+		case "failed":
+			stats.Failed = row.Count
+		}
+	}
+	if stats.Downloads > 0 {
+		stats.SuccessRate = float64(stats.Applied) / float64(stats.Downloads)
+	}
+	return stats, nil
+}
+
+// unmetCriteria 列出 release 尚未滿足的推進條件。
+func (pc PromotionCriteria) unmetCriteria(release *Release, stats PromotionStats) []string {
+	var unmet []string
+	if release.Status != "approved" {
+		unmet = append(unmet, fmt.Sprintf("release status is %q, must be approved", release.Status))
+	}
+	if stats.Downloads < pc.MinDownloads {
+		unmet = append(unmet, fmt.Sprintf("downloads %d below minimum %d", stats.Downloads, pc.MinDownloads))
+	}
+	if stats.Downloads > 0 && stats.SuccessRate < pc.MinSuccessRate {
+		unmet = append(unmet, fmt.Sprintf("apply success rate %.2f below minimum %.2f", stats.SuccessRate, pc.MinSuccessRate))
+	}
+	if stats.Failed > 0 {
+		unmet = append(unmet, fmt.Sprintf("%d failed apply reports", stats.Failed))
+	}
+	switch release.SBOMCheck {
+	case "passed":
+	case "":
+		unmet = append(unmet, "SBOM policy check has not been run")
+	default:
+		unmet = append(unmet, "SBOM policy check failed")
+	}
+	return unmet
+}
+
+// findRelease 依路徑參數取得所屬組織的 release，失敗時直接寫入回應並回傳 nil。
+func findRelease(c *gin.Context) *Release {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid release ID"})
+		return nil
+	}
+	var release Release
+	if err := db.Where("org_id = ?", tenant.OrgID(c)).First(&release, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "release not found"})
+		return nil
+	}
+	return &release
+}
+
+// registerPromotionRoutes 註冊衛星回報、SBOM 檢查與通道推進端點。
+func registerPromotionRoutes(r *gin.Engine, maxBody gin.HandlerFunc) {
+	criteria := loadPromotionCriteria()
+	if _, err := sbom.OSVClientFromEnv(); err != nil {
+		log.Fatalf("無效的 OSV 設定: %v", err)
+	}
+	// SBOM_POLICY_FILE 可依專案調整授權、組件數量、套件清單與失敗門檻，未設定時使用內建規則
+	var sbomPolicy *sbom.PolicyConfig
+	if path := os.Getenv("SBOM_POLICY_FILE"); path != "" {
+		config, err := sbom.LoadPolicyConfig(path)
+		if err != nil {
+			log.Fatalf("無效的 SBOM policy: %v", err)
+		}
+		sbomPolicy = &config
+	}
+
+	// 衛星回報下載 / 套用結果
+	r.POST("/api/v1/releases/:id/reports", maxBody, func(c *gin.Context) {
+		release := findRelease(c)
+		if release == nil {
+			return
+		}
+
+		var req struct {
+			SatelliteID string `json:"satelliteId" binding:"required"`
+			Status      string `json:"status" binding:"required"`
+			Message     string `json:"message"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !validReportStatuses[req.Status] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid status %q", req.Status)})
+			return
+		}
+
+		report := ReleaseReport{
+			OrgID:       release.OrgID,
+			ReleaseID:   release.ID,
+			SatelliteID: req.SatelliteID,
+			Status:      req.Status,
+			Message:     req.Message,
+			CreatedAt:   time.Now().UTC(),
+		}
+		if err := db.Create(&report).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法記錄回報"})
+			return
+		}
+		if err := recordSatelliteReport(release, report); err != nil {
+			log.Printf("無法更新衛星 %s 的狀態: %v", req.SatelliteID, err)
+		}
+
+		if req.Status == "failed" {
+			logEvent(c.Request.Context(), "release_apply_failed", map[string]interface{}{
+				"releaseId":   release.ID,
+				"component":   release.Component,
+				"version":     release.Version,
+				"satelliteId": req.SatelliteID,
+				"message":     req.Message,
+				"severity":    "high",
+				"orgId":       release.OrgID,
+			})
+		}
+
+		c.JSON(http.StatusCreated, report)
+	})
+
+	// 上傳 CycloneDX SBOM，由 controller 執行 policy 檢查並記錄結果
+	r.POST("/api/v1/releases/:id/sbom-check", maxBody, func(c *gin.Context) {
+		release := findRelease(c)
+		if release == nil {
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		// 簽章涵蓋 SBOM 時只接受簽章時的那一份，避免以替換過的 SBOM 通過 policy 檢查
+		if release.SBOMDigest != "" {
+			sum := sha256.Sum256(data)
+			if computed := "sha256:" + hex.EncodeToString(sum[:]); computed != release.SBOMDigest {
+				logEvent(c.Request.Context(), "sbom_digest_mismatch", map[string]interface{}{
+					"releaseId":      release.ID,
+					"component":      release.Component,
+					"version":        release.Version,
+					"sbomDigest":     release.SBOMDigest,
+					"computedDigest": computed,
+					"severity":       "high",
+					"orgId":          release.OrgID,
+				})
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error":          "SBOM does not match the signed sbomDigest",
+					"sbomDigest":     release.SBOMDigest,
+					"computedDigest": computed,
+				})
+				return
+			}
+		}
+
+		bom, err := sbom.ParseSBOMData(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 每次檢查使用新的 OSV client，快取只在單次檢查內有效，不會錯過新公布的漏洞
+		opts := sbom.DefaultPolicyOptions()
+		opts.OSV, _ = sbom.OSVClientFromEnv()
+		opts.Policy = sbomPolicy
+		result := sbom.CheckPolicyWithOptions(bom, opts)
+		now := time.Now().UTC()
+		release.SBOMCheck = "failed"
+		if result.Allowed {
+			release.SBOMCheck = "passed"
+		}
+		release.SBOMCheckedAt = &now
+		release.UpdatedAt = now
+		if err := db.Save(release).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法更新 release"})
+			return
+		}
+
+		logEvent(c.Request.Context(), "release_sbom_checked", map[string]interface{}{
+			"releaseId":  release.ID,
+			"component":  release.Component,
+			"version":    release.Version,
+			"result":     release.SBOMCheck,
+			"violations": len(result.Violations),
+			"orgId":      release.OrgID,
+		})
+
+		c.JSON(http.StatusOK, gin.H{"sbomCheck": release.SBOMCheck, "result": result})
+	})
+
+	// 推進到下一個通道（例如 beta → stable），未達條件時回傳 409 與未滿足的條件
+	r.POST("/api/v1/releases/:id/promote", maxBody, func(c *gin.Context) {
+		release := findRelease(c)
+		if release == nil {
+			return
+		}
+
+		var req struct {
+			Actor string `json:"actor" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		idx := channelIndex(release.Channel)
+		if idx < 0 || idx == len(releaseChannels)-1 {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("release channel %q cannot be promoted", release.Channel)})
+			return
+		}
+		if blocked, ok := findBlockedDigest(release.ImageDigest); ok {
+			logEvent(c.Request.Context(), "blocked_digest_denied", map[string]interface{}{
+				"releaseId":   release.ID,
+				"component":   release.Component,
+				"version":     release.Version,
+				"channel":     release.Channel,
+				"imageDigest": release.ImageDigest,
+				"reason":      blocked.Reason,
+				"source":      blocked.Source,
+				"actor":       req.Actor,
+				"severity":    "critical",
+				"orgId":       release.OrgID,
+			})
+			c.JSON(http.StatusForbidden, gin.H{"error": "image digest is blocklisted", "reason": blocked.Reason})
+			return
+		}
+
+		stats, err := releaseStats(release)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢 release 回報"})
+			return
+		}
+		if unmet := criteria.unmetCriteria(release, stats); len(unmet) > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "promotion criteria not met",
+				"unmet": unmet,
+				"stats": stats,
+			})
+			return
+		}
+
+		now := time.Now().UTC()
+		promotion := ReleasePromotion{
+			OrgID:       release.OrgID,
+			ReleaseID:   release.ID,
+			FromChannel: release.Channel,
+			ToChannel:   releaseChannels[idx+1],
+			PromotedBy:  req.Actor,
+			PromotedAt:  now,
+		}
+		release.Channel = promotion.ToChannel
+		release.UpdatedAt = now
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(release).Error; err != nil {
+				return err
+			}
+			return tx.Create(&promotion).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法推進 release"})
+			return
+		}
+
+		logEvent(c.Request.Context(), "release_promoted", map[string]interface{}{
+			"releaseId":   release.ID,
+			"component":   release.Component,
+			"version":     release.Version,
+			"fromChannel": promotion.FromChannel,
+			"toChannel":   promotion.ToChannel,
+			"promotedBy":  promotion.PromotedBy,
+			"orgId":       release.OrgID,
+		})
+
+		c.JSON(http.StatusOK, gin.H{"release": release, "promotion": promotion, "stats": stats})
+	})
+
+	// 查詢 release 的推進紀錄
+	r.GET("/api/v1/releases/:id/promotions", func(c *gin.Context) {
+		release := findRelease(c)
+		if release == nil {
+			return
+		}
+		var promotions []ReleasePromotion
+		if err := db.Where("org_id = ? AND release_id = ?", release.OrgID, release.ID).
+			Order("promoted_at ASC").Find(&promotions).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢推進紀錄"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"promotions": promotions, "count": len(promotions)})
+	})
+}
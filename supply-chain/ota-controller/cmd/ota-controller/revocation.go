@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// findRevokedRelease 回傳組織中指定元件與版本的已撤銷 release。
+func findRevokedRelease(orgID, component, version string) (*Release, bool) {
+	var release Release
+	err := db.Where("org_id = ? AND component = ? AND version = ? AND status = ?", orgID, component, version, "revoked").
+		First(&release).Error
+	if err != nil {
+		return nil, false
+	}
+	return &release, true
+}
+
+// findRollbackTarget 回傳訂閱通道中版本最高、未撤銷且 digest 未被封鎖的已批准版本（排除 excludeVersion）。
+func findRollbackTarget(orgID, component, excludeVersion string, channels []string) (*Release, bool) {
+	var candidates []Release
+	err := db.Where("org_id = ? AND component = ? AND status = ? AND channel IN ? AND version <> ?", orgID, component, "approved", channels, excludeVersion).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, false
+	}
+	safe := candidates[:0]
+	for _, candidate := range candidates {
+		if _, blocked := findBlockedDigest(candidate.ImageDigest); !blocked {
+			safe = append(safe, candidate)
+		}
+	}
+	if len(safe) == 0 {
+		return nil, false
+	}
+	target := newestRelease(safe)
+	return &target, true
+}
+
+// rollbackResponse 回應執行已撤銷版本的衛星：建議回滾到最後一個安全的已批准版本（RollbackTo），
+// 關鍵任務階段仍依任務政策禁止更新。
+func rollbackResponse(c *gin.Context, orgID string, req UpdateRequest, revoked *Release, channels []string) UpdateResponse {
+	target, ok := findRollbackTarget(orgID, req.Component, revoked.Version, channels)
+
+	event := map[string]interface{}{
+		"component":        req.Component,
+		"revokedVersion":   revoked.Version,
+		"revocationReason": revoked.RevocationReason,
+		"satelliteId":      req.SatelliteID,
+		"severity":         "high",
+		"orgId":            orgID,
+	}
+	if ok {
+		event["rollbackTo"] = target.Version
+	}
+	logEvent(c.Request.Context(), "revoked_version_reported", event)
+
+	if !ok {
+		return UpdateResponse{
+			Available:     false,
+			Message:       "current version has been revoked; no safe approved version available",
+			UpdateAllowed: false,
+			DenialReason:  "no rollback target",
+			Timestamp:     time.Now().UTC(),
+		}
+	}
+
+	resp := UpdateResponse{
+		Available:     true,
+		ReleaseID:     target.ID,
+		Channel:       target.Channel,
+		Version:       target.Version,
+		ImageDigest:   target.ImageDigest,
+		SBOMURL:       target.SBOMURL,
+		Attestation:   target.Attestation,
+		RollbackTo:    target.Version,
+		Message:       "current version has been revoked; rollback recommended",
+		UpdateAllowed: true,
+		Timestamp:     time.Now().UTC(),
+	}
+	if missionPhaseBlocksUpdates() {
+		resp.UpdateAllowed = false
+		resp.DenialReason = "updates blocked during critical mission phase"
+	}
+	return resp
+}
+
+// registerRevocationRoutes 註冊 release 撤銷端點：已批准的版本發現漏洞後撤銷，
+// updates/check 不再下發，執行中的衛星會收到回滾建議。
+func registerRevocationRoutes(r *gin.Engine, maxBody gin.HandlerFunc) {
+	r.POST("/api/v1/releases/:id/revoke", maxBody, func(c *gin.Context) {
+		release := findRelease(c)
+		if release == nil {
+			return
+		}
+
+		var req struct {
+			Actor  string `json:"actor" binding:"required"`
+			Reason string `json:"reason" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if release.Status != "approved" {
+			c.JSON(http.StatusConflict, gin.H{"error": "only approved releases can be revoked", "status": release.Status})
+			return
+		}
+
+		now := time.Now().UTC()
+		release.Status = "revoked"
+		release.RevokedBy = req.Actor
+		release.RevocationReason = req.Reason
+		release.RevokedAt = &now
+		release.UpdatedAt = now
+		if err := db.Save(release).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法撤銷 release"})
+			return
+		}
+
+		logEvent(c.Request.Context(), "release_revoked", map[string]interface{}{
+			"releaseId":   release.ID,
+			"component":   release.Component,
+			"version":     release.Version,
+			"imageDigest": release.ImageDigest,
+			"channel":     release.Channel,
+			"revokedBy":   req.Actor,
+			"reason":      req.Reason,
+			"severity":    "high",
+			"orgId":       release.OrgID,
+		})
+
+		c.JSON(http.StatusOK, release)
+	})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fullRollout 是全面推出的百分比；未分階段的 release 對所有衛星（包含未帶 satelliteId 的請求）可見。
+const fullRollout = 100
+
+// migrateRolloutPercent 為分階段推出欄位加入前建立的 release 補上全面推出。
+// 欄位刻意不使用 gorm default tag，否則註冊時指定的 0% 會被當成零值改寫為預設值。
+func migrateRolloutPercent() error {
+	return db.Model(&Release{}).Where("rollout_percent IS NULL").Update("rollout_percent", fullRollout).Error
+}
+
+// rolloutBucket 將衛星對應到 0–99 的固定桶位：sha256(component:version:satelliteId) 的前 8 bytes 取餘數。
+// 同一衛星對同一 release 永遠落在同一桶位，提高百分比時已納入的衛星不會被移出；
+// 每個版本的雜湊不同，不會總是同一批衛星先承擔 canary 風險。
+func rolloutBucket(release *Release, satelliteID string) uint64 {
+	sum := sha256.Sum256([]byte(release.Component + ":" + release.Version + ":" + satelliteID))
+	return binary.BigEndian.Uint64(sum[:8]) % 100
+}
+
+// inRolloutCohort 回傳衛星是否在 release 目前的推出範圍內：明確列入 RolloutSatellites，
+// 或桶位小於 RolloutPercent。未帶 satelliteId 的請求只會看到全面推出的 release。
+func inRolloutCohort(release *Release, satelliteID string) bool {
+	if release.RolloutPercent >= fullRollout {
+		return true
+	}
+	if satelliteID == "" {
+		return false
+	}
+	if slices.Contains(release.RolloutSatellites, satelliteID) {
+		return true
+	}
+	return rolloutBucket(release, satelliteID) < uint64(release.RolloutPercent)
+}
+
+// filterRolloutCohort 只保留衛星在推出範圍內的 release；不在範圍內的衛星繼續取得先前全面推出的版本。
+func filterRolloutCohort(releases []Release, satelliteID string) []Release {
+	included := releases[:0]
+	for _, release := range releases {
+		if inRolloutCohort(&release, satelliteID) {
+			included = append(included, release)
+		}
+	}
+	return included
+}
+
+// normalizeRollout 驗證推出百分比並整理 allow-list（去除空白、空值與重複）。
+func normalizeRollout(percent int, satellites []string) ([]string, error) {
+	if percent < 0 || percent > fullRollout {
+		return nil, errors.New("rolloutPercent must be between 0 and 100")
+	}
+	var cleaned []string
+	for _, id := range satellites {
+		id = strings.TrimSpace(id)
+		if id != "" && !slices.Contains(cleaned, id) {
+			cleaned = append(cleaned, id)
+		}
+	}
+	return cleaned, nil
+}
+
+// registerRolloutRoutes 註冊分階段推出端點：逐步調整 release 的推出百分比與指定衛星清單。
+func registerRolloutRoutes(r *gin.Engine, maxBody gin.HandlerFunc) {
+	r.POST("/api/v1/releases/:id/rollout", maxBody, func(c *gin.Context) {
+		release := findRelease(c)
+		if release == nil {
+			return
+		}
+
+		var req struct {
+			Actor      string    `json:"actor" binding:"required"`
+			Percent    *int      `json:"percent" binding:"required"`
+			Satellites *[]string `json:"satellites,omitempty"` // 未提供時保留現有清單
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if release.Status == "revoked" || release.Status == "rejected" {
+			c.JSON(http.StatusConflict, gin.H{"error": "release is no longer deployable", "status": release.Status})
+			return
+		}
+
+		satellites := release.RolloutSatellites
+		if req.Satellites != nil {
+			satellites = *req.Satellites
+		}
+		satellites, err := normalizeRollout(*req.Percent, satellites)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		previous := release.RolloutPercent
+		release.RolloutPercent = *req.Percent
+		release.RolloutSatellites = satellites
+		release.UpdatedAt = time.Now().UTC()
+		if err := db.Save(release).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法更新推出範圍"})
+			return
+		}
+
+		logEvent(c.Request.Context(), "release_rollout_updated", map[string]interface{}{
+			"releaseId":       release.ID,
+			"component":       release.Component,
+			"version":         release.Version,
+			"channel":         release.Channel,
+			"previousPercent": previous,
+			"rolloutPercent":  release.RolloutPercent,
+			"satellites":      release.RolloutSatellites,
+			"actor":           req.Actor,
+			"orgId":           release.OrgID,
+		})
+
+		c.JSON(http.StatusOK, release)
+	})
+}
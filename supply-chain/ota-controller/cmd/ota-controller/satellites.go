@@ -0,0 +1,143 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"actinspace.org/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SatelliteState 是單顆衛星對某個元件的最新更新狀態，在每次 updates/check 與套用回報時更新。
+type SatelliteState struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	OrgID              string     `gorm:"not null;uniqueIndex:idx_satellite_component;default:default" json:"orgId"`
+	SatelliteID        string     `gorm:"not null;uniqueIndex:idx_satellite_component" json:"satelliteId"`
+	Component          string     `gorm:"not null;uniqueIndex:idx_satellite_component" json:"component"`
+	Channel            string     `json:"channel,omitempty"`
+	ReportedVersion    string     `json:"reportedVersion"`         // 衛星最近一次回報的執行版本
+	TargetVersion      string     `json:"targetVersion,omitempty"` // controller 最近一次提供、尚未套用的版本
+	LastCheckAt        *time.Time `json:"lastCheckAt,omitempty"`
+	LastAppliedVersion string     `json:"lastAppliedVersion,omitempty"`
+	LastAppliedAt      *time.Time `json:"lastAppliedAt,omitempty"`
+	Status             string     `gorm:"not null;index" json:"status"` // 見 satelliteStatus* 常數
+	StatusMessage      string     `json:"statusMessage,omitempty"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+}
+
+// 衛星更新狀態。
+const (
+	satelliteStatusUpToDate        = "up_to_date"       // 沒有更新的版本可套用
+	satelliteStatusUpdatePending   = "update_pending"   // 已提供更新，尚未回報套用結果
+	satelliteStatusUpdateBlocked   = "update_blocked"   // 有更新但被任務政策擋下
+	satelliteStatusRollbackPending = "rollback_pending" // 執行中的版本已撤銷，已建議回滾
+	satelliteStatusDownloaded      = "downloaded"       // 已下載，尚未套用
+	satelliteStatusApplied         = "applied"          // 已套用最近提供的版本
+	satelliteStatusFailed          = "failed"           // 套用失敗，重試同一版本前維持此狀態
+)
+
+// updateSatelliteState 以 (org, satellite, component) 讀取或初始化狀態，交給 update 修改後寫回。
+func updateSatelliteState(orgID, satelliteID, component string, update func(state *SatelliteState)) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var state SatelliteState
+		err := tx.Where(SatelliteState{OrgID: orgID, SatelliteID: satelliteID, Component: component}).
+			FirstOrInit(&state).Error
+		if err != nil {
+			return err
+		}
+		update(&state)
+		state.UpdatedAt = time.Now().UTC()
+		return tx.Save(&state).Error
+	})
+}
+
+// recordSatelliteCheck 依 updates/check 的回應更新衛星狀態；沒有 satelliteId 的請求不記錄。
+// 失敗記錄只在 controller 改提供其他版本後才被覆蓋，避免重試中的衛星看起來正常。
+func recordSatelliteCheck(orgID string, req UpdateRequest, resp UpdateResponse) {
+	if req.SatelliteID == "" {
+		return
+	}
+	err := updateSatelliteState(orgID, req.SatelliteID, req.Component, func(state *SatelliteState) {
+		now := resp.Timestamp
+		state.Channel = req.Channel
+		state.ReportedVersion = req.CurrentVersion
+		state.LastCheckAt = &now
+
+		target, status, message := "", satelliteStatusUpToDate, resp.Message
+		switch {
+		case resp.RollbackTo != "":
+			target, status = resp.RollbackTo, satelliteStatusRollbackPending
+		case resp.Available && resp.UpdateAllowed:
+			target, status = resp.Version, satelliteStatusUpdatePending
+		case resp.Available:
+			target, status, message = resp.Version, satelliteStatusUpdateBlocked, resp.DenialReason
+		case resp.DenialReason != "":
+			message = resp.DenialReason
+		}
+		if target != "" && state.Status == satelliteStatusFailed && state.TargetVersion == target {
+			return
+		}
+		state.TargetVersion = target
+		state.Status = status
+		state.StatusMessage = message
+	})
+	if err != nil {
+		log.Printf("無法記錄衛星 %s 的更新狀態: %v", req.SatelliteID, err)
+	}
+}
+
+// recordSatelliteReport 依衛星的下載 / 套用回報更新衛星狀態。
+func recordSatelliteReport(release *Release, report ReleaseReport) error {
+	return updateSatelliteState(release.OrgID, report.SatelliteID, release.Component, func(state *SatelliteState) {
+		state.Channel = release.Channel
+		state.TargetVersion = release.Version
+		state.Status = report.Status
+		state.StatusMessage = report.Message
+		if report.Status == satelliteStatusApplied {
+			now := report.CreatedAt
+			state.ReportedVersion = release.Version
+			state.LastAppliedVersion = release.Version
+			state.LastAppliedAt = &now
+			state.TargetVersion = ""
+		}
+	})
+}
+
+// registerSatelliteRoutes 註冊衛星群狀態查詢端點，用於觀察推出進度與落後的衛星。
+func registerSatelliteRoutes(r *gin.Engine) {
+	r.GET("/api/v1/satellites", func(c *gin.Context) {
+		query := db.Model(&SatelliteState{}).Where("org_id = ?", tenant.OrgID(c))
+		if component := c.Query("component"); component != "" {
+			query = query.Where("component = ?", component)
+		}
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if version := c.Query("version"); version != "" {
+			query = query.Where("reported_version = ?", version)
+		}
+
+		var states []SatelliteState
+		if err := query.Order("satellite_id, component").Limit(1000).Find(&states).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法查詢衛星狀態"})
+			return
+		}
+
+		// 依版本與狀態彙總，方便一眼看出推出進度
+		versions := make(map[string]int)
+		statuses := make(map[string]int)
+		for _, state := range states {
+			versions[state.ReportedVersion]++
+			statuses[state.Status]++
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"satellites": states,
+			"count":      len(states),
+			"versions":   versions,
+			"statuses":   statuses,
+		})
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// semVersion 是解析後的語意化版本（MAJOR.MINOR.PATCH[-prerelease][+build]，可帶 v 前綴）。
+type semVersion struct {
+	major, minor, patch uint64
+	prerelease          []string
+}
+
+// parseSemver 解析語意化版本；build metadata 不影響排序，會被忽略。
+func parseSemver(version string) (semVersion, bool) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	v, _, _ = strings.Cut(v, "+")
+	core, prerelease, hasPrerelease := strings.Cut(v, "-")
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return semVersion{}, false
+	}
+	var numbers [3]uint64
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || (len(part) > 1 && part[0] == '0') {
+			return semVersion{}, false
+		}
+		numbers[i] = n
+	}
+
+	parsed := semVersion{major: numbers[0], minor: numbers[1], patch: numbers[2]}
+	if hasPrerelease {
+		if prerelease == "" {
+			return semVersion{}, false
+		}
+		parsed.prerelease = strings.Split(prerelease, ".")
+		for _, identifier := range parsed.prerelease {
+			if identifier == "" {
+				return semVersion{}, false
+			}
+		}
+	}
+	return parsed, true
+}
+
+// compare 依語意化版本的優先順序比較：回傳 -1、0 或 1。預發布版本低於同號的正式版本。
+func (v semVersion) compare(other semVersion) int {
+	for _, pair := range [][2]uint64{{v.major, other.major}, {v.minor, other.minor}, {v.patch, other.patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(other.prerelease); i++ {
+		if c := comparePrereleaseIdentifier(v.prerelease[i], other.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.prerelease) < len(other.prerelease):
+		return -1
+	case len(v.prerelease) > len(other.prerelease):
+		return 1
+	}
+	return 0
+}
+
+// comparePrereleaseIdentifier 比較預發布識別字：數字依數值比較且低於英數字識別字，英數字依字典順序。
+func comparePrereleaseIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an == bn {
+			return 0
+		}
+		if an < bn {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// isNewerVersion 回傳 candidate 是否比 current 新；current 為空時視為可更新。
+// 任一方不是語意化版本時記錄警告，退回「版本不同即可更新」的完全比對。
+func isNewerVersion(candidate, current string) bool {
+	if current == "" {
+		return true
+	}
+	candidateVersion, candidateOK := parseSemver(candidate)
+	currentVersion, currentOK := parseSemver(current)
+	if !candidateOK || !currentOK {
+		log.Printf("警告: 版本 %q 或 %q 不是語意化版本，改以完全比對判斷是否更新", candidate, current)
+		return candidate != current
+	}
+	return candidateVersion.compare(currentVersion) > 0
+}
+
+// newestRelease 從依 created_at 由新到舊排列的 releases 中選出版本最高者；
+// 任何版本不是語意化版本時退回最新建立的 release。
+func newestRelease(releases []Release) Release {
+	newest := releases[0]
+	newestVersion, ok := parseSemver(newest.Version)
+	if !ok {
+		return newest
+	}
+	for _, release := range releases[1:] {
+		version, ok := parseSemver(release.Version)
+		if !ok {
+			return releases[0]
+		}
+		if version.compare(newestVersion) > 0 {
+			newest, newestVersion = release, version
+		}
+	}
+	return newest
+}
@@ -0,0 +1,101 @@
+package sbom
+
+// advisory 是已知漏洞：只有同一生態系（purl type）中同名同版本的套件才會符合。
+type advisory struct {
+	Ecosystem   string
+	Name        string // 含 namespace 的完整名稱
+	Version     string
+	ID          string // CVE（或 OSV）ID
+	Severity    string // 漏洞本身的嚴重度，直接依賴時會再提高一級
+	Description string
+}
+
+// knownAdvisories 是內建的簡化漏洞清單，在離線（OSV_OFFLINE=true）或 OSV 無法連線時使用。
+var knownAdvisories = []advisory{
+	{Ecosystem: "npm", Name: "lodash", Version: "4.17.15", ID: "CVE-2020-8203", Severity: "high", Description: "CVE-2020-8203: Prototype Pollution"},
+	{Ecosystem: "npm", Name: "axios", Version: "0.18.0", ID: "CVE-2019-10742", Severity: "high", Description: "CVE-2019-10742: SSRF"},
+	{Ecosystem: "npm", Name: "express", Version: "4.16.0", ID: "CVE-2022-24999", Severity: "high", Description: "CVE-2022-24999: Open Redirect"},
+}
+
+// 漏洞資料來源（PolicyResult.AdvisorySource）。
+const (
+	AdvisorySourceOSV     = "osv"
+	AdvisorySourceBuiltin = "builtin"
+)
+
+// lookupAdvisories 回傳每個組件（依 index）的已知漏洞與使用的資料來源。
+// 有 OSV client 時以 purl 查詢 OSV，沒有 purl 的組件仍以內建清單比對；OSV 查詢失敗時整體退回內建清單並回傳錯誤。
+func lookupAdvisories(components []Component, osv *OSVClient) ([][]advisory, string, error) {
+	matches := make([][]advisory, len(components))
+	if osv != nil {
+		var queries []osvQuery
+		for _, comp := range components {
+			if q, ok := componentQuery(comp); ok {
+				queries = append(queries, q)
+			}
+		}
+		results, err := osv.Lookup(queries)
+		if err == nil {
+			for i, comp := range components {
+				if q, ok := componentQuery(comp); ok {
+					matches[i] = results[q.key()]
+				} else {
+					matches[i] = matchAdvisories(comp, knownAdvisories)
+				}
+			}
+			return matches, AdvisorySourceOSV, nil
+		}
+		for i, comp := range components {
+			matches[i] = matchAdvisories(comp, knownAdvisories)
+		}
+		return matches, AdvisorySourceBuiltin, err
+	}
+
+	for i, comp := range components {
+		matches[i] = matchAdvisories(comp, knownAdvisories)
+	}
+	return matches, AdvisorySourceBuiltin, nil
+}
+
+// matchAdvisories 回傳 advisories 中符合組件的漏洞。有 purl 時只比對同生態系的完整名稱與版本；
+// 沒有（或無法解析）purl 時退回以名稱與版本比對所有生態系。
+func matchAdvisories(comp Component, advisories []advisory) []advisory {
+	var matches []advisory
+	pkg, hasPurl := componentPackage(comp)
+	for _, adv := range advisories {
+		if hasPurl {
+			if adv.Ecosystem == pkg.Type && adv.Name == pkg.FullName() && adv.Version == pkg.Version {
+				matches = append(matches, adv)
+			}
+		} else if adv.Name == comp.Name && adv.Version == comp.Version {
+			matches = append(matches, adv)
+		}
+	}
+	return matches
+}
+
+// LicenseRule 是受限授權；Ecosystem 為空時適用所有生態系。
+type LicenseRule struct {
+	Ecosystem string `json:"ecosystem,omitempty" yaml:"ecosystem"`
+	License   string `json:"license" yaml:"license"`
+}
+
+// restrictedLicenses 是預設禁止使用的高風險授權（policy 檔可覆寫）。
+var restrictedLicenses = []LicenseRule{
+	{License: "AGPL-3.0"},
+	{License: "GPL-3.0"},
+}
+
+// matchRestrictedLicense 判斷組件的授權是否符合 rules 中的受限授權；限定生態系的規則只套用在 purl type 相同的組件。
+func matchRestrictedLicense(comp Component, licenseID string, rules []LicenseRule) bool {
+	pkg, hasPurl := componentPackage(comp)
+	for _, rule := range rules {
+		if rule.License != licenseID {
+			continue
+		}
+		if rule.Ecosystem == "" || (hasPurl && rule.Ecosystem == pkg.Type) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package sbom
+
+import "testing"
+
+func TestMatchAdvisoriesSameNameAcrossEcosystems(t *testing.T) {
+	advisories := []advisory{
+		{Ecosystem: "npm", Name: "requests", Version: "2.0.0", ID: "NPM-1"},
+		{Ecosystem: "pypi", Name: "requests", Version: "2.0.0", ID: "PYPI-1"},
+		{Ecosystem: "npm", Name: "@acme/core", Version: "1.0.0", ID: "NPM-2"},
+		{Ecosystem: "golang", Name: "github.com/acme/core", Version: "v1.0.0", ID: "GO-1"},
+	}
+
+	tests := []struct {
+		name string
+		comp Component
+		want []string
+	}{
+		{"npm package matches only the npm advisory", Component{Name: "requests", Version: "2.0.0", Purl: "pkg:npm/requests@2.0.0"}, []string{"NPM-1"}},
+		{"pypi package matches only the pypi advisory", Component{Name: "requests", Version: "2.0.0", Purl: "pkg:pypi/requests@2.0.0"}, []string{"PYPI-1"}},
+		{"same name in an ecosystem without advisories", Component{Name: "requests", Version: "2.0.0", Purl: "pkg:gem/requests@2.0.0"}, nil},
+		{"npm scope is part of the name", Component{Name: "core", Version: "1.0.0", Purl: "pkg:npm/%40acme/core@1.0.0"}, []string{"NPM-2"}},
+		{"unscoped name does not match a scoped advisory", Component{Name: "core", Version: "1.0.0", Purl: "pkg:npm/core@1.0.0"}, nil},
+		{"go module path is part of the name", Component{Name: "core", Version: "v1.0.0", Purl: "pkg:golang/github.com/acme/core@v1.0.0"}, []string{"GO-1"}},
+		{"pypi package with a go module's short name", Component{Name: "core", Version: "v1.0.0", Purl: "pkg:pypi/core@v1.0.0"}, nil},
+		{"version from the component when purl has none", Component{Name: "requests", Version: "2.0.0", Purl: "pkg:pypi/requests"}, []string{"PYPI-1"}},
+		{"without purl every ecosystem is a candidate", Component{Name: "requests", Version: "2.0.0"}, []string{"NPM-1", "PYPI-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := matchAdvisories(tt.comp, advisories)
+			var got []string
+			for _, adv := range matches {
+				got = append(got, adv.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("matched %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("matched %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPolicyNoCrossEcosystemVulnerability(t *testing.T) {
+	// lodash 4.17.15 is only vulnerable on npm; a same-named PyPI or Go package must not be flagged
+	sbom := &CycloneDX{
+		BOMFormat: "CycloneDX",
+		Components: []Component{
+			{Type: "library", Name: "lodash", Version: "4.17.15", Purl: "pkg:npm/lodash@4.17.15"},
+			{Type: "library", Name: "lodash", Version: "4.17.15", Purl: "pkg:pypi/lodash@4.17.15"},
+			{Type: "library", Name: "lodash", Version: "4.17.15", Purl: "pkg:golang/example.com/lodash@4.17.15"},
+		},
+	}
+
+	result := CheckPolicy(sbom)
+	var flagged []string
+	for _, v := range result.Violations {
+		if v.Reason == "known_vulnerability" {
+			flagged = append(flagged, v.Ecosystem)
+		}
+	}
+	if len(flagged) != 1 || flagged[0] != "npm" {
+		t.Fatalf("known_vulnerability violations for ecosystems %v, want only [npm]", flagged)
+	}
+}
+
+func TestMatchRestrictedLicenseByEcosystem(t *testing.T) {
+	rules := []LicenseRule{
+		{License: "AGPL-3.0"},
+		{Ecosystem: "npm", License: "GPL-2.0"},
+	}
+
+	tests := []struct {
+		name    string
+		comp    Component
+		license string
+		want    bool
+	}{
+		{"unscoped rule applies to every ecosystem", Component{Name: "x", Purl: "pkg:pypi/x@1.0"}, "AGPL-3.0", true},
+		{"npm rule applies to npm", Component{Name: "x", Purl: "pkg:npm/x@1.0"}, "GPL-2.0", true},
+		{"npm rule ignores a same-named pypi package", Component{Name: "x", Purl: "pkg:pypi/x@1.0"}, "GPL-2.0", false},
+		{"npm rule ignores a component without purl", Component{Name: "x"}, "GPL-2.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchRestrictedLicense(tt.comp, tt.license, rules); got != tt.want {
+				t.Fatalf("matchRestrictedLicense = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePurl(t *testing.T) {
+	tests := []struct {
+		raw      string
+		typ      string
+		fullName string
+		version  string
+	}{
+		{"pkg:npm/%40babel/core@7.22.0", "npm", "@babel/core", "7.22.0"},
+		{"pkg:npm/@babel/core@7.22.0", "npm", "@babel/core", "7.22.0"},
+		{"pkg:PyPI/requests@2.31.0", "pypi", "requests", "2.31.0"},
+		{"pkg:golang/github.com/gin-gonic/gin@v1.9.1?type=module", "golang", "github.com/gin-gonic/gin", "v1.9.1"},
+		{"pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1", "maven", "org.apache.logging.log4j/log4j-core", "2.14.1"},
+		{"pkg:gem/rails", "gem", "rails", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			p, err := ParsePurl(tt.raw)
+			if err != nil {
+				t.Fatalf("ParsePurl: %v", err)
+			}
+			if p.Type != tt.typ || p.FullName() != tt.fullName || p.Version != tt.version {
+				t.Fatalf("got type=%s name=%s version=%s, want type=%s name=%s version=%s", p.Type, p.FullName(), p.Version, tt.typ, tt.fullName, tt.version)
+			}
+		})
+	}
+
+	for _, raw := range []string{"npm/lodash@1.0.0", "pkg:lodash", "pkg:npm/"} {
+		if _, err := ParsePurl(raw); err == nil {
+			t.Errorf("ParsePurl(%q) should fail", raw)
+		}
+	}
+}
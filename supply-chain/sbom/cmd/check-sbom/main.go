@@ -5,13 +5,17 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 
 	"actinspace.org/supply-chain/sbom"
 )
 
 func main() {
 	sbomFile := flag.String("sbom", "", "SBOM 檔案路徑（必填）")
-	jsonOutput := flag.Bool("json", false, "以 JSON 格式輸出結果")
+	format := flag.String("format", "text", "輸出格式：text、json 或 sarif（SARIF 2.1.0，供 code scanning 使用）")
+	jsonOutput := flag.Bool("json", false, "以 JSON 格式輸出結果（等同 -format json）")
+	maxDepth := flag.Int("max-depth", sbom.DefaultMaxDependencyDepth, "允許的最大傳遞依賴深度（直接依賴為 1，0 表示不檢查）")
+	policyFile := flag.String("policy", "", "SBOM policy 檔（YAML 或 JSON）；未指定時使用內建規則")
 	flag.Parse()
 
 	if *sbomFile == "" {
@@ -19,6 +23,15 @@ func main() {
 		flag.Usage()
 		os.Exit(1)
 	}
+	if *jsonOutput {
+		*format = "json"
+	}
+	switch *format {
+	case "text", "json", "sarif":
+	default:
+		fmt.Fprintf(os.Stderr, "錯誤: 不支援的輸出格式 %q（text、json 或 sarif）\n", *format)
+		os.Exit(1)
+	}
 
 	// 解析 SBOM
 	sbomData, err := sbom.ParseSBOM(*sbomFile)
@@ -27,29 +40,71 @@ func main() {
 		os.Exit(1)
 	}
 
-	// 檢查 policy
-	result := sbom.CheckPolicy(sbomData)
+	// 已知漏洞以 OSV.dev 查詢（OSV_OFFLINE=true 時只用內建清單）
+	osv, err := sbom.OSVClientFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: %v\n", err)
+		os.Exit(1)
+	}
 
-	if *jsonOutput {
+	// 檢查 policy（授權、組件數量、套件清單與失敗門檻可由 policy 檔設定）
+	opts := sbom.PolicyOptions{MaxDependencyDepth: *maxDepth, OSV: osv}
+	if *policyFile != "" {
+		config, err := sbom.LoadPolicyConfig(*policyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "錯誤: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Policy = &config
+	}
+	result := sbom.CheckPolicyWithOptions(sbomData, opts)
+	if result.AdvisoryError != "" {
+		fmt.Fprintf(os.Stderr, "警告: OSV 查詢失敗，改用內建漏洞清單: %s\n", result.AdvisoryError)
+	}
+
+	switch *format {
+	case "json":
 		data, _ := json.MarshalIndent(result, "", "  ")
 		fmt.Println(string(data))
-	} else {
+	case "sarif":
+		data, _ := json.MarshalIndent(toSARIF(result, *sbomFile), "", "  ")
+		fmt.Println(string(data))
+	default:
 		fmt.Printf("SBOM Policy 檢查結果\n")
 		fmt.Printf("==================\n\n")
+		fmt.Printf("SBOM 格式: %s %s\n", sbomData.BOMFormat, sbomData.SpecVersion)
 		fmt.Printf("組件數量: %d\n", len(sbomData.Components))
+		fmt.Printf("漏洞資料來源: %s\n", result.AdvisorySource)
+		printDependencyAnalysis(result.Dependencies)
 		fmt.Printf("Policy 狀態: ")
 		if result.Allowed {
 			fmt.Printf("✅ 通過\n")
 		} else {
 			fmt.Printf("❌ 失敗\n")
 		}
-		fmt.Printf("違規數量: %d\n\n", len(result.Violations))
+		fmt.Printf("違規數量: %d（達到 %s 以上: %d）\n\n", len(result.Violations), result.FailOnSeverity, result.BlockingViolations)
 
 		if len(result.Violations) > 0 {
 			fmt.Printf("違規詳情:\n")
 			for i, v := range result.Violations {
-				fmt.Printf("%d. [%s] %s@%s\n", i+1, v.Severity, v.Component, v.Version)
+				warning := ""
+				if v.Warning {
+					warning = "（警告）"
+				}
+				fmt.Printf("%d. [%s] %s@%s%s\n", i+1, v.Severity, v.Component, v.Version, warning)
 				fmt.Printf("   原因: %s\n", v.Reason)
+				if v.VulnerabilityID != "" {
+					fmt.Printf("   漏洞: %s\n", v.VulnerabilityID)
+				}
+				if v.Ecosystem != "" {
+					fmt.Printf("   生態系: %s\n", v.Ecosystem)
+				}
+				switch {
+				case v.Depth == 1:
+					fmt.Printf("   依賴深度: 1（直接依賴）\n")
+				case v.Depth > 1:
+					fmt.Printf("   依賴深度: %d（傳遞依賴）\n", v.Depth)
+				}
 				fmt.Printf("   說明: %s\n\n", v.Description)
 			}
 		}
@@ -60,3 +115,24 @@ func main() {
 	}
 }
 
+
+// printDependencyAnalysis 輸出依賴深度分布。
+func printDependencyAnalysis(analysis *sbom.DependencyAnalysis) {
+	if analysis == nil || !analysis.HasGraph {
+		fmt.Printf("依賴圖: 未提供（略過深度分析）\n")
+		return
+	}
+
+	fmt.Printf("最大依賴深度: %d\n", analysis.MaxDepth)
+	depths := make([]int, 0, len(analysis.DepthDistribution))
+	for depth := range analysis.DepthDistribution {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+	for _, depth := range depths {
+		fmt.Printf("  深度 %d: %d 個組件\n", depth, analysis.DepthDistribution[depth])
+	}
+	if len(analysis.Unlinked) > 0 {
+		fmt.Printf("  未連到主組件: %d 個組件\n", len(analysis.Unlinked))
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"actinspace.org/supply-chain/sbom"
+)
+
+// SARIF 2.1.0 報告的簡化結構（只包含 code scanning 顯示結果所需的欄位）。
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifReport struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	ShortDescription     sarifMessage      `json:"shortDescription"`
+	DefaultConfiguration sarifRuleConfig   `json:"defaultConfiguration"`
+	Properties           map[string]string `json:"properties,omitempty"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// sarifRuleDescriptions 是各違規原因的規則說明。
+var sarifRuleDescriptions = map[string]string{
+	"known_vulnerability":        "Component has a known vulnerability",
+	"restricted_license":         "Component uses a restricted license",
+	"excessive_dependencies":     "SBOM contains an unusually large number of components",
+	"deep_transitive_dependency": "Transitive dependency exceeds the allowed depth",
+	"missing_provenance":         "Component declares no provenance",
+	"denied_package":             "Component matches a denied package pattern",
+	"package_not_allowed":        "Component is not in the allowed package list",
+	"missing_integrity_hash":     "Component declares no integrity hash",
+	"missing_license":            "Component declares no license",
+}
+
+// securitySeverity 是 GitHub code scanning 使用的 security-severity 分數（CVSS 區間）。
+var securitySeverity = map[string]string{
+	"critical": "9.5",
+	"high":     "8.0",
+	"medium":   "5.5",
+	"low":      "2.0",
+}
+
+// sarifLevel 將違規嚴重度轉為 SARIF level；警告最多為 warning，不會被當成錯誤。
+func sarifLevel(v sbom.PolicyViolation) string {
+	level := "note"
+	switch v.Severity {
+	case "critical", "high":
+		level = "error"
+	case "medium":
+		level = "warning"
+	}
+	if v.Warning && level == "error" {
+		level = "warning"
+	}
+	return level
+}
+
+// toSARIF 將 policy 結果轉為 SARIF 2.1.0 報告：每個違規原因是一條規則，每個違規是一筆結果，
+// 位置為 SBOM 檔案（code scanning 需要實體位置）並以組件作為邏輯位置。
+func toSARIF(result sbom.PolicyResult, sbomPath string) sarifReport {
+	uri := filepath.ToSlash(sbomPath)
+	if rel, err := filepath.Rel(".", sbomPath); err == nil && filepath.IsLocal(rel) {
+		uri = filepath.ToSlash(rel)
+	}
+
+	// 每條規則的 security-severity 取該原因中最高的嚴重度
+	ruleSeverity := make(map[string]string)
+	for _, v := range result.Violations {
+		if current, ok := ruleSeverity[v.Reason]; !ok || severityOrder(v.Severity) > severityOrder(current) {
+			ruleSeverity[v.Reason] = v.Severity
+		}
+	}
+	reasons := make([]string, 0, len(ruleSeverity))
+	for reason := range ruleSeverity {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	rules := make([]sarifRule, 0, len(reasons))
+	ruleIndex := make(map[string]int, len(reasons))
+	for i, reason := range reasons {
+		description, ok := sarifRuleDescriptions[reason]
+		if !ok {
+			description = reason
+		}
+		ruleIndex[reason] = i
+		rules = append(rules, sarifRule{
+			ID:                   reason,
+			Name:                 reason,
+			ShortDescription:     sarifMessage{Text: description},
+			DefaultConfiguration: sarifRuleConfig{Level: sarifLevel(sbom.PolicyViolation{Severity: ruleSeverity[reason]})},
+			Properties: map[string]string{
+				"security-severity": securitySeverity[ruleSeverity[reason]],
+			},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(result.Violations))
+	for _, v := range result.Violations {
+		component := v.Component
+		if v.Version != "" {
+			component += "@" + v.Version
+		}
+		properties := map[string]interface{}{"severity": v.Severity}
+		if v.VulnerabilityID != "" {
+			properties["vulnerabilityId"] = v.VulnerabilityID
+		}
+		if v.Ecosystem != "" {
+			properties["ecosystem"] = v.Ecosystem
+		}
+		if v.Depth > 0 {
+			properties["depth"] = v.Depth
+		}
+		if v.Warning {
+			properties["warning"] = true
+		}
+		results = append(results, sarifResult{
+			RuleID:    v.Reason,
+			RuleIndex: ruleIndex[v.Reason],
+			Level:     sarifLevel(v),
+			Message:   sarifMessage{Text: fmt.Sprintf("%s: %s", component, v.Description)},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+					Region:           sarifRegion{StartLine: 1},
+				},
+				LogicalLocations: []sarifLogicalLocation{{Name: component, Kind: "package"}},
+			}},
+			Properties: properties,
+		})
+	}
+
+	return sarifReport{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:  "check-sbom",
+				Rules: rules,
+			}},
+			Results: results,
+		}},
+	}
+}
+
+// severityOrder 回傳嚴重度的排序值（low 最小），未知的嚴重度為 0。
+func severityOrder(severity string) int {
+	switch severity {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	}
+	return 0
+}
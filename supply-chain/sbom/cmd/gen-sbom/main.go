@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"actinspace.org/supply-chain/sbom"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "包含 go.mod 的目錄")
+	module := flag.String("module", "", "Go 模組路徑（例如 github.com/google/uuid@v1.6.0，未指定版本時為 latest），與 -dir 擇一")
+	version := flag.String("version", "", "主組件版本（例如 v1.0.0；使用 -module 時預設為模組版本）")
+	output := flag.String("output", "", "輸出檔案路徑（預設輸出到 stdout）")
+	flag.Parse()
+
+	dirSet := false
+	flag.Visit(func(f *flag.Flag) { dirSet = dirSet || f.Name == "dir" })
+	if *module != "" && dirSet {
+		fmt.Fprintln(os.Stderr, "錯誤: -module 與 -dir 只能擇一")
+		os.Exit(2)
+	}
+
+	var bom *sbom.CycloneDX
+	var err error
+	if *module != "" {
+		bom, err = sbom.GenerateFromModulePath(*module, *version)
+	} else {
+		bom, err = sbom.GenerateFromGoModule(*dir, *version)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: 無法序列化 SBOM: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		fmt.Println(string(data))
+		return
+	}
+
+	if err := os.WriteFile(*output, append(data, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "錯誤: 無法寫入 SBOM: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "已產生 SBOM: %s（%d 個組件）\n", *output, len(bom.Components))
+}
@@ -0,0 +1,391 @@
+package sbom
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GoModule 是從 go.mod 解析出的模組資訊。
+type GoModule struct {
+	Path     string
+	Requires []GoRequirement
+	Replaces []GoReplace
+}
+
+// GoRequirement 是 go.mod 中的單一 require 項目。
+type GoRequirement struct {
+	Path     string
+	Version  string
+	Indirect bool
+}
+
+// GoReplace 是 go.mod 中的單一 replace 項目。OldVersion 為空時取代所有版本；
+// NewVersion 為空時 NewPath 是本機目錄（相對於 go.mod 所在目錄）。
+type GoReplace struct {
+	OldPath    string
+	OldVersion string
+	NewPath    string
+	NewVersion string
+}
+
+// IsLocal 表示以本機目錄取代模組。
+func (r GoReplace) IsLocal() bool {
+	return r.NewVersion == ""
+}
+
+// Replacement 回傳套用在 req 上的 replace；指定版本的 replace 優先於未指定版本者。
+func (m *GoModule) Replacement(req GoRequirement) (GoReplace, bool) {
+	var match GoReplace
+	found := false
+	for _, rep := range m.Replaces {
+		if rep.OldPath != req.Path {
+			continue
+		}
+		if rep.OldVersion == req.Version {
+			return rep, true
+		}
+		if rep.OldVersion == "" {
+			match, found = rep, true
+		}
+	}
+	return match, found
+}
+
+// GenerateFromGoModule 讀取目錄下的 go.mod/go.sum，產生 CycloneDX SBOM。
+// version 為主組件版本（空字串時使用 "devel"）；授權資訊從本機 module cache 的 LICENSE 檔偵測。
+// replace 指向其他模組時，組件的名稱、版本、purl 與 go.sum 雜湊使用取代後的模組；指向本機目錄時不帶 purl。
+func GenerateFromGoModule(dir, version string) (*CycloneDX, error) {
+	return generateFromGoMod(filepath.Join(dir, "go.mod"), filepath.Join(dir, "go.sum"), dir, version)
+}
+
+// GenerateFromModulePath 以 go mod download 取得模組後產生 SBOM。target 為 "path@version"，未指定版本時使用 latest；
+// version 為空時使用解析後的模組版本。
+func GenerateFromModulePath(target, version string) (*CycloneDX, error) {
+	if !strings.Contains(target, "@") {
+		target += "@latest"
+	}
+	download, err := downloadModule(target)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		version = download.Version
+	}
+
+	// 沒有 go.mod 的舊模組只有 module cache 產生的 .mod 檔，因此 go.mod 取自 GoMod 而非 Dir
+	bom, err := generateFromGoMod(download.GoMod, filepath.Join(download.Dir, "go.sum"), download.Dir, version)
+	if err != nil {
+		return nil, err
+	}
+	if download.Sum != "" && version == download.Version {
+		bom.Metadata.Component.Properties = append(bom.Metadata.Component.Properties, Property{Name: "go:sum", Value: download.Sum})
+	}
+	return bom, nil
+}
+
+func generateFromGoMod(goModPath, goSumPath, dir, version string) (*CycloneDX, error) {
+	mod, err := ParseGoMod(goModPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sums, err := parseGoSum(goSumPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		version = "devel"
+	}
+
+	modCache := goModCache()
+	components := make([]Component, 0, len(mod.Requires))
+	for _, req := range mod.Requires {
+		components = append(components, requirementComponent(mod, req, sums, modCache, dir))
+	}
+
+	return &CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata: Metadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Component: Component{
+				Type:    "application",
+				Name:    mod.Path,
+				Version: version,
+				Purl:    fmt.Sprintf("pkg:golang/%s@%s", mod.Path, version),
+			},
+		},
+		Components: components,
+	}, nil
+}
+
+// requirementComponent 將 require 項目轉為組件，套用 replace 後以實際建置使用的模組填寫版本、purl、雜湊與授權。
+// go.sum 的 h1: 是模組目錄的 dirhash 而非檔案的 SHA-256，因此放在 go:sum property，不列為 CycloneDX hash。
+func requirementComponent(mod *GoModule, req GoRequirement, sums map[string]string, modCache, dir string) Component {
+	comp := Component{
+		Type:    "library",
+		Name:    req.Path,
+		Version: req.Version,
+	}
+	if req.Indirect {
+		comp.Properties = append(comp.Properties, Property{Name: "go:indirect", Value: "true"})
+	}
+
+	rep, replaced := mod.Replacement(req)
+	if replaced {
+		comp.Properties = append(comp.Properties, Property{Name: "go:replaces", Value: req.Path + "@" + req.Version})
+	}
+	if replaced && rep.IsLocal() {
+		localDir := rep.NewPath
+		if !filepath.IsAbs(localDir) {
+			localDir = filepath.Join(dir, localDir)
+		}
+		comp.Version = "devel"
+		comp.Properties = append(comp.Properties, Property{Name: "go:replace-dir", Value: rep.NewPath})
+		if lic := detectLicenseIn(localDir); lic != "" {
+			comp.Licenses = append(comp.Licenses, License{License: LicenseInfo{ID: lic}})
+		}
+		return comp
+	}
+
+	path, version := req.Path, req.Version
+	if replaced {
+		path, version = rep.NewPath, rep.NewVersion
+		comp.Name, comp.Version = path, version
+	}
+	comp.Purl = fmt.Sprintf("pkg:golang/%s@%s", path, version)
+	if sum, ok := sums[path+"@"+version]; ok {
+		comp.Properties = append(comp.Properties, Property{Name: "go:sum", Value: sum})
+	}
+	if modCache != "" {
+		if lic := detectLicense(modCache, path, version); lic != "" {
+			comp.Licenses = append(comp.Licenses, License{License: LicenseInfo{ID: lic}})
+		}
+	}
+	return comp
+}
+
+// moduleDownload 是 go mod download -json 的輸出。
+type moduleDownload struct {
+	Path    string
+	Version string
+	Dir     string
+	GoMod   string
+	Sum     string
+	Error   string
+}
+
+// downloadModule 以 go mod download 將模組下載到 module cache（已存在時直接使用）並回傳其位置。
+func downloadModule(target string) (*moduleDownload, error) {
+	cmd := exec.Command("go", "mod", "download", "-json", target)
+	// 在任何模組之外執行，避免修改目前目錄的 go.mod / go.sum
+	cmd.Dir = os.TempDir()
+	out, err := cmd.Output()
+
+	var download moduleDownload
+	if jsonErr := json.Unmarshal(out, &download); jsonErr == nil && download.Error != "" {
+		return nil, fmt.Errorf("無法下載模組 %s: %s", target, download.Error)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("無法下載模組 %s: %w", target, err)
+	}
+	if download.Dir == "" || download.GoMod == "" {
+		return nil, fmt.Errorf("無法下載模組 %s: go mod download 未回傳模組位置", target)
+	}
+	return &download, nil
+}
+
+// ParseGoMod 解析 go.mod 的 module 與 require 區塊（含單行與括號區塊）。
+func ParseGoMod(path string) (*GoModule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("無法讀取 go.mod: %w", err)
+	}
+	defer file.Close()
+
+	mod := &GoModule{}
+	block := "" // 目前所在的括號區塊（require、replace 或其他）
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		indirect := strings.HasSuffix(line, "// indirect")
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "module "):
+			mod.Path = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		case block != "" && line == ")":
+			block = ""
+		case block == "require":
+			if req, ok := parseRequire(line, indirect); ok {
+				mod.Requires = append(mod.Requires, req)
+			}
+		case block == "replace":
+			if rep, ok := parseReplace(line); ok {
+				mod.Replaces = append(mod.Replaces, rep)
+			}
+		case block != "":
+			// exclude、retract 等區塊不影響 SBOM
+		case strings.HasSuffix(line, "("):
+			block = strings.TrimSpace(strings.TrimSuffix(line, "("))
+		case strings.HasPrefix(line, "require "):
+			if req, ok := parseRequire(strings.TrimPrefix(line, "require "), indirect); ok {
+				mod.Requires = append(mod.Requires, req)
+			}
+		case strings.HasPrefix(line, "replace "):
+			if rep, ok := parseReplace(strings.TrimPrefix(line, "replace ")); ok {
+				mod.Replaces = append(mod.Replaces, rep)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("無法解析 go.mod: %w", err)
+	}
+	if mod.Path == "" {
+		return nil, fmt.Errorf("go.mod 缺少 module 宣告")
+	}
+
+	return mod, nil
+}
+
+func parseRequire(line string, indirect bool) (GoRequirement, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return GoRequirement{}, false
+	}
+	return GoRequirement{Path: fields[0], Version: fields[1], Indirect: indirect}, true
+}
+
+// parseReplace 解析 "old [version] => new [version]"；新模組沒有版本時為本機目錄。
+func parseReplace(line string) (GoReplace, bool) {
+	oldSpec, newSpec, ok := strings.Cut(line, "=>")
+	if !ok {
+		return GoReplace{}, false
+	}
+	oldFields, newFields := strings.Fields(oldSpec), strings.Fields(newSpec)
+	if len(oldFields) < 1 || len(oldFields) > 2 || len(newFields) < 1 || len(newFields) > 2 {
+		return GoReplace{}, false
+	}
+
+	rep := GoReplace{OldPath: oldFields[0], NewPath: newFields[0]}
+	if len(oldFields) == 2 {
+		rep.OldVersion = oldFields[1]
+	}
+	if len(newFields) == 2 {
+		rep.NewVersion = newFields[1]
+	}
+	return rep, true
+}
+
+// parseGoSum 讀取 go.sum 的模組內容雜湊（h1:，模組目錄的 dirhash），key 為 "path@version"。
+// go.sum 不存在時回傳空 map（沒有依賴的模組不會有 go.sum）。
+func parseGoSum(path string) (map[string]string, error) {
+	sums := make(map[string]string)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return sums, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("無法讀取 go.sum: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// 略過 "/go.mod" 行，只保留整個模組內容的雜湊
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") || !strings.HasPrefix(fields[2], "h1:") {
+			continue
+		}
+		sums[fields[0]+"@"+fields[1]] = fields[2]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("無法解析 go.sum: %w", err)
+	}
+
+	return sums, nil
+}
+
+// goModCache 回傳本機 module cache 路徑，找不到時回傳空字串（略過授權偵測）。
+func goModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	out, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// licenseSignatures 以關鍵字粗略辨識常見授權（順序即優先權）。
+var licenseSignatures = []struct {
+	id       string
+	keywords []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+}
+
+// detectLicense 從 module cache 中的 LICENSE 檔偵測授權 SPDX ID。
+func detectLicense(modCache, modPath, version string) string {
+	return detectLicenseIn(filepath.Join(modCache, escapeModulePath(modPath)+"@"+version))
+}
+
+// detectLicenseIn 從模組目錄中的 LICENSE 檔偵測授權 SPDX ID。
+func detectLicenseIn(dir string) string {
+	for _, name := range []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		text := string(data)
+		for _, sig := range licenseSignatures {
+			matched := true
+			for _, kw := range sig.keywords {
+				if !strings.Contains(text, kw) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return sig.id
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// escapeModulePath 依 module cache 規則將大寫字母轉為 "!" + 小寫。
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteRune('!')
+			b.WriteRune(r + ('a' - 'A'))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
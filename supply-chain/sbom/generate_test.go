@@ -0,0 +1,155 @@
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testGoMod = `module example.com/app
+
+go 1.23
+
+require (
+	example.com/plain v1.0.0
+	example.com/forked v1.2.0 // indirect
+	example.com/pinned v1.3.0
+	example.com/local v0.1.0
+)
+
+require example.com/other v2.0.0
+
+replace example.com/forked => example.com/fork v1.2.1
+
+replace (
+	example.com/pinned v1.3.0 => example.com/pinned v1.3.1
+	example.com/pinned v1.2.0 => example.com/unused v9.9.9
+	example.com/local => ./third_party/local
+)
+
+exclude (
+	example.com/plain v0.9.0
+)
+`
+
+const testGoSum = `example.com/plain v1.0.0 h1:cGxhaW4=
+example.com/plain v1.0.0/go.mod h1:bW9k
+example.com/fork v1.2.1 h1:Zm9yaw==
+example.com/forked v1.2.0 h1:b3JpZ2luYWw=
+example.com/pinned v1.3.1 h1:cGlubmVk
+`
+
+func writeTestModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	localDir := filepath.Join(dir, "third_party", "local")
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(dir, "go.mod"):        testGoMod,
+		filepath.Join(dir, "go.sum"):        testGoSum,
+		filepath.Join(localDir, "LICENSE"):  "Permission is hereby granted, free of charge, to any person",
+		filepath.Join(localDir, "go.mod"):   "module example.com/local\n",
+		filepath.Join(localDir, "local.go"): "package local\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 空的 module cache：不偵測遠端模組的授權，也不執行 go env
+	t.Setenv("GOMODCACHE", t.TempDir())
+	return dir
+}
+
+func TestParseGoModReplaces(t *testing.T) {
+	mod, err := ParseGoMod(filepath.Join(writeTestModule(t), "go.mod"))
+	if err != nil {
+		t.Fatalf("ParseGoMod: %v", err)
+	}
+	if len(mod.Requires) != 5 {
+		t.Fatalf("parsed %d requires, want 5: %+v", len(mod.Requires), mod.Requires)
+	}
+	want := []GoReplace{
+		{OldPath: "example.com/forked", NewPath: "example.com/fork", NewVersion: "v1.2.1"},
+		{OldPath: "example.com/pinned", OldVersion: "v1.3.0", NewPath: "example.com/pinned", NewVersion: "v1.3.1"},
+		{OldPath: "example.com/pinned", OldVersion: "v1.2.0", NewPath: "example.com/unused", NewVersion: "v9.9.9"},
+		{OldPath: "example.com/local", NewPath: "./third_party/local"},
+	}
+	if len(mod.Replaces) != len(want) {
+		t.Fatalf("parsed replaces %+v, want %+v", mod.Replaces, want)
+	}
+	for i := range want {
+		if mod.Replaces[i] != want[i] {
+			t.Errorf("replace %d = %+v, want %+v", i, mod.Replaces[i], want[i])
+		}
+	}
+}
+
+func TestGenerateFromGoModuleHonorsReplace(t *testing.T) {
+	bom, err := GenerateFromGoModule(writeTestModule(t), "v1.0.0")
+	if err != nil {
+		t.Fatalf("GenerateFromGoModule: %v", err)
+	}
+
+	components := make(map[string]Component)
+	for _, comp := range bom.Components {
+		for _, prop := range comp.Properties {
+			if prop.Name == "go:replaces" {
+				components[prop.Value] = comp
+			}
+		}
+		components[comp.Name] = comp
+	}
+	property := func(comp Component, name string) string {
+		for _, prop := range comp.Properties {
+			if prop.Name == name {
+				return prop.Value
+			}
+		}
+		return ""
+	}
+
+	tests := []struct {
+		key     string
+		name    string
+		version string
+		purl    string
+		sum     string
+	}{
+		{"example.com/plain", "example.com/plain", "v1.0.0", "pkg:golang/example.com/plain@v1.0.0", "h1:cGxhaW4="},
+		{"example.com/forked@v1.2.0", "example.com/fork", "v1.2.1", "pkg:golang/example.com/fork@v1.2.1", "h1:Zm9yaw=="},
+		{"example.com/pinned@v1.3.0", "example.com/pinned", "v1.3.1", "pkg:golang/example.com/pinned@v1.3.1", "h1:cGlubmVk"},
+		{"example.com/local@v0.1.0", "example.com/local", "devel", "", ""},
+		{"example.com/other", "example.com/other", "v2.0.0", "pkg:golang/example.com/other@v2.0.0", ""},
+	}
+	for _, tt := range tests {
+		comp, ok := components[tt.key]
+		if !ok {
+			t.Errorf("no component for %s", tt.key)
+			continue
+		}
+		if comp.Name != tt.name || comp.Version != tt.version || comp.Purl != tt.purl {
+			t.Errorf("%s: got %s %s %s, want %s %s %s", tt.key, comp.Name, comp.Version, comp.Purl, tt.name, tt.version, tt.purl)
+		}
+		if got := property(comp, "go:sum"); got != tt.sum {
+			t.Errorf("%s: go:sum = %q, want %q", tt.key, got, tt.sum)
+		}
+		// go.sum 的 h1: 不是檔案的 SHA-256，不可列為 CycloneDX hash
+		if len(comp.Hashes) != 0 {
+			t.Errorf("%s: unexpected hashes %+v", tt.key, comp.Hashes)
+		}
+	}
+
+	local := components["example.com/local@v0.1.0"]
+	if property(local, "go:replace-dir") != "./third_party/local" {
+		t.Errorf("local replacement should record its directory, got %+v", local.Properties)
+	}
+	if len(local.Licenses) != 1 || local.Licenses[0].License.ID != "MIT" {
+		t.Errorf("local replacement license should be detected from its directory, got %+v", local.Licenses)
+	}
+	if property(components["example.com/forked@v1.2.0"], "go:indirect") != "true" {
+		t.Error("indirect flag lost on a replaced requirement")
+	}
+}
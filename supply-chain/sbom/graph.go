@@ -0,0 +1,193 @@
+package sbom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dependency 是 CycloneDX dependencies 區段的一筆紀錄：Ref 直接依賴 DependsOn 中的組件。
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// ExternalReference 定義組件的外部參考（例如 vcs、distribution）。
+type ExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// Supplier 定義組件的供應者。
+type Supplier struct {
+	Name string   `json:"name,omitempty"`
+	URL  []string `json:"url,omitempty"`
+}
+
+// DefaultMaxDependencyDepth 是未設定時允許的最大傳遞依賴深度（直接依賴為 1）。
+const DefaultMaxDependencyDepth = 4
+
+// DependencyAnalysis 是依賴圖的分析結果。
+type DependencyAnalysis struct {
+	HasGraph          bool           `json:"hasGraph"`           // SBOM 是否提供 dependencies 區段
+	MaxDepth          int            `json:"maxDepth"`           // 最深的傳遞層級
+	DepthDistribution map[int]int    `json:"depthDistribution"`  // 深度 -> 組件數
+	Depths            map[string]int `json:"depths,omitempty"`   // 組件 ref -> 最短依賴深度
+	Unlinked          []string       `json:"unlinked,omitempty"` // 無法從主組件到達的組件
+	paths             map[string][]string
+}
+
+// Path 回傳從主組件引入 ref 的最短依賴鏈（不含主組件）。
+func (a *DependencyAnalysis) Path(ref string) []string {
+	return a.paths[ref]
+}
+
+// componentDepth 回傳組件的最短依賴深度；沒有依賴圖或無法從主組件到達時回傳 0。
+func (a *DependencyAnalysis) componentDepth(comp Component) int {
+	return a.Depths[componentRef(comp)]
+}
+
+// severityLevels 是嚴重度由低到高的順序。
+var severityLevels = []string{"low", "medium", "high", "critical"}
+
+// raiseSeverity 將嚴重度提高一級（critical 維持不變）。
+func raiseSeverity(severity string) string {
+	for i, level := range severityLevels[:len(severityLevels)-1] {
+		if level == severity {
+			return severityLevels[i+1]
+		}
+	}
+	return severity
+}
+
+// vulnerabilitySeverity 依依賴深度調整已知漏洞的嚴重度並附上處置說明：直接依賴（深度 1）可以自行升級，
+// 嚴重度提高一級；傳遞依賴需要等上游升級或覆寫版本，維持漏洞本身的嚴重度。沒有依賴圖時無法判斷，維持原嚴重度。
+func vulnerabilitySeverity(analysis *DependencyAnalysis, comp Component, base string) (string, string) {
+	switch depth := analysis.componentDepth(comp); {
+	case depth == 1:
+		return raiseSeverity(base), "direct dependency; upgrade it directly"
+	case depth > 1:
+		return base, fmt.Sprintf("transitive dependency at depth %d via %s; upgrade or override the parent",
+			depth, strings.Join(analysis.Path(componentRef(comp)), " -> "))
+	}
+	return base, ""
+}
+
+// componentRef 回傳組件在依賴圖中的識別：優先使用 bom-ref，其次 purl。
+func componentRef(comp Component) string {
+	if comp.BOMRef != "" {
+		return comp.BOMRef
+	}
+	return comp.Purl
+}
+
+// hasProvenance 判斷組件是否宣告來源（purl、供應者或外部參考其一）。
+func hasProvenance(comp Component) bool {
+	return comp.Purl != "" || comp.Supplier != nil || len(comp.ExternalReferences) > 0
+}
+
+// AnalyzeDependencies 以 CycloneDX dependencies 計算每個組件的最短依賴深度。
+// 起點為 metadata.component；主組件未出現在依賴圖時，以沒有被任何組件依賴的節點為起點。
+func AnalyzeDependencies(bom *CycloneDX) *DependencyAnalysis {
+	analysis := &DependencyAnalysis{
+		DepthDistribution: make(map[int]int),
+		Depths:            make(map[string]int),
+		paths:             make(map[string][]string),
+	}
+	if len(bom.Dependencies) == 0 {
+		return analysis
+	}
+	analysis.HasGraph = true
+
+	edges := make(map[string][]string, len(bom.Dependencies))
+	dependedOn := make(map[string]bool)
+	for _, dep := range bom.Dependencies {
+		edges[dep.Ref] = append(edges[dep.Ref], dep.DependsOn...)
+		for _, child := range dep.DependsOn {
+			dependedOn[child] = true
+		}
+	}
+
+	var roots []string
+	if root := componentRef(bom.Metadata.Component); root != "" {
+		if _, ok := edges[root]; ok {
+			roots = []string{root}
+		}
+	}
+	if roots == nil {
+		for _, dep := range bom.Dependencies {
+			if !dependedOn[dep.Ref] {
+				roots = append(roots, dep.Ref)
+			}
+		}
+		sort.Strings(roots)
+	}
+
+	// BFS：第一次到達即為最短深度，同時記錄引入路徑
+	depth := make(map[string]int)
+	queue := make([]string, 0, len(roots))
+	for _, root := range roots {
+		depth[root] = 0
+		queue = append(queue, root)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range edges[current] {
+			if _, seen := depth[child]; seen {
+				continue
+			}
+			depth[child] = depth[current] + 1
+			analysis.paths[child] = append(append([]string{}, analysis.paths[current]...), child)
+			queue = append(queue, child)
+		}
+	}
+
+	for _, comp := range bom.Components {
+		ref := componentRef(comp)
+		d, ok := depth[ref]
+		if !ok || d == 0 {
+			analysis.Unlinked = append(analysis.Unlinked, comp.Name)
+			continue
+		}
+		analysis.Depths[ref] = d
+		analysis.DepthDistribution[d]++
+		if d > analysis.MaxDepth {
+			analysis.MaxDepth = d
+		}
+	}
+	return analysis
+}
+
+// checkDependencyGraph 標記超過 maxDepth 的傳遞依賴，以及未宣告來源的組件。
+func checkDependencyGraph(bom *CycloneDX, analysis *DependencyAnalysis, maxDepth int) []PolicyViolation {
+	var violations []PolicyViolation
+
+	for _, comp := range bom.Components {
+		if d, ok := analysis.Depths[componentRef(comp)]; ok && maxDepth > 0 && d > maxDepth {
+			path := analysis.Path(componentRef(comp))
+			violations = append(violations, PolicyViolation{
+				Severity:  "medium",
+				Component: comp.Name,
+				Version:   comp.Version,
+				Reason:    "deep_transitive_dependency",
+				Description: fmt.Sprintf("introduced at depth %d (threshold: %d) via %s",
+					d, maxDepth, strings.Join(path, " -> ")),
+				Depth: d,
+			})
+		}
+
+		if !hasProvenance(comp) {
+			violations = append(violations, PolicyViolation{
+				Severity:    "low",
+				Component:   comp.Name,
+				Version:     comp.Version,
+				Reason:      "missing_provenance",
+				Description: "component declares no purl, supplier or external reference",
+				Depth:       analysis.componentDepth(comp),
+			})
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,308 @@
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultOSVEndpoint 是 OSV.dev API 的位址。
+	DefaultOSVEndpoint = "https://api.osv.dev"
+	// DefaultOSVTimeout 是一次 policy 檢查中 OSV 查詢（batch 與漏洞詳情）的總逾時。
+	DefaultOSVTimeout = 30 * time.Second
+
+	osvBatchSize        = 1000     // querybatch 每次最多的查詢數
+	osvDetailWorkers    = 8        // 同時查詢漏洞詳情的數量
+	osvMaxResponseBytes = 32 << 20 // 單一回應大小上限
+)
+
+// OSVClient 以 OSV.dev 的 batch API 查詢組件 purl 的已知漏洞。
+// 查詢結果快取在 client 中，同一次執行裡重複的 purl 與漏洞詳情只查詢一次。
+type OSVClient struct {
+	Endpoint   string
+	Timeout    time.Duration
+	HTTPClient *http.Client
+
+	mu        sync.Mutex
+	purlVulns map[string][]string         // 查詢 key -> OSV 漏洞 ID
+	vulns     map[string]osvVulnerability // OSV 漏洞 ID -> 詳情
+}
+
+// NewOSVClient 建立使用預設端點與逾時的 OSV client。
+func NewOSVClient() *OSVClient {
+	return &OSVClient{Endpoint: DefaultOSVEndpoint, Timeout: DefaultOSVTimeout}
+}
+
+// OSVClientFromEnv 依環境變數建立 OSV client：
+//   - OSV_OFFLINE: 設為 true 時回傳 nil，只使用內建漏洞清單
+//   - OSV_API_URL: OSV API 位址（預設 https://api.osv.dev）
+//   - OSV_TIMEOUT: 查詢總逾時（預設 30s）
+func OSVClientFromEnv() (*OSVClient, error) {
+	if os.Getenv("OSV_OFFLINE") == "true" {
+		return nil, nil
+	}
+	client := NewOSVClient()
+	if v := os.Getenv("OSV_API_URL"); v != "" {
+		client.Endpoint = strings.TrimRight(v, "/")
+	}
+	if v := os.Getenv("OSV_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid OSV_TIMEOUT %q", v)
+		}
+		client.Timeout = timeout
+	}
+	return client, nil
+}
+
+// osvQuery 是 querybatch 的單筆查詢；purl 未帶版本時以 Version 指定。
+type osvQuery struct {
+	Version string `json:"version,omitempty"`
+	Package struct {
+		Purl string `json:"purl"`
+	} `json:"package"`
+}
+
+// key 回傳查詢的快取 key。
+func (q osvQuery) key() string {
+	if q.Version == "" {
+		return q.Package.Purl
+	}
+	return q.Package.Purl + "#" + q.Version
+}
+
+// osvVulnerability 是 OSV 漏洞詳情中 policy 需要的欄位。
+type osvVulnerability struct {
+	ID               string   `json:"id"`
+	Summary          string   `json:"summary"`
+	Aliases          []string `json:"aliases"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// advisory 將 OSV 漏洞轉為 policy 使用的格式：ID 優先使用 CVE 別名。
+func (v osvVulnerability) advisory() advisory {
+	id := v.ID
+	if !strings.HasPrefix(id, "CVE-") {
+		for _, alias := range v.Aliases {
+			if strings.HasPrefix(alias, "CVE-") {
+				id = alias
+				break
+			}
+		}
+	}
+	description := v.Summary
+	if description == "" {
+		description = "known vulnerability"
+	}
+	if id != v.ID {
+		description += " (" + v.ID + ")"
+	}
+	return advisory{ID: id, Severity: osvSeverity(v.DatabaseSpecific.Severity), Description: id + ": " + description}
+}
+
+// osvSeverity 將 OSV database_specific.severity（GitHub advisory 的 LOW / MODERATE / HIGH / CRITICAL）
+// 轉為 policy 的嚴重度；沒有提供時視為 high。
+func osvSeverity(severity string) string {
+	switch strings.ToUpper(severity) {
+	case "LOW":
+		return "low"
+	case "MODERATE", "MEDIUM":
+		return "medium"
+	case "CRITICAL":
+		return "critical"
+	}
+	return "high"
+}
+
+// componentQuery 回傳組件的 OSV 查詢；沒有可解析的 purl 時 ok 為 false。
+func componentQuery(comp Component) (query osvQuery, ok bool) {
+	purl, err := ParsePurl(comp.Purl)
+	if comp.Purl == "" || err != nil {
+		return osvQuery{}, false
+	}
+	query.Package.Purl = comp.Purl
+	if purl.Version == "" {
+		if comp.Version == "" {
+			return osvQuery{}, false
+		}
+		query.Version = comp.Version
+	}
+	return query, true
+}
+
+// Lookup 查詢 queries 的漏洞，回傳查詢 key 到漏洞的對應。任何請求失敗或逾時都回傳錯誤，
+// 呼叫端應退回內建清單，避免部分結果被當成完整結果。
+func (c *OSVClient) Lookup(queries []osvQuery) (map[string][]advisory, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultOSVTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c.mu.Lock()
+	if c.purlVulns == nil {
+		c.purlVulns = make(map[string][]string)
+		c.vulns = make(map[string]osvVulnerability)
+	}
+	var pending []osvQuery
+	seen := make(map[string]bool)
+	for _, q := range queries {
+		if _, cached := c.purlVulns[q.key()]; !cached && !seen[q.key()] {
+			seen[q.key()] = true
+			pending = append(pending, q)
+		}
+	}
+	c.mu.Unlock()
+
+	for start := 0; start < len(pending); start += osvBatchSize {
+		end := min(start+osvBatchSize, len(pending))
+		if err := c.queryBatch(ctx, pending[start:end]); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.fetchDetails(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := make(map[string][]advisory, len(queries))
+	for _, q := range queries {
+		if _, done := results[q.key()]; done {
+			continue
+		}
+		matches := []advisory{}
+		for _, id := range c.purlVulns[q.key()] {
+			matches = append(matches, c.vulns[id].advisory())
+		}
+		results[q.key()] = matches
+	}
+	return results, nil
+}
+
+// queryBatch 以 /v1/querybatch 查詢一批 purl 的漏洞 ID。
+func (c *OSVClient) queryBatch(ctx context.Context, queries []osvQuery) error {
+	body, err := json.Marshal(map[string]interface{}{"queries": queries})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Results []struct {
+			Vulns []struct {
+				ID string `json:"id"`
+			} `json:"vulns"`
+		} `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/querybatch", body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Results) != len(queries) {
+		return fmt.Errorf("osv querybatch returned %d results for %d queries", len(resp.Results), len(queries))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, result := range resp.Results {
+		ids := make([]string, 0, len(result.Vulns))
+		for _, v := range result.Vulns {
+			ids = append(ids, v.ID)
+		}
+		c.purlVulns[queries[i].key()] = ids
+	}
+	return nil
+}
+
+// fetchDetails 以 /v1/vulns/{id} 取得尚未快取的漏洞詳情（batch 回應只有 ID）。
+func (c *OSVClient) fetchDetails(ctx context.Context) error {
+	c.mu.Lock()
+	var missing []string
+	queued := make(map[string]bool)
+	for _, ids := range c.purlVulns {
+		for _, id := range ids {
+			if _, ok := c.vulns[id]; !ok && !queued[id] {
+				queued[id] = true
+				missing = append(missing, id)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	ids := make(chan string)
+	errs := make(chan error, len(missing))
+	var wg sync.WaitGroup
+	for range min(osvDetailWorkers, len(missing)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				var vuln osvVulnerability
+				if err := c.do(ctx, http.MethodGet, "/v1/vulns/"+url.PathEscape(id), nil, &vuln); err != nil {
+					errs <- err
+					continue
+				}
+				vuln.ID = id
+				c.mu.Lock()
+				c.vulns[id] = vuln
+				c.mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range missing {
+		ids <- id
+	}
+	close(ids)
+	wg.Wait()
+	close(errs)
+
+	// 只有成功取得的詳情會寫入快取，失敗的漏洞下次查詢時重試
+	return <-errs
+}
+
+// do 送出 OSV API 請求並解析 JSON 回應。
+func (c *OSVClient) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultOSVEndpoint
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("osv request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("osv %s %s: unexpected status %d", method, path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, osvMaxResponseBytes)).Decode(out); err != nil {
+		return fmt.Errorf("osv %s %s: invalid response: %w", method, path, err)
+	}
+	return nil
+}
@@ -4,15 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 )
 
-// CycloneDX 定義 CycloneDX SBOM 的簡化結構。
+// CycloneDX 定義 CycloneDX SBOM 的簡化結構。SPDX 文件解析後也轉為此結構（BOMFormat 為 "SPDX"）。
 type CycloneDX struct {
 	BOMFormat   string      `json:"bomFormat"`
 	SpecVersion string      `json:"specVersion"`
 	Version     int         `json:"version"`
 	Metadata    Metadata    `json:"metadata"`
 	Components  []Component `json:"components"`
+	// Dependencies 是組件之間的依賴圖（可選），用於傳遞依賴深度分析
+	Dependencies []Dependency `json:"dependencies,omitempty"`
 }
 
 // Metadata 定義 SBOM 元資料。
@@ -23,13 +26,16 @@ type Metadata struct {
 
 // Component 定義軟體組件。
 type Component struct {
-	Type       string          `json:"type"`
-	Name       string          `json:"name"`
-	Version    string          `json:"version"`
-	Purl       string          `json:"purl,omitempty"`
-	Properties []Property      `json:"properties,omitempty"`
-	Licenses   []License       `json:"licenses,omitempty"`
-	Hashes     []Hash          `json:"hashes,omitempty"`
+	Type               string              `json:"type"`
+	BOMRef             string              `json:"bom-ref,omitempty"`
+	Name               string              `json:"name"`
+	Version            string              `json:"version"`
+	Purl               string              `json:"purl,omitempty"`
+	Properties         []Property          `json:"properties,omitempty"`
+	Licenses           []License           `json:"licenses,omitempty"`
+	Hashes             []Hash              `json:"hashes,omitempty"`
+	Supplier           *Supplier           `json:"supplier,omitempty"`
+	ExternalReferences []ExternalReference `json:"externalReferences,omitempty"`
 }
 
 // Property 定義組件屬性。
@@ -62,6 +68,14 @@ type PolicyViolation struct {
 	Version     string `json:"version"`
 	Reason      string `json:"reason"`
 	Description string `json:"description"`
+	// Ecosystem 是比對時使用的 purl type（依名稱比對時為空）
+	Ecosystem string `json:"ecosystem,omitempty"`
+	// Depth 是組件的最短依賴深度（1 為直接依賴），沒有依賴圖或無法到達時為 0
+	Depth int `json:"depth,omitempty"`
+	// VulnerabilityID 是已知漏洞的 CVE（沒有 CVE 別名時為 OSV ID）
+	VulnerabilityID string `json:"vulnerabilityId,omitempty"`
+	// Warning 表示此違規只是警告，不論嚴重度都不會讓檢查失敗
+	Warning bool `json:"warning,omitempty"`
 }
 
 // PolicyResult 定義 policy 檢查結果。
@@ -69,15 +83,53 @@ type PolicyResult struct {
 	Allowed    bool              `json:"allowed"`
 	Violations []PolicyViolation `json:"violations"`
 	Summary    string            `json:"summary"`
+	// Dependencies 是依賴圖分析（深度分布等），SBOM 未提供 dependencies 時 HasGraph 為 false
+	Dependencies *DependencyAnalysis `json:"dependencies,omitempty"`
+	// AdvisorySource 是已知漏洞的資料來源（"osv" 或 "builtin"）
+	AdvisorySource string `json:"advisorySource"`
+	// AdvisoryError 是 OSV 查詢失敗的原因（此時退回內建清單）
+	AdvisoryError string `json:"advisoryError,omitempty"`
+	// FailOnSeverity 是導致失敗的最低嚴重度，BlockingViolations 是達到此嚴重度且不是警告的違規數
+	FailOnSeverity     string `json:"failOnSeverity"`
+	BlockingViolations int    `json:"blockingViolations"`
 }
 
-// ParseSBOM 解析 CycloneDX SBOM 檔案。
+// PolicyOptions 定義 policy 檢查的可調參數。
+type PolicyOptions struct {
+	// MaxDependencyDepth 是允許的最大傳遞依賴深度（直接依賴為 1），0 表示不檢查
+	MaxDependencyDepth int
+	// OSV 不為 nil 時以 OSV.dev 查詢已知漏洞，否則只使用內建清單
+	OSV *OSVClient
+	// Policy 是授權、組件數量、套件 glob 與失敗門檻等規則，nil 時使用 DefaultPolicyConfig
+	Policy *PolicyConfig
+}
+
+// DefaultPolicyOptions 回傳預設的 policy 參數。
+func DefaultPolicyOptions() PolicyOptions {
+	return PolicyOptions{MaxDependencyDepth: DefaultMaxDependencyDepth}
+}
+
+// ParseSBOM 解析 SBOM 檔案（CycloneDX 或 SPDX JSON，自動判斷格式）。
 func ParseSBOM(filePath string) (*CycloneDX, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("無法讀取 SBOM 檔案: %w", err)
 	}
 
+	return ParseSBOMData(data)
+}
+
+// ParseSBOMData 解析記憶體中的 SBOM（例如 API 上傳的內容）。依 bomFormat / spdxVersion 判斷格式，
+// 兩者皆無時回傳錯誤，避免以零個組件通過 policy 檢查。
+func ParseSBOMData(data []byte) (*CycloneDX, error) {
+	format, err := detectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatSPDX {
+		return parseSPDX(data)
+	}
+
 	var sbom CycloneDX
 	if err := json.Unmarshal(data, &sbom); err != nil {
 		return nil, fmt.Errorf("無法解析 SBOM: %w", err)
@@ -86,71 +138,125 @@ func ParseSBOM(filePath string) (*CycloneDX, error) {
 	return &sbom, nil
 }
 
-// CheckPolicy 檢查 SBOM 是否符合 policy。
+// CheckPolicy 以預設參數檢查 SBOM 是否符合 policy。
 func CheckPolicy(sbom *CycloneDX) PolicyResult {
-	var violations []PolicyViolation
+	return CheckPolicyWithOptions(sbom, DefaultPolicyOptions())
+}
 
-	// Policy 1: 禁止已知有漏洞的套件（簡化版，實際應查詢漏洞資料庫）
-	vulnerablePackages := map[string]string{
-		"lodash@4.17.15":    "CVE-2020-8203: Prototype Pollution",
-		"axios@0.18.0":      "CVE-2019-10742: SSRF",
-		"express@4.16.0":    "CVE-2022-24999: Open Redirect",
+// CheckPolicyWithConfig 以 policy 檔的規則（其餘參數使用預設值）檢查 SBOM。
+func CheckPolicyWithConfig(sbom *CycloneDX, config PolicyConfig) PolicyResult {
+	opts := DefaultPolicyOptions()
+	opts.Policy = &config
+	return CheckPolicyWithOptions(sbom, opts)
+}
+
+// CheckPolicyWithOptions 檢查 SBOM 是否符合 policy。
+func CheckPolicyWithOptions(sbom *CycloneDX, opts PolicyOptions) PolicyResult {
+	var violations []PolicyViolation
+	config := DefaultPolicyConfig()
+	if opts.Policy != nil {
+		config = *opts.Policy
 	}
 
-	for _, comp := range sbom.Components {
-		key := fmt.Sprintf("%s@%s", comp.Name, comp.Version)
-		if vuln, exists := vulnerablePackages[key]; exists {
+	// 依賴圖分析：違規紀錄附上組件深度，已知漏洞依直接 / 傳遞依賴調整嚴重度
+	analysis := AnalyzeDependencies(sbom)
+
+	// Policy 1: 禁止已知有漏洞的套件（OSV.dev，離線時使用內建清單），另加 policy 檔中的漏洞
+	advisories, advisorySource, advisoryErr := lookupAdvisories(sbom.Components, opts.OSV)
+	extraAdvisories := config.advisories()
+	for i, comp := range sbom.Components {
+		for _, extra := range matchAdvisories(comp, extraAdvisories) {
+			if !slices.ContainsFunc(advisories[i], func(a advisory) bool { return a.ID == extra.ID }) {
+				advisories[i] = append(advisories[i], extra)
+			}
+		}
+		for _, advisory := range advisories[i] {
+			severity, note := vulnerabilitySeverity(analysis, comp, advisory.Severity)
+			description := advisory.Description
+			if note != "" {
+				description += " (" + note + ")"
+			}
 			violations = append(violations, PolicyViolation{
-				Severity:    "high",
+				Severity:    severity,
 				Component:   comp.Name,
 				Version:     comp.Version,
 				Reason:      "known_vulnerability",
-				Description: vuln,
+				Description: description,
+				Ecosystem:   componentEcosystem(comp),
+				Depth:       analysis.componentDepth(comp),
+
+				VulnerabilityID: advisory.ID,
 			})
 		}
 	}
 
 	// Policy 2: 禁止某些高風險授權
-	restrictedLicenses := map[string]bool{
-		"AGPL-3.0": true,
-		"GPL-3.0":  true,
-	}
-
 	for _, comp := range sbom.Components {
 		for _, lic := range comp.Licenses {
-			if restrictedLicenses[lic.License.ID] {
+			if matchRestrictedLicense(comp, lic.License.ID, config.RestrictedLicenses) {
 				violations = append(violations, PolicyViolation{
 					Severity:    "medium",
 					Component:   comp.Name,
 					Version:     comp.Version,
 					Reason:      "restricted_license",
 					Description: fmt.Sprintf("License %s is restricted", lic.License.ID),
+					Ecosystem:   componentEcosystem(comp),
+					Depth:       analysis.componentDepth(comp),
 				})
 			}
 		}
 	}
 
 	// Policy 3: 檢查組件數量（異常大量依賴可能是供應鏈攻擊）
-	if len(sbom.Components) > 500 {
+	if config.MaxComponents > 0 && len(sbom.Components) > config.MaxComponents {
 		violations = append(violations, PolicyViolation{
 			Severity:    "medium",
 			Component:   "SBOM",
 			Version:     "",
 			Reason:      "excessive_dependencies",
-			Description: fmt.Sprintf("SBOM contains %d components (threshold: 500)", len(sbom.Components)),
+			Description: fmt.Sprintf("SBOM contains %d components (threshold: %d)", len(sbom.Components), config.MaxComponents),
 		})
 	}
 
-	allowed := len(violations) == 0
+	// Policy 4: 依賴圖深度與來源（過深的傳遞依賴、未宣告來源的組件）
+	violations = append(violations, checkDependencyGraph(sbom, analysis, opts.MaxDependencyDepth)...)
+
+	// Policy 5: 套件 allow / deny 清單
+	violations = append(violations, checkPackageRules(sbom, analysis, config)...)
+
+	// Policy 6: 缺少完整性雜湊或授權資訊的組件（預設只是警告）
+	violations = append(violations, checkCompleteness(sbom, analysis, config)...)
+
+	// 只有達到失敗門檻且不是警告的違規才讓檢查失敗，其餘僅列出
+	threshold := config.failThreshold()
+	blocking := 0
+	for _, v := range violations {
+		if !v.Warning && severityRank(v.Severity) >= threshold {
+			blocking++
+		}
+	}
+	allowed := blocking == 0
 	summary := fmt.Sprintf("SBOM policy check: %d violations found", len(violations))
-	if allowed {
+	switch {
+	case len(violations) == 0:
 		summary = "SBOM policy check: passed"
+	case allowed:
+		summary = fmt.Sprintf("SBOM policy check: passed (%d non-blocking violations)", len(violations))
+	case blocking < len(violations):
+		summary = fmt.Sprintf("SBOM policy check: %d violations found (%d blocking)", len(violations), blocking)
 	}
 
-	return PolicyResult{
-		Allowed:    allowed,
-		Violations: violations,
-		Summary:    summary,
+	result := PolicyResult{
+		Allowed:            allowed,
+		Violations:         violations,
+		Summary:            summary,
+		Dependencies:       analysis,
+		AdvisorySource:     advisorySource,
+		FailOnSeverity:     severityLevels[threshold],
+		BlockingViolations: blocking,
+	}
+	if advisoryErr != nil {
+		result.AdvisoryError = advisoryErr.Error()
 	}
+	return result
 }
-
@@ -0,0 +1,235 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxComponents 是預設的組件數量上限（異常大量依賴可能是供應鏈攻擊）。
+const DefaultMaxComponents = 500
+
+// PolicyConfig 是 SBOM policy 檔（YAML 或 JSON）的格式，讓不同專案（例如可接受 GPL 的內部工具與飛行軟體）
+// 使用不同的規則而不需修改程式。policy 檔中未出現的欄位沿用 DefaultPolicyConfig 的值。
+type PolicyConfig struct {
+	// RestrictedLicenses 是禁止使用的授權；設為空清單表示不限制授權
+	RestrictedLicenses []LicenseRule `json:"restrictedLicenses" yaml:"restrictedLicenses"`
+	// MaxComponents 是組件數量上限，0 表示不檢查
+	MaxComponents int `json:"maxComponents" yaml:"maxComponents"`
+	// AllowedPackages 不為空時，只允許符合其中任一 glob 的組件
+	AllowedPackages []string `json:"allowedPackages" yaml:"allowedPackages"`
+	// DeniedPackages 是禁止使用的組件 glob，優先於 AllowedPackages
+	DeniedPackages []string `json:"deniedPackages" yaml:"deniedPackages"`
+	// Advisories 是額外的已知漏洞（例如內部套件的公告），不論是否使用 OSV 都會比對
+	Advisories []AdvisoryRule `json:"advisories" yaml:"advisories"`
+	// FailOnSeverity 是導致檢查失敗的最低嚴重度；低於此嚴重度的違規仍會列出但不影響結果
+	FailOnSeverity string `json:"failOnSeverity" yaml:"failOnSeverity"`
+	// FailOnIncomplete 為 true 時，缺少雜湊或授權的組件與其他違規一樣會讓檢查失敗（預設只是警告）
+	FailOnIncomplete bool `json:"failOnIncomplete" yaml:"failOnIncomplete"`
+}
+
+// AdvisoryRule 是 policy 檔中的已知漏洞；Ecosystem 為 purl type（例如 npm、golang）。
+type AdvisoryRule struct {
+	Ecosystem   string `json:"ecosystem" yaml:"ecosystem"`
+	Name        string `json:"name" yaml:"name"` // 含 namespace 的完整名稱
+	Version     string `json:"version" yaml:"version"`
+	ID          string `json:"id" yaml:"id"`
+	Severity    string `json:"severity" yaml:"severity"` // 預設 high
+	Description string `json:"description" yaml:"description"`
+}
+
+// DefaultPolicyConfig 回傳內建的 policy：限制 AGPL-3.0 / GPL-3.0、最多 500 個組件，警告以外的任何違規都視為失敗。
+func DefaultPolicyConfig() PolicyConfig {
+	return PolicyConfig{
+		RestrictedLicenses: slices.Clone(restrictedLicenses),
+		MaxComponents:      DefaultMaxComponents,
+		FailOnSeverity:     "low",
+	}
+}
+
+// LoadPolicyConfig 讀取 policy 檔並套用在預設值上。副檔名為 .json 時以 JSON 解析，其他一律以 YAML 解析；
+// 未知欄位、無效的 glob 或嚴重度都回傳錯誤，因此也可在 CI 中用來驗證 policy 檔。
+func LoadPolicyConfig(filePath string) (PolicyConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return PolicyConfig{}, fmt.Errorf("read SBOM policy: %w", err)
+	}
+
+	config := DefaultPolicyConfig()
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	} else {
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+	}
+	if err != nil {
+		return PolicyConfig{}, fmt.Errorf("parse SBOM policy %s: %w", filePath, err)
+	}
+	if err := config.Validate(); err != nil {
+		return PolicyConfig{}, fmt.Errorf("SBOM policy %s: %w", filePath, err)
+	}
+	return config, nil
+}
+
+// Validate 檢查 policy 設定是否有效。
+func (c PolicyConfig) Validate() error {
+	if c.MaxComponents < 0 {
+		return fmt.Errorf("maxComponents must not be negative")
+	}
+	if c.FailOnSeverity != "" && severityRank(c.FailOnSeverity) < 0 {
+		return fmt.Errorf("unknown failOnSeverity %q", c.FailOnSeverity)
+	}
+	for _, rule := range c.RestrictedLicenses {
+		if rule.License == "" {
+			return fmt.Errorf("restrictedLicenses: license is required")
+		}
+	}
+	for _, pattern := range append(slices.Clone(c.AllowedPackages), c.DeniedPackages...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid package pattern %q: %w", pattern, err)
+		}
+	}
+	for _, rule := range c.Advisories {
+		if rule.Name == "" || rule.Version == "" || rule.ID == "" {
+			return fmt.Errorf("advisories: name, version and id are required")
+		}
+		if rule.Severity != "" && severityRank(rule.Severity) < 0 {
+			return fmt.Errorf("advisory %s: unknown severity %q", rule.ID, rule.Severity)
+		}
+	}
+	return nil
+}
+
+// advisories 將 policy 檔中的已知漏洞轉為比對使用的格式。
+func (c PolicyConfig) advisories() []advisory {
+	advisories := make([]advisory, 0, len(c.Advisories))
+	for _, rule := range c.Advisories {
+		severity := rule.Severity
+		if severity == "" {
+			severity = "high"
+		}
+		description := rule.ID
+		if rule.Description != "" {
+			description += ": " + rule.Description
+		}
+		advisories = append(advisories, advisory{
+			Ecosystem:   rule.Ecosystem,
+			Name:        rule.Name,
+			Version:     rule.Version,
+			ID:          rule.ID,
+			Severity:    severity,
+			Description: description,
+		})
+	}
+	return advisories
+}
+
+// failThreshold 回傳導致檢查失敗的最低嚴重度等級；未設定時任何違規都會失敗。
+func (c PolicyConfig) failThreshold() int {
+	if rank := severityRank(c.FailOnSeverity); rank >= 0 {
+		return rank
+	}
+	return 0
+}
+
+// severityRank 回傳嚴重度在 severityLevels 中的等級，未知的嚴重度回傳 -1。
+func severityRank(severity string) int {
+	return slices.Index(severityLevels, severity)
+}
+
+// packageNames 回傳組件可用於 glob 比對的名稱：組件名稱，有 purl 時另加完整名稱與 "type/完整名稱"。
+func packageNames(comp Component) []string {
+	names := []string{comp.Name}
+	if pkg, ok := componentPackage(comp); ok {
+		names = append(names, pkg.FullName(), pkg.Type+"/"+pkg.FullName())
+	}
+	return names
+}
+
+// matchPackagePattern 回傳第一個符合組件名稱的 glob（path.Match 語法，"*" 不跨越 "/"）。
+func matchPackagePattern(comp Component, patterns []string) (string, bool) {
+	names := packageNames(comp)
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if ok, _ := path.Match(pattern, name); ok {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
+
+// checkPackageRules 依 deniedPackages / allowedPackages 檢查組件。
+func checkPackageRules(bom *CycloneDX, analysis *DependencyAnalysis, config PolicyConfig) []PolicyViolation {
+	var violations []PolicyViolation
+	for _, comp := range bom.Components {
+		if pattern, denied := matchPackagePattern(comp, config.DeniedPackages); denied {
+			violations = append(violations, PolicyViolation{
+				Severity:    "high",
+				Component:   comp.Name,
+				Version:     comp.Version,
+				Reason:      "denied_package",
+				Description: fmt.Sprintf("package matches denied pattern %q", pattern),
+				Ecosystem:   componentEcosystem(comp),
+				Depth:       analysis.componentDepth(comp),
+			})
+			continue
+		}
+		if len(config.AllowedPackages) > 0 {
+			if _, allowed := matchPackagePattern(comp, config.AllowedPackages); !allowed {
+				violations = append(violations, PolicyViolation{
+					Severity:    "medium",
+					Component:   comp.Name,
+					Version:     comp.Version,
+					Reason:      "package_not_allowed",
+					Description: "package does not match any allowed pattern",
+					Ecosystem:   componentEcosystem(comp),
+					Depth:       analysis.componentDepth(comp),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// checkCompleteness 標記缺少完整性雜湊（medium）或授權資訊（low）的組件：無法驗證下載內容或授權義務本身就是供應鏈風險。
+// 預設為警告，讓不完整的 SBOM 不會無聲通過，又不會直接擋下建置；FailOnIncomplete 時視為一般違規。
+func checkCompleteness(bom *CycloneDX, analysis *DependencyAnalysis, config PolicyConfig) []PolicyViolation {
+	var violations []PolicyViolation
+	for _, comp := range bom.Components {
+		if len(comp.Hashes) == 0 {
+			violations = append(violations, PolicyViolation{
+				Severity:    "medium",
+				Component:   comp.Name,
+				Version:     comp.Version,
+				Reason:      "missing_integrity_hash",
+				Description: "component declares no integrity hash",
+				Ecosystem:   componentEcosystem(comp),
+				Depth:       analysis.componentDepth(comp),
+				Warning:     !config.FailOnIncomplete,
+			})
+		}
+		if len(comp.Licenses) == 0 {
+			violations = append(violations, PolicyViolation{
+				Severity:    "low",
+				Component:   comp.Name,
+				Version:     comp.Version,
+				Reason:      "missing_license",
+				Description: "component declares no license",
+				Ecosystem:   componentEcosystem(comp),
+				Depth:       analysis.componentDepth(comp),
+				Warning:     !config.FailOnIncomplete,
+			})
+		}
+	}
+	return violations
+}
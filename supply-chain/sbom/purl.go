@@ -0,0 +1,112 @@
+package sbom
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PackageURL 是解析後的 purl（pkg:type/namespace/name@version?qualifiers#subpath）。
+type PackageURL struct {
+	Type       string // 生態系，例如 npm、golang、pypi（小寫）
+	Namespace  string // 例如 npm scope、Go module 路徑前綴、Maven groupId
+	Name       string
+	Version    string
+	Qualifiers map[string]string
+	Subpath    string
+}
+
+// FullName 回傳含 namespace 的套件名稱（例如 "@babel/core"、"github.com/gin-gonic/gin"）。
+func (p PackageURL) FullName() string {
+	if p.Namespace == "" {
+		return p.Name
+	}
+	return p.Namespace + "/" + p.Name
+}
+
+// ParsePurl 解析 purl 字串；各段落會做 percent-decoding，type 一律轉為小寫。
+func ParsePurl(raw string) (PackageURL, error) {
+	var p PackageURL
+
+	rest, ok := strings.CutPrefix(strings.TrimSpace(raw), "pkg:")
+	if !ok {
+		return p, fmt.Errorf("purl %q must start with pkg:", raw)
+	}
+	rest = strings.TrimLeft(rest, "/")
+
+	if i := strings.Index(rest, "#"); i >= 0 {
+		p.Subpath = strings.Trim(rest[i+1:], "/")
+		rest = rest[:i]
+	}
+	if i := strings.Index(rest, "?"); i >= 0 {
+		values, err := url.ParseQuery(rest[i+1:])
+		if err != nil {
+			return p, fmt.Errorf("purl %q has invalid qualifiers: %w", raw, err)
+		}
+		p.Qualifiers = make(map[string]string, len(values))
+		for key, v := range values {
+			p.Qualifiers[strings.ToLower(key)] = v[0]
+		}
+		rest = rest[:i]
+	}
+
+	slash := strings.Index(rest, "/")
+	if slash <= 0 {
+		return p, fmt.Errorf("purl %q is missing a type or name", raw)
+	}
+	p.Type = strings.ToLower(rest[:slash])
+	path := strings.Trim(rest[slash+1:], "/")
+
+	// 版本在最後一段的 @ 之後（namespace 中未編碼的 npm scope 也以 @ 開頭）
+	lastSlash := strings.LastIndex(path, "/")
+	if at := strings.LastIndex(path, "@"); at > lastSlash {
+		version, err := url.PathUnescape(path[at+1:])
+		if err != nil {
+			return p, fmt.Errorf("purl %q has invalid version: %w", raw, err)
+		}
+		p.Version = version
+		path = path[:at]
+		lastSlash = strings.LastIndex(path, "/")
+	}
+
+	name, err := url.PathUnescape(path[lastSlash+1:])
+	if err != nil || name == "" {
+		return p, fmt.Errorf("purl %q has invalid name", raw)
+	}
+	p.Name = name
+	if lastSlash > 0 {
+		segments := strings.Split(path[:lastSlash], "/")
+		for i, segment := range segments {
+			if segments[i], err = url.PathUnescape(segment); err != nil {
+				return p, fmt.Errorf("purl %q has invalid namespace: %w", raw, err)
+			}
+		}
+		p.Namespace = strings.Join(segments, "/")
+	}
+
+	return p, nil
+}
+
+// componentPackage 回傳組件的生態系與套件資訊；purl 缺少或無法解析時 ok 為 false，呼叫端應退回名稱比對。
+// purl 未帶版本時使用組件的 version 欄位。
+func componentPackage(comp Component) (pkg PackageURL, ok bool) {
+	if comp.Purl == "" {
+		return pkg, false
+	}
+	pkg, err := ParsePurl(comp.Purl)
+	if err != nil {
+		return pkg, false
+	}
+	if pkg.Version == "" {
+		pkg.Version = comp.Version
+	}
+	return pkg, true
+}
+
+// componentEcosystem 回傳組件 purl 的 type，沒有（或無法解析）purl 時為空字串。
+func componentEcosystem(comp Component) string {
+	if pkg, ok := componentPackage(comp); ok {
+		return pkg.Type
+	}
+	return ""
+}
@@ -0,0 +1,231 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SBOM 格式（CycloneDX.BOMFormat）。SPDX 文件解析後轉為相同的內部結構，BOMFormat 記錄原始格式。
+const (
+	FormatCycloneDX = "CycloneDX"
+	FormatSPDX      = "SPDX"
+)
+
+// SPDXDocument 定義 SPDX 2.x JSON 文件的簡化結構。
+type SPDXDocument struct {
+	SPDXVersion  string `json:"spdxVersion"`
+	SPDXID       string `json:"SPDXID"`
+	Name         string `json:"name"`
+	CreationInfo struct {
+		Created string `json:"created"`
+	} `json:"creationInfo"`
+	DocumentDescribes []string           `json:"documentDescribes,omitempty"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Relationships     []SPDXRelationship `json:"relationships,omitempty"`
+}
+
+// SPDXPackage 定義 SPDX 套件。
+type SPDXPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	Supplier         string            `json:"supplier,omitempty"` // "Organization: X"、"Person: Y" 或 NOASSERTION
+	DownloadLocation string            `json:"downloadLocation,omitempty"`
+	Homepage         string            `json:"homepage,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded,omitempty"`
+	LicenseDeclared  string            `json:"licenseDeclared,omitempty"`
+	Checksums        []SPDXChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []SPDXExternalRef `json:"externalRefs,omitempty"`
+}
+
+// SPDXChecksum 定義套件雜湊。
+type SPDXChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+// SPDXExternalRef 定義套件的外部參考（purl 為 PACKAGE-MANAGER 類別的 purl 型別）。
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+// SPDXRelationship 定義 SPDX 元素之間的關係（DESCRIBES、DEPENDS_ON、DEPENDENCY_OF 等）。
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxHashAlgorithms 將 SPDX checksum 演算法轉為 CycloneDX 的名稱。
+var spdxHashAlgorithms = map[string]string{
+	"MD5":    "MD5",
+	"SHA1":   "SHA-1",
+	"SHA256": "SHA-256",
+	"SHA384": "SHA-384",
+	"SHA512": "SHA-512",
+}
+
+// detectFormat 依頂層欄位判斷 SBOM 格式：CycloneDX 有 bomFormat，SPDX 有 spdxVersion。
+func detectFormat(data []byte) (string, error) {
+	var probe struct {
+		BOMFormat   *string `json:"bomFormat"`
+		SPDXVersion *string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", fmt.Errorf("無法解析 SBOM: %w", err)
+	}
+	switch {
+	case probe.BOMFormat != nil:
+		if *probe.BOMFormat != FormatCycloneDX {
+			return "", fmt.Errorf("不支援的 bomFormat %q（需要 CycloneDX）", *probe.BOMFormat)
+		}
+		return FormatCycloneDX, nil
+	case probe.SPDXVersion != nil:
+		if !strings.HasPrefix(*probe.SPDXVersion, "SPDX-2.") {
+			return "", fmt.Errorf("不支援的 spdxVersion %q（需要 SPDX 2.x JSON）", *probe.SPDXVersion)
+		}
+		return FormatSPDX, nil
+	}
+	return "", fmt.Errorf("無法辨識 SBOM 格式：需要 CycloneDX（bomFormat）或 SPDX（spdxVersion）JSON")
+}
+
+// parseSPDX 解析 SPDX 2.x JSON 並轉為內部結構：文件描述（DESCRIBES）的套件作為主組件，
+// 其餘套件作為組件，DEPENDS_ON / *DEPENDENCY_OF 關係轉為依賴圖。
+func parseSPDX(data []byte) (*CycloneDX, error) {
+	var doc SPDXDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("無法解析 SPDX SBOM: %w", err)
+	}
+
+	described := make(map[string]bool)
+	for _, id := range doc.DocumentDescribes {
+		described[id] = true
+	}
+	dependsOn := make(map[string][]string)
+	var order []string
+	addEdge := func(from, to string) {
+		if _, ok := dependsOn[from]; !ok {
+			order = append(order, from)
+		}
+		dependsOn[from] = append(dependsOn[from], to)
+	}
+	for _, rel := range doc.Relationships {
+		switch relType := strings.ToUpper(rel.RelationshipType); {
+		case relType == "DESCRIBES" && rel.SPDXElementID == doc.SPDXID:
+			described[rel.RelatedSPDXElement] = true
+		case relType == "DESCRIBED_BY" && rel.RelatedSPDXElement == doc.SPDXID:
+			described[rel.SPDXElementID] = true
+		case relType == "DEPENDS_ON":
+			addEdge(rel.SPDXElementID, rel.RelatedSPDXElement)
+		case strings.HasSuffix(relType, "DEPENDENCY_OF"):
+			addEdge(rel.RelatedSPDXElement, rel.SPDXElementID)
+		}
+	}
+
+	bom := &CycloneDX{
+		BOMFormat:   FormatSPDX,
+		SpecVersion: strings.TrimPrefix(doc.SPDXVersion, "SPDX-"),
+		Metadata:    Metadata{Timestamp: doc.CreationInfo.Created},
+	}
+	// 只有一個被描述的套件時作為主組件（依賴深度的起點），多個時全部視為組件
+	mainFound := false
+	for _, pkg := range doc.Packages {
+		comp := pkg.component()
+		if described[pkg.SPDXID] && len(described) == 1 {
+			comp.Type = "application"
+			bom.Metadata.Component = comp
+			mainFound = true
+			continue
+		}
+		bom.Components = append(bom.Components, comp)
+	}
+	if !mainFound {
+		bom.Metadata.Component = Component{Type: "application", Name: doc.Name}
+	}
+	for _, ref := range order {
+		bom.Dependencies = append(bom.Dependencies, Dependency{Ref: ref, DependsOn: dependsOn[ref]})
+	}
+	return bom, nil
+}
+
+// component 將 SPDX 套件轉為組件：SPDXID 作為 bom-ref，purl 取自 externalRefs，
+// 供應者、下載位置與首頁作為來源資訊。
+func (pkg SPDXPackage) component() Component {
+	comp := Component{
+		Type:    "library",
+		BOMRef:  pkg.SPDXID,
+		Name:    pkg.Name,
+		Version: pkg.VersionInfo,
+	}
+	for _, ref := range pkg.ExternalRefs {
+		if strings.EqualFold(ref.ReferenceType, "purl") && comp.Purl == "" {
+			comp.Purl = ref.ReferenceLocator
+		}
+	}
+
+	license := pkg.LicenseConcluded
+	if !spdxValueSet(license) {
+		license = pkg.LicenseDeclared
+	}
+	if spdxValueSet(license) {
+		for _, id := range spdxLicenseIDs(license) {
+			comp.Licenses = append(comp.Licenses, License{License: LicenseInfo{ID: id}})
+		}
+	}
+
+	for _, checksum := range pkg.Checksums {
+		if alg, ok := spdxHashAlgorithms[strings.ToUpper(checksum.Algorithm)]; ok {
+			comp.Hashes = append(comp.Hashes, Hash{Alg: alg, Content: checksum.ChecksumValue})
+		}
+	}
+
+	if spdxValueSet(pkg.Supplier) {
+		name := pkg.Supplier
+		if _, after, ok := strings.Cut(name, ":"); ok {
+			name = strings.TrimSpace(after)
+		}
+		comp.Supplier = &Supplier{Name: name}
+	}
+	if spdxValueSet(pkg.DownloadLocation) {
+		comp.ExternalReferences = append(comp.ExternalReferences, ExternalReference{Type: "distribution", URL: pkg.DownloadLocation})
+	}
+	if spdxValueSet(pkg.Homepage) {
+		comp.ExternalReferences = append(comp.ExternalReferences, ExternalReference{Type: "website", URL: pkg.Homepage})
+	}
+	return comp
+}
+
+// spdxValueSet 判斷 SPDX 欄位是否有實際值（NOASSERTION / NONE 視為未提供）。
+func spdxValueSet(value string) bool {
+	switch strings.TrimSpace(value) {
+	case "", "NOASSERTION", "NONE":
+		return false
+	}
+	return true
+}
+
+// spdxLicenseIDs 拆解 SPDX 授權表達式（例如 "(MIT OR GPL-3.0-only) AND Apache-2.0"）中的授權 ID。
+// OR 的每個選項都列出，受限授權檢查因此較保守；WITH 之後的例外條款不視為授權。
+func spdxLicenseIDs(expression string) []string {
+	expression = strings.NewReplacer("(", " ", ")", " ").Replace(expression)
+	var ids []string
+	skipNext := false
+	for _, token := range strings.Fields(expression) {
+		switch strings.ToUpper(token) {
+		case "AND", "OR":
+			continue
+		case "WITH":
+			skipNext = true
+			continue
+		}
+		if skipNext {
+			skipNext = false
+			continue
+		}
+		ids = append(ids, token)
+	}
+	return ids
+}
@@ -1,52 +1,117 @@
 package main
 
 import (
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"actinspace.org/supply-chain/signing-service/signer"
+)
+
 // SignedMetadata 是最小簽章輸出格式，供 OTA / SOC 使用。
+// Version 決定簽章方式：2 為 Ed25519（驗證端只需公鑰），1 為舊版共享 secret。
+// 附 SBOM 時簽章涵蓋 signer.Payload(Digest, SBOMDigest)，SBOM 內容被替換就無法通過驗證。
 type SignedMetadata struct {
-	Artefact string    `json:"artefact"`
-	Digest   string    `json:"digest"`
-	Signature string   `json:"signature"`
-	SignedAt time.Time `json:"signedAt"`
-	Signer   string    `json:"signer"`
+	Version    int       `json:"version"`
+	Artefact   string    `json:"artefact"`
+	Digest     string    `json:"digest"`
+	SBOMDigest string    `json:"sbomDigest,omitempty"` // SBOM 檔案內容的 sha256:<hex>
+	SBOMURL    string    `json:"sbomUrl,omitempty"`    // SBOM 的發布位置（僅供參考，完整性由 SBOMDigest 保證）
+	Signature  string    `json:"signature"`
+	SignedAt   time.Time `json:"signedAt"`
+	Signer     string    `json:"signer"`
+	KeyID      string    `json:"keyId,omitempty"` // Ed25519 公鑰指紋
 }
 
 func main() {
 	outPath := flag.String("o", "", "輸出 JSON 檔案路徑（預設輸出到 stdout）")
+	keyPath := flag.String("key", "", "Ed25519 私鑰（PKCS#8 PEM）路徑（預設讀取 SIGNING_PRIVATE_KEY_FILE / SIGNING_PRIVATE_KEY）")
+	version := flag.Int("version", 0, "簽章版本：2 = Ed25519，1 = 舊版共享 secret（預設有私鑰時為 2，否則為 1）")
+	filePath := flag.String("file", "", "對檔案內容計算 digest（取代對識別字串計算）")
+	sbomPath := flag.String("sbom", "", "SBOM 檔案路徑；其 digest 會一併簽章")
+	sbomURL := flag.String("sbom-url", "", "SBOM 的發布 URL（需搭配 -sbom）")
 	flag.Parse()
 
-	if flag.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "usage: sign-artifact [-o output.json] <artefact-identifier>")
+	if (*filePath == "" && flag.NArg() < 1) || (*filePath != "" && flag.NArg() > 0) {
+		fmt.Fprintln(os.Stderr, "usage: sign-artifact [-o output.json] [-key private.pem] [-version 1|2] <artefact-identifier | -file path>")
 		os.Exit(1)
 	}
-
-	artefact := flag.Arg(0)
-	secret := os.Getenv("SIGNING_SECRET")
-	if secret == "" {
-		secret = "dev-secret"
+	if *sbomURL != "" && *sbomPath == "" {
+		fmt.Fprintln(os.Stderr, "-sbom-url requires -sbom")
+		os.Exit(1)
 	}
 
-	digestBytes := sha256.Sum256([]byte(artefact))
-	digest := hex.EncodeToString(digestBytes[:])
+	key, err := loadPrivateKey(*keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load signing key: %v\n", err)
+		os.Exit(1)
+	}
+	if *version == 0 {
+		*version = signer.VersionSharedSecret
+		if key != nil {
+			*version = signer.VersionEd25519
+		}
+	}
 
-	sigBytes := sha256.Sum256([]byte(digest + ":" + secret))
-	signature := hex.EncodeToString(sigBytes[:])
+	// 提供 -file 時 digest 綁定檔案內容；只有識別字串時沿用對字串本身計算的行為
+	var artefact, digest string
+	if *filePath != "" {
+		artefact = filepath.Base(*filePath)
+		digest, err = fileDigest(*filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to hash file: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		artefact = flag.Arg(0)
+		digestBytes := sha256.Sum256([]byte(artefact))
+		digest = hex.EncodeToString(digestBytes[:])
+	}
 
 	meta := SignedMetadata{
+		Version:  *version,
 		Artefact: artefact,
 		Digest:   digest,
-		Signature: signature,
+		SBOMURL:  *sbomURL,
 		SignedAt: time.Now().UTC(),
-		Signer:   "local-dev-signer",
+	}
+	if *sbomPath != "" {
+		sbomDigest, err := fileDigest(*sbomPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to hash SBOM: %v\n", err)
+			os.Exit(1)
+		}
+		meta.SBOMDigest = "sha256:" + sbomDigest
+	}
+	payload := signer.Payload(meta.Digest, meta.SBOMDigest)
+
+	switch *version {
+	case signer.VersionEd25519:
+		if key == nil {
+			fmt.Fprintln(os.Stderr, "version 2 requires an Ed25519 private key (-key or SIGNING_PRIVATE_KEY_FILE)")
+			os.Exit(1)
+		}
+		meta.Signature = signer.SignEd25519(payload, key)
+		meta.KeyID = signer.KeyID(key.Public().(ed25519.PublicKey))
+		meta.Signer = "ed25519"
+	case signer.VersionSharedSecret:
+		secret := os.Getenv("SIGNING_SECRET")
+		if secret == "" {
+			secret = "dev-secret"
+		}
+		meta.Signature = signer.Sign(payload, secret)
+		meta.Signer = "local-dev-signer"
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported signature version %d\n", *version)
+		os.Exit(1)
 	}
 
 	data, err := json.MarshalIndent(meta, "", "  ")
@@ -72,4 +137,29 @@ func main() {
 	}
 }
 
+// loadPrivateKey 讀取 -key 指定的私鑰，未指定時改讀環境變數；都沒有時回傳 nil。
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return signer.LoadPrivateKey()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return signer.ParsePrivateKey(data)
+}
+
+// fileDigest 以串流方式計算檔案內容的 sha256（hex），不需將大型映像檔整個載入記憶體。
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
 
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
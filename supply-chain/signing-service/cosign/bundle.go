@@ -0,0 +1,470 @@
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fulcio 憑證中記錄 OIDC issuer 的擴充欄位（舊版為原始字串，新版為 DER UTF8String）。
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Bundle 是 Sigstore bundle（v0.1 JSON 格式）中驗證所需的欄位子集。
+type Bundle struct {
+	MediaType            string               `json:"mediaType,omitempty"`
+	VerificationMaterial VerificationMaterial `json:"verificationMaterial"`
+	MessageSignature     MessageSignature     `json:"messageSignature"`
+}
+
+// VerificationMaterial 包含簽署憑證鏈與透明度日誌紀錄。
+type VerificationMaterial struct {
+	X509CertificateChain struct {
+		Certificates []struct {
+			RawBytes []byte `json:"rawBytes"` // DER（base64）
+		} `json:"certificates"`
+	} `json:"x509CertificateChain"`
+	TlogEntries []TlogEntry `json:"tlogEntries"`
+}
+
+// MessageSignature 是對 artifact digest 的簽章。
+type MessageSignature struct {
+	MessageDigest struct {
+		Algorithm string `json:"algorithm"` // "SHA2_256"
+		Digest    []byte `json:"digest"`
+	} `json:"messageDigest"`
+	Signature []byte `json:"signature"`
+}
+
+// TlogEntry 是 Rekor 透明度日誌中的一筆紀錄、其 SET 與 inclusion proof。
+type TlogEntry struct {
+	LogIndex       flexInt `json:"logIndex"`
+	IntegratedTime flexInt `json:"integratedTime"`
+	LogID          struct {
+		KeyID []byte `json:"keyId"` // Rekor 公鑰 DER 的 SHA-256
+	} `json:"logId"`
+	InclusionPromise struct {
+		SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+	} `json:"inclusionPromise"`
+	CanonicalizedBody []byte         `json:"canonicalizedBody"`
+	InclusionProof    InclusionProof `json:"inclusionProof"`
+}
+
+// InclusionProof 是 RFC 6962 Merkle tree inclusion proof。
+type InclusionProof struct {
+	LogIndex   flexInt  `json:"logIndex"`
+	TreeSize   flexInt  `json:"treeSize"`
+	RootHash   []byte   `json:"rootHash"`
+	Hashes     [][]byte `json:"hashes"`
+	Checkpoint struct {
+		Envelope string `json:"envelope"` // signed note 格式的 checkpoint
+	} `json:"checkpoint"`
+}
+
+// flexInt 接受 protobuf JSON 的字串整數（"123"）與一般數字。
+type flexInt int64
+
+func (f *flexInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", data)
+	}
+	*f = flexInt(v)
+	return nil
+}
+
+// Policy 是 keyless 驗證的信任設定：Fulcio root、預期的簽署身分與 OIDC issuer。
+type Policy struct {
+	Roots    *x509.CertPool
+	Identity string           // 憑證 SAN（email 或 URI）必須完全相符
+	Issuer   string           // 憑證中的 OIDC issuer 必須完全相符
+	RekorKey crypto.PublicKey // 驗證 SET 與 checkpoint 簽章，確認整合時間與 rootHash 來自 Rekor
+}
+
+// Result 是驗證成功後的摘要，供稽核記錄。
+type Result struct {
+	Identity       string    `json:"identity"`
+	Issuer         string    `json:"issuer"`
+	LogIndex       int64     `json:"logIndex"`
+	IntegratedTime time.Time `json:"integratedTime"`
+}
+
+// LoadPolicy 從 PEM 檔案載入 Fulcio root 憑證與 Rekor 公鑰。
+// 沒有 Rekor 公鑰就無法確認整合時間與 inclusion proof，因此為必填。
+func LoadPolicy(rootsPath, identity, issuer, rekorKeyPath string) (*Policy, error) {
+	if identity == "" || issuer == "" {
+		return nil, fmt.Errorf("trusted identity and issuer are required")
+	}
+	if rekorKeyPath == "" {
+		return nil, fmt.Errorf("Rekor public key is required")
+	}
+
+	rootsPEM, err := os.ReadFile(rootsPath)
+	if err != nil {
+		return nil, fmt.Errorf("無法讀取 trusted root: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootsPEM) {
+		return nil, fmt.Errorf("trusted root 檔案不含有效的 PEM 憑證")
+	}
+
+	keyPEM, err := os.ReadFile(rekorKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("無法讀取 Rekor 公鑰: %w", err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("Rekor 公鑰不是有效的 PEM")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("無法解析 Rekor 公鑰: %w", err)
+	}
+
+	return &Policy{Roots: roots, Identity: identity, Issuer: issuer, RekorKey: key}, nil
+}
+
+// Verify 驗證 bundle 是否為受信任身分對 imageDigest（"sha256:<hex>"）的簽章：
+//  1. Rekor SET 簽章涵蓋 integratedTime、logIndex 與紀錄內容，整合時間才可信
+//  2. 憑證鏈可追溯到 Fulcio root（以已驗證的整合時間為準，因 keyless 憑證僅短暫有效）
+//  3. 憑證 SAN 與 OIDC issuer 符合設定
+//  4. 簽章以憑證公鑰驗證，且簽署的 digest 與 release 相同
+//  5. Rekor 紀錄內容對應此簽章，inclusion proof 可重建 rootHash，且 checkpoint 由 Rekor 簽署
+func (p *Policy) Verify(bundleJSON []byte, imageDigest string) (*Result, error) {
+	if p.RekorKey == nil {
+		return nil, fmt.Errorf("policy has no Rekor public key")
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(bundleJSON, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	digest, err := parseImageDigest(imageDigest)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := bundle.VerificationMaterial.X509CertificateChain.Certificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("bundle has no signing certificate")
+	}
+	leaf, err := x509.ParseCertificate(certs[0].RawBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing certificate: %w", err)
+	}
+
+	if len(bundle.VerificationMaterial.TlogEntries) == 0 {
+		return nil, fmt.Errorf("bundle has no transparency log entry")
+	}
+	entry := bundle.VerificationMaterial.TlogEntries[0]
+
+	// 1. SET：未驗證前不可把 integratedTime 當作憑證的驗證時間
+	if err := verifySET(entry, p.RekorKey); err != nil {
+		return nil, err
+	}
+	integratedTime := time.Unix(int64(entry.IntegratedTime), 0).UTC()
+
+	// 2. 憑證鏈
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		if cert, err := x509.ParseCertificate(c.RawBytes); err == nil {
+			intermediates.AddCert(cert)
+		}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         p.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   integratedTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, fmt.Errorf("certificate chain verification failed: %w", err)
+	}
+
+	// 3. 身分與 issuer
+	identity := certIdentity(leaf, p.Identity)
+	if identity == "" {
+		return nil, fmt.Errorf("certificate identity does not match trusted identity %q", p.Identity)
+	}
+	issuer := certIssuer(leaf)
+	if issuer != p.Issuer {
+		return nil, fmt.Errorf("certificate issuer %q does not match trusted issuer %q", issuer, p.Issuer)
+	}
+
+	// 4. 簽章
+	sig := bundle.MessageSignature
+	if sig.MessageDigest.Algorithm != "" && sig.MessageDigest.Algorithm != "SHA2_256" {
+		return nil, fmt.Errorf("unsupported digest algorithm %s", sig.MessageDigest.Algorithm)
+	}
+	if !bytes.Equal(sig.MessageDigest.Digest, digest) {
+		return nil, fmt.Errorf("bundle digest does not match release image digest")
+	}
+	if err := verifySignature(leaf.PublicKey, digest, sig.Signature); err != nil {
+		return nil, err
+	}
+
+	// 5. 透明度日誌
+	if err := verifyTlogBody(entry.CanonicalizedBody, digest, sig.Signature); err != nil {
+		return nil, err
+	}
+	if err := verifyInclusion(entry); err != nil {
+		return nil, err
+	}
+	if err := verifyCheckpoint(entry.InclusionProof, p.RekorKey); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Identity:       identity,
+		Issuer:         issuer,
+		LogIndex:       int64(entry.LogIndex),
+		IntegratedTime: integratedTime,
+	}, nil
+}
+
+// parseImageDigest 將 "sha256:<hex>" 轉為原始位元組。
+func parseImageDigest(imageDigest string) ([]byte, error) {
+	hexDigest := strings.TrimPrefix(imageDigest, "sha256:")
+	digest, err := hex.DecodeString(hexDigest)
+	if err != nil || len(digest) != sha256.Size {
+		return nil, fmt.Errorf("image digest %q is not a valid sha256 digest", imageDigest)
+	}
+	return digest, nil
+}
+
+// certIdentity 回傳與預期身分相符的 SAN（email 或 URI），不符時回傳空字串。
+func certIdentity(cert *x509.Certificate, expected string) string {
+	for _, email := range cert.EmailAddresses {
+		if email == expected {
+			return email
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == expected {
+			return uri.String()
+		}
+	}
+	return ""
+}
+
+// certIssuer 讀取 Fulcio 憑證中的 OIDC issuer 擴充欄位。
+func certIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// verifySignature 以憑證公鑰驗證對 digest 的簽章（ECDSA 或 Ed25519）。
+func verifySignature(pub crypto.PublicKey, digest, signature []byte) error {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(key, digest, signature) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, digest, signature) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported signing key type %T", pub)
+	}
+	return fmt.Errorf("signature verification failed")
+}
+
+// verifySET 驗證 Rekor 的 signed entry timestamp：簽章內容為
+// {"body","integratedTime","logID","logIndex"} 的 canonical JSON（鍵依字母排序），
+// logID 為 Rekor 公鑰 DER 的 SHA-256（hex）。
+func verifySET(entry TlogEntry, key crypto.PublicKey) error {
+	set := entry.InclusionPromise.SignedEntryTimestamp
+	if len(set) == 0 {
+		return fmt.Errorf("transparency log entry has no signed entry timestamp")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return fmt.Errorf("invalid Rekor public key: %w", err)
+	}
+	logID := sha256.Sum256(der)
+	if len(entry.LogID.KeyID) > 0 && !bytes.Equal(entry.LogID.KeyID, logID[:]) {
+		return fmt.Errorf("transparency log entry was not issued by the trusted Rekor instance")
+	}
+
+	// 欄位順序即 canonical JSON 的鍵順序
+	payload, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{
+		Body:           base64.StdEncoding.EncodeToString(entry.CanonicalizedBody),
+		IntegratedTime: int64(entry.IntegratedTime),
+		LogID:          hex.EncodeToString(logID[:]),
+		LogIndex:       int64(entry.LogIndex),
+	})
+	if err != nil {
+		return err
+	}
+
+	payloadHash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, payloadHash[:], set) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(k, payload, set) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported Rekor key type %T", key)
+	}
+	return fmt.Errorf("signed entry timestamp verification failed")
+}
+
+// verifyTlogBody 確認 Rekor hashedrekord 紀錄記錄的正是這個 digest 與簽章，
+// 避免拿其他紀錄的 inclusion proof 冒充。
+func verifyTlogBody(body, digest, signature []byte) error {
+	var entry struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content []byte `json:"content"`
+			} `json:"signature"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return fmt.Errorf("invalid transparency log entry body: %w", err)
+	}
+	if entry.Kind != "hashedrekord" {
+		return fmt.Errorf("unsupported transparency log entry kind %q", entry.Kind)
+	}
+	if entry.Spec.Data.Hash.Value != hex.EncodeToString(digest) {
+		return fmt.Errorf("transparency log entry digest does not match")
+	}
+	if !bytes.Equal(entry.Spec.Signature.Content, signature) {
+		return fmt.Errorf("transparency log entry signature does not match")
+	}
+	return nil
+}
+
+// verifyInclusion 依 RFC 9162 §2.1.3.2 由 leaf hash 與 proof 重建 root hash。
+func verifyInclusion(entry TlogEntry) error {
+	proof := entry.InclusionProof
+	index, size := uint64(proof.LogIndex), uint64(proof.TreeSize)
+	if size == 0 || index >= size {
+		return fmt.Errorf("inclusion proof index %d out of range for tree size %d", index, size)
+	}
+
+	leaf := sha256.Sum256(append([]byte{0x00}, entry.CanonicalizedBody...))
+	r := leaf[:]
+	fn, sn := index, size-1
+	for _, p := range proof.Hashes {
+		if sn == 0 {
+			return fmt.Errorf("inclusion proof has too many hashes")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = hashChildren(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = hashChildren(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 || !bytes.Equal(r, proof.RootHash) {
+		return fmt.Errorf("inclusion proof does not match root hash")
+	}
+	return nil
+}
+
+func hashChildren(left, right []byte) []byte {
+	buf := make([]byte, 0, 1+len(left)+len(right))
+	buf = append(buf, 0x01)
+	buf = append(buf, left...)
+	buf = append(buf, right...)
+	sum := sha256.Sum256(buf)
+	return sum[:]
+}
+
+// verifyCheckpoint 驗證 signed note 格式的 checkpoint：
+// 內文（origin、tree size、base64 root hash）需與 proof 相符，且簽章可由 Rekor 公鑰驗證。
+func verifyCheckpoint(proof InclusionProof, key crypto.PublicKey) error {
+	envelope := proof.Checkpoint.Envelope
+	sep := strings.Index(envelope, "\n\n")
+	if sep < 0 {
+		return fmt.Errorf("checkpoint is missing signatures")
+	}
+	body := envelope[:sep+1]
+
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	if len(lines) < 3 {
+		return fmt.Errorf("checkpoint body is malformed")
+	}
+	if lines[1] != strconv.FormatInt(int64(proof.TreeSize), 10) {
+		return fmt.Errorf("checkpoint tree size does not match inclusion proof")
+	}
+	if lines[2] != base64.StdEncoding.EncodeToString(proof.RootHash) {
+		return fmt.Errorf("checkpoint root hash does not match inclusion proof")
+	}
+
+	bodyHash := sha256.Sum256([]byte(body))
+	for _, line := range strings.Split(envelope[sep+2:], "\n") {
+		if !strings.HasPrefix(line, "— ") {
+			continue
+		}
+		fields := strings.Fields(line)
+		raw, err := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+		if err != nil || len(raw) <= 4 {
+			continue
+		}
+		// 前 4 bytes 為 key hint，其後才是簽章
+		sig := raw[4:]
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, bodyHash[:], sig) {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, []byte(body), sig) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("checkpoint signature verification failed")
+}
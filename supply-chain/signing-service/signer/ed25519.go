@@ -0,0 +1,176 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 簽章版本（attestation / SignedMetadata 的 version 欄位）。
+const (
+	// VersionSharedSecret 是舊版 sha256(digest + ":" + secret)，任何持有 secret 的人都能偽造；缺少 version 時視為此版本。
+	VersionSharedSecret = 1
+	// VersionEd25519 是以 Ed25519 私鑰對 digest 簽章（base64 編碼），驗證端只需要公鑰。
+	VersionEd25519 = 2
+)
+
+// Payload 回傳要簽章的內容：沒有 SBOM 時只有 digest（與既有簽章相容）；
+// 附 SBOM 時為 digest + "\nsbom:" + sbomDigest，讓簽章同時涵蓋 SBOM 的 digest。
+func Payload(digest, sbomDigest string) string {
+	if sbomDigest == "" {
+		return digest
+	}
+	return digest + "\nsbom:" + sbomDigest
+}
+
+// SignEd25519 以 Ed25519 私鑰對 digest 簽章，回傳 base64 編碼的簽章。
+func SignEd25519(digest string, key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(digest)))
+}
+
+// VerifyEd25519 以公鑰驗證 digest 的 Ed25519 簽章。
+func VerifyEd25519(digest, signature string, key ed25519.PublicKey) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(key, []byte(digest), sig)
+}
+
+// KeyID 回傳公鑰的短指紋（sha256 前 8 bytes 的 hex），用於標示簽署者。
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Verifier 依簽章版本驗證 digest：Ed25519 使用公鑰，舊版使用共享 secret。
+// 設定公鑰後預設拒絕舊版簽章，除非 AllowLegacy（過渡期間相容既有的 release）。
+type Verifier struct {
+	PublicKey   ed25519.PublicKey
+	Secret      string
+	AllowLegacy bool
+}
+
+// LoadVerifier 從環境變數建立 Verifier：
+//   - SIGNING_PUBLIC_KEY_FILE / SIGNING_PUBLIC_KEY: Ed25519 公鑰（PEM 或 base64）
+//   - SIGNING_SECRET: 舊版共享 secret（預設 dev-secret）
+//   - SIGNING_ALLOW_LEGACY: 設定公鑰時是否仍接受舊版簽章（true 才接受）
+func LoadVerifier() (*Verifier, error) {
+	v := &Verifier{Secret: os.Getenv("SIGNING_SECRET"), AllowLegacy: true}
+	if v.Secret == "" {
+		v.Secret = "dev-secret"
+	}
+	data, err := loadKeyMaterial("SIGNING_PUBLIC_KEY_FILE", "SIGNING_PUBLIC_KEY")
+	if err != nil || data == nil {
+		return v, err
+	}
+	if v.PublicKey, err = ParsePublicKey(data); err != nil {
+		return nil, err
+	}
+	v.AllowLegacy = os.Getenv("SIGNING_ALLOW_LEGACY") == "true"
+	return v, nil
+}
+
+// Verify 依 version 驗證 payload（見 Payload）的簽章；version 為 0 時視為舊版共享 secret。
+func (v *Verifier) Verify(version int, payload, signature string) error {
+	switch version {
+	case 0, VersionSharedSecret:
+		if v.PublicKey != nil && !v.AllowLegacy {
+			return errors.New("legacy shared-secret signatures are not accepted; sign with Ed25519")
+		}
+		if !Verify(payload, signature, v.Secret) {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	case VersionEd25519:
+		if v.PublicKey == nil {
+			return errors.New("ed25519 signature but no signing public key is configured")
+		}
+		if !VerifyEd25519(payload, signature, v.PublicKey) {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported signature version %d", version)
+}
+
+// LoadPrivateKey 從 SIGNING_PRIVATE_KEY_FILE 或 SIGNING_PRIVATE_KEY 讀取 Ed25519 私鑰；都未設定時回傳 nil。
+func LoadPrivateKey() (ed25519.PrivateKey, error) {
+	data, err := loadKeyMaterial("SIGNING_PRIVATE_KEY_FILE", "SIGNING_PRIVATE_KEY")
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data)
+}
+
+// loadKeyMaterial 優先讀取 fileEnv 指向的檔案，否則使用 valueEnv 的內容。
+func loadKeyMaterial(fileEnv, valueEnv string) ([]byte, error) {
+	if path := os.Getenv(fileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", fileEnv, err)
+		}
+		return data, nil
+	}
+	if value := os.Getenv(valueEnv); value != "" {
+		return []byte(value), nil
+	}
+	return nil, nil
+}
+
+// ParsePrivateKey 解析 Ed25519 私鑰：PKCS#8 PEM（openssl genpkey -algorithm ed25519），
+// 或 base64 編碼的 32 bytes seed / 64 bytes 私鑰。
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not ed25519")
+		}
+		return key, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("private key must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+}
+
+// ParsePublicKey 解析 Ed25519 公鑰：PKIX PEM（openssl pkey -pubout），或 base64 編碼的 32 bytes 公鑰。
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		key, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not ed25519")
+		}
+		return key, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
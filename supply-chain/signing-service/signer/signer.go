@@ -0,0 +1,20 @@
+package signer
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// Sign 以共享 secret 對 digest 產生簽章（sha256(digest + ":" + secret) 的 hex 編碼）。
+// sign-artifact、OTA 驗證與 Space-SOC 稽核匯出都使用同一套格式。
+func Sign(digest, secret string) string {
+	sum := sha256.Sum256([]byte(digest + ":" + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify 以常數時間比較驗證 digest 的簽章。
+func Verify(digest, signature, secret string) bool {
+	expected := Sign(digest, secret)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) == 1
+}
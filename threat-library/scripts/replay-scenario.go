@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,9 +11,12 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"actinspace.org/internal/reqsign"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,21 +28,65 @@ type Scenario struct {
 	Objectives  []string               `yaml:"objectives"`
 	Playbook    Playbook               `yaml:"playbook_steps"`
 	Severity    string                 `yaml:"severity"`
+	Expected    []ExpectedOutcome      `yaml:"expected_outcomes"`
+	ML          *MLExpectations        `yaml:"ml_expectations"`
 	Metadata    map[string]interface{} `yaml:",inline"`
 }
 
+// ExpectedOutcome 定義重演後應觀察到的 gateway 回應：符合 Command（與 Role，未指定時不限）的每個已送出指令，
+// 其決策與 HTTP 狀態碼都必須符合 Decision 與 Status（未指定的欄位不檢查）。
+// 場景重演時沒有送出對應的指令也視為失敗。
+type ExpectedOutcome struct {
+	Command  string `yaml:"command" json:"command"`
+	Role     string `yaml:"role,omitempty" json:"role,omitempty"`
+	Decision string `yaml:"decision,omitempty" json:"decision,omitempty"`
+	Status   int    `yaml:"status,omitempty" json:"status,omitempty"`
+}
+
+// MLExpectations 定義 ML 異常偵測場景對突發指令評分的預期：最後一筆突發指令必須含有 Features 中的特徵，
+// 突發指令的最高分數須達 MinScore 且建議動作為 Action（未指定的欄位不檢查）。
+type MLExpectations struct {
+	Features []string `yaml:"features"`
+	MinScore float64  `yaml:"min_score"`
+	Action   string   `yaml:"action"`
+}
+
 // Playbook 定義場景的執行步驟。
 type Playbook struct {
 	Steps []string `yaml:"steps"`
 }
 
+// UnmarshalYAML 也接受場景檔中以多行文字（每行一個步驟）或清單撰寫的 playbook_steps。
+func (p *Playbook) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&p.Steps)
+	}
+	if node.Kind == yaml.ScalarNode {
+		p.Steps = nil
+		for _, line := range strings.Split(node.Value, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				p.Steps = append(p.Steps, line)
+			}
+		}
+		return nil
+	}
+	type plain Playbook
+	return node.Decode((*plain)(p))
+}
+
 func main() {
 	scenarioFile := flag.String("scenario", "", "威脅場景 YAML 檔案路徑（必填）")
 	gatewayURL := flag.String("gateway", "http://localhost:8081", "TT&C Gateway URL")
 	token := flag.String("token", "operator-token", "認證 token")
+	adminToken := flag.String("admin-token", "admin-token", "設定 gateway 任務階段用的 admin token")
 	delay := flag.Duration("delay", 2*time.Second, "步驟之間的延遲時間")
+	jsonOutput := flag.Bool("json", false, "在 stdout 輸出 JSON 格式的檢查結果（過程訊息改寫到 stderr）")
 	flag.Parse()
 
+	if *jsonOutput {
+		out = os.Stderr
+	}
+
 	if *scenarioFile == "" {
 		fmt.Fprintf(os.Stderr, "錯誤: 必須指定場景檔案 (-scenario)\n")
 		flag.Usage()
@@ -68,23 +117,327 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Printf("開始重演場景: %s\n", scenario.Name)
-	fmt.Printf("描述: %s\n\n", scenario.Description)
+	fmt.Fprintf(out, "開始重演場景: %s\n", scenario.Name)
+	fmt.Fprintf(out, "描述: %s\n\n", scenario.Description)
+
+	summary := &ReplaySummary{Scenario: scenario.ID, Name: scenario.Name}
+
+	// playbook 含可執行步驟時以通用執行器重演，否則使用場景專屬的重演流程
+	if hasReplaySteps(scenario.Playbook.Steps) {
+		if err := validateGatewayURL(*gatewayURL); err != nil {
+			fmt.Fprintf(os.Stderr, "錯誤: %v\n", err)
+			os.Exit(1)
+		}
+		runner := &stepRunner{gatewayURL: *gatewayURL, token: *token, delay: *delay, summary: summary}
+		runner.run(scenario.Playbook.Steps)
+	} else {
+		// 根據場景 ID 執行對應的攻擊流程
+		switch scenario.ID {
+		case "unauthorized-dangerous-command":
+			replayUnauthorizedCommand(*gatewayURL, *token, *delay)
+		case "uplink-spoofing-flood":
+			replayUplinkFlood(*gatewayURL, *delay)
+		case "critical-phase-violation":
+			replayCriticalPhaseViolation(*gatewayURL, *token, *adminToken, *delay)
+		case "ml-anomaly-burst":
+			replayMLBurst(*gatewayURL, *adminToken, scenario.ML, summary)
+		default:
+			fmt.Fprintf(out, "場景 '%s' 的重演腳本尚未實作\n", scenario.ID)
+			fmt.Fprintf(out, "請手動執行場景步驟\n")
+		}
+	}
+
+	checkExpectedOutcomes(summary, scenario.Expected)
+	summary.Success = summary.Failed == 0
+
+	fmt.Fprintf(out, "\n檢查結果: %d 通過, %d 失敗\n", summary.Passed, summary.Failed)
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(summary); err != nil {
+			fmt.Fprintf(os.Stderr, "錯誤: 無法輸出 JSON: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if !summary.Success {
+		os.Exit(1)
+	}
+	fmt.Fprintln(out, "\n場景重演完成")
+}
+
+// out 是重演過程訊息的輸出；使用 -json 時改為 stderr，讓 stdout 只有 JSON 結果。
+var out io.Writer = os.Stdout
+
+// StepResult 是一項檢查的結果（playbook 的 send_command / expect_* 步驟或場景的 expected_outcomes）。
+type StepResult struct {
+	Step     int    `json:"step,omitempty"` // playbook 步驟編號；expected_outcomes 的檢查為 0
+	Check    string `json:"check"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ReplaySummary 是一次場景重演的檢查結果，-json 時輸出到 stdout 供 CI 使用。
+type ReplaySummary struct {
+	Scenario string       `json:"scenario"`
+	Name     string       `json:"name"`
+	Passed   int          `json:"passed"`
+	Failed   int          `json:"failed"`
+	Success  bool         `json:"success"`
+	Results  []StepResult `json:"results"`
+}
+
+// record 記錄一項檢查結果並更新計數。
+func (s *ReplaySummary) record(result StepResult) {
+	if result.Passed {
+		s.Passed++
+	} else {
+		s.Failed++
+	}
+	s.Results = append(s.Results, result)
+}
+
+// sentCommand 是重演過程中送出的一個指令與 gateway 的回應。
+type sentCommand struct {
+	Command  string
+	Token    string
+	Status   int
+	Decision string
+}
 
-	// 根據場景 ID 執行對應的攻擊流程
-	switch scenario.ID {
-	case "unauthorized-dangerous-command":
-		replayUnauthorizedCommand(*gatewayURL, *token, *delay)
-	case "uplink-spoofing-flood":
-		replayUplinkFlood(*gatewayURL, *delay)
-	case "critical-phase-violation":
-		replayCriticalPhaseViolation(*gatewayURL, *token, *delay)
-	default:
-		fmt.Printf("場景 '%s' 的重演腳本尚未實作\n", scenario.ID)
-		fmt.Printf("請手動執行場景步驟\n")
+// sentCommands 記錄 sendCommandRequest 送出的所有指令，供 expected_outcomes 比對。
+var sentCommands []sentCommand
+
+// checkExpectedOutcomes 以已送出的指令比對場景的 expected_outcomes；指定 role 時只比對
+// 使用該角色開發模式 token（<role>-token）送出的指令。
+func checkExpectedOutcomes(summary *ReplaySummary, expected []ExpectedOutcome) {
+	for _, want := range expected {
+		check := "expected_outcome " + want.Command
+		if want.Role != "" {
+			check += " role:" + want.Role
+		}
+		result := StepResult{Check: check, Expected: describeOutcome(want.Decision, want.Status), Passed: true}
+
+		matched := 0
+		for _, sent := range sentCommands {
+			if sent.Command != want.Command || (want.Role != "" && sent.Token != want.Role+"-token") {
+				continue
+			}
+			matched++
+			actual := describeOutcome(sent.Decision, sent.Status)
+			if (want.Decision != "" && sent.Decision != want.Decision) || (want.Status != 0 && sent.Status != want.Status) {
+				result.Passed = false
+				result.Actual = actual
+				break
+			}
+			result.Actual = actual
+		}
+		if matched == 0 {
+			result.Passed = false
+			result.Error = "重演過程沒有送出此指令"
+		}
+
+		summary.record(result)
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(out, "預期結果: %s %s（預期 %s，實際 %s）", status, check, result.Expected, result.Actual)
+		if result.Error != "" {
+			fmt.Fprintf(out, ": %s", result.Error)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// describeOutcome 以「decision=X status=N」描述一個回應，省略空白欄位。
+func describeOutcome(decision string, status int) string {
+	var parts []string
+	if decision != "" {
+		parts = append(parts, "decision="+decision)
+	}
+	if status != 0 {
+		parts = append(parts, "status="+strconv.Itoa(status))
 	}
+	return strings.Join(parts, " ")
+}
 
-	fmt.Println("\n場景重演完成")
+// replayStep 是 playbook 中可自動執行的步驟，格式為「動作: 參數」：
+//   - send_command: <指令> [role:<角色>] [token:<token>] [satellite:<衛星 ID>] [param:<key>=<value>]
+//   - wait: <時間>（Go duration，例如 2s）
+//   - expect_decision: <決策>（比對上一個指令的 decision，例如 denied、allowed、pending_approval）
+//   - expect_status: <HTTP 狀態碼>（比對上一個指令的回應狀態碼，例如 429）
+//
+// 其他文字（例如「1. Operator authenticates...」）視為說明，只會印出。
+type replayStep struct {
+	Action string
+	Args   string
+}
+
+var replayActions = map[string]bool{
+	"send_command":    true,
+	"wait":            true,
+	"expect_decision": true,
+	"expect_status":   true,
+}
+
+// parseReplayStep 解析一行 playbook 步驟（可帶「1.」或「-」編號），不是可執行步驟時回傳 false。
+func parseReplayStep(line string) (replayStep, bool) {
+	line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-"))
+	if number, rest, ok := strings.Cut(line, "."); ok {
+		if _, err := strconv.Atoi(number); err == nil {
+			line = strings.TrimSpace(rest)
+		}
+	}
+	action, args, ok := strings.Cut(line, ":")
+	action = strings.ToLower(strings.TrimSpace(action))
+	if !ok || !replayActions[action] {
+		return replayStep{}, false
+	}
+	return replayStep{Action: action, Args: strings.TrimSpace(args)}, true
+}
+
+// hasReplaySteps 回傳 playbook 是否含有任何可執行步驟。
+func hasReplaySteps(steps []string) bool {
+	for _, line := range steps {
+		if _, ok := parseReplayStep(line); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stepRunner 依序執行 playbook 步驟，並把每個可執行步驟的結果記錄到 summary。
+type stepRunner struct {
+	gatewayURL string
+	token      string
+	delay      time.Duration // send_command 之間的延遲
+	summary    *ReplaySummary
+
+	sent       bool
+	lastStatus int
+	last       *CommandResponse
+}
+
+func (r *stepRunner) run(steps []string) {
+	for i, line := range steps {
+		step, ok := parseReplayStep(line)
+		if !ok {
+			fmt.Fprintf(out, "步驟 %d（說明）: %s\n", i+1, strings.TrimSpace(line))
+			continue
+		}
+		result := StepResult{Step: i + 1, Check: step.Action + ": " + step.Args, Passed: true}
+		if err := r.execute(i+1, step, &result); err != nil {
+			result.Passed = false
+			result.Error = err.Error()
+			fmt.Fprintf(out, "步驟 %d: FAIL %s: %v\n", i+1, step.Action, err)
+		}
+		r.summary.record(result)
+	}
+}
+
+// execute 執行一個步驟並在 result 填入預期與實際值；回傳錯誤表示步驟失敗（格式錯誤、請求失敗或不符預期）。
+func (r *stepRunner) execute(n int, step replayStep, result *StepResult) error {
+	switch step.Action {
+	case "send_command":
+		if err := r.sendCommand(n, step.Args); err != nil {
+			return err
+		}
+		result.Actual = describeOutcome(r.last.Decision, r.lastStatus)
+		return nil
+
+	case "wait":
+		d, err := time.ParseDuration(step.Args)
+		if err != nil {
+			return fmt.Errorf("無效的等待時間 %q", step.Args)
+		}
+		fmt.Fprintf(out, "步驟 %d: 等待 %v\n", n, d)
+		time.Sleep(d)
+		return nil
+
+	case "expect_decision":
+		result.Expected = "decision=" + step.Args
+		if r.last == nil {
+			return fmt.Errorf("尚未送出任何指令")
+		}
+		result.Actual = describeOutcome(r.last.Decision, r.lastStatus)
+		if r.last.Decision != step.Args {
+			return fmt.Errorf("預期決策 %s，實際為 %q（HTTP %d）", step.Args, r.last.Decision, r.lastStatus)
+		}
+		fmt.Fprintf(out, "步驟 %d: PASS 決策為 %s\n", n, step.Args)
+		return nil
+
+	case "expect_status":
+		want, err := strconv.Atoi(step.Args)
+		if err != nil {
+			return fmt.Errorf("無效的狀態碼 %q", step.Args)
+		}
+		result.Expected = "status=" + step.Args
+		if r.last == nil {
+			return fmt.Errorf("尚未送出任何指令")
+		}
+		result.Actual = describeOutcome(r.last.Decision, r.lastStatus)
+		if r.lastStatus != want {
+			return fmt.Errorf("預期 HTTP %d，實際為 %d", want, r.lastStatus)
+		}
+		fmt.Fprintf(out, "步驟 %d: PASS HTTP %d\n", n, want)
+		return nil
+	}
+	return fmt.Errorf("未知的動作")
+}
+
+// sendCommand 解析 send_command 的參數並送出指令，記錄回應供之後的 expect_* 步驟比對。
+func (r *stepRunner) sendCommand(n int, args string) error {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return fmt.Errorf("缺少指令名稱")
+	}
+	command, token, satelliteID := fields[0], r.token, ""
+	var params map[string]interface{}
+	for _, option := range fields[1:] {
+		key, value, ok := strings.Cut(option, ":")
+		if !ok {
+			return fmt.Errorf("無效的參數 %q", option)
+		}
+		switch key {
+		case "role":
+			token = value + "-token" // 對應 gateway 開發模式的 token
+		case "token":
+			token = value
+		case "satellite":
+			satelliteID = value
+		case "param":
+			name, paramValue, ok := strings.Cut(value, "=")
+			if !ok {
+				return fmt.Errorf("無效的指令參數 %q（格式為 param:<key>=<value>）", value)
+			}
+			if params == nil {
+				params = map[string]interface{}{}
+			}
+			params[name] = paramValue
+		default:
+			return fmt.Errorf("未知的參數 %q", key)
+		}
+	}
+
+	if r.sent {
+		time.Sleep(r.delay)
+	}
+	r.sent = true
+
+	status, resp, err := sendCommandRequest(r.gatewayURL, token, command, satelliteID, params)
+	r.lastStatus, r.last = status, resp
+	if err != nil {
+		r.last = nil
+		return err
+	}
+	fmt.Fprintf(out, "步驟 %d: 送出 %s → HTTP %d，決策 %q", n, command, status, resp.Decision)
+	if resp.Reason != "" {
+		fmt.Fprintf(out, "（%s）", resp.Reason)
+	}
+	fmt.Fprintln(out)
+	return nil
 }
 
 // validateGatewayURL 驗證 gateway URL（防止 SSRF）。
@@ -131,35 +484,35 @@ func validateGatewayURL(gatewayURL string) error {
 // replayUnauthorizedCommand 重演未授權危險指令場景。
 func replayUnauthorizedCommand(gatewayURL, token string, delay time.Duration) {
 	if err := validateGatewayURL(gatewayURL); err != nil {
-		fmt.Printf("警告: %v\n", err)
+		fmt.Fprintf(out, "警告: %v\n", err)
 		return
 	}
-	fmt.Println("步驟 1: 使用 operator 角色嘗試發送 deorbit 指令...")
+	fmt.Fprintln(out, "步驟 1: 使用 operator 角色嘗試發送 deorbit 指令...")
 	time.Sleep(delay)
 
 	resp, err := sendCommand(gatewayURL, token, "deorbit", nil)
 	if err != nil {
-		fmt.Printf("錯誤: %v\n", err)
+		fmt.Fprintf(out, "錯誤: %v\n", err)
 		return
 	}
 
-	fmt.Printf("回應: %s - %s\n", resp.Status, resp.Message)
-	fmt.Printf("決策: %s\n", resp.Decision)
+	fmt.Fprintf(out, "回應: %s - %s\n", resp.Status, resp.Message)
+	fmt.Fprintf(out, "決策: %s\n", resp.Decision)
 	if resp.Reason != "" {
-		fmt.Printf("原因: %s\n", resp.Reason)
+		fmt.Fprintf(out, "原因: %s\n", resp.Reason)
 	}
 
-	fmt.Println("\n步驟 2: 嘗試發送多個危險指令...")
+	fmt.Fprintln(out, "\n步驟 2: 嘗試發送多個危險指令...")
 	time.Sleep(delay)
 
 	commands := []string{"disable_power", "format_memory", "orbit_change"}
 	for _, cmd := range commands {
 		resp, err := sendCommand(gatewayURL, token, cmd, nil)
 		if err != nil {
-			fmt.Printf("錯誤發送 %s: %v\n", cmd, err)
+			fmt.Fprintf(out, "錯誤發送 %s: %v\n", cmd, err)
 			continue
 		}
-		fmt.Printf("  %s: %s\n", cmd, resp.Decision)
+		fmt.Fprintf(out, "  %s: %s\n", cmd, resp.Decision)
 		time.Sleep(delay / 2)
 	}
 }
@@ -167,10 +520,10 @@ func replayUnauthorizedCommand(gatewayURL, token string, delay time.Duration) {
 // replayUplinkFlood 重演 uplink flood 場景。
 func replayUplinkFlood(gatewayURL string, delay time.Duration) {
 	if err := validateGatewayURL(gatewayURL); err != nil {
-		fmt.Printf("警告: %v\n", err)
+		fmt.Fprintf(out, "警告: %v\n", err)
 		return
 	}
-	fmt.Println("步驟 1: 發送未認證的請求...")
+	fmt.Fprintln(out, "步驟 1: 發送未認證的請求...")
 	time.Sleep(delay)
 
 	// 嘗試未認證請求
@@ -180,16 +533,17 @@ func replayUplinkFlood(gatewayURL string, delay time.Duration) {
 	
 	resp, err := http.Post(gatewayURL+"/command", "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
-		fmt.Printf("錯誤: %v\n", err)
+		fmt.Fprintf(out, "錯誤: %v\n", err)
 		return
 	}
 	defer resp.Body.Close()
 
-	fmt.Printf("回應狀態碼: %d\n", resp.StatusCode)
+	fmt.Fprintf(out, "回應狀態碼: %d\n", resp.StatusCode)
 
-	fmt.Println("\n步驟 2: 發送大量指令（flood attack）...")
+	fmt.Fprintln(out, "\n步驟 2: 發送大量指令（flood attack）...")
 	time.Sleep(delay)
 
+	rateLimited := 0
 	for i := 0; i < 15; i++ {
 		reqBody, _ := json.Marshal(map[string]interface{}{
 			"command": fmt.Sprintf("test_command_%d", i),
@@ -198,42 +552,313 @@ func replayUplinkFlood(gatewayURL string, delay time.Duration) {
 		httpReq, _ := http.NewRequest("POST", gatewayURL+"/command", bytes.NewBuffer(reqBody))
 		httpReq.Header.Set("Authorization", "Bearer operator-token")
 		httpReq.Header.Set("Content-Type", "application/json")
+		setReplayHeaders(httpReq, reqBody)
 		
 		client := &http.Client{Timeout: 1 * time.Second}
-		client.Do(httpReq)
+		if resp, err := client.Do(httpReq); err == nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				rateLimited++
+				fmt.Fprintf(out, "  指令 %d 被限流（429，Retry-After: %ss）\n", i+1, resp.Header.Get("Retry-After"))
+			}
+			resp.Body.Close()
+		}
 		
 		if i%5 == 0 {
-			fmt.Printf("  已發送 %d 個指令...\n", i+1)
+			fmt.Fprintf(out, "  已發送 %d 個指令...\n", i+1)
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
+	fmt.Fprintf(out, "\n共 %d / 15 個指令被 gateway 限流\n", rateLimited)
 }
 
-// replayCriticalPhaseViolation 重演關鍵階段違規場景。
-func replayCriticalPhaseViolation(gatewayURL, token string, delay time.Duration) {
+// replayCriticalPhaseViolation 重演關鍵階段違規場景：以 admin token 讓 gateway 在場景期間進入 critical 階段，
+// 結束後還原原本的任務階段。
+func replayCriticalPhaseViolation(gatewayURL, token, adminToken string, delay time.Duration) {
 	if err := validateGatewayURL(gatewayURL); err != nil {
-		fmt.Printf("警告: %v\n", err)
+		fmt.Fprintf(out, "警告: %v\n", err)
 		return
 	}
-	fmt.Println("步驟 1: 模擬關鍵任務階段...")
-	fmt.Println("（注意: 實際環境中需要設定 MISSION_PHASE 環境變數）")
+	fmt.Fprintln(out, "步驟 1: 將 gateway 切換到關鍵任務階段...")
+	restore, err := enterMissionPhase(gatewayURL, adminToken, "critical")
+	if err != nil {
+		fmt.Fprintf(out, "錯誤: 無法設定任務階段: %v\n", err)
+		return
+	}
+	defer func() {
+		if err := restore(); err != nil {
+			fmt.Fprintf(out, "警告: 無法還原任務階段: %v\n", err)
+		}
+	}()
 	time.Sleep(delay)
 
-	fmt.Println("步驟 2: 嘗試發送非關鍵指令...")
+	fmt.Fprintln(out, "步驟 2: 嘗試發送非關鍵指令...")
 	time.Sleep(delay)
 
 	nonCriticalCommands := []string{"payload_toggle", "diagnostics", "system_status"}
 	for _, cmd := range nonCriticalCommands {
 		resp, err := sendCommand(gatewayURL, token, cmd, nil)
 		if err != nil {
-			fmt.Printf("錯誤: %v\n", err)
+			fmt.Fprintf(out, "錯誤: %v\n", err)
 			continue
 		}
-		fmt.Printf("  %s: %s\n", cmd, resp.Decision)
+		fmt.Fprintf(out, "  %s: %s\n", cmd, resp.Decision)
 		time.Sleep(delay / 2)
 	}
 }
 
+// mlBurstSatellite 是 ML 突發場景的合成流量使用的衛星 ID。
+const mlBurstSatellite = "SAT-ML-REPLAY"
+
+// mlBaselineSchedule 是 baseline 每個平日的指令（UTC 整點、指令、角色）。
+var mlBaselineSchedule = []struct {
+	hour    int
+	command string
+	role    string
+}{
+	{9, "system_status", "operator"},
+	{10, "health_check", "operator"},
+	{11, "telemetry_dump", "operator"},
+	{13, "diagnostics", "engineer"},
+	{14, "system_status", "operator"},
+	{15, "payload_toggle", "operator"},
+	{16, "health_check", "operator"},
+}
+
+// mlBurstCommands 是離峰突發中 operator 依序循環送出的指令。
+var mlBurstCommands = []string{"system_status", "diagnostics", "format_memory", "disable_power"}
+
+// mlBurstSize 是突發的指令數，超過 ML 偵測器「5 分鐘內 20 筆」的門檻。
+const mlBurstSize = 25
+
+// mlSimulationResult 是 gateway POST /anomaly/simulate 回傳的單筆結果。
+type mlSimulationResult struct {
+	Command   string    `json:"command"`
+	Role      string    `json:"role"`
+	Timestamp time.Time `json:"timestamp"`
+	Score     struct {
+		Score             float64 `json:"score"`
+		IsAnomaly         bool    `json:"isAnomaly"`
+		RecommendedAction string  `json:"recommendedAction"`
+		TopFeatures       []struct {
+			Feature string `json:"feature"`
+		} `json:"topFeatures"`
+	} `json:"score"`
+}
+
+// mlBurstTraffic 產生 ML 突發場景的合成流量：兩週內每個平日白天的正常指令作為 baseline，
+// 接著在最近一個週六 03:00 UTC 每 5 秒送出一筆指令，共 mlBurstSize 筆。回傳流量與突發的起始索引。
+func mlBurstTraffic(now time.Time) ([]map[string]interface{}, int) {
+	burstDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	for burstDay.Weekday() != time.Saturday {
+		burstDay = burstDay.AddDate(0, 0, -1)
+	}
+	burstStart := burstDay.Add(3 * time.Hour)
+
+	var traffic []map[string]interface{}
+	add := func(command, role string, at time.Time) {
+		traffic = append(traffic, map[string]interface{}{
+			"satelliteId": mlBurstSatellite,
+			"command":     command,
+			"role":        role,
+			"timestamp":   at.Format(time.RFC3339),
+		})
+	}
+	for day := burstDay.AddDate(0, 0, -14); day.Before(burstDay); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		for _, entry := range mlBaselineSchedule {
+			add(entry.command, entry.role, day.Add(time.Duration(entry.hour)*time.Hour))
+		}
+	}
+
+	burstIndex := len(traffic)
+	for i := 0; i < mlBurstSize; i++ {
+		add(mlBurstCommands[i%len(mlBurstCommands)], "operator", burstStart.Add(time.Duration(i)*5*time.Second))
+	}
+	return traffic, burstIndex
+}
+
+// replayMLBurst 重演 ML 異常偵測的離峰突發場景：以 admin token 呼叫 gateway 的 POST /anomaly/simulate，
+// 在全新的模型上先以平日白天的正常指令建立 baseline，再重演週六凌晨的快速突發，
+// 並依 ml_expectations 檢查突發指令的特徵、分數與建議動作。模擬不會影響 gateway 執行中的模型。
+func replayMLBurst(gatewayURL, adminToken string, expectations *MLExpectations, summary *ReplaySummary) {
+	if err := validateGatewayURL(gatewayURL); err != nil {
+		fmt.Fprintf(out, "警告: %v\n", err)
+		return
+	}
+	traffic, burstIndex := mlBurstTraffic(time.Now().UTC())
+	fmt.Fprintf(out, "步驟 1: 建立正常 baseline（%d 筆平日白天指令）...\n", burstIndex)
+	fmt.Fprintf(out, "步驟 2: 重演離峰突發（%d 筆，%s 起每 5 秒一筆）...\n", len(traffic)-burstIndex, traffic[burstIndex]["timestamp"])
+
+	reqBody, err := json.Marshal(map[string]interface{}{"commands": traffic})
+	if err != nil {
+		fmt.Fprintf(out, "錯誤: %v\n", err)
+		return
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, gatewayURL+"/anomaly/simulate", bytes.NewReader(reqBody))
+	if err != nil {
+		fmt.Fprintf(out, "錯誤: %v\n", err)
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+adminToken)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		summary.record(StepResult{Check: "ml_simulation", Error: err.Error()})
+		fmt.Fprintf(out, "錯誤: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Results []mlSimulationResult `json:"results"`
+		Error   string               `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || resp.StatusCode != http.StatusOK {
+		message := fmt.Sprintf("HTTP %d %s", resp.StatusCode, result.Error)
+		if err != nil {
+			message = fmt.Sprintf("HTTP %d: %v", resp.StatusCode, err)
+		}
+		summary.record(StepResult{Check: "ml_simulation", Error: message})
+		fmt.Fprintf(out, "錯誤: ML 模擬失敗（需要 ML_ANOMALY_ENABLED=true 與 admin token）: %s\n", message)
+		return
+	}
+	if len(result.Results) != len(traffic) {
+		summary.record(StepResult{Check: "ml_simulation", Error: fmt.Sprintf("expected %d results, got %d", len(traffic), len(result.Results))})
+		return
+	}
+
+	burst := result.Results[burstIndex:]
+	highest := burst[0]
+	for _, r := range burst {
+		fmt.Fprintf(out, "  %s %s: 分數 %.2f，建議 %s\n", r.Timestamp.Format("15:04:05"), r.Command, r.Score.Score, r.Score.RecommendedAction)
+		if r.Score.Score > highest.Score.Score {
+			highest = r
+		}
+	}
+	if expectations == nil {
+		return
+	}
+
+	fmt.Fprintln(out, "\n步驟 3: 檢查突發指令的 ML 評分...")
+	last := burst[len(burst)-1]
+	var lastFeatures []string
+	for _, f := range last.Score.TopFeatures {
+		lastFeatures = append(lastFeatures, f.Feature)
+	}
+	for _, feature := range expectations.Features {
+		recordMLCheck(summary, StepResult{
+			Check:    "ml_feature " + feature,
+			Expected: feature,
+			Actual:   strings.Join(lastFeatures, ","),
+			Passed:   slices.Contains(lastFeatures, feature),
+		})
+	}
+	if expectations.MinScore > 0 {
+		recordMLCheck(summary, StepResult{
+			Check:    "ml_min_score",
+			Expected: fmt.Sprintf(">= %.2f", expectations.MinScore),
+			Actual:   fmt.Sprintf("%.2f", highest.Score.Score),
+			Passed:   highest.Score.Score >= expectations.MinScore,
+		})
+	}
+	if expectations.Action != "" {
+		recordMLCheck(summary, StepResult{
+			Check:    "ml_action",
+			Expected: expectations.Action,
+			Actual:   highest.Score.RecommendedAction,
+			Passed:   highest.Score.RecommendedAction == expectations.Action,
+		})
+	}
+}
+
+// recordMLCheck 記錄並印出一項 ML 評分檢查。
+func recordMLCheck(summary *ReplaySummary, check StepResult) {
+	summary.record(check)
+	status := "PASS"
+	if !check.Passed {
+		status = "FAIL"
+	}
+	fmt.Fprintf(out, "  %s %s（預期 %s", status, check.Check, check.Expected)
+	if check.Actual != "" {
+		fmt.Fprintf(out, "，實際 %s", check.Actual)
+	}
+	fmt.Fprintln(out, "）")
+}
+
+// missionPhaseTimeout 是重演設定任務階段的時限；重演中斷時 gateway 會在到期後自動回到原本的階段。
+const missionPhaseTimeout = 10 * time.Minute
+
+// missionPhaseStatus 是 gateway /mission-phase 的回應格式。
+type missionPhaseStatus struct {
+	Phase    string `json:"phase"`
+	Override bool   `json:"override"`
+	Error    string `json:"error"`
+}
+
+// enterMissionPhase 讓 gateway 進入指定的任務階段（PUT /mission-phase，需要 admin），
+// 回傳還原原本階段的函式：原本沒有執行期設定時清除設定，否則設回原本的階段。
+func enterMissionPhase(gatewayURL, adminToken, phase string) (func() error, error) {
+	current, err := missionPhaseRequest(http.MethodGet, gatewayURL, adminToken, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := missionPhaseRequest(http.MethodPut, gatewayURL, adminToken, map[string]string{
+		"phase":    phase,
+		"duration": missionPhaseTimeout.String(),
+	}); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(out, "  任務階段: %s → %s\n", current.Phase, phase)
+
+	return func() error {
+		if !current.Override {
+			_, err := missionPhaseRequest(http.MethodDelete, gatewayURL, adminToken, nil)
+			return err
+		}
+		_, err := missionPhaseRequest(http.MethodPut, gatewayURL, adminToken, map[string]string{"phase": current.Phase})
+		return err
+	}, nil
+}
+
+// missionPhaseRequest 呼叫 gateway 的 /mission-phase API。
+func missionPhaseRequest(method, gatewayURL, token string, body map[string]string) (*missionPhaseStatus, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequest(method, gatewayURL+"/mission-phase", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var status missionPhaseStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("HTTP %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, status.Error)
+	}
+	return &status, nil
+}
+
 // CommandResponse 定義指令回應格式。
 type CommandResponse struct {
 	Status      string `json:"status"`
@@ -243,41 +868,57 @@ type CommandResponse struct {
 	ProcessedAt string `json:"processedAt"`
 }
 
+// setReplayHeaders 設定 gateway 重放保護所需的 nonce、時間戳記與簽章（REPLAY_SIGNING_SECRET 需與 gateway 相同）。
+func setReplayHeaders(req *http.Request, body []byte) {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	reqsign.SetHeaders(req, []byte(os.Getenv("REPLAY_SIGNING_SECRET")), hex.EncodeToString(nonce), strconv.FormatInt(time.Now().Unix(), 10), body)
+}
+
 // sendCommand 發送指令到 gateway。
 func sendCommand(gatewayURL, token, command string, params map[string]interface{}) (*CommandResponse, error) {
+	_, resp, err := sendCommandRequest(gatewayURL, token, command, "", params)
+	return resp, err
+}
+
+// sendCommandRequest 發送指令到 gateway 並回傳 HTTP 狀態碼；錯誤回應（例如 401、429）的 decision 為空。
+func sendCommandRequest(gatewayURL, token, command, satelliteID string, params map[string]interface{}) (int, *CommandResponse, error) {
 	reqBody, err := json.Marshal(map[string]interface{}{
-		"command": command,
-		"params":  params,
+		"command":     command,
+		"params":      params,
+		"satelliteId": satelliteID,
 	})
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 
 	httpReq, err := http.NewRequest("POST", gatewayURL+"/command", bytes.NewBuffer(reqBody))
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+token)
+	setReplayHeaders(httpReq, reqBody)
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return resp.StatusCode, nil, err
 	}
 
 	var cmdResp CommandResponse
 	if err := json.Unmarshal(body, &cmdResp); err != nil {
-		return nil, err
+		return resp.StatusCode, nil, err
 	}
+	sentCommands = append(sentCommands, sentCommand{Command: command, Token: token, Status: resp.StatusCode, Decision: cmdResp.Decision})
 
-	return &cmdResp, nil
+	return resp.StatusCode, &cmdResp, nil
 }
 
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // 內嵌時區資料，執行映像（alpine）未安裝 tzdata 時 ANOMALY_TIMEZONE 仍可使用
+
+	"actinspace.org/ttc-gateway/internal/anomaly"
+	"actinspace.org/ttc-gateway/internal/ml"
+	"actinspace.org/ttc-gateway/internal/policy"
+)
+
+// mlDetector 是選用的 ML 異常偵測器（ML_ANOMALY_ENABLED=true 時啟用）。
+var mlDetector *ml.MLAnomalyDetector
+
+// configureAnomalyPolicy 讀取異常與 policy 的耦合設定：
+//   - ANOMALY_POLICY_MODE: off（預設）、log、confirm、deny
+//   - ANOMALY_POLICY_MIN_SEVERITY: 影響決策的最低異常嚴重性（預設 high）
+//   - ML_ANOMALY_ENABLED / ML_MODEL_PATH: 啟用 ML 異常分數及其模型檔路徑
+//   - ML_DECAY_HALF_LIFE: baseline 權重減半所需時間（預設 168h，0 表示不衰減）
+//   - ML_HISTORY_MAX_AGE: 超過此時間的指令紀錄一律移除（預設 0，僅依筆數上限淘汰）
+//   - ML_MIN_SATELLITE_HISTORY: 衛星專屬模型啟用前所需的紀錄數（預設 50）
+func configureAnomalyPolicy() {
+	mode, err := policy.ParseAnomalyMode(os.Getenv("ANOMALY_POLICY_MODE"))
+	if err != nil {
+		log.Fatalf("無效的異常耦合設定: %v", err)
+	}
+	minSeverity := os.Getenv("ANOMALY_POLICY_MIN_SEVERITY")
+	if minSeverity != "" && !policy.SeverityAtLeast(minSeverity, "low") {
+		log.Fatalf("無效的 ANOMALY_POLICY_MIN_SEVERITY: %q", minSeverity)
+	}
+	policyEngine.SetAnomalyCoupling(policy.AnomalyCoupling{Mode: mode, MinSeverity: minSeverity})
+
+	if os.Getenv("ML_ANOMALY_ENABLED") == "true" {
+		mlDetector = ml.NewMLAnomalyDetector(os.Getenv("ML_MODEL_PATH"), 1000)
+		halfLife := ml.DefaultDecayHalfLife
+		if v := os.Getenv("ML_DECAY_HALF_LIFE"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				log.Fatalf("無效的 ML_DECAY_HALF_LIFE: %q", v)
+			}
+			halfLife = d
+		}
+		var maxAge time.Duration
+		if v := os.Getenv("ML_HISTORY_MAX_AGE"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				log.Fatalf("無效的 ML_HISTORY_MAX_AGE: %q", v)
+			}
+			maxAge = d
+		}
+		mlDetector.SetDecay(halfLife, maxAge)
+		if v := os.Getenv("ML_MIN_SATELLITE_HISTORY"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				log.Fatalf("無效的 ML_MIN_SATELLITE_HISTORY: %q", v)
+			}
+			mlDetector.SetMinSatelliteHistory(n)
+		}
+	}
+}
+
+// loadAnomalyConfig 讀取異常偵測的滑動視窗設定（未設定時使用偵測器預設值）：
+//   - ANOMALY_RATE_WINDOW: 頻率限制視窗（預設 1m）
+//   - ANOMALY_BURST_WINDOW / ANOMALY_BURST_THRESHOLD: 突發視窗與門檻（預設 10s / 10）
+//   - ANOMALY_ROLE_WINDOW / ANOMALY_ROLE_THRESHOLD: 角色活動視窗與門檻（預設 1h / 50）
+//   - ANOMALY_HISTORY_WINDOW: 指令紀錄最短保留時間（預設 5m）
+//   - ANOMALY_ROLE_RATE_LIMITS: 每個角色在頻率視窗內的指令上限，例如 operator=10,default=20（預設停用）
+//   - ANOMALY_STATE_PATH / ANOMALY_STATE_SAVE_INTERVAL: 滑動視窗快照檔與寫入間隔（預設停用 / 5s）
+//   - ANOMALY_TIMEZONE: 正常時間與離峰時段使用的 IANA 時區，例如 America/Los_Angeles（預設 UTC）
+func loadAnomalyConfig() anomaly.Config {
+	duration := func(name string) time.Duration {
+		v := os.Getenv(name)
+		if v == "" {
+			return 0
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("無效的 %s: %q", name, v)
+		}
+		return d
+	}
+	count := func(name string) int {
+		v := os.Getenv(name)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("無效的 %s: %q", name, v)
+		}
+		return n
+	}
+
+	config := anomaly.Config{
+		RateLimitWindow:       duration("ANOMALY_RATE_WINDOW"),
+		BurstTimeWindow:       duration("ANOMALY_BURST_WINDOW"),
+		BurstThreshold:        count("ANOMALY_BURST_THRESHOLD"),
+		RoleActivityWindow:    duration("ANOMALY_ROLE_WINDOW"),
+		RoleActivityThreshold: count("ANOMALY_ROLE_THRESHOLD"),
+		HistoryWindow:         duration("ANOMALY_HISTORY_WINDOW"),
+		StatePath:             os.Getenv("ANOMALY_STATE_PATH"),
+		StateSaveInterval:     duration("ANOMALY_STATE_SAVE_INTERVAL"),
+	}
+	if v := os.Getenv("ANOMALY_ROLE_RATE_LIMITS"); v != "" {
+		config.MaxCommandsPerRole = make(map[string]int)
+		for _, item := range splitList(v) {
+			role, limit, ok := strings.Cut(item, "=")
+			n, err := strconv.Atoi(strings.TrimSpace(limit))
+			if !ok || strings.TrimSpace(role) == "" || err != nil || n <= 0 {
+				log.Fatalf("無效的 ANOMALY_ROLE_RATE_LIMITS: %q", item)
+			}
+			config.MaxCommandsPerRole[strings.TrimSpace(role)] = n
+		}
+	}
+	if v := os.Getenv("ANOMALY_TIMEZONE"); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			log.Fatalf("無效的 ANOMALY_TIMEZONE: %q", v)
+		}
+		config.Location = loc
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatalf("無效的異常偵測設定: %v", err)
+	}
+	return config
+}
+
+// mlActionSeverity 將 ML 建議動作對應到異常嚴重性。
+func mlActionSeverity(action string) anomaly.Severity {
+	switch action {
+	case "block_and_alert":
+		return anomaly.SeverityCritical
+	case "alert_and_log":
+		return anomaly.SeverityHigh
+	case "log_for_review":
+		return anomaly.SeverityMedium
+	}
+	return anomaly.SeverityLow
+}
+
+// strongestAnomaly 從規則式偵測結果與 ML 分數中選出最嚴重的訊號，沒有異常時回傳 nil。
+func strongestAnomaly(anomalies []anomaly.Anomaly, score *ml.AnomalyScore) *policy.AnomalySignal {
+	var strongest *policy.AnomalySignal
+	for _, anom := range anomalies {
+		if strongest == nil || !policy.SeverityAtLeast(strongest.Severity, string(anom.Severity)) {
+			strongest = &policy.AnomalySignal{
+				Source:   "detector",
+				Type:     string(anom.Type),
+				Severity: string(anom.Severity),
+				Message:  anom.Message,
+			}
+		}
+	}
+
+	if score != nil && score.IsAnomaly {
+		severity := string(mlActionSeverity(score.RecommendedAction))
+		if strongest == nil || !policy.SeverityAtLeast(strongest.Severity, severity) {
+			strongest = &policy.AnomalySignal{
+				Source:   "ml",
+				Type:     "ml_score",
+				Severity: severity,
+				Score:    score.Score,
+				Action:   score.RecommendedAction,
+				Message:  "model " + score.Model,
+				// 精簡解釋：只附上貢獻最大的三個特徵
+				Explanation: score.Summary(3),
+			}
+		}
+	}
+	return strongest
+}
+
+// mlBlockRuleID 是 ML 建議 block_and_alert 時拒絕指令的 RuleID。
+const mlBlockRuleID = "ml-block-and-alert"
+
+// applyMLBlock 在 ML 建議 block_and_alert 時拒絕指令，不受 ANOMALY_POLICY_MODE 影響；
+// 也取代等待異常確認或雙人授權的決策。已被規則拒絕的指令維持原決策。
+func applyMLBlock(decision policy.PolicyDecision, score *ml.AnomalyScore) policy.PolicyDecision {
+	if score == nil || !score.IsAnomaly || score.RecommendedAction != "block_and_alert" {
+		return decision
+	}
+	if !decision.Allowed && !decision.RequiresConfirmation && !decision.RequiresApproval {
+		return decision
+	}
+	return policy.PolicyDecision{
+		Allowed:  false,
+		Reason:   fmt.Sprintf("blocked by ML anomaly detector (score %.2f, model %s)", score.Score, score.Model),
+		RuleID:   mlBlockRuleID,
+		Severity: "critical",
+		Anomaly: &policy.AnomalySignal{
+			Source:      "ml",
+			Type:        "ml_score",
+			Severity:    "critical",
+			Score:       score.Score,
+			Action:      score.RecommendedAction,
+			Message:     "model " + score.Model,
+			Explanation: score.Summary(3),
+		},
+	}
+}
+
+// mlAnomalyEvent 回傳送往 Space-SOC 的 ML 異常事件（分數、原因與建議動作）。
+func mlAnomalyEvent(score *ml.AnomalyScore, command, operatorRole, operatorID, satelliteID string) map[string]interface{} {
+	return map[string]interface{}{
+		"component":    "ttc-gateway",
+		"eventType":    "ml_anomaly_detected",
+		"anomalyType":  "ml_score",
+		"command":      command,
+		"operatorRole": operatorRole,
+		"operatorId":   operatorID,
+		"message":      fmt.Sprintf("ML anomaly score %.2f (threshold %.2f), recommended action %s", score.Score, score.Threshold, score.RecommendedAction),
+		"severity":     mlActionSeverity(score.RecommendedAction),
+		"metadata": map[string]interface{}{
+			"score":             score.Score,
+			"threshold":         score.Threshold,
+			"confidence":        score.Confidence,
+			"reasons":           score.Reasons,
+			"recommendedAction": score.RecommendedAction,
+			"model":             score.Model,
+			"satelliteId":       satelliteID,
+			"explanation":       score.Summary(3),
+		},
+	}
+}
+
+// anomalyEventFields 回傳決策事件中描述促成異常的欄位。
+func anomalyEventFields(signal *policy.AnomalySignal) map[string]interface{} {
+	fields := map[string]interface{}{
+		"source":   signal.Source,
+		"type":     signal.Type,
+		"severity": signal.Severity,
+		"score":    signal.Score,
+		"action":   signal.Action,
+		"message":  signal.Message,
+	}
+	if len(signal.Explanation) > 0 {
+		fields["explanation"] = signal.Explanation
+	}
+	return fields
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/ttc-gateway/internal/auth"
+)
+
+// authConfig 是 gateway 的驗證設定。
+type authConfig struct {
+	verifier *auth.Verifier
+	// devMode 允許未簽章的示範 token（admin-token / engineer-token / 其他皆為 operator）
+	devMode bool
+}
+
+// loadAuthConfig 讀取驗證設定：
+//   - JWT_HS256_SECRET: HS256 簽章密鑰
+//   - JWT_RS256_PUBLIC_KEY_FILE: RS256 公鑰（PEM）路徑，與 JWT_HS256_SECRET 擇一
+//   - JWT_ISSUER / JWT_AUDIENCE: 設定時檢查 iss / aud
+//   - JWT_LEEWAY: exp / nbf 允許的時鐘誤差（預設 30s）
+//   - AUTH_DEV_MODE=true: 接受示範 token；同時設定簽章密鑰時，JWT 格式的 token 仍會驗證
+//
+// 未設定簽章密鑰且未啟用 AUTH_DEV_MODE 時 gateway 拒絕啟動。
+func loadAuthConfig() authConfig {
+	config := authConfig{devMode: os.Getenv("AUTH_DEV_MODE") == "true"}
+
+	jwtConfig := auth.Config{
+		HMACSecret: []byte(os.Getenv("JWT_HS256_SECRET")),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		Leeway:     30 * time.Second,
+	}
+	if path := os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"); path != "" {
+		key, err := auth.LoadRSAPublicKey(path)
+		if err != nil {
+			log.Fatalf("無法載入 JWT 公鑰: %v", err)
+		}
+		jwtConfig.RSAPublicKey = key
+	}
+	if v := os.Getenv("JWT_LEEWAY"); v != "" {
+		leeway, err := time.ParseDuration(v)
+		if err != nil || leeway < 0 {
+			log.Fatalf("無效的 JWT_LEEWAY: %q", v)
+		}
+		jwtConfig.Leeway = leeway
+	}
+
+	if len(jwtConfig.HMACSecret) > 0 || jwtConfig.RSAPublicKey != nil {
+		verifier, err := auth.NewVerifier(jwtConfig)
+		if err != nil {
+			log.Fatalf("無效的 JWT 設定: %v", err)
+		}
+		config.verifier = verifier
+		log.Printf("JWT 驗證已啟用（%s）", verifier.Algorithm())
+	}
+
+	switch {
+	case config.verifier == nil && !config.devMode:
+		log.Fatalf("未設定 JWT_HS256_SECRET 或 JWT_RS256_PUBLIC_KEY_FILE；示範環境請設定 AUTH_DEV_MODE=true")
+	case config.devMode:
+		log.Printf("警告: AUTH_DEV_MODE 已啟用，接受未簽章的示範 token，請勿用於正式環境")
+	}
+	return config
+}
+
+// newAuthMiddleware 驗證 Bearer token，並在 context 設定 operatorRole、operatorID（JWT sub）與 token。
+func newAuthMiddleware(config authConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization token"})
+			c.Abort()
+			return
+		}
+		token, hasBearer := strings.CutPrefix(header, "Bearer ")
+
+		var role, operatorID string
+		switch {
+		case config.verifier != nil && hasBearer && (!config.devMode || strings.Count(token, ".") == 2):
+			claims, err := config.verifier.Verify(token)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": authErrorMessage(err)})
+				c.Abort()
+				return
+			}
+			role, operatorID = claims.Role, claims.Subject
+		case config.devMode:
+			role = devTokenRole(token)
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header must use the Bearer scheme"})
+			c.Abort()
+			return
+		}
+
+		c.Set("operatorRole", role)
+		if operatorID != "" {
+			c.Set("operatorID", operatorID)
+		}
+		c.Set("token", header)
+		c.Next()
+	}
+}
+
+// operatorIdentity 回傳稽核用的操作員識別：JWT 的 sub；沒有 sub 時（例如 AUTH_DEV_MODE 的示範 token）
+// 以 token 雜湊的前綴代替，不直接記錄 token。
+func operatorIdentity(c *gin.Context) string {
+	if operatorID := c.GetString("operatorID"); operatorID != "" {
+		return operatorID
+	}
+	return "token-" + operatorScope(c)[:12]
+}
+
+// devTokenRole 是 AUTH_DEV_MODE 的示範 token 對應。
+func devTokenRole(token string) string {
+	switch token {
+	case "admin-token":
+		return "admin"
+	case "engineer-token":
+		return "engineer"
+	default:
+		return "operator"
+	}
+}
+
+// authErrorMessage 將驗證錯誤轉為回應訊息；簽章與演算法錯誤不區分原因。
+func authErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrTokenExpired):
+		return "token expired"
+	case errors.Is(err, auth.ErrInvalidSignature), errors.Is(err, auth.ErrUnexpectedAlg):
+		return "invalid token signature"
+	default:
+		return "invalid token: " + err.Error()
+	}
+}
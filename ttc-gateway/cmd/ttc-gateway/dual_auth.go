@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/ttc-gateway/internal/policy"
+)
+
+// configureDualAuth 讀取雙人授權設定（未設定時使用 policy.DefaultDualAuthConfig）：
+//   - DUAL_AUTH_COMMANDS: 需要第二位操作員核准的指令（逗號分隔，none 表示停用）
+//   - DUAL_AUTH_APPROVER_ROLES: 可核准的角色（逗號分隔）
+//   - DUAL_AUTH_TIMEOUT: 核准與重送的期限
+func configureDualAuth() {
+	config := policy.DefaultDualAuthConfig()
+	if v, ok := os.LookupEnv("DUAL_AUTH_COMMANDS"); ok {
+		config.Commands = splitList(v)
+		if len(config.Commands) == 1 && config.Commands[0] == "none" {
+			config.Commands = nil
+		}
+	}
+	if v := os.Getenv("DUAL_AUTH_APPROVER_ROLES"); v != "" {
+		config.ApproverRoles = splitList(v)
+	}
+	if v := os.Getenv("DUAL_AUTH_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			log.Fatalf("無效的 DUAL_AUTH_TIMEOUT: %q", v)
+		}
+		config.Timeout = timeout
+	}
+	policyEngine.SetDualAuth(config)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// auditDualAuth 記錄雙人授權事件並發送到 Space-SOC。
+func auditDualAuth(c *gin.Context, eventType string, req policy.DualAuthRequest) {
+	message := fmt.Sprintf("%s: %s (%s)", eventType, req.Command, req.ID)
+	logCommandEvent(c.Request.Context(), eventType, map[string]interface{}{
+		"command":      req.Command,
+		"operatorRole": c.GetString("operatorRole"),
+		"operatorId":   operatorIdentity(c),
+		"approvalId":   req.ID,
+	})
+	sendEventToSOC(os.Getenv("SPACE_SOC_URL"), map[string]interface{}{
+		"component":    "ttc-gateway",
+		"eventType":    eventType,
+		"command":      req.Command,
+		"operatorRole": c.GetString("operatorRole"),
+		"operatorId":   operatorIdentity(c),
+		"message":      message,
+		"severity":     "high",
+		"metadata": map[string]interface{}{
+			"approvalId":    req.ID,
+			"satelliteId":   req.SatelliteID,
+			"requestedRole": req.RequestedRole,
+			"approvedRole":  req.ApprovedRole,
+			"expiresAt":     req.ExpiresAt,
+		},
+	})
+}
+
+// registerDualAuthRoutes 註冊雙人授權 API：查詢待核准請求，並由第二位操作員核准。
+func registerDualAuthRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc) {
+	group := r.Group("/dual-auth", authMiddleware)
+
+	group.GET("", func(c *gin.Context) {
+		requests := policyEngine.PendingDualAuth()
+		c.JSON(http.StatusOK, gin.H{"requests": requests, "count": len(requests)})
+	})
+
+	group.GET("/:id", func(c *gin.Context) {
+		req, ok := policyEngine.GetDualAuth(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": policy.ErrDualAuthNotFound.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, req)
+	})
+
+	group.POST("/:id/approve", func(c *gin.Context) {
+		req, err := policyEngine.ApproveDualAuth(c.Param("id"), operatorIdentity(c), c.GetString("operatorRole"))
+		switch {
+		case errors.Is(err, policy.ErrDualAuthNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, policy.ErrDualAuthSameOperator), errors.Is(err, policy.ErrDualAuthApproverRole):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, policy.ErrDualAuthAlreadyDecided):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		auditDualAuth(c, "dual_auth_approved", req)
+		c.JSON(http.StatusOK, req)
+	})
+}
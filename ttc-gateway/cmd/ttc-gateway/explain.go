@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/ttc-gateway/internal/policy"
+)
+
+// PolicyExplainRequest 是 /policy/explain 的請求內容；未指定角色時使用驗證後的操作員角色，
+// 未指定任務階段時使用目前的任務階段（執行期設定或 MISSION_PHASE）。
+type PolicyExplainRequest struct {
+	Command      string `json:"command" form:"command" binding:"required"`
+	OperatorRole string `json:"operatorRole,omitempty" form:"operatorRole"`
+	SatelliteID  string `json:"satelliteId,omitempty" form:"satelliteId"`
+	MissionPhase string `json:"missionPhase,omitempty" form:"missionPhase"`
+}
+
+// registerExplainRoutes 註冊 GET/POST /policy/explain：說明指令會被哪些規則命中及最終決策，
+// 不轉送、不記錄、不觸發異常偵測。
+func registerExplainRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc) {
+	handler := func(c *gin.Context) {
+		var req PolicyExplainRequest
+		var err error
+		if c.Request.Method == http.MethodGet {
+			err = c.ShouldBindQuery(&req)
+		} else {
+			err = c.ShouldBindJSON(&req)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if req.OperatorRole == "" {
+			req.OperatorRole = c.GetString("operatorRole")
+		}
+		if req.MissionPhase == "" {
+			req.MissionPhase = currentMissionPhase()
+		}
+
+		policyCtx := policy.CommandContext{
+			Command:      req.Command,
+			OperatorRole: req.OperatorRole,
+			SatelliteID:  req.SatelliteID,
+			MissionPhase: req.MissionPhase,
+			TimeOfDay:    time.Now(),
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"command":      req.Command,
+			"operatorRole": req.OperatorRole,
+			"missionPhase": req.MissionPhase,
+			"decision":     policyEngine.Evaluate(policyCtx),
+			"rules":        policyEngine.Explain(policyCtx),
+		})
+	}
+
+	r.GET("/policy/explain", authMiddleware, handler)
+	r.POST("/policy/explain", authMiddleware, handler)
+}
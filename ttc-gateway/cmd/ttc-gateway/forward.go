@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"actinspace.org/internal/simulation"
+)
+
+// defaultForwardTimeout 是 satellite-sim 處理指令的基本時限，未啟用網路模擬時即為轉發時限。
+const defaultForwardTimeout = 10 * time.Second
+
+// forwardBaseTimeout 是轉發時限中不含模擬延遲的部分（FORWARD_TIMEOUT）。
+var forwardBaseTimeout = defaultForwardTimeout
+
+// configureForwarding 讀取轉發與網路模擬設定：
+//   - FORWARD_TIMEOUT: satellite-sim 處理指令的基本時限（預設 10s）
+//   - NETWORK_SIMULATION: 轉發前模擬的軌道網路狀況（leo、meo、geo、deep_space、degraded；預設停用），
+//     custom 時從 NETWORK_CONDITION_FILE 載入自訂參數
+//   - NETWORK_LATENCY_DISTRIBUTION: 覆寫該軌道預設的延遲分佈（uniform、normal、lognormal、pareto）
+func configureForwarding() {
+	if v := os.Getenv("FORWARD_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("無效的 FORWARD_TIMEOUT: %q", v)
+		}
+		forwardBaseTimeout = d
+	}
+
+	name := os.Getenv("NETWORK_SIMULATION")
+	if name == "" {
+		return
+	}
+	condition, err := simulation.ParseNetworkCondition(name)
+	if err != nil {
+		log.Fatalf("無效的 NETWORK_SIMULATION: %v", err)
+	}
+
+	networkSim = simulation.NewNetworkSimulator()
+	if condition == simulation.Custom {
+		applyCustomCondition(networkSim)
+	} else {
+		networkSim.SetCondition(condition)
+	}
+	if v := os.Getenv("NETWORK_LATENCY_DISTRIBUTION"); v != "" {
+		if err := networkSim.SetLatencyDistribution(simulation.LatencyDistribution(v)); err != nil {
+			log.Fatalf("無效的 NETWORK_LATENCY_DISTRIBUTION: %v", err)
+		}
+	}
+	networkSim.Enable()
+	log.Printf("網路模擬已啟用: %s（轉發時限 %v）", condition, forwardTimeout(0))
+}
+
+// applyCustomCondition 從 NETWORK_CONDITION_FILE（simulation.CustomCondition 的 JSON）套用自訂網路狀況。
+func applyCustomCondition(sim *simulation.NetworkSimulator) {
+	path := os.Getenv("NETWORK_CONDITION_FILE")
+	if path == "" {
+		log.Fatalf("自訂網路狀況需要設定 NETWORK_CONDITION_FILE")
+	}
+	custom, err := simulation.LoadCustomCondition(path)
+	if err != nil {
+		log.Fatalf("無法載入自訂網路狀況: %v", err)
+	}
+	if err := custom.Apply(sim); err != nil {
+		log.Fatalf("無效的自訂網路狀況: %v", err)
+	}
+}
+
+// forwardTimeout 回傳轉發一筆 sizeBytes 指令的時限：基本時限加上目前網路狀況可能的最大模擬延遲，
+// 因此 GEO 與深空的時限遠長於 LEO。
+func forwardTimeout(sizeBytes int) time.Duration {
+	if networkSim == nil {
+		return forwardBaseTimeout
+	}
+	return forwardBaseTimeout + networkSim.MaxDelay(sizeBytes)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"actinspace.org/ttc-gateway/internal/idempotency"
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKeyLength 限制 Idempotency-Key 長度，避免以超長 key 佔用 store。
+const maxIdempotencyKeyLength = 255
+
+// newIdempotencyCache 依環境變數建立 idempotency cache：
+//   - IDEMPOTENCY_TTL: 結果保留時間（預設 24h）
+//   - REDIS_URL: 設定時使用 Redis 讓多個實例共享結果，否則退回單實例記憶體模式
+func newIdempotencyCache() *idempotency.Cache {
+	ttl := 24 * time.Hour
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			log.Printf("無效的 IDEMPOTENCY_TTL %q，使用預設值 %v", v, ttl)
+		} else {
+			ttl = parsed
+		}
+	}
+
+	var store idempotency.Store
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		redisStore, err := idempotency.NewRedisStore(redisURL)
+		if err != nil {
+			log.Printf("無法建立 Redis idempotency store，改用單實例模式: %v", err)
+		} else {
+			store = redisStore
+		}
+	}
+
+	return idempotency.NewCache(store, ttl)
+}
+
+// operatorScope 回傳 idempotency key 的操作員範圍，讓不同操作員的相同 key 互不影響。
+// 有 JWT sub 時以其識別操作員（同一人換發 token 仍視為同一操作員），否則以驗證 token 的雜湊識別，不直接保存 token。
+func operatorScope(c *gin.Context) string {
+	if operatorID := c.GetString("operatorID"); operatorID != "" {
+		sum := sha256.Sum256([]byte("sub:" + operatorID))
+		return hex.EncodeToString(sum[:16])
+	}
+	sum := sha256.Sum256([]byte(c.GetString("token")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// cacheableStatus 判斷回應是否代表請求已生效：成功、policy 拒絕與轉發失敗（衛星可能已收到）都需要保留，
+// 其他 4xx（例如格式錯誤、異常確認）請求未生效，客戶端可以修正後用相同 key 重送。
+func cacheableStatus(status int) bool {
+	return status < http.StatusBadRequest || status == http.StatusForbidden || status >= http.StatusInternalServerError
+}
+
+// responseRecorder 在寫出回應的同時保留一份內容供快取。
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotencyMiddleware 處理 Idempotency-Key：相同操作員在 TTL 內重送同一 key 時直接回傳原本的回應，
+// 不重新評估 policy，也不會再次轉發到衛星。未帶 header 的請求不受影響。
+func idempotencyMiddleware(cache *idempotency.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key too long"})
+			c.Abort()
+			return
+		}
+
+		// body 已由 MaxBodyBytes 限制大小，這裡讀出計算指紋後放回
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		scopedKey := operatorScope(c) + ":" + key
+		entry, reserved, err := cache.Begin(scopedKey)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "idempotency store unavailable"})
+			c.Abort()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case entry == nil:
+				c.JSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still in progress"})
+			case entry.Fingerprint != fingerprint:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			default:
+				logCommandEvent(c.Request.Context(), "idempotent_replay", map[string]interface{}{
+					"operatorRole": c.GetString("operatorRole"),
+					"operatorId":   operatorIdentity(c),
+					"status":       entry.Status,
+				})
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(entry.Status, entry.ContentType, entry.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		completed := false
+		defer func() {
+			// 請求未生效（或 handler panic）時釋放 key，讓客戶端可以重試
+			if !completed {
+				if err := cache.Abandon(scopedKey); err != nil {
+					log.Printf("無法釋放 idempotency key: %v", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if !cacheableStatus(status) {
+			return
+		}
+		if err := cache.Finish(scopedKey, idempotency.Entry{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}); err != nil {
+			log.Printf("無法儲存 idempotency 結果: %v", err)
+			return
+		}
+		completed = true
+	}
+}
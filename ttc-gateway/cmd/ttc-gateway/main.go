@@ -2,15 +2,24 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
 
+	"actinspace.org/internal/logging"
+	"actinspace.org/internal/middleware"
+	"actinspace.org/internal/server"
+	"actinspace.org/internal/socclient"
 	"github.com/gin-gonic/gin"
 	"actinspace.org/ttc-gateway/internal/anomaly"
+	"actinspace.org/ttc-gateway/internal/ml"
 	"actinspace.org/ttc-gateway/internal/policy"
+	"actinspace.org/ttc-gateway/internal/rbac"
 )
 
 // CommandRequest 定義從 ground-station 接收到的指令格式。
@@ -18,6 +27,10 @@ type CommandRequest struct {
 	Command string                 `json:"command" binding:"required"`
 	Params  map[string]interface{} `json:"params,omitempty"`
 	SatelliteID string             `json:"satelliteId,omitempty"`
+	// ConfirmAnomaly 由操作員在異常確認後重送時設定（ANOMALY_POLICY_MODE=confirm）
+	ConfirmAnomaly bool `json:"confirmAnomaly,omitempty"`
+	// ApprovalID 是第二位操作員核准後重送指令時帶上的雙人授權請求 ID
+	ApprovalID string `json:"approvalId,omitempty"`
 }
 
 // CommandResponse 是 gateway 回應的格式。
@@ -26,29 +39,112 @@ type CommandResponse struct {
 	Message     string    `json:"message"`
 	Decision    string    `json:"decision"` // "allowed" or "denied"
 	Reason      string    `json:"reason,omitempty"`
+	RequiresConfirmation bool `json:"requiresConfirmation,omitempty"`
+	// RequiresApproval 表示指令需要第二位操作員核准，核准後以 ApprovalID 重送
+	RequiresApproval  bool       `json:"requiresApproval,omitempty"`
+	ApprovalID        string     `json:"approvalId,omitempty"`
+	ApprovalExpiresAt *time.Time `json:"approvalExpiresAt,omitempty"`
 	ProcessedAt time.Time `json:"processedAt"`
 }
 
-// 全域變數：policy 引擎和異常偵測器
+// 全域變數：policy 引擎、角色設定和異常偵測器
 var (
 	policyEngine  *policy.Engine
+	roleStore     *rbac.Store
 	anomalyDetector *anomaly.Detector
+	// socSender 在背景將事件送往 Space-SOC（重試與 spool，見 internal/socclient）
+	socSender *socclient.Sender
 )
 
 // 初始化 policy 和異常偵測
 func init() {
-	policyEngine = policy.NewEngine()
-	anomalyDetector = anomaly.NewDetector(anomaly.Config{})
+	var err error
+	roleStore, err = rbac.NewStore(os.Getenv("RBAC_STORE_PATH"))
+	if err != nil {
+		log.Fatalf("無法載入 RBAC 設定: %v", err)
+	}
+	policyEngine = policy.NewEngineWithRoles(roleStore)
+	if path := os.Getenv("POLICY_RULES_FILE"); path != "" {
+		if err := policyEngine.LoadRulesFromFile(path); err != nil {
+			log.Fatalf("無法載入 policy 規則: %v", err)
+		}
+	}
+	if path := os.Getenv("SATELLITE_OVERRIDES_FILE"); path != "" {
+		if err := loadSatelliteOverrides(path); err != nil {
+			log.Fatalf("無法載入衛星指令覆寫: %v", err)
+		}
+	}
+	if path := os.Getenv("MAINTENANCE_WINDOWS_FILE"); path != "" {
+		if err := loadMaintenanceWindows(path); err != nil {
+			log.Fatalf("無法載入維護時段: %v", err)
+		}
+	}
+	anomalyDetector = anomaly.NewDetector(loadAnomalyConfig())
+	configureAnomalyPolicy()
+	configureDualAuth()
+	configureForwarding()
+	configureMissionPhase()
+}
+
+// loadSatelliteOverrides 從 JSON 檔（SatelliteOverride 陣列）載入各衛星的指令覆寫。
+func loadSatelliteOverrides(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var overrides []policy.SatelliteOverride
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, override := range overrides {
+		if override.SatelliteID == "" {
+			return fmt.Errorf("parse %s: satelliteId is required", path)
+		}
+		policyEngine.SetSatelliteOverride(override.SatelliteID, override.Allow, override.Deny)
+	}
+	return nil
+}
+
+// loadMaintenanceWindows 從 JSON 檔（Window 陣列，時間為 RFC3339）載入維護時段。
+func loadMaintenanceWindows(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var windows []policy.Window
+	if err := json.Unmarshal(data, &windows); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return policyEngine.SetMaintenanceWindows(windows)
 }
 
-// 轉發指令到 satellite-sim
-func forwardToSatellite(satelliteURL string, req CommandRequest) (*CommandResponse, error) {
+// 轉發指令到 satellite-sim（帶上 X-Request-ID 以便跨服務關聯日誌）。
+// 啟用網路模擬時先套用模擬延遲；整段轉發受 forwardTimeout 限制，逾時回傳 context.DeadlineExceeded。
+func forwardToSatellite(ctx context.Context, satelliteURL string, req CommandRequest) (*CommandResponse, error) {
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.Post(satelliteURL+"/command", "application/json", bytes.NewBuffer(reqBody))
+	ctx, cancel := context.WithTimeout(ctx, forwardTimeout(len(reqBody)))
+	defer cancel()
+
+	if networkSim != nil {
+		if err := networkSim.SimulateCommandDelayContext(ctx, req.Command, len(reqBody)); err != nil {
+			return nil, err
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, satelliteURL+"/command", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		httpReq.Header.Set(logging.RequestIDHeader, requestID)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -62,21 +158,12 @@ func forwardToSatellite(satelliteURL string, req CommandRequest) (*CommandRespon
 	return &cmdResp, nil
 }
 
-// 記錄結構化日誌
-func logCommandEvent(eventType string, data map[string]interface{}) {
-	logData := map[string]interface{}{
-		"component": "ttc-gateway",
-		"event":     eventType,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-	}
-	for k, v := range data {
-		logData[k] = v
-	}
-	jsonData, _ := json.Marshal(logData)
-	log.Println(string(jsonData))
+// 記錄結構化日誌（帶上請求的 requestId）
+func logCommandEvent(ctx context.Context, eventType string, data map[string]interface{}) {
+	logging.Event(ctx, eventType, data)
 }
 
-// 發送事件到 Space-SOC
+// 發送事件到 Space-SOC：由 socSender 在背景送出，失敗時重試，仍失敗則寫入 spool 待 SOC 恢復後重送
 func sendEventToSOC(socURL string, event map[string]interface{}) {
 	if socURL == "" {
 		return // 如果未設定 SOC URL，跳過
@@ -87,21 +174,15 @@ func sendEventToSOC(socURL string, event map[string]interface{}) {
 		log.Printf("無法序列化事件: %v", err)
 		return
 	}
-
-	resp, err := http.Post(socURL+"/api/v1/events", "application/json", bytes.NewBuffer(eventData))
-	if err != nil {
-		log.Printf("無法發送事件到 Space-SOC: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		log.Printf("Space-SOC 回應錯誤狀態碼: %d", resp.StatusCode)
-	}
+	socSender.Send(socURL+"/api/v1/events", eventData, "")
 }
 
 func main() {
-	r := gin.Default()
+	logger := logging.Setup("ttc-gateway")
+	socSender = socclient.New()
+
+	r := gin.New()
+	r.Use(gin.Recovery(), logging.Middleware(logger))
 
 	// 從環境變數讀取配置
 	satelliteURL := os.Getenv("SATELLITE_SIM_URL")
@@ -109,38 +190,50 @@ func main() {
 		satelliteURL = "http://satellite-sim:8082"
 	}
 
-	// Token 驗證中間件（簡化版，Phase 1 MVP）
-	authMiddleware := func(c *gin.Context) {
-		token := c.GetHeader("Authorization")
-		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization token"})
-			c.Abort()
-			return
-		}
-
-		// 簡化的 token 驗證（實際應使用 JWT 或 OIDC）
-		// 這裡假設 token 格式為 "Bearer <role>"
-		role := "operator" // 預設角色
-		if len(token) > 7 && token[:7] == "Bearer " {
-			roleToken := token[7:]
-			// 簡單的角色映射（實際應從 token 解析）
-			if roleToken == "admin-token" {
-				role = "admin"
-			} else if roleToken == "engineer-token" {
-				role = "engineer"
-			}
-		}
-
-		c.Set("operatorRole", role)
-		c.Set("token", token)
-		c.Next()
-	}
+	// Token 驗證中間件（JWT；AUTH_DEV_MODE=true 時接受示範 token）
+	authMiddleware := newAuthMiddleware(loadAuthConfig())
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
-	r.POST("/command", authMiddleware, func(c *gin.Context) {
+	// 就緒檢查：確認 satellite-sim（與選擇性的 Space-SOC）可連線（結果短暫快取）；/readyz 為舊路徑
+	readiness := newReadinessChecker(satelliteURL)
+	r.GET("/ready", readiness.Handler())
+	r.GET("/readyz", readiness.Handler())
+
+	// Prometheus 指標
+	r.GET("/metrics", metricsHandler())
+
+	// 角色管理 API（僅限 admin）
+	registerRBACRoutes(r, authMiddleware)
+
+	// 重放保護（REPLAY_PROTECTION=true 時啟用，nonce 需以 REPLAY_SIGNING_SECRET 簽章，可透過 REDIS_URL 跨實例共享 nonce）
+	replayGuard, replaySecret := newReplayGuard()
+
+	// Idempotency-Key：重試時回傳原本的結果，避免指令重複執行
+	idempotencyCache := newIdempotencyCache()
+
+	// 依角色與衛星限制指令速率（在 policy 評估之前）
+	rateLimiter := newCommandRateLimiter()
+
+	// 唯讀的設定狀態（policy 規則、異常偵測與模擬設定）
+	registerStatusRoutes(r, authMiddleware, replayGuard, idempotencyCache, rateLimiter)
+
+	// ML 異常分數試算（不計入模型）與模型檢視
+	registerMLRoutes(r, authMiddleware)
+	registerExplainRoutes(r, authMiddleware)
+
+	// 雙人授權（two-person rule）核准
+	registerDualAuthRoutes(r, authMiddleware)
+
+	// 任務階段查詢與執行期設定（設定僅限 admin）
+	registerMissionPhaseRoutes(r, authMiddleware)
+
+	// 即時決策事件串流（WebSocket）
+	r.GET("/command/stream", streamTokenFromQuery, authMiddleware, commandStreamHandler)
+
+	r.POST("/command", middleware.MaxBodyBytes(middleware.MaxBodyBytesFromEnv()), authMiddleware, replayMiddleware(replayGuard, replaySecret), idempotencyMiddleware(idempotencyCache), rateLimiter.middleware(), func(c *gin.Context) {
 		var req CommandRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -149,6 +242,7 @@ func main() {
 
 		operatorRole, _ := c.Get("operatorRole")
 		roleStr := operatorRole.(string)
+		operatorID := operatorIdentity(c)
 
 		// 異常偵測（在 policy 評估之前）
 		timestamp := time.Now().UTC()
@@ -157,31 +251,55 @@ func main() {
 		// 如果有異常，發送到 Space-SOC
 		socURL := os.Getenv("SPACE_SOC_URL")
 		for _, anom := range anomalies {
-			logCommandEvent("anomaly_detected", map[string]interface{}{
+			anomaliesDetected.WithLabelValues("rule", string(anom.Type), string(anom.Severity)).Inc()
+			logCommandEvent(c.Request.Context(), "anomaly_detected", map[string]interface{}{
 				"type":         anom.Type,
 				"command":      anom.Command,
 				"operatorRole": anom.OperatorRole,
+				"operatorId":   operatorID,
 				"message":      anom.Message,
 				"severity":     anom.Severity,
 			})
 
-			sendEventToSOC(socURL, map[string]interface{}{
+			anomalyEvent := map[string]interface{}{
 				"component":    "ttc-gateway",
 				"eventType":    "anomaly_detected",
 				"anomalyType":  string(anom.Type),
 				"command":      anom.Command,
 				"operatorRole": anom.OperatorRole,
+				"operatorId":   operatorID,
 				"message":      anom.Message,
 				"severity":     anom.Severity,
 				"metadata":     anom.Metadata,
-			})
+			}
+			sendEventToSOC(socURL, anomalyEvent)
+			publishDecisionEvent("anomaly_detected", roleStr, req.SatelliteID, anomalyEvent)
 		}
 
-		// Policy 評估（使用新的 policy 引擎）
-		missionPhase := os.Getenv("MISSION_PHASE")
-		if missionPhase == "" {
-			missionPhase = "normal"
+		// ML 異常分數（啟用時）
+		var mlScore *ml.AnomalyScore
+		if mlDetector != nil {
+			score := mlDetector.DetectAnomaly(req.SatelliteID, req.Command, roleStr, req.Params)
+			mlScore = &score
+
+			if score.IsAnomaly {
+				mlEvent := mlAnomalyEvent(mlScore, req.Command, roleStr, operatorID, req.SatelliteID)
+				anomaliesDetected.WithLabelValues("ml", "ml_score", string(mlActionSeverity(score.RecommendedAction))).Inc()
+				logCommandEvent(c.Request.Context(), "ml_anomaly_detected", map[string]interface{}{
+					"command":           req.Command,
+					"operatorRole":      roleStr,
+					"operatorId":        operatorID,
+					"score":             score.Score,
+					"recommendedAction": score.RecommendedAction,
+					"model":             score.Model,
+				})
+				sendEventToSOC(socURL, mlEvent)
+				publishDecisionEvent("ml_anomaly_detected", roleStr, req.SatelliteID, mlEvent)
+			}
 		}
+
+		// Policy 評估（使用新的 policy 引擎）
+		missionPhase := currentMissionPhase()
 		
 		policyCtx := policy.CommandContext{
 			Command:      req.Command,
@@ -189,35 +307,109 @@ func main() {
 			SatelliteID:  req.SatelliteID,
 			MissionPhase: missionPhase,
 			TimeOfDay:    timestamp,
+			Anomaly:      strongestAnomaly(anomalies, mlScore),
+			AnomalyConfirmed: req.ConfirmAnomaly,
+			OperatorID:       operatorID,
+			ApprovalID:       req.ApprovalID,
 		}
 		
 		decision := policyEngine.Evaluate(policyCtx)
+		// ML 建議 block_and_alert 時一律拒絕
+		decision = applyMLBlock(decision, mlScore)
+
+		// 雙人授權：建立待核准請求（重送仍在等待核准的請求時沿用原本的請求）
+		var dualAuth *policy.DualAuthRequest
+		if decision.RequiresApproval {
+			pending, ok := policyEngine.GetDualAuth(decision.ApprovalID)
+			if !ok {
+				pending = policyEngine.RequestDualAuth(policyCtx)
+				auditDualAuth(c, "dual_auth_requested", pending)
+			}
+			dualAuth = &pending
+		}
 
 		// 記錄決策
 		decisionStr := "denied"
 		if decision.Allowed {
 			decisionStr = "allowed"
 		}
-		logCommandEvent("policy_decision", map[string]interface{}{
+		decisionLabel := decisionStr
+		switch {
+		case decision.RequiresConfirmation:
+			decisionLabel = "confirmation_required"
+		case dualAuth != nil:
+			decisionLabel = "pending_approval"
+		}
+		commandDecisions.WithLabelValues(decisionLabel, decision.RuleID, commandLabel(req.Command)).Inc()
+		logCommandEvent(c.Request.Context(), "policy_decision", map[string]interface{}{
 			"command":      req.Command,
 			"operatorRole": roleStr,
+			"operatorId":   operatorID,
 			"decision":     decisionStr,
 			"reason":       decision.Reason,
 			"ruleID":       decision.RuleID,
 			"severity":     decision.Severity,
+			"requiresConfirmation": decision.RequiresConfirmation,
 		})
 
-		// 發送到 Space-SOC
-		sendEventToSOC(socURL, map[string]interface{}{
+		// 發送到 Space-SOC，並推送給即時訂閱者
+		decisionEvent := map[string]interface{}{
 			"component":    "ttc-gateway",
 			"eventType":    "policy_decision",
 			"command":      req.Command,
 			"operatorRole": roleStr,
+			"operatorId":   operatorID,
 			"decision":     decisionStr,
 			"reason":       decision.Reason,
 			"ruleID":       decision.RuleID,
 			"severity":     decision.Severity,
-		})
+		}
+		// 促成決策的異常一併送出，方便在 SOC 關聯
+		metadata := map[string]interface{}{}
+		if decision.Anomaly != nil {
+			decisionEvent["anomalyType"] = decision.Anomaly.Type
+			metadata["anomaly"] = anomalyEventFields(decision.Anomaly)
+			metadata["anomalyMode"] = string(policyEngine.AnomalyCouplingConfig().Mode)
+			metadata["requiresConfirmation"] = decision.RequiresConfirmation
+		}
+		// ML 判定為異常時附上精簡的分數解釋
+		if mlScore != nil && mlScore.IsAnomaly {
+			metadata["ml"] = mlScoreEventFields(mlScore)
+		}
+		if dualAuth != nil {
+			metadata["approvalId"] = dualAuth.ID
+		}
+		if len(metadata) > 0 {
+			decisionEvent["metadata"] = metadata
+		}
+		sendEventToSOC(socURL, decisionEvent)
+		publishDecisionEvent("policy_decision", roleStr, req.SatelliteID, decisionEvent)
+
+		if decision.RequiresConfirmation {
+			c.JSON(http.StatusPreconditionRequired, CommandResponse{
+				Status:               "confirmation_required",
+				Message:              "command flagged as anomalous; resubmit with confirmAnomaly=true to proceed",
+				Decision:             "denied",
+				Reason:               decision.Reason,
+				RequiresConfirmation: true,
+				ProcessedAt:          time.Now().UTC(),
+			})
+			return
+		}
+
+		if dualAuth != nil {
+			c.JSON(http.StatusAccepted, CommandResponse{
+				Status:            "pending_approval",
+				Message:           "command requires a second operator's approval; resubmit with approvalId once approved",
+				Decision:          "denied",
+				Reason:            decision.Reason,
+				RequiresApproval:  true,
+				ApprovalID:        dualAuth.ID,
+				ApprovalExpiresAt: &dualAuth.ExpiresAt,
+				ProcessedAt:       time.Now().UTC(),
+			})
+			return
+		}
 
 		if !decision.Allowed {
 			resp := CommandResponse{
@@ -231,10 +423,58 @@ func main() {
 			return
 		}
 
+		// 已核准的雙人授權只能使用一次（同時重送時僅一個成功）
+		if req.ApprovalID != "" && policyEngine.RequiresDualAuth(req.Command) {
+			if err := policyEngine.ConsumeDualAuth(policyCtx); err != nil {
+				c.JSON(http.StatusForbidden, CommandResponse{
+					Status:      "denied",
+					Message:     "command rejected by policy",
+					Decision:    "denied",
+					Reason:      err.Error(),
+					ProcessedAt: time.Now().UTC(),
+				})
+				return
+			}
+		}
+
+		// 只有最終允許的指令計入 ML baseline；被拒絕（包括 ML 自己阻擋）的指令不能教模型把重試視為正常
+		if mlDetector != nil {
+			mlDetector.RecordCommand(req.SatelliteID, req.Command, roleStr, req.Params)
+		}
+
 		// 轉發到 satellite-sim
-		satResp, err := forwardToSatellite(satelliteURL, req)
+		forwardStart := time.Now()
+		satResp, err := forwardToSatellite(c.Request.Context(), satelliteURL, req)
+		if errors.Is(err, context.DeadlineExceeded) {
+			observeForward(req.Command, "timeout", forwardStart)
+			timeout := forwardTimeout(0).String()
+			logCommandEvent(c.Request.Context(), "forward_timeout", map[string]interface{}{
+				"command":      req.Command,
+				"operatorRole": roleStr,
+				"operatorId":   operatorID,
+				"satelliteId":  req.SatelliteID,
+				"timeout":      timeout,
+			})
+			sendEventToSOC(socURL, map[string]interface{}{
+				"component":    "ttc-gateway",
+				"eventType":    "forward_timeout",
+				"command":      req.Command,
+				"operatorRole": roleStr,
+				"operatorId":   operatorID,
+				"message":      fmt.Sprintf("satellite did not respond within %s", timeout),
+				"severity":     "medium",
+				"metadata": map[string]interface{}{
+					"satelliteId": req.SatelliteID,
+					"timeout":     timeout,
+					"network":     networkStatus(),
+				},
+			})
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "satellite did not respond in time"})
+			return
+		}
 		if err != nil {
-			logCommandEvent("forward_error", map[string]interface{}{
+			observeForward(req.Command, "error", forwardStart)
+			logCommandEvent(c.Request.Context(), "forward_error", map[string]interface{}{
 				"command": req.Command,
 				"error":   err.Error(),
 			})
@@ -243,9 +483,11 @@ func main() {
 		}
 
 		// 記錄成功
-		logCommandEvent("command_forwarded", map[string]interface{}{
+		observeForward(req.Command, "success", forwardStart)
+		logCommandEvent(c.Request.Context(), "command_forwarded", map[string]interface{}{
 			"command":      req.Command,
 			"operatorRole": roleStr,
+			"operatorId":   operatorID,
 			"satelliteResponse": satResp.Status,
 		})
 
@@ -255,6 +497,7 @@ func main() {
 			"eventType":    "command_forwarded",
 			"command":      req.Command,
 			"operatorRole": roleStr,
+			"operatorId":   operatorID,
 			"status":       satResp.Status,
 			"message":      satResp.Message,
 		})
@@ -274,7 +517,20 @@ func main() {
 		port = "8081"
 	}
 
-	if err := r.Run(":" + port); err != nil {
+	// 收到 SIGTERM 時先結束 WebSocket 串流並等待處理中的指令，再寫入異常偵測的滑動視窗與 ML 模型，尚未送出的 SOC 事件寫入 spool
+	srv := server.New(":"+port, r)
+	srv.OnShutdown(decisionHub.Close)
+	srv.AddCleanup("anomaly_state", func(context.Context) error {
+		return anomalyDetector.SaveState()
+	})
+	srv.AddCleanup("ml_model", func(context.Context) error {
+		if mlDetector == nil {
+			return nil
+		}
+		return mlDetector.SaveModel()
+	})
+	srv.AddCleanup("soc_sender", socSender.Close)
+	if err := srv.Run(); err != nil {
 		log.Fatalf("ttc-gateway server failed: %v", err)
 	}
 }
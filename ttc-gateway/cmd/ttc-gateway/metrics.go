@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// maxCommandLabels 是 command 標籤最多保留的不同值；指令名稱由用戶端送出，
+// 超過上限後的新指令一律記為 "other"，避免任意字串造成時間序列無限增長。
+const maxCommandLabels = 64
+
+// Prometheus 指標（GET /metrics，Prometheus text 格式）。
+var (
+	commandDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ttc_gateway_command_decisions_total",
+		Help: "Policy decisions for submitted commands by decision, rule and command.",
+	}, []string{"decision", "rule", "command"})
+
+	anomaliesDetected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ttc_gateway_anomalies_total",
+		Help: "Anomalies detected before policy evaluation by source (rule or ml), type and severity.",
+	}, []string{"source", "type", "severity"})
+
+	// 轉發延遲包含模擬的軌道網路延遲，因此上限涵蓋深空的數分鐘
+	forwardDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ttc_gateway_forward_duration_seconds",
+		Help:    "Time spent forwarding allowed commands to satellite-sim by command and outcome (success, timeout, error).",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2.5, 12),
+	}, []string{"command", "outcome"})
+)
+
+// commandLabels 記錄已用於 command 標籤的指令名稱。
+var commandLabels = struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}{seen: make(map[string]bool)}
+
+// commandLabel 回傳指令的 command 標籤值；已見過的指令超過 maxCommandLabels 後，新指令回傳 "other"。
+func commandLabel(command string) string {
+	commandLabels.mu.Lock()
+	defer commandLabels.mu.Unlock()
+
+	if commandLabels.seen[command] {
+		return command
+	}
+	if len(commandLabels.seen) >= maxCommandLabels {
+		return "other"
+	}
+	commandLabels.seen[command] = true
+	return command
+}
+
+// observeForward 記錄一次轉發的延遲與結果。
+func observeForward(command, outcome string, start time.Time) {
+	forwardDuration.WithLabelValues(commandLabel(command), outcome).Observe(time.Since(start).Seconds())
+}
+
+// metricsHandler 提供預設 registry 的指標（含 Go runtime 與 process 指標）；與 /health 一樣不需要驗證，
+// 僅應開放給叢集內的 Prometheus 抓取。
+func metricsHandler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/ttc-gateway/internal/policy"
+)
+
+// missionPhaseOverride 是 admin 在執行期間設定的任務階段；未設定或已過期時使用 MISSION_PHASE。
+var missionPhaseOverride struct {
+	mu        sync.Mutex
+	phase     string
+	expiresAt time.Time // 零值表示不會自動過期
+}
+
+// configureMissionPhase 驗證 MISSION_PHASE（normal、critical、safe_mode、maintenance；預設 normal）。
+func configureMissionPhase() {
+	if v := os.Getenv("MISSION_PHASE"); v != "" && !policy.IsValidMissionPhase(v) {
+		log.Fatalf("無效的 MISSION_PHASE: %q", v)
+	}
+}
+
+// defaultMissionPhase 回傳 MISSION_PHASE 設定的任務階段，未設定時為 normal。
+func defaultMissionPhase() string {
+	if v := os.Getenv("MISSION_PHASE"); v != "" {
+		return v
+	}
+	return "normal"
+}
+
+// currentMissionPhase 回傳目前的任務階段：優先使用未過期的執行期設定，否則為 MISSION_PHASE。
+func currentMissionPhase() string {
+	phase, _ := missionPhaseState()
+	return phase
+}
+
+// missionPhaseState 回傳目前的任務階段與執行期設定的過期時間（沒有執行期設定時為 nil）。
+func missionPhaseState() (string, *time.Time) {
+	missionPhaseOverride.mu.Lock()
+	defer missionPhaseOverride.mu.Unlock()
+
+	if missionPhaseOverride.phase == "" {
+		return defaultMissionPhase(), nil
+	}
+	if !missionPhaseOverride.expiresAt.IsZero() && !time.Now().Before(missionPhaseOverride.expiresAt) {
+		missionPhaseOverride.phase = ""
+		return defaultMissionPhase(), nil
+	}
+	expiresAt := missionPhaseOverride.expiresAt
+	return missionPhaseOverride.phase, &expiresAt
+}
+
+// setMissionPhase 設定執行期任務階段（duration 為 0 時不會自動過期）；phase 為空字串時回到 MISSION_PHASE。
+// 回傳設定前的任務階段。
+func setMissionPhase(phase string, duration time.Duration) string {
+	previous := currentMissionPhase()
+
+	missionPhaseOverride.mu.Lock()
+	defer missionPhaseOverride.mu.Unlock()
+	missionPhaseOverride.phase = phase
+	missionPhaseOverride.expiresAt = time.Time{}
+	if phase != "" && duration > 0 {
+		missionPhaseOverride.expiresAt = time.Now().Add(duration)
+	}
+	return previous
+}
+
+// auditMissionPhaseChange 記錄任務階段變更並發送稽核事件到 Space-SOC。
+func auditMissionPhaseChange(c *gin.Context, previous, phase string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["previous"] = previous
+	metadata["phase"] = phase
+
+	logCommandEvent(c.Request.Context(), "mission_phase_changed", map[string]interface{}{
+		"operatorRole": c.GetString("operatorRole"),
+		"operatorId":   operatorIdentity(c),
+		"previous":     previous,
+		"phase":        phase,
+	})
+
+	sendEventToSOC(os.Getenv("SPACE_SOC_URL"), map[string]interface{}{
+		"component":    "ttc-gateway",
+		"eventType":    "mission_phase_changed",
+		"operatorRole": c.GetString("operatorRole"),
+		"operatorId":   operatorIdentity(c),
+		"message":      fmt.Sprintf("mission phase changed: %s -> %s", previous, phase),
+		"severity":     "medium",
+		"metadata":     metadata,
+	})
+}
+
+// registerMissionPhaseRoutes 註冊任務階段 API：GET 查詢目前階段，PUT / DELETE 設定或清除執行期階段（僅限 admin）。
+// 威脅場景重演以此讓 gateway 在場景期間處於指定階段，不需重新啟動或修改 MISSION_PHASE。
+func registerMissionPhaseRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc) {
+	r.GET("/mission-phase", authMiddleware, func(c *gin.Context) {
+		c.JSON(http.StatusOK, missionPhaseResponse())
+	})
+
+	// 設定執行期任務階段；duration（Go duration）到期後自動回到 MISSION_PHASE
+	r.PUT("/mission-phase", authMiddleware, requireAdmin, func(c *gin.Context) {
+		var req struct {
+			Phase    string `json:"phase" binding:"required"`
+			Duration string `json:"duration"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !policy.IsValidMissionPhase(req.Phase) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid mission phase %q", req.Phase)})
+			return
+		}
+		var duration time.Duration
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid duration %q", req.Duration)})
+				return
+			}
+			duration = d
+		}
+
+		previous := setMissionPhase(req.Phase, duration)
+		auditMissionPhaseChange(c, previous, req.Phase, map[string]interface{}{"duration": req.Duration})
+
+		resp := missionPhaseResponse()
+		resp["previous"] = previous
+		c.JSON(http.StatusOK, resp)
+	})
+
+	// 清除執行期任務階段，回到 MISSION_PHASE
+	r.DELETE("/mission-phase", authMiddleware, requireAdmin, func(c *gin.Context) {
+		previous := setMissionPhase("", 0)
+		auditMissionPhaseChange(c, previous, defaultMissionPhase(), nil)
+
+		resp := missionPhaseResponse()
+		resp["previous"] = previous
+		c.JSON(http.StatusOK, resp)
+	})
+}
+
+func missionPhaseResponse() gin.H {
+	phase, expiresAt := missionPhaseState()
+	resp := gin.H{
+		"phase":    phase,
+		"default":  defaultMissionPhase(),
+		"override": expiresAt != nil,
+	}
+	if expiresAt != nil && !expiresAt.IsZero() {
+		resp["expiresAt"] = expiresAt.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
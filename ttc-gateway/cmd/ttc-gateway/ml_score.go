@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/ttc-gateway/internal/ml"
+)
+
+// MLScoreRequest 是 POST /ml/score 的請求內容；角色取自驗證後的操作員。
+type MLScoreRequest struct {
+	Command     string                 `json:"command" binding:"required"`
+	SatelliteID string                 `json:"satelliteId,omitempty"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+}
+
+// registerMLRoutes 註冊 POST /ml/score：以目前的 ML 模型為指令評分並回傳各項分數與特徵貢獻，
+// 但不記錄指令，因此不會影響模型；另註冊 admin 專用的 /anomaly 模型檢視、重設與重新訓練 API。
+func registerMLRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc) {
+	r.POST("/ml/score", authMiddleware, func(c *gin.Context) {
+		if mlDetector == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ML anomaly detection is disabled"})
+			return
+		}
+
+		var req MLScoreRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		score := mlDetector.DetectAnomaly(req.SatelliteID, req.Command, c.GetString("operatorRole"), req.Params)
+		c.JSON(http.StatusOK, score)
+	})
+
+	// 模型檢視（僅限 admin）：統計與學到的 baseline，用來排查誤報
+	anomaly := r.Group("/anomaly", authMiddleware, requireAdmin)
+	anomaly.GET("/stats", requireMLDetector, func(c *gin.Context) {
+		c.JSON(http.StatusOK, mlDetector.GetStatistics())
+	})
+	anomaly.GET("/baselines", requireMLDetector, func(c *gin.Context) {
+		snapshot, ok := mlDetector.Baselines(c.Query("satelliteId"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no model for satellite"})
+			return
+		}
+		c.JSON(http.StatusOK, snapshot)
+	})
+
+	// 模型重設與重新訓練（僅限 admin）：baseline 遭攻擊或設定變更污染時使用
+	anomaly.POST("/reset", requireMLDetector, func(c *gin.Context) {
+		if err := mlDetector.Reset(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		auditMLModelChange(c, "reset", nil)
+		c.JSON(http.StatusOK, mlDetector.GetStatistics())
+	})
+	anomaly.POST("/prune", requireMLDetector, func(c *gin.Context) {
+		var req struct {
+			Before time.Time `json:"before" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		removed := mlDetector.PruneHistoryBefore(req.Before)
+		auditMLModelChange(c, "pruned", map[string]interface{}{
+			"before":  req.Before.UTC().Format(time.RFC3339),
+			"removed": removed,
+		})
+		c.JSON(http.StatusOK, gin.H{"removed": removed, "before": req.Before})
+	})
+	// 以合成流量試算（僅限 admin）：依時間戳記在全新的模型上重演指令並回傳每筆的分數，不影響執行中的模型
+	anomaly.POST("/simulate", requireMLDetector, func(c *gin.Context) {
+		var req struct {
+			Commands []ml.SimulatedCommand `json:"commands" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.Commands) > mlSimulationMaxCommands {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d commands per simulation", mlSimulationMaxCommands)})
+			return
+		}
+		results, err := mlDetector.Simulate(req.Commands)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		anomalies, maxScore := 0, 0.0
+		for _, result := range results {
+			if result.Score.IsAnomaly {
+				anomalies++
+			}
+			maxScore = max(maxScore, result.Score.Score)
+		}
+		c.JSON(http.StatusOK, gin.H{"results": results, "count": len(results), "anomalies": anomalies, "maxScore": maxScore})
+	})
+	anomaly.POST("/retrain", requireMLDetector, func(c *gin.Context) {
+		if err := mlDetector.RetrainFromHistory(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		auditMLModelChange(c, "retrained", nil)
+		c.JSON(http.StatusOK, mlDetector.GetStatistics())
+	})
+}
+
+// mlSimulationMaxCommands 是 POST /anomaly/simulate 一次可重演的指令數上限。
+const mlSimulationMaxCommands = 10000
+
+// requireMLDetector 在未啟用 ML 時回傳 503。
+func requireMLDetector(c *gin.Context) {
+	if mlDetector == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ML anomaly detection is disabled"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// auditMLModelChange 記錄 ML 模型的重設、清除與重新訓練，並發送稽核事件到 Space-SOC。
+func auditMLModelChange(c *gin.Context, action string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["action"] = action
+
+	logCommandEvent(c.Request.Context(), "ml_model_changed", map[string]interface{}{
+		"operatorRole": c.GetString("operatorRole"),
+		"operatorId":   operatorIdentity(c),
+		"action":       action,
+	})
+
+	sendEventToSOC(os.Getenv("SPACE_SOC_URL"), map[string]interface{}{
+		"component":    "ttc-gateway",
+		"eventType":    "ml_model_changed",
+		"operatorRole": c.GetString("operatorRole"),
+		"operatorId":   operatorIdentity(c),
+		"message":      fmt.Sprintf("ml model %s", action),
+		"severity":     "medium",
+		"metadata":     metadata,
+	})
+}
+
+// mlScoreEventFields 回傳送往 Space-SOC 的精簡 ML 分數（分數、模型與前三大特徵）。
+func mlScoreEventFields(score *ml.AnomalyScore) map[string]interface{} {
+	return map[string]interface{}{
+		"score":       score.Score,
+		"model":       score.Model,
+		"action":      score.RecommendedAction,
+		"explanation": score.Summary(3),
+	}
+}
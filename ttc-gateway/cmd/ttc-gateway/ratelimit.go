@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/ttc-gateway/internal/ratelimit"
+)
+
+// defaultSatelliteKey 是未指定 satelliteId 的指令所使用的限流 key（單一衛星的模擬環境）。
+const defaultSatelliteKey = "default"
+
+// commandRateLimiter 依操作員角色與目標衛星分別限制 /command 的速率，保護衛星上行鏈路。
+type commandRateLimiter struct {
+	role      *ratelimit.Limiter
+	satellite *ratelimit.Limiter
+}
+
+// newCommandRateLimiter 讀取限流設定（rate 為每秒補充的指令數，設為 0 停用該維度）：
+//   - RATE_LIMIT_ROLE_RATE / RATE_LIMIT_ROLE_BURST: 每個角色（預設 5 / 10）
+//   - RATE_LIMIT_SATELLITE_RATE / RATE_LIMIT_SATELLITE_BURST: 每顆衛星（預設 2 / 5）
+func newCommandRateLimiter() *commandRateLimiter {
+	return &commandRateLimiter{
+		role:      ratelimit.New(loadRateLimitConfig("RATE_LIMIT_ROLE", 5, 10)),
+		satellite: ratelimit.New(loadRateLimitConfig("RATE_LIMIT_SATELLITE", 2, 5)),
+	}
+}
+
+func loadRateLimitConfig(prefix string, rate float64, burst int) ratelimit.Config {
+	config := ratelimit.Config{Rate: rate, Burst: burst}
+	if v := os.Getenv(prefix + "_RATE"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed < 0 {
+			log.Fatalf("無效的 %s_RATE: %q", prefix, v)
+		}
+		config.Rate = parsed
+	}
+	if v := os.Getenv(prefix + "_BURST"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			log.Fatalf("無效的 %s_BURST: %q", prefix, v)
+		}
+		config.Burst = parsed
+	}
+	return config
+}
+
+// middleware 在 policy 評估前檢查角色與衛星的 token bucket，超過時回傳 429 與 Retry-After；只有兩者都放行時才消耗 token。
+// 每個 key 自上次放行後只在第一次被拒絕時發出 rate_limited 事件，避免洪水攻擊放大成事件洪水。
+func (l *commandRateLimiter) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("operatorRole")
+		satelliteID := peekSatelliteID(c)
+
+		scope, key := "role", role
+		result := l.role.Allow(role)
+		if result.Allowed {
+			scope, key = "satellite", satelliteID
+			result = l.satellite.Allow(satelliteID)
+			if !result.Allowed {
+				// 被衛星限流拒絕的請求不消耗角色額度，否則單一衛星的洪水會耗盡同角色所有操作員的額度
+				l.role.Refund(role)
+			}
+		}
+		if result.Allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		if result.FirstRejection {
+			logCommandEvent(c.Request.Context(), "rate_limited", map[string]interface{}{
+				"operatorRole": role,
+				"operatorId":   operatorIdentity(c),
+				"scope":        scope,
+				"key":          key,
+				"retryAfter":   retryAfter,
+			})
+			sendEventToSOC(os.Getenv("SPACE_SOC_URL"), map[string]interface{}{
+				"component":    "ttc-gateway",
+				"eventType":    "rate_limited",
+				"operatorRole": role,
+				"operatorId":   operatorIdentity(c),
+				"message":      "command rate limit exceeded for " + scope + " " + key,
+				"severity":     "medium",
+				"metadata": map[string]interface{}{
+					"scope":             scope,
+					"key":               key,
+					"satelliteId":       satelliteID,
+					"retryAfterSeconds": retryAfter,
+				},
+			})
+		}
+
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":      "command rate limit exceeded",
+			"scope":      scope,
+			"retryAfter": retryAfter,
+		})
+		c.Abort()
+	}
+}
+
+// peekSatelliteID 讀取請求 body 的 satelliteId 後還原 body，供後續的 handler 解析。
+func peekSatelliteID(c *gin.Context) string {
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return defaultSatelliteKey
+	}
+
+	var req struct {
+		SatelliteID string `json:"satelliteId"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.SatelliteID == "" {
+		return defaultSatelliteKey
+	}
+	return req.SatelliteID
+}
+
+func (l *commandRateLimiter) status() gin.H {
+	return gin.H{
+		"role":      rateLimitStatus(l.role),
+		"satellite": rateLimitStatus(l.satellite),
+	}
+}
+
+func rateLimitStatus(limiter *ratelimit.Limiter) gin.H {
+	config := limiter.Config()
+	return gin.H{
+		"enabled":  limiter.Enabled(),
+		"rate":     config.Rate,
+		"burst":    config.Burst,
+		"rejected": limiter.Rejected(),
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"actinspace.org/ttc-gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin 僅允許 admin 角色存取（需在 authMiddleware 之後使用）。
+func requireAdmin(c *gin.Context) {
+	if c.GetString("operatorRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin role required"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// auditRoleChange 記錄角色變更並發送稽核事件到 Space-SOC。
+func auditRoleChange(c *gin.Context, action, roleName string, metadata map[string]interface{}) {
+	message := fmt.Sprintf("rbac %s: %s", action, roleName)
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["action"] = action
+	metadata["role"] = roleName
+
+	logCommandEvent(c.Request.Context(), "rbac_role_changed", map[string]interface{}{
+		"operatorRole": c.GetString("operatorRole"),
+		"operatorId":   operatorIdentity(c),
+		"action":       action,
+		"role":         roleName,
+	})
+
+	sendEventToSOC(os.Getenv("SPACE_SOC_URL"), map[string]interface{}{
+		"component":    "ttc-gateway",
+		"eventType":    "rbac_role_changed",
+		"operatorRole": c.GetString("operatorRole"),
+		"operatorId":   operatorIdentity(c),
+		"message":      message,
+		"severity":     "medium",
+		"metadata":     metadata,
+	})
+}
+
+// registerRBACRoutes 註冊角色管理 API（僅限 admin）。
+func registerRBACRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc) {
+	group := r.Group("/rbac", authMiddleware, requireAdmin)
+
+	group.GET("/roles", func(c *gin.Context) {
+		roles := roleStore.ListRoles()
+		c.JSON(http.StatusOK, gin.H{"roles": roles, "count": len(roles)})
+	})
+
+	group.GET("/roles/:name", func(c *gin.Context) {
+		role, ok := roleStore.GetRole(c.Param("name"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+			return
+		}
+		c.JSON(http.StatusOK, role)
+	})
+
+	// 新增或更新角色
+	group.PUT("/roles/:name", func(c *gin.Context) {
+		var req struct {
+			Description      string   `json:"description"`
+			AllowedCommands  []string `json:"allowedCommands" binding:"required"`
+			DangerousAllowed bool     `json:"dangerousAllowed"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		name := c.Param("name")
+		created, err := roleStore.PutRole(rbac.Role{
+			Name:             name,
+			Description:      req.Description,
+			AllowedCommands:  req.AllowedCommands,
+			DangerousAllowed: req.DangerousAllowed,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		action := "role_updated"
+		status := http.StatusOK
+		if created {
+			action = "role_created"
+			status = http.StatusCreated
+		}
+		auditRoleChange(c, action, name, map[string]interface{}{
+			"allowedCommands":  req.AllowedCommands,
+			"dangerousAllowed": req.DangerousAllowed,
+		})
+
+		role, _ := roleStore.GetRole(name)
+		c.JSON(status, role)
+	})
+
+	group.DELETE("/roles/:name", func(c *gin.Context) {
+		name := c.Param("name")
+		if _, ok := roleStore.GetRole(name); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+			return
+		}
+		if err := roleStore.DeleteRole(name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		auditRoleChange(c, "role_deleted", name, nil)
+		c.JSON(http.StatusOK, gin.H{"deleted": name})
+	})
+
+	group.GET("/dangerous-commands", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"commands": roleStore.DangerousCommands()})
+	})
+
+	group.PUT("/dangerous-commands", func(c *gin.Context) {
+		var req struct {
+			Commands []string `json:"commands" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := roleStore.SetDangerousCommands(req.Commands); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		auditRoleChange(c, "dangerous_commands_updated", "*", map[string]interface{}{
+			"commands": req.Commands,
+		})
+		c.JSON(http.StatusOK, gin.H{"commands": roleStore.DangerousCommands()})
+	})
+}
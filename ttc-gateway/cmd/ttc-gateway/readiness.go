@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"actinspace.org/internal/health"
+)
+
+// newReadinessChecker 建立 gateway 的就緒檢查：一律探測 satellite-sim；
+// READINESS_CHECK_SOC=true 且設定了 SPACE_SOC_URL 時另外探測 Space-SOC
+// （事件送不到 SOC 時指令仍可處理，因此預設不列入）。
+func newReadinessChecker(satelliteURL string) *health.Checker {
+	client := &http.Client{}
+	checker := health.NewChecker()
+	checker.Add("satellite-sim", health.HTTPCheck(client, satelliteURL))
+	if socURL := os.Getenv("SPACE_SOC_URL"); socURL != "" && os.Getenv("READINESS_CHECK_SOC") == "true" {
+		checker.Add("space-soc", health.HTTPCheck(client, socURL))
+	}
+	return checker
+}
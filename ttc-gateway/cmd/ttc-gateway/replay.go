@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"actinspace.org/internal/reqsign"
+	"actinspace.org/ttc-gateway/internal/replay"
+	"github.com/gin-gonic/gin"
+)
+
+// newReplayGuard 依環境變數建立重放保護與請求簽章 secret；REPLAY_PROTECTION 非 true 時回傳 nil（停用）。
+//   - REPLAY_SIGNING_SECRET: 簽署 nonce / 時間戳記 / 請求內容的 HMAC secret（必填）
+//   - REPLAY_SKEW_WINDOW: 允許的時間偏移（預設 30s），nonce 在兩倍視窗內有效
+//   - REDIS_URL: 設定時使用 Redis 共享 nonce，否則使用單實例記憶體模式
+func newReplayGuard() (*replay.Guard, []byte) {
+	if os.Getenv("REPLAY_PROTECTION") != "true" {
+		return nil, nil
+	}
+
+	// 未簽章的 nonce / 時間戳記可被任意替換，沒有 secret 的重放保護沒有意義
+	secret := os.Getenv("REPLAY_SIGNING_SECRET")
+	if secret == "" {
+		log.Fatalf("REPLAY_PROTECTION=true 需要設定 REPLAY_SIGNING_SECRET")
+	}
+
+	skew := 30 * time.Second
+	if v := os.Getenv("REPLAY_SKEW_WINDOW"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			log.Printf("無效的 REPLAY_SKEW_WINDOW %q，使用預設值 %v", v, skew)
+		} else {
+			skew = parsed
+		}
+	}
+
+	var store replay.NonceStore
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		redisStore, err := replay.NewRedisStore(redisURL)
+		if err != nil {
+			// 設定了共享 store 卻退回單實例會讓 nonce 可重放到其他實例，直接停止啟動
+			log.Fatalf("無法建立 Redis nonce store: %v", err)
+		}
+		store = redisStore
+	}
+
+	guard := replay.NewGuard(store, skew)
+	log.Printf("重放保護已啟用（nonce store: %s, skew: %v）", guard.Backend(), guard.SkewWindow())
+	return guard, []byte(secret)
+}
+
+// parseRequestTimestamp 接受 Unix 秒數或 RFC3339 格式的 X-Request-Timestamp。
+func parseRequestTimestamp(value string) (time.Time, bool) {
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// replayMiddleware 拒絕簽章不符、缺少 nonce/時間戳記、時間偏移過大或 nonce 重複的指令請求。
+// 簽章涵蓋 nonce、時間戳記、method、path 與 body 雜湊，先於 nonce 記錄驗證，避免偽造請求佔用 nonce。
+func replayMiddleware(guard *replay.Guard, secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if guard == nil {
+			c.Next()
+			return
+		}
+
+		nonce := c.GetHeader(reqsign.HeaderNonce)
+		rawTimestamp := c.GetHeader(reqsign.HeaderTimestamp)
+		timestamp, ok := parseRequestTimestamp(rawTimestamp)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing or invalid X-Request-Timestamp"})
+			c.Abort()
+			return
+		}
+
+		// body 已由 MaxBodyBytes 限制大小，這裡讀出計算簽章後放回
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		err = reqsign.Verify(secret, c.GetHeader(reqsign.HeaderSignature), nonce, rawTimestamp, c.Request.Method, c.Request.URL.Path, body)
+		if err == nil {
+			err = guard.Check(nonce, timestamp, time.Now().UTC())
+		}
+		if errors.Is(err, replay.ErrStoreUnavailable) {
+			// 無法確認 nonce 是否用過時不放行，也不視為重放攻擊
+			log.Printf("警告: 重放檢查無法使用 nonce store，拒絕指令: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "replay protection temporarily unavailable"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			logCommandEvent(c.Request.Context(), "replay_rejected", map[string]interface{}{
+				"operatorRole": c.GetString("operatorRole"),
+				"operatorId":   operatorIdentity(c),
+				"nonce":        nonce,
+				"reason":       err.Error(),
+			})
+			sendEventToSOC(os.Getenv("SPACE_SOC_URL"), map[string]interface{}{
+				"component":    "ttc-gateway",
+				"eventType":    "replay_rejected",
+				"operatorRole": c.GetString("operatorRole"),
+				"operatorId":   operatorIdentity(c),
+				"reason":       err.Error(),
+				"severity":     "high",
+				"metadata": map[string]interface{}{
+					"nonce":     nonce,
+					"timestamp": timestamp.Format(time.RFC3339),
+					"backend":   guard.Backend(),
+				},
+			})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
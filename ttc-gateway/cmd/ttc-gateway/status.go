@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"actinspace.org/internal/simulation"
+	"actinspace.org/ttc-gateway/internal/idempotency"
+	"actinspace.org/ttc-gateway/internal/replay"
+)
+
+// networkSim 是 gateway 使用的網路模擬器；未啟用模擬時為 nil。
+var networkSim *simulation.NetworkSimulator
+
+// registerStatusRoutes 註冊唯讀的 GET /status 與 GET /network/stats，回報目前載入的 policy、異常偵測與模擬設定及統計。
+// 回應只包含行為相關的設定，不含 token、REDIS_URL 等連線資訊。
+func registerStatusRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc, replayGuard *replay.Guard, idempotencyCache *idempotency.Cache, rateLimiter *commandRateLimiter) {
+	r.GET("/status", authMiddleware, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"policy":       policyStatus(),
+			"missionPhase": missionPhaseResponse(),
+			"anomaly":      anomalyStatus(),
+			"ml":           mlStatus(),
+			"replay":       replayStatus(replayGuard),
+			"idempotency":  gin.H{"backend": idempotencyCache.Backend(), "ttl": idempotencyCache.TTL().String()},
+			"network":      networkStatus(),
+			"forward":      gin.H{"timeout": forwardTimeout(0).String()},
+			"rateLimit":    rateLimiter.status(),
+		})
+	})
+
+	// 網路模擬統計：掉包、亂序與延遲百分位數（含各指令類型）
+	r.GET("/network/stats", authMiddleware, func(c *gin.Context) {
+		if networkSim == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		config := networkSim.Config()
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":   config.Enabled,
+			"condition": config.Condition,
+			"stats":     networkSim.GetStats(),
+		})
+	})
+}
+
+func policyStatus() gin.H {
+	coupling := policyEngine.AnomalyCouplingConfig()
+	rules := policyEngine.Rules()
+	dualAuth := policyEngine.DualAuthConfig()
+	return gin.H{
+		"rules":              rules,
+		"count":              len(rules),
+		"satelliteOverrides": policyEngine.SatelliteOverrides(),
+		"maintenanceWindows": policyEngine.MaintenanceWindows(),
+		"dualAuth": gin.H{
+			"commands":      dualAuth.Commands,
+			"approverRoles": dualAuth.ApproverRoles,
+			"timeout":       dualAuth.Timeout.String(),
+		},
+		"anomalyCoupling": gin.H{
+			"mode":        coupling.Mode,
+			"minSeverity": coupling.MinSeverity,
+		},
+	}
+}
+
+func anomalyStatus() gin.H {
+	config := anomalyDetector.Config()
+	return gin.H{
+		"maxCommandsPerWindow":  config.MaxCommandsPerMinute,
+		"maxCommandsPerRole":    config.MaxCommandsPerRole,
+		"rateLimitWindow":       config.RateLimitWindow.String(),
+		"normalHoursStart":      config.NormalHoursStart,
+		"normalHoursEnd":        config.NormalHoursEnd,
+		"timezone":              config.Location.String(),
+		"burstThreshold":        config.BurstThreshold,
+		"burstWindow":           config.BurstTimeWindow.String(),
+		"roleActivityWindow":    config.RoleActivityWindow.String(),
+		"roleActivityThreshold": config.RoleActivityThreshold,
+		"historyWindow":         config.HistoryWindow.String(),
+		"statePath":             config.StatePath,
+	}
+}
+
+func mlStatus() gin.H {
+	if mlDetector == nil {
+		return gin.H{"enabled": false}
+	}
+	stats := gin.H{"enabled": true}
+	for key, value := range mlDetector.GetStatistics() {
+		stats[key] = value
+	}
+	return stats
+}
+
+func replayStatus(guard *replay.Guard) gin.H {
+	if guard == nil {
+		return gin.H{"enabled": false}
+	}
+	return gin.H{"enabled": true, "signed": true, "backend": guard.Backend(), "skewWindow": guard.SkewWindow().String()}
+}
+
+func networkStatus() interface{} {
+	if networkSim == nil {
+		return gin.H{"enabled": false}
+	}
+	return networkSim.Config()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"actinspace.org/ttc-gateway/internal/stream"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamBufferSize 是每個連線最多暫存的事件數，超過時丟棄（慢速 console 不影響其他人）
+	streamBufferSize = 256
+	streamWriteWait  = 10 * time.Second
+	streamPongWait   = 60 * time.Second
+	streamPingPeriod = streamPongWait * 9 / 10
+)
+
+// decisionHub 將 policy_decision / anomaly_detected 事件即時推送給 /command/stream 訂閱者。
+var decisionHub = stream.NewHub()
+
+var streamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// publishDecisionEvent 將送往 Space-SOC 的事件同步推送給即時訂閱者（非阻塞）。
+func publishDecisionEvent(eventType, operatorRole, satelliteID string, data map[string]interface{}) {
+	decisionHub.Publish(stream.Event{
+		Type:         eventType,
+		OperatorRole: operatorRole,
+		SatelliteID:  satelliteID,
+		Timestamp:    time.Now().UTC(),
+		Data:         data,
+	})
+}
+
+// streamTokenFromQuery 允許瀏覽器以 ?access_token= 帶入 token（WebSocket 無法自訂 header）。
+func streamTokenFromQuery(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" {
+		if token := c.Query("access_token"); token != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	c.Next()
+}
+
+// commandStreamHandler 升級為 WebSocket 並推送決策事件；
+// 可用 ?role= 與 ?satellite= 過濾，連線中斷時自動取消訂閱。
+func commandStreamHandler(c *gin.Context) {
+	conn, err := streamUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket 升級失敗: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := decisionHub.Subscribe(stream.Filter{
+		OperatorRole: c.Query("role"),
+		SatelliteID:  c.Query("satellite"),
+	}, streamBufferSize)
+	defer func() {
+		decisionHub.Unsubscribe(sub)
+		if dropped := sub.Dropped(); dropped > 0 {
+			log.Printf("command stream 連線關閉（丟棄 %d 筆事件）", dropped)
+		}
+	}()
+
+	// 讀取迴圈僅用於偵測斷線與處理 pong
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(streamPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				// 服務關閉中：通知客戶端重新連線
+				conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
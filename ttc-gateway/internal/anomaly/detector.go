@@ -2,6 +2,7 @@ package anomaly
 
 import (
 	"fmt"
+	"log"
 	"sync"
 	"time"
 )
@@ -16,15 +17,43 @@ const (
 	AnomalyTypeUnusualRole  AnomalyType = "unusual_role"
 )
 
+// Severity 是異常嚴重性，字串值與 Space-SOC 的事件嚴重性相同。
+//
+// 對應：頻率限制、突發為 high；非正常時間、離峰角色活動為 medium；頻率限制發生在非正常時間時升級為 critical。
+// Space-SOC 收到 high / critical 事件時會自動建立或更新 incident，medium 以下只留存事件。
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+var severityOrder = []Severity{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Escalate 回傳高一級的嚴重性（critical 維持不變，未知值視為 low）。
+func (s Severity) Escalate() Severity {
+	for i, severity := range severityOrder {
+		if severity == s {
+			if i+1 < len(severityOrder) {
+				return severityOrder[i+1]
+			}
+			return s
+		}
+	}
+	return SeverityMedium
+}
+
 // Anomaly 表示一個偵測到的異常。
 type Anomaly struct {
-	Type        AnomalyType
-	Command     string
+	Type         AnomalyType
+	Command      string
 	OperatorRole string
-	Message     string
-	Severity    string // "low", "medium", "high", "critical"
-	Timestamp   time.Time
-	Metadata    map[string]interface{}
+	Message      string
+	Severity     Severity
+	Timestamp    time.Time
+	Metadata     map[string]interface{}
 }
 
 // Detector 是異常偵測器。
@@ -39,46 +68,140 @@ type Detector struct {
 
 	// 配置
 	config Config
+
+	// 狀態快照（StatePath 有設定時）；saveMu 讓寫檔依序進行
+	saveMu      sync.Mutex
+	savePending bool
+	lastSave    time.Time
 }
 
-// Config 定義異常偵測的配置。
+// Config 定義異常偵測的配置。未設定（零值）的欄位使用預設值。
 type Config struct {
-	// 每種指令的最大頻率（每分鐘）
+	// 每種指令在 RateLimitWindow 內的最大次數
 	MaxCommandsPerMinute map[string]int
+	// 頻率限制的滑動視窗（預設 1 分鐘）
+	RateLimitWindow time.Duration
+	// 每個角色在 RateLimitWindow 內的最大指令數（不分指令類型）；"default" 套用於未列出的角色。
+	// 未設定（nil）時停用，避免單一帳號以多種指令分散頻率
+	MaxCommandsPerRole map[string]int
 
-	// 正常操作時間範圍（UTC）
+	// 正常操作時間範圍，以 Location 的當地時間計算
 	NormalHoursStart int // 小時 (0-23)
 	NormalHoursEnd   int
+	// 判斷正常時間與離峰時段所用的時區（預設 UTC）
+	Location *time.Location
 
 	// 突發指令閾值（短時間內大量指令）
-	BurstThreshold      int           // 指令數量
-	BurstTimeWindow     time.Duration // 時間窗口
+	BurstThreshold  int           // 指令數量
+	BurstTimeWindow time.Duration // 時間窗口
+
+	// 角色活動量的滑動視窗（預設 1 小時）與觸發門檻（預設 50）
+	RoleActivityWindow    time.Duration
+	RoleActivityThreshold int
+
+	// 指令紀錄的最短保留時間（預設 5 分鐘）；實際保留時間取此值與所有視窗中的最大者
+	HistoryWindow time.Duration
+
+	// 滑動視窗快照檔案；設定後啟動時載入，重啟不會清空頻率與突發狀態（空字串停用）
+	StatePath string
+	// 快照寫入的最短間隔（預設 5 秒）
+	StateSaveInterval time.Duration
+}
+
+// Validate 檢查配置值是否合理（套用預設值前後皆可呼叫）。
+func (c Config) Validate() error {
+	for name, window := range map[string]time.Duration{
+		"RateLimitWindow":    c.RateLimitWindow,
+		"BurstTimeWindow":    c.BurstTimeWindow,
+		"RoleActivityWindow": c.RoleActivityWindow,
+		"HistoryWindow":      c.HistoryWindow,
+		"StateSaveInterval":  c.StateSaveInterval,
+	} {
+		if window < 0 {
+			return fmt.Errorf("%s must not be negative, got %v", name, window)
+		}
+	}
+	if c.NormalHoursStart < 0 || c.NormalHoursStart > 23 || c.NormalHoursEnd < 0 || c.NormalHoursEnd > 23 {
+		return fmt.Errorf("normal hours must be within 0-23, got %d-%d", c.NormalHoursStart, c.NormalHoursEnd)
+	}
+	if c.BurstThreshold < 0 || c.RoleActivityThreshold < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	for command, limit := range c.MaxCommandsPerMinute {
+		if limit <= 0 {
+			return fmt.Errorf("rate limit for %q must be positive, got %d", command, limit)
+		}
+	}
+	for role, limit := range c.MaxCommandsPerRole {
+		if limit <= 0 {
+			return fmt.Errorf("rate limit for role %q must be positive, got %d", role, limit)
+		}
+	}
+	return nil
+}
+
+// retention 回傳紀錄需保留的時間：所有視窗中的最大值，避免較長視窗的檢查讀不到資料。
+func (c Config) retention() time.Duration {
+	retention := c.HistoryWindow
+	for _, window := range []time.Duration{c.RateLimitWindow, c.BurstTimeWindow, c.RoleActivityWindow} {
+		if window > retention {
+			retention = window
+		}
+	}
+	return retention
 }
 
 // NewDetector 創建新的異常偵測器。
 func NewDetector(config Config) *Detector {
 	if config.MaxCommandsPerMinute == nil {
 		config.MaxCommandsPerMinute = map[string]int{
-			"deorbit":       1,  // 每小時最多 1 次
-			"orbit_change":  2,  // 每小時最多 2 次
+			"deorbit":        1,  // 每小時最多 1 次
+			"orbit_change":   2,  // 每小時最多 2 次
 			"payload_toggle": 10, // 每分鐘最多 10 次
-			"default":       30, // 預設每分鐘最多 30 次
+			"default":        30, // 預設每分鐘最多 30 次
 		}
 	}
 	if config.NormalHoursStart == 0 && config.NormalHoursEnd == 0 {
-		config.NormalHoursStart = 8  // 08:00 UTC
-		config.NormalHoursEnd = 20  // 20:00 UTC
+		config.NormalHoursStart = 8 // 08:00（Location 當地時間）
+		config.NormalHoursEnd = 20  // 20:00
+	}
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.RateLimitWindow == 0 {
+		config.RateLimitWindow = time.Minute
 	}
 	if config.BurstThreshold == 0 {
 		config.BurstThreshold = 10
+	}
+	if config.BurstTimeWindow == 0 {
 		config.BurstTimeWindow = 10 * time.Second
 	}
+	if config.RoleActivityWindow == 0 {
+		config.RoleActivityWindow = time.Hour
+	}
+	if config.RoleActivityThreshold == 0 {
+		config.RoleActivityThreshold = 50
+	}
+	if config.HistoryWindow == 0 {
+		config.HistoryWindow = 5 * time.Minute
+	}
+	if config.StateSaveInterval == 0 {
+		config.StateSaveInterval = 5 * time.Second
+	}
 
-	return &Detector{
+	detector := &Detector{
 		commandCounts:    make(map[string][]time.Time),
 		operatorActivity: make(map[string][]time.Time),
 		config:           config,
 	}
+
+	// 載入上次的快照；無法讀取時以空狀態啟動
+	if err := detector.loadState(time.Now()); err != nil {
+		log.Printf("異常偵測狀態無法載入，以空狀態啟動: %v", err)
+	}
+
+	return detector
 }
 
 // CheckCommand 檢查指令是否異常。
@@ -88,8 +211,8 @@ func (d *Detector) CheckCommand(command string, operatorRole string, timestamp t
 
 	var anomalies []Anomaly
 
-	// 清理舊記錄（保留最近 5 分鐘）
-	cutoff := timestamp.Add(-5 * time.Minute)
+	// 清理舊記錄（保留所有視窗中最長的時間）
+	cutoff := timestamp.Add(-d.config.retention())
 	d.cleanup(cutoff)
 
 	// 檢查 1: 頻率限制
@@ -97,6 +220,11 @@ func (d *Detector) CheckCommand(command string, operatorRole string, timestamp t
 		anomalies = append(anomalies, *anomaly)
 	}
 
+	// 檢查 1b: 角色頻率限制（有設定時）
+	if anomaly := d.checkRoleRateLimit(operatorRole, timestamp); anomaly != nil {
+		anomalies = append(anomalies, *anomaly)
+	}
+
 	// 檢查 2: 時間異常
 	if anomaly := d.checkTimeOfDay(timestamp); anomaly != nil {
 		anomalies = append(anomalies, *anomaly)
@@ -112,12 +240,37 @@ func (d *Detector) CheckCommand(command string, operatorRole string, timestamp t
 		anomalies = append(anomalies, *anomaly)
 	}
 
+	escalateOffHoursRateLimit(anomalies)
+
 	// 記錄此次指令
 	d.recordCommand(command, operatorRole, timestamp)
+	d.scheduleSave(time.Now())
 
 	return anomalies
 }
 
+// escalateOffHoursRateLimit 在同一指令同時觸發頻率限制與非正常時間時，將頻率限制異常升級一級
+// （high → critical）：非上班時間的大量指令比兩者單獨發生更可能是帳號遭盜用。
+func escalateOffHoursRateLimit(anomalies []Anomaly) {
+	offHours := false
+	for _, anomaly := range anomalies {
+		if anomaly.Type == AnomalyTypeTimeOfDay {
+			offHours = true
+		}
+	}
+	if !offHours {
+		return
+	}
+	for i := range anomalies {
+		if anomalies[i].Type != AnomalyTypeRateLimit {
+			continue
+		}
+		anomalies[i].Metadata["escalatedFrom"] = string(anomalies[i].Severity)
+		anomalies[i].Metadata["escalation"] = "rate_limit_off_hours"
+		anomalies[i].Severity = anomalies[i].Severity.Escalate()
+	}
+}
+
 // checkRateLimit 檢查指令頻率是否超過限制。
 func (d *Detector) checkRateLimit(command string, timestamp time.Time) *Anomaly {
 	maxRate, exists := d.config.MaxCommandsPerMinute[command]
@@ -125,25 +278,62 @@ func (d *Detector) checkRateLimit(command string, timestamp time.Time) *Anomaly
 		maxRate = d.config.MaxCommandsPerMinute["default"]
 	}
 
-	// 計算最近一分鐘內的指令數量
-	oneMinuteAgo := timestamp.Add(-1 * time.Minute)
+	// 計算視窗內的指令數量
+	windowStart := timestamp.Add(-d.config.RateLimitWindow)
 	count := 0
 	for _, t := range d.commandCounts[command] {
-		if t.After(oneMinuteAgo) {
+		if t.After(windowStart) {
+			count++
+		}
+	}
+
+	if count >= maxRate {
+		return &Anomaly{
+			Type:      AnomalyTypeRateLimit,
+			Command:   command,
+			Message:   fmt.Sprintf("command '%s' rate limit exceeded: %d commands in last %v (limit: %d)", command, count+1, d.config.RateLimitWindow, maxRate),
+			Severity:  SeverityHigh,
+			Timestamp: timestamp,
+			Metadata: map[string]interface{}{
+				"count":  count + 1,
+				"limit":  maxRate,
+				"window": d.config.RateLimitWindow.String(),
+			},
+		}
+	}
+
+	return nil
+}
+
+// checkRoleRateLimit 檢查角色在視窗內的指令總數（不分指令類型）是否超過限制。
+func (d *Detector) checkRoleRateLimit(operatorRole string, timestamp time.Time) *Anomaly {
+	maxRate, exists := d.config.MaxCommandsPerRole[operatorRole]
+	if !exists {
+		if maxRate, exists = d.config.MaxCommandsPerRole["default"]; !exists {
+			return nil
+		}
+	}
+
+	windowStart := timestamp.Add(-d.config.RateLimitWindow)
+	count := 0
+	for _, t := range d.operatorActivity[operatorRole] {
+		if t.After(windowStart) {
 			count++
 		}
 	}
 
 	if count >= maxRate {
 		return &Anomaly{
-			Type:        AnomalyTypeRateLimit,
-			Command:     command,
-			Message:     fmt.Sprintf("command '%s' rate limit exceeded: %d commands in last minute (limit: %d)", command, count+1, maxRate),
-			Severity:    "high",
-			Timestamp:   timestamp,
+			Type:         AnomalyTypeRateLimit,
+			OperatorRole: operatorRole,
+			Message:      fmt.Sprintf("role '%s' rate limit exceeded: %d commands in last %v (limit: %d)", operatorRole, count+1, d.config.RateLimitWindow, maxRate),
+			Severity:     SeverityHigh,
+			Timestamp:    timestamp,
 			Metadata: map[string]interface{}{
-				"count": count + 1,
-				"limit": maxRate,
+				"role":   operatorRole,
+				"count":  count + 1,
+				"limit":  maxRate,
+				"window": d.config.RateLimitWindow.String(),
 			},
 		}
 	}
@@ -153,8 +343,8 @@ func (d *Detector) checkRateLimit(command string, timestamp time.Time) *Anomaly
 
 // checkTimeOfDay 檢查是否在異常時間執行指令。
 func (d *Detector) checkTimeOfDay(timestamp time.Time) *Anomaly {
-	hour := timestamp.UTC().Hour()
-	
+	hour := timestamp.In(d.config.Location).Hour()
+
 	// 檢查是否在正常時間範圍內
 	inNormalHours := false
 	if d.config.NormalHoursStart <= d.config.NormalHoursEnd {
@@ -168,13 +358,14 @@ func (d *Detector) checkTimeOfDay(timestamp time.Time) *Anomaly {
 	if !inNormalHours {
 		return &Anomaly{
 			Type:      AnomalyTypeTimeOfDay,
-			Message:   fmt.Sprintf("command executed outside normal hours (current: %02d:00 UTC, normal: %02d:00-%02d:00 UTC)", hour, d.config.NormalHoursStart, d.config.NormalHoursEnd),
-			Severity:  "medium",
+			Message:   fmt.Sprintf("command executed outside normal hours (current: %02d:00 %s, normal: %02d:00-%02d:00 %s)", hour, d.config.Location, d.config.NormalHoursStart, d.config.NormalHoursEnd, d.config.Location),
+			Severity:  SeverityMedium,
 			Timestamp: timestamp,
 			Metadata: map[string]interface{}{
-				"hour": hour,
+				"hour":        hour,
 				"normalStart": d.config.NormalHoursStart,
 				"normalEnd":   d.config.NormalHoursEnd,
+				"timezone":    d.config.Location.String(),
 			},
 		}
 	}
@@ -186,7 +377,7 @@ func (d *Detector) checkTimeOfDay(timestamp time.Time) *Anomaly {
 func (d *Detector) checkCommandBurst(command string, timestamp time.Time) *Anomaly {
 	windowStart := timestamp.Add(-d.config.BurstTimeWindow)
 	count := 0
-	
+
 	for _, times := range d.commandCounts {
 		// 檢查所有指令類型（不僅是當前指令）
 		for _, t := range times {
@@ -198,15 +389,15 @@ func (d *Detector) checkCommandBurst(command string, timestamp time.Time) *Anoma
 
 	if count >= d.config.BurstThreshold {
 		return &Anomaly{
-			Type:     AnomalyTypeCommandBurst,
-			Command:  command,
-			Message:  fmt.Sprintf("command burst detected: %d commands in last %v (threshold: %d)", count+1, d.config.BurstTimeWindow, d.config.BurstThreshold),
-			Severity: "high",
+			Type:      AnomalyTypeCommandBurst,
+			Command:   command,
+			Message:   fmt.Sprintf("command burst detected: %d commands in last %v (threshold: %d)", count+1, d.config.BurstTimeWindow, d.config.BurstThreshold),
+			Severity:  SeverityHigh,
 			Timestamp: timestamp,
 			Metadata: map[string]interface{}{
-				"count":    count + 1,
+				"count":     count + 1,
 				"threshold": d.config.BurstThreshold,
-				"window":   d.config.BurstTimeWindow.String(),
+				"window":    d.config.BurstTimeWindow.String(),
 			},
 		}
 	}
@@ -217,27 +408,28 @@ func (d *Detector) checkCommandBurst(command string, timestamp time.Time) *Anoma
 // checkUnusualRoleActivity 檢查異常角色活動。
 func (d *Detector) checkUnusualRoleActivity(operatorRole string, timestamp time.Time) *Anomaly {
 	// 檢查該角色在短時間內是否有異常活動
-	oneHourAgo := timestamp.Add(-1 * time.Hour)
+	windowStart := timestamp.Add(-d.config.RoleActivityWindow)
 	activityCount := 0
-	
+
 	for _, t := range d.operatorActivity[operatorRole] {
-		if t.After(oneHourAgo) {
+		if t.After(windowStart) {
 			activityCount++
 		}
 	}
 
-	// 如果某個角色在非正常時間有大量活動，標記為異常
-	hour := timestamp.UTC().Hour()
-	if activityCount > 50 && (hour < 6 || hour > 22) {
+	// 如果某個角色在非正常時間（當地時間）有大量活動，標記為異常
+	hour := timestamp.In(d.config.Location).Hour()
+	if activityCount > d.config.RoleActivityThreshold && (hour < 6 || hour > 22) {
 		return &Anomaly{
-			Type:        AnomalyTypeUnusualRole,
+			Type:         AnomalyTypeUnusualRole,
 			OperatorRole: operatorRole,
-			Message:     fmt.Sprintf("unusual activity for role '%s': %d commands in last hour during off-hours", operatorRole, activityCount),
-			Severity:    "medium",
-			Timestamp:   timestamp,
+			Message:      fmt.Sprintf("unusual activity for role '%s': %d commands in last %v during off-hours", operatorRole, activityCount, d.config.RoleActivityWindow),
+			Severity:     SeverityMedium,
+			Timestamp:    timestamp,
 			Metadata: map[string]interface{}{
 				"activityCount": activityCount,
-				"hour":         hour,
+				"hour":          hour,
+				"window":        d.config.RoleActivityWindow.String(),
 			},
 		}
 	}
@@ -284,3 +476,21 @@ func (d *Detector) cleanup(cutoff time.Time) {
 	}
 }
 
+// Config 回傳偵測器實際使用的配置（已套用預設值）的副本。
+func (d *Detector) Config() Config {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	config := d.config
+	config.MaxCommandsPerMinute = make(map[string]int, len(d.config.MaxCommandsPerMinute))
+	for command, limit := range d.config.MaxCommandsPerMinute {
+		config.MaxCommandsPerMinute[command] = limit
+	}
+	if d.config.MaxCommandsPerRole != nil {
+		config.MaxCommandsPerRole = make(map[string]int, len(d.config.MaxCommandsPerRole))
+		for role, limit := range d.config.MaxCommandsPerRole {
+			config.MaxCommandsPerRole[role] = limit
+		}
+	}
+	return config
+}
@@ -0,0 +1,154 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // 測試環境不一定有系統時區資料
+)
+
+// noon 是正常時間內的基準時刻，避免時間異常干擾視窗測試
+var noon = time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+
+func hasAnomaly(anomalies []Anomaly, anomalyType AnomalyType) bool {
+	for _, anomaly := range anomalies {
+		if anomaly.Type == anomalyType {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRateLimitWindowLongerThanHistoryWindow(t *testing.T) {
+	// 一小時視窗遠長於預設 5 分鐘的 HistoryWindow，舊紀錄不可被提早清除
+	d := NewDetector(Config{
+		MaxCommandsPerMinute: map[string]int{"deorbit": 2, "default": 30},
+		RateLimitWindow:      time.Hour,
+	})
+
+	if got := d.CheckCommand("deorbit", "admin", noon); hasAnomaly(got, AnomalyTypeRateLimit) {
+		t.Fatalf("first command flagged: %+v", got)
+	}
+	if got := d.CheckCommand("deorbit", "admin", noon.Add(20*time.Minute)); hasAnomaly(got, AnomalyTypeRateLimit) {
+		t.Fatalf("second command flagged: %+v", got)
+	}
+	if got := d.CheckCommand("deorbit", "admin", noon.Add(40*time.Minute)); !hasAnomaly(got, AnomalyTypeRateLimit) {
+		t.Fatalf("third command within the hour should exceed the limit, got %+v", got)
+	}
+
+	// 視窗滑過前兩筆後，只剩 40 分與 85 分兩筆
+	if got := d.CheckCommand("deorbit", "admin", noon.Add(85*time.Minute)); hasAnomaly(got, AnomalyTypeRateLimit) {
+		t.Fatalf("command after the window slid past earlier ones flagged: %+v", got)
+	}
+}
+
+func TestRateLimitWindowShorterThanDefault(t *testing.T) {
+	d := NewDetector(Config{
+		MaxCommandsPerMinute: map[string]int{"default": 2},
+		RateLimitWindow:      10 * time.Second,
+		BurstThreshold:       100,
+	})
+
+	// 每 6 秒一筆：10 秒視窗內最多只有一筆先前紀錄
+	for i := 0; i < 5; i++ {
+		if got := d.CheckCommand("ping", "operator", noon.Add(time.Duration(i)*6*time.Second)); hasAnomaly(got, AnomalyTypeRateLimit) {
+			t.Fatalf("command %d flagged with a 10s window: %+v", i, got)
+		}
+	}
+	if got := d.CheckCommand("ping", "operator", noon.Add(25*time.Second)); !hasAnomaly(got, AnomalyTypeRateLimit) {
+		t.Fatalf("two prior commands within 10s should exceed the limit, got %+v", got)
+	}
+}
+
+func TestBurstTimeWindow(t *testing.T) {
+	d := NewDetector(Config{
+		BurstThreshold:  3,
+		BurstTimeWindow: 2 * time.Minute,
+	})
+
+	// 不同指令也計入突發；三筆落在兩分鐘內，第四筆觸發
+	commands := []string{"ping", "payload_toggle", "ping"}
+	for i, command := range commands {
+		if got := d.CheckCommand(command, "operator", noon.Add(time.Duration(i)*30*time.Second)); hasAnomaly(got, AnomalyTypeCommandBurst) {
+			t.Fatalf("command %d flagged before reaching the threshold: %+v", i, got)
+		}
+	}
+	if got := d.CheckCommand("ping", "operator", noon.Add(100*time.Second)); !hasAnomaly(got, AnomalyTypeCommandBurst) {
+		t.Fatalf("fourth command within 2m should be a burst, got %+v", got)
+	}
+
+	// 兩分鐘後前面的紀錄都已離開視窗
+	if got := d.CheckCommand("ping", "operator", noon.Add(5*time.Minute)); hasAnomaly(got, AnomalyTypeCommandBurst) {
+		t.Fatalf("command after the burst window flagged: %+v", got)
+	}
+}
+
+func TestRoleActivityWindow(t *testing.T) {
+	night := time.Date(2025, 3, 4, 1, 0, 0, 0, time.UTC)
+	d := NewDetector(Config{
+		RoleActivityWindow:    3 * time.Hour,
+		RoleActivityThreshold: 3,
+		BurstThreshold:        100,
+	})
+
+	// 每 40 分鐘一筆：預設一小時視窗內最多兩筆，三小時視窗則會累積超過門檻
+	var flaggedAt int
+	for i := 0; i < 6; i++ {
+		if got := d.CheckCommand("ping", "operator", night.Add(time.Duration(i)*40*time.Minute)); hasAnomaly(got, AnomalyTypeUnusualRole) {
+			flaggedAt = i
+			break
+		}
+	}
+	if flaggedAt != 4 {
+		t.Fatalf("unusual role activity flagged at command %d, want 4 (after 4 prior commands within 3h)", flaggedAt)
+	}
+}
+
+func TestConfigRetentionCoversLongestWindow(t *testing.T) {
+	config := Config{
+		HistoryWindow:      time.Minute,
+		RateLimitWindow:    10 * time.Minute,
+		BurstTimeWindow:    30 * time.Second,
+		RoleActivityWindow: 2 * time.Hour,
+	}
+	if got := config.retention(); got != 2*time.Hour {
+		t.Fatalf("retention() = %v, want 2h", got)
+	}
+}
+
+func TestConfigValidateRejectsNegativeWindow(t *testing.T) {
+	if err := (Config{BurstTimeWindow: -time.Second}).Validate(); err == nil {
+		t.Fatal("expected an error for a negative burst window")
+	}
+}
+
+func TestTimeOfDayUsesConfiguredLocation(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	d := NewDetector(Config{
+		NormalHoursStart: 8,
+		NormalHoursEnd:   20,
+		Location:         la,
+	})
+
+	// 09:00 PT 是 UTC 16:00（夏令時間）或 17:00，兩者都應視為正常時間
+	for _, local := range []time.Time{
+		time.Date(2025, 7, 15, 9, 0, 0, 0, la),
+		time.Date(2025, 1, 15, 9, 0, 0, 0, la),
+	} {
+		if got := d.checkTimeOfDay(local.UTC()); got != nil {
+			t.Fatalf("09:00 PT (%s) flagged as off-hours: %s", local.UTC().Format(time.RFC3339), got.Message)
+		}
+	}
+
+	// 03:00 PT 是 UTC 10:00，以 UTC 判斷會落在正常時間內，以 PT 判斷則為非正常時間
+	early := time.Date(2025, 7, 15, 3, 0, 0, 0, la).UTC()
+	got := d.checkTimeOfDay(early)
+	if got == nil {
+		t.Fatalf("03:00 PT (%s) should be off-hours", early.Format(time.RFC3339))
+	}
+	if got.Metadata["hour"] != 3 || got.Metadata["timezone"] != "America/Los_Angeles" {
+		t.Fatalf("unexpected metadata: %+v", got.Metadata)
+	}
+}
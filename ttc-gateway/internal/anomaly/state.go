@@ -0,0 +1,121 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// detectorState 是寫入 StatePath 的滑動視窗快照。
+type detectorState struct {
+	SavedAt          time.Time              `json:"savedAt"`
+	CommandCounts    map[string][]time.Time `json:"commandCounts"`
+	OperatorActivity map[string][]time.Time `json:"operatorActivity"`
+}
+
+// scheduleSave 在狀態變更後安排寫入快照：距上次寫入已超過 StateSaveInterval 時立即在背景寫入，
+// 否則延後到間隔結束時寫入一次，確保重啟後最多遺失一個間隔內的紀錄。需持有 d.mu。
+func (d *Detector) scheduleSave(now time.Time) {
+	if d.config.StatePath == "" || d.savePending {
+		return
+	}
+	d.savePending = true
+
+	delay := d.config.StateSaveInterval - now.Sub(d.lastSave)
+	if delay < 0 {
+		delay = 0
+	}
+	time.AfterFunc(delay, func() {
+		if err := d.SaveState(); err != nil {
+			log.Printf("異常偵測狀態寫入失敗: %v", err)
+		}
+	})
+}
+
+// SaveState 將目前的滑動視窗寫入 StatePath（未設定時不做任何事），可在關閉服務前呼叫。
+func (d *Detector) SaveState() error {
+	if d.config.StatePath == "" {
+		return nil
+	}
+
+	d.saveMu.Lock()
+	defer d.saveMu.Unlock()
+
+	d.mu.Lock()
+	state := detectorState{
+		SavedAt:          time.Now(),
+		CommandCounts:    copyTimes(d.commandCounts),
+		OperatorActivity: copyTimes(d.operatorActivity),
+	}
+	d.savePending = false
+	d.lastSave = state.SavedAt
+	d.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode detector state: %w", err)
+	}
+
+	// 先寫入暫存檔再改名，避免中途當機留下不完整的快照
+	tmp, err := os.CreateTemp(filepath.Dir(d.config.StatePath), filepath.Base(d.config.StatePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create detector state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write detector state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write detector state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.config.StatePath); err != nil {
+		return fmt.Errorf("failed to replace detector state: %w", err)
+	}
+	return nil
+}
+
+// loadState 從 StatePath 載入快照，並清除超過保留時間的紀錄以免舊資料影響計數。
+// 檔案不存在時視為全新狀態。
+func (d *Detector) loadState(now time.Time) error {
+	if d.config.StatePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(d.config.StatePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read detector state: %w", err)
+	}
+
+	var state detectorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode detector state: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if state.CommandCounts != nil {
+		d.commandCounts = state.CommandCounts
+	}
+	if state.OperatorActivity != nil {
+		d.operatorActivity = state.OperatorActivity
+	}
+	d.cleanup(now.Add(-d.config.retention()))
+	d.lastSave = state.SavedAt
+	return nil
+}
+
+func copyTimes(src map[string][]time.Time) map[string][]time.Time {
+	dst := make(map[string][]time.Time, len(src))
+	for key, times := range src {
+		dst[key] = append([]time.Time(nil), times...)
+	}
+	return dst
+}
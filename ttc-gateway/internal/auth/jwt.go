@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 支援的簽章演算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+var (
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnexpectedAlg    = errors.New("unexpected signing algorithm")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNotYetValid = errors.New("token not yet valid")
+	ErrMissingExpiry    = errors.New("token has no exp claim")
+	ErrMissingRole      = errors.New("token has no role claim")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+)
+
+// Claims 是 gateway 使用的 JWT claims。Subject（sub）識別操作員本人，Role 對應 RBAC 角色。
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Role      string   `json:"role"`
+	Issuer    string   `json:"iss"`
+	Audience  Audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	IssuedAt  int64    `json:"iat"`
+}
+
+// Audience 接受 JWT aud 的字串或字串陣列形式。
+type Audience []string
+
+// UnmarshalJSON 實作 json.Unmarshaler。
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// Config 設定 JWT 驗證；HMACSecret 與 RSAPublicKey 擇一。
+// Issuer / Audience 為空時不檢查；Leeway 是 exp / nbf 允許的時鐘誤差。
+type Config struct {
+	HMACSecret   []byte
+	RSAPublicKey *rsa.PublicKey
+	Issuer       string
+	Audience     string
+	Leeway       time.Duration
+}
+
+// Verifier 驗證 Bearer JWT 的簽章與有效期限。
+type Verifier struct {
+	config Config
+	alg    string
+	now    func() time.Time
+}
+
+// NewVerifier 創建 JWT 驗證器；必須且只能設定 HS256 密鑰或 RS256 公鑰其中之一。
+func NewVerifier(config Config) (*Verifier, error) {
+	var alg string
+	switch {
+	case len(config.HMACSecret) > 0 && config.RSAPublicKey != nil:
+		return nil, fmt.Errorf("configure either an HS256 secret or an RS256 public key, not both")
+	case len(config.HMACSecret) > 0:
+		alg = AlgHS256
+	case config.RSAPublicKey != nil:
+		alg = AlgRS256
+	default:
+		return nil, fmt.Errorf("no JWT signing key configured")
+	}
+	return &Verifier{config: config, alg: alg, now: time.Now}, nil
+}
+
+// Algorithm 回傳驗證器接受的簽章演算法。
+func (v *Verifier) Algorithm() string {
+	return v.alg
+}
+
+// LoadRSAPublicKey 讀取 PEM 格式的 RSA 公鑰（PKIX "PUBLIC KEY"、PKCS#1 "RSA PUBLIC KEY" 或憑證）。
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	default:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if rsaKey, ok := key.(*rsa.PublicKey); ok {
+			return rsaKey, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: not an RSA public key", path)
+}
+
+// Verify 驗證 compact JWT（header.payload.signature）並回傳 claims。
+// 只接受設定的演算法（拒絕 none 與演算法混用），且必須有 exp 與 role。
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if header.Alg != v.alg {
+		return nil, fmt.Errorf("%w: %q", ErrUnexpectedAlg, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := v.verifySignature(parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *Verifier) verifySignature(signingInput string, signature []byte) error {
+	switch v.alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, v.config.HMACSecret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+	case AlgRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(v.config.RSAPublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) validateClaims(claims *Claims) error {
+	now := v.now()
+	if claims.ExpiresAt == 0 {
+		return ErrMissingExpiry
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(v.config.Leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(v.config.Leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+	if v.config.Issuer != "" && claims.Issuer != v.config.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.config.Audience != "" && !claims.Audience.contains(v.config.Audience) {
+		return ErrInvalidAudience
+	}
+	if claims.Role == "" {
+		return ErrMissingRole
+	}
+	return nil
+}
+
+func (a Audience) contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,248 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Entry 是已完成請求的快取回應，Fingerprint 用來偵測同一 key 被用在不同請求上。
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+}
+
+// Store 保存 Idempotency-Key 對應的處理狀態與回應。
+type Store interface {
+	// Reserve 嘗試取得 key 的處理權；key 已存在時回傳 false 與既有 entry（仍在處理中時為 nil）。
+	Reserve(ctx context.Context, key string, ttl time.Duration) (*Entry, bool, error)
+	// Complete 儲存處理結果，後續相同 key 的請求直接取得此回應。
+	Complete(ctx context.Context, key string, entry Entry, ttl time.Duration) error
+	// Release 放棄處理權（請求未生效），讓客戶端可用相同 key 重試。
+	Release(ctx context.Context, key string) error
+	// Name 回傳 store 類型，供 status/log 使用。
+	Name() string
+}
+
+type memoryEntry struct {
+	entry  *Entry // nil 表示處理中
+	expiry time.Time
+}
+
+// MemoryStore 是單一 gateway 實例內的 idempotency store。
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	lastGC  time.Time
+}
+
+// NewMemoryStore 創建記憶體 idempotency store。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+	}
+}
+
+// Reserve 實作 Store。
+func (s *MemoryStore) Reserve(_ context.Context, key string, ttl time.Duration) (*Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.gc(now, ttl)
+
+	if existing, ok := s.entries[key]; ok && now.Before(existing.expiry) {
+		return existing.entry, false, nil
+	}
+	s.entries[key] = memoryEntry{expiry: now.Add(ttl)}
+	return nil, true, nil
+}
+
+// Complete 實作 Store。
+func (s *MemoryStore) Complete(_ context.Context, key string, entry Entry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = memoryEntry{entry: &entry, expiry: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release 實作 Store。
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// Name 實作 Store。
+func (s *MemoryStore) Name() string {
+	return "memory"
+}
+
+// gc 定期清除過期的 key（最多每個 ttl 一次）。
+func (s *MemoryStore) gc(now time.Time, ttl time.Duration) {
+	if now.Sub(s.lastGC) < ttl {
+		return
+	}
+	for key, existing := range s.entries {
+		if !now.Before(existing.expiry) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastGC = now
+}
+
+// pendingMarker 是 Redis 中表示「處理中」的值。
+const pendingMarker = "pending"
+
+// RedisStore 將 idempotency 狀態存放在 Redis，讓多個 gateway 實例共享。
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore 依 redis URL（例如 redis://:password@redis:6379/0）創建共享 idempotency store。
+func NewRedisStore(redisURL string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+
+	return &RedisStore{
+		client: redis.NewClient(opts),
+		prefix: "ttc-gateway:idempotency:",
+	}, nil
+}
+
+// Reserve 使用 SET NX 取得處理權；已存在時讀取既有結果。
+func (s *RedisStore) Reserve(ctx context.Context, key string, ttl time.Duration) (*Entry, bool, error) {
+	reserved, err := s.client.SetNX(ctx, s.prefix+key, pendingMarker, ttl).Result()
+	if err != nil || reserved {
+		return nil, reserved, err
+	}
+
+	value, err := s.client.Get(ctx, s.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		// key 在兩次操作之間過期，視為處理中讓客戶端稍後重試
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if value == pendingMarker {
+		return nil, false, nil
+	}
+
+	var entry Entry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, false, fmt.Errorf("invalid idempotency entry: %w", err)
+	}
+	return &entry, false, nil
+}
+
+// Complete 實作 Store。
+func (s *RedisStore) Complete(ctx context.Context, key string, entry Entry, ttl time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.prefix+key, data, ttl).Err()
+}
+
+// Release 實作 Store。
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+// Name 實作 Store。
+func (s *RedisStore) Name() string {
+	return "redis"
+}
+
+// Cache 包裝 Store，提供固定 TTL 與操作逾時。
+// 共享 store 發生錯誤時會退回本機記憶體 store，而非讓所有指令失敗。
+type Cache struct {
+	store    Store
+	fallback *MemoryStore
+	ttl      time.Duration
+	timeout  time.Duration
+}
+
+// NewCache 創建 idempotency cache；store 為 nil 時僅使用本機記憶體（單實例模式）。
+func NewCache(store Store, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	fallback := NewMemoryStore()
+	if store == nil {
+		store = fallback
+	}
+
+	return &Cache{
+		store:    store,
+		fallback: fallback,
+		ttl:      ttl,
+		timeout:  500 * time.Millisecond,
+	}
+}
+
+// call 以逾時執行 store 操作，共享 store 失敗時改用本機記憶體。
+func (c *Cache) call(op func(ctx context.Context, store Store) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	err := op(ctx, c.store)
+	if err != nil && c.store != Store(c.fallback) {
+		log.Printf("共享 idempotency store (%s) 無法使用，改用本機記憶體: %v", c.store.Name(), err)
+		err = op(ctx, c.fallback)
+	}
+	return err
+}
+
+// Begin 嘗試開始處理 key；回傳 false 時 entry 為既有結果（nil 表示仍在處理中）。
+func (c *Cache) Begin(key string) (*Entry, bool, error) {
+	var (
+		entry    *Entry
+		reserved bool
+	)
+	err := c.call(func(ctx context.Context, store Store) error {
+		var err error
+		entry, reserved, err = store.Reserve(ctx, key, c.ttl)
+		return err
+	})
+	return entry, reserved, err
+}
+
+// Finish 儲存 key 的處理結果。
+func (c *Cache) Finish(key string, entry Entry) error {
+	return c.call(func(ctx context.Context, store Store) error {
+		return store.Complete(ctx, key, entry, c.ttl)
+	})
+}
+
+// Abandon 放棄 key 的處理權，讓相同 key 可以重新送出。
+func (c *Cache) Abandon(key string) error {
+	return c.call(func(ctx context.Context, store Store) error {
+		return store.Release(ctx, key)
+	})
+}
+
+// Backend 回傳目前使用的 store 類型。
+func (c *Cache) Backend() string {
+	return c.store.Name()
+}
+
+// TTL 回傳結果保留時間。
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
@@ -0,0 +1,127 @@
+package ml
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// trainDaily records cmd for role at each of the given UTC hours on every day in [start, start+days)
+func trainDaily(d *MLAnomalyDetector, start time.Time, days int, cmd, role string, hours ...int) time.Time {
+	day := start
+	for i := 0; i < days; i++ {
+		for _, hour := range hours {
+			d.recordAt("sat-1", cmd, role, nil, day.Add(time.Duration(hour)*time.Hour))
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// hasFeature reports whether the named feature contributed to score
+func hasFeature(score AnomalyScore, feature string) bool {
+	for _, f := range score.TopFeatures {
+		if f.Feature == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDetectorAdaptsToChangedCommandPattern(t *testing.T) {
+	const halfLife = 24 * time.Hour
+	start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	d := NewMLAnomalyDetector("", 1000)
+	d.SetDecay(halfLife, 0)
+	d.SetMinSatelliteHistory(10)
+
+	// Two weeks of morning passes, then the operations team moves the same command to evening
+	next := trainDaily(d, start, 14, "status", "operator", 9, 10, 11)
+	evening := next.Add(20 * time.Hour)
+
+	before := d.detectAt("sat-1", "status", "operator", nil, evening)
+	if !hasFeature(before, "hour_of_day") {
+		t.Fatalf("first evening command should deviate from the morning baseline, got %v", before.Summary(5))
+	}
+
+	// Five half-lives of the new pattern are enough for the old baseline to fade
+	next = trainDaily(d, next, 5, "status", "operator", 19, 20, 21)
+	after := d.detectAt("sat-1", "status", "operator", nil, next.Add(20*time.Hour))
+	if hasFeature(after, "hour_of_day") {
+		t.Fatalf("evening command still flagged after %v of the new pattern: %v", 5*halfLife, after.Summary(5))
+	}
+	if after.Score >= before.Score {
+		t.Fatalf("score did not drop after adapting: before %.2f, after %.2f", before.Score, after.Score)
+	}
+
+	snapshot, ok := d.Baselines("sat-1")
+	if !ok {
+		t.Fatal("expected a satellite baseline")
+	}
+	if len(snapshot.Commands) != 1 || snapshot.Commands[0].AvgHourOfDay < 18 {
+		t.Fatalf("baseline hour should have moved to the evening pattern, got %+v", snapshot.Commands)
+	}
+}
+
+func TestDetectorWithoutDecayKeepsOldPattern(t *testing.T) {
+	start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	d := NewMLAnomalyDetector("", 1000)
+	d.SetDecay(0, 0)
+	d.SetMinSatelliteHistory(10)
+
+	next := trainDaily(d, start, 14, "status", "operator", 9, 10, 11)
+	next = trainDaily(d, next, 5, "status", "operator", 19, 20, 21)
+
+	// With all history weighted equally, the morning passes still dominate the baseline
+	score := d.detectAt("sat-1", "status", "operator", nil, next.Add(20*time.Hour))
+	if !hasFeature(score, "hour_of_day") {
+		t.Fatalf("without decay the evening command should still deviate, got %v", score.Summary(5))
+	}
+}
+
+func TestWelfordStdConvergesToKnownDistribution(t *testing.T) {
+	// Cycle through a uniform distribution over 0..23 (hours of day): mean 11.5, population std sqrt((24²-1)/12)
+	wantMean := 11.5
+	wantStd := math.Sqrt((24*24 - 1) / 12.0)
+
+	var weight, mean, m2 float64
+	for i := 0; i < 24*100; i++ {
+		weight, mean, m2 = welfordUpdate(weight, mean, m2, 1, float64(i%24))
+	}
+	if math.Abs(mean-wantMean) > 0.01 {
+		t.Fatalf("mean = %.4f, want %.4f", mean, wantMean)
+	}
+	if std := welfordStd(weight, m2); math.Abs(std-wantStd) > 0.01 {
+		t.Fatalf("std = %.4f, want %.4f", std, wantStd)
+	}
+}
+
+func TestWelfordStdWithDecayTracksRecentValues(t *testing.T) {
+	// A constant stream followed by a shifted stream: with decay the std collapses back towards 0
+	// once the old values have faded instead of staying inflated by the level shift
+	var weight, mean, m2 float64
+	for i := 0; i < 200; i++ {
+		weight, mean, m2 = welfordUpdate(weight, mean, m2, 0.5, 5)
+	}
+	for i := 0; i < 200; i++ {
+		weight, mean, m2 = welfordUpdate(weight, mean, m2, 0.5, 15)
+	}
+	if math.Abs(mean-15) > 0.01 {
+		t.Fatalf("mean = %.4f, want 15", mean)
+	}
+	if std := welfordStd(weight, m2); std > 0.01 {
+		t.Fatalf("std = %.4f, want ~0 after the old level decayed", std)
+	}
+}
+
+func TestWelfordStdNeedsTwoObservations(t *testing.T) {
+	weight, mean, m2 := welfordUpdate(0, 0, 0, 1, 7)
+	if mean != 7 {
+		t.Fatalf("mean = %v, want 7", mean)
+	}
+	if std := welfordStd(weight, m2); std != 0 {
+		t.Fatalf("std after one observation = %v, want 0", std)
+	}
+}
@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"fmt"
+)
+
+// AnomalySignal 是評估時納入決策的異常訊號（規則式偵測器或 ML 分數中最嚴重的一個）。
+type AnomalySignal struct {
+	Source   string  // "detector" 或 "ml"
+	Type     string  // 異常類型，例如 "command_burst"、"ml_score"
+	Severity string  // "low", "medium", "high", "critical"
+	Score    float64 // ML 分數（僅 Source 為 "ml" 時有意義）
+	Action   string  // ML 建議動作，例如 "block_and_alert"
+	Message  string
+	// Explanation 是 ML 分數中貢獻最大的特徵（精簡文字）
+	Explanation []string
+}
+
+// AnomalyMode 定義異常訊號如何影響 policy 已允許的指令。
+type AnomalyMode string
+
+const (
+	AnomalyModeOff     AnomalyMode = "off"     // 不納入決策
+	AnomalyModeLog     AnomalyMode = "log"     // 仍允許，但在理由中標示異常
+	AnomalyModeConfirm AnomalyMode = "confirm" // 需要操作員確認後才允許
+	AnomalyModeDeny    AnomalyMode = "deny"    // 直接拒絕
+)
+
+// ParseAnomalyMode 解析設定值，空字串視為 off。
+func ParseAnomalyMode(value string) (AnomalyMode, error) {
+	switch AnomalyMode(value) {
+	case "":
+		return AnomalyModeOff, nil
+	case AnomalyModeOff, AnomalyModeLog, AnomalyModeConfirm, AnomalyModeDeny:
+		return AnomalyMode(value), nil
+	}
+	return AnomalyModeOff, fmt.Errorf("invalid anomaly mode %q (off, log, confirm, deny)", value)
+}
+
+// AnomalyCoupling 設定異常訊號與 policy 決策的耦合方式：
+// 嚴重性達到 MinSeverity 的訊號才會依 Mode 影響決策。
+type AnomalyCoupling struct {
+	Mode        AnomalyMode
+	MinSeverity string
+}
+
+var severityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// SeverityAtLeast 判斷 severity 是否達到 threshold。
+func SeverityAtLeast(severity, threshold string) bool {
+	return severityRank[severity] >= severityRank[threshold]
+}
+
+// SetAnomalyCoupling 設定異常耦合（預設為 off）。
+func (e *Engine) SetAnomalyCoupling(coupling AnomalyCoupling) {
+	if coupling.MinSeverity == "" {
+		coupling.MinSeverity = "high"
+	}
+	e.anomaly = coupling
+}
+
+// AnomalyCouplingConfig 回傳目前的異常耦合設定。
+func (e *Engine) AnomalyCouplingConfig() AnomalyCoupling {
+	return e.anomaly
+}
+
+// applyAnomaly 依耦合設定調整規則已允許的決策；被規則拒絕的指令不受影響。
+func (e *Engine) applyAnomaly(ctx CommandContext, decision PolicyDecision) PolicyDecision {
+	signal := ctx.Anomaly
+	if e.anomaly.Mode == AnomalyModeOff || signal == nil || !decision.Allowed {
+		return decision
+	}
+	if !SeverityAtLeast(signal.Severity, e.anomaly.MinSeverity) {
+		return decision
+	}
+
+	decision.Anomaly = signal
+	anomalyReason := fmt.Sprintf("anomaly %s (%s, severity %s)", signal.Type, signal.Source, signal.Severity)
+	if signal.Source == "ml" {
+		anomalyReason = fmt.Sprintf("anomaly %s (ml score %.2f, action %s)", signal.Type, signal.Score, signal.Action)
+	}
+
+	switch e.anomaly.Mode {
+	case AnomalyModeLog:
+		decision.Reason = fmt.Sprintf("%s; %s flagged", decision.Reason, anomalyReason)
+	case AnomalyModeConfirm:
+		if ctx.AnomalyConfirmed {
+			decision.Reason = fmt.Sprintf("%s; %s confirmed by operator", decision.Reason, anomalyReason)
+			break
+		}
+		decision.Allowed = false
+		decision.RequiresConfirmation = true
+		decision.Reason = fmt.Sprintf("%s requires operator confirmation", anomalyReason)
+		decision.RuleID = "anomaly-confirmation-required"
+	case AnomalyModeDeny:
+		decision.Allowed = false
+		decision.Reason = fmt.Sprintf("denied due to %s", anomalyReason)
+		decision.RuleID = "anomaly-deny"
+	}
+	if severityRank[signal.Severity] > severityRank[decision.Severity] {
+		decision.Severity = signal.Severity
+	}
+	return decision
+}
@@ -0,0 +1,277 @@
+package policy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DualAuthRuleID 是等待第二位操作員核准時的決策 RuleID。
+const DualAuthRuleID = "dual-authorization"
+
+// DualAuthReason 是等待第二位操作員核准時的決策理由。
+const DualAuthReason = "awaiting second approval"
+
+// 雙人授權請求的狀態
+const (
+	DualAuthPending  = "pending"
+	DualAuthApproved = "approved"
+)
+
+var (
+	ErrDualAuthNotFound       = errors.New("dual authorization request not found or expired")
+	ErrDualAuthSameOperator   = errors.New("approver must be a different operator than the requester")
+	ErrDualAuthApproverRole   = errors.New("role not permitted to approve dual authorization requests")
+	ErrDualAuthNotApproved    = errors.New("dual authorization request not approved")
+	ErrDualAuthAlreadyDecided = errors.New("dual authorization request already approved")
+	ErrDualAuthMismatch       = errors.New("dual authorization request does not match command")
+)
+
+// DualAuthConfig 設定雙人授權（two-person rule）。Commands 為空時停用。
+type DualAuthConfig struct {
+	Commands      []string      `json:"commands"`
+	ApproverRoles []string      `json:"approverRoles"`
+	Timeout       time.Duration `json:"-"`
+}
+
+// DefaultDualAuthConfig 回傳預設設定：deorbit 與 format_memory 需由 admin 或 engineer 在 5 分鐘內核准。
+func DefaultDualAuthConfig() DualAuthConfig {
+	return DualAuthConfig{
+		Commands:      []string{"deorbit", "format_memory"},
+		ApproverRoles: []string{"admin", "engineer"},
+		Timeout:       5 * time.Minute,
+	}
+}
+
+// DualAuthRequest 是等待（或已取得）第二位操作員核准的指令。
+// RequestedBy / ApprovedBy 是操作員識別（CommandContext.OperatorID），不是 token 本身。
+type DualAuthRequest struct {
+	ID            string     `json:"id"`
+	Command       string     `json:"command"`
+	SatelliteID   string     `json:"satelliteId,omitempty"`
+	RequestedBy   string     `json:"requestedBy"`
+	RequestedRole string     `json:"requestedRole"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ExpiresAt     time.Time  `json:"expiresAt"`
+	ApprovedBy    string     `json:"approvedBy,omitempty"`
+	ApprovedRole  string     `json:"approvedRole,omitempty"`
+	ApprovedAt    *time.Time `json:"approvedAt,omitempty"`
+}
+
+// dualAuthStore 是短期保存於記憶體的雙人授權請求；逾時的請求在存取時清除。
+type dualAuthStore struct {
+	mu       sync.Mutex
+	config   DualAuthConfig
+	commands map[string]bool
+	approver map[string]bool
+	requests map[string]*DualAuthRequest
+}
+
+func newDualAuthStore(config DualAuthConfig) *dualAuthStore {
+	s := &dualAuthStore{requests: make(map[string]*DualAuthRequest)}
+	s.configure(config)
+	return s
+}
+
+func (s *dualAuthStore) configure(config DualAuthConfig) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultDualAuthConfig().Timeout
+	}
+	s.config = config
+	s.commands = toSet(config.Commands)
+	s.approver = toSet(config.ApproverRoles)
+}
+
+// purgeLocked 移除已逾時的請求（呼叫端需持有 mu）。
+func (s *dualAuthStore) purgeLocked(now time.Time) {
+	for id, req := range s.requests {
+		if !now.Before(req.ExpiresAt) {
+			delete(s.requests, id)
+		}
+	}
+}
+
+// SetDualAuth 設定需要雙人授權的指令、可核准的角色與核准期限；不影響已建立的請求。
+func (e *Engine) SetDualAuth(config DualAuthConfig) {
+	e.dualAuth.mu.Lock()
+	defer e.dualAuth.mu.Unlock()
+	e.dualAuth.configure(config)
+}
+
+// DualAuthConfig 回傳目前的雙人授權設定。
+func (e *Engine) DualAuthConfig() DualAuthConfig {
+	e.dualAuth.mu.Lock()
+	defer e.dualAuth.mu.Unlock()
+	return e.dualAuth.config
+}
+
+// RequiresDualAuth 判斷指令是否需要雙人授權。
+func (e *Engine) RequiresDualAuth(command string) bool {
+	e.dualAuth.mu.Lock()
+	defer e.dualAuth.mu.Unlock()
+	return e.dualAuth.commands[command]
+}
+
+// RequestDualAuth 為被 DualAuthRuleID 暫停的指令建立待核准請求。
+func (e *Engine) RequestDualAuth(ctx CommandContext) DualAuthRequest {
+	s := e.dualAuth
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	s.purgeLocked(now)
+	req := &DualAuthRequest{
+		ID:            newDualAuthID(),
+		Command:       ctx.Command,
+		SatelliteID:   ctx.SatelliteID,
+		RequestedBy:   ctx.OperatorID,
+		RequestedRole: ctx.OperatorRole,
+		Status:        DualAuthPending,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(s.config.Timeout),
+	}
+	s.requests[req.ID] = req
+	return *req
+}
+
+// ApproveDualAuth 由第二位操作員核准請求；核准者必須與申請者不同且角色可核准。
+// 核准後申請者須在原期限內帶上請求 ID 重送指令。
+func (e *Engine) ApproveDualAuth(id, approverID, approverRole string) (DualAuthRequest, error) {
+	s := e.dualAuth
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	s.purgeLocked(now)
+	req, ok := s.requests[id]
+	if !ok {
+		return DualAuthRequest{}, ErrDualAuthNotFound
+	}
+	if req.Status != DualAuthPending {
+		return *req, ErrDualAuthAlreadyDecided
+	}
+	if approverID == "" || approverID == req.RequestedBy {
+		return *req, ErrDualAuthSameOperator
+	}
+	if !s.approver[approverRole] {
+		return *req, ErrDualAuthApproverRole
+	}
+
+	req.Status = DualAuthApproved
+	req.ApprovedBy = approverID
+	req.ApprovedRole = approverRole
+	req.ApprovedAt = &now
+	return *req, nil
+}
+
+// GetDualAuth 回傳尚未逾時的請求。
+func (e *Engine) GetDualAuth(id string) (DualAuthRequest, bool) {
+	s := e.dualAuth
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeLocked(time.Now())
+	req, ok := s.requests[id]
+	if !ok {
+		return DualAuthRequest{}, false
+	}
+	return *req, true
+}
+
+// PendingDualAuth 依建立時間回傳尚未逾時的請求（含已核准但尚未執行者）。
+func (e *Engine) PendingDualAuth() []DualAuthRequest {
+	s := e.dualAuth
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeLocked(time.Now())
+	requests := make([]DualAuthRequest, 0, len(s.requests))
+	for _, req := range s.requests {
+		requests = append(requests, *req)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+	})
+	return requests
+}
+
+// ConsumeDualAuth 在已核准的指令放行時移除請求，確保每次核准只能執行一次。
+func (e *Engine) ConsumeDualAuth(ctx CommandContext) error {
+	s := e.dualAuth
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkApprovedLocked(ctx); err != nil {
+		return err
+	}
+	delete(s.requests, ctx.ApprovalID)
+	return nil
+}
+
+// checkApprovedLocked 檢查 ctx.ApprovalID 是否為同一申請者、同一指令且已核准的請求（呼叫端需持有 mu）。
+func (s *dualAuthStore) checkApprovedLocked(ctx CommandContext) error {
+	s.purgeLocked(time.Now())
+	req, ok := s.requests[ctx.ApprovalID]
+	if !ok {
+		return ErrDualAuthNotFound
+	}
+	if req.Command != ctx.Command || req.SatelliteID != ctx.SatelliteID || req.RequestedBy != ctx.OperatorID {
+		return ErrDualAuthMismatch
+	}
+	if req.Status != DualAuthApproved {
+		return ErrDualAuthNotApproved
+	}
+	return nil
+}
+
+// applyDualAuth 暫停需要雙人授權、但尚未取得核准的已允許指令；不會建立請求。
+func (e *Engine) applyDualAuth(ctx CommandContext, decision PolicyDecision) PolicyDecision {
+	if !decision.Allowed || !e.RequiresDualAuth(ctx.Command) {
+		return decision
+	}
+
+	pending := PolicyDecision{
+		Allowed:          false,
+		Reason:           DualAuthReason,
+		RuleID:           DualAuthRuleID,
+		Severity:         "high",
+		Anomaly:          decision.Anomaly,
+		RequiresApproval: true,
+	}
+	if ctx.ApprovalID == "" {
+		return pending
+	}
+
+	e.dualAuth.mu.Lock()
+	err := e.dualAuth.checkApprovedLocked(ctx)
+	e.dualAuth.mu.Unlock()
+	switch err {
+	case nil:
+		decision.Reason += "; second approval granted"
+		return decision
+	case ErrDualAuthNotApproved:
+		// 請求仍在等待核准，沿用原本的請求 ID
+		pending.ApprovalID = ctx.ApprovalID
+		return pending
+	default:
+		return PolicyDecision{
+			Allowed:  false,
+			Reason:   err.Error(),
+			RuleID:   DualAuthRuleID,
+			Severity: "high",
+			Anomaly:  decision.Anomaly,
+		}
+	}
+}
+
+func newDualAuthID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
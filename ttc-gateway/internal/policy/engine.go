@@ -2,15 +2,27 @@ package policy
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 	"time"
+
+	"actinspace.org/ttc-gateway/internal/rbac"
 )
 
 // PolicyDecision 定義 policy 引擎的決策結果。
 type PolicyDecision struct {
-	Allowed   bool
-	Reason    string
-	RuleID    string
-	Severity  string // "low", "medium", "high", "critical"
+	Allowed  bool   `json:"allowed"`
+	Reason   string `json:"reason"`
+	RuleID   string `json:"ruleId"`
+	Severity string `json:"severity"` // "low", "medium", "high", "critical"
+
+	// Anomaly 是影響此決策的異常訊號（僅在啟用異常耦合且訊號達門檻時設定）
+	Anomaly *AnomalySignal `json:"anomaly,omitempty"`
+	// RequiresConfirmation 表示指令因異常被暫停，操作員確認後可重送
+	RequiresConfirmation bool `json:"requiresConfirmation,omitempty"`
+	// RequiresApproval 表示指令需要第二位操作員核准（雙人授權）；ApprovalID 是既有的待核准請求
+	RequiresApproval bool   `json:"requiresApproval,omitempty"`
+	ApprovalID       string `json:"approvalId,omitempty"`
 }
 
 // CommandContext 包含評估 policy 所需的上下文。
@@ -20,25 +32,67 @@ type CommandContext struct {
 	SatelliteID  string
 	MissionPhase string // "normal", "critical", "safe_mode", "maintenance"
 	TimeOfDay    time.Time
+
+	// Anomaly 是目前指令的異常訊號（可為 nil），依 AnomalyCoupling 影響決策
+	Anomaly *AnomalySignal
+	// AnomalyConfirmed 表示操作員已確認異常，confirm 模式下允許放行
+	AnomalyConfirmed bool
+
+	// OperatorID 識別操作員本人（JWT sub），用於稽核與區分雙人授權的不同操作員
+	OperatorID string
+	// ApprovalID 是已取得第二位操作員核准的雙人授權請求 ID
+	ApprovalID string
+}
+
+// RoleStore 提供角色權限查詢，取代寫死在規則中的角色與指令對應。
+// 實作（rbac.Store）在執行期間的變更會立即影響後續評估。
+type RoleStore interface {
+	IsDangerousCommand(command string) bool
+	RoleAllowsDangerous(role string) bool
+	RoleAllowsCommand(role, command string) bool
+	IsRestrictedRole(role string) bool
 }
 
 // Engine 是 policy 引擎的主要結構。
 type Engine struct {
-	rules []Rule
+	rules   []Rule
+	roles   RoleStore
+	anomaly AnomalyCoupling
+
+	// overrides 是各衛星的指令覆寫，可在執行期間變更
+	overrideMu sync.RWMutex
+	overrides  map[string]satelliteOverride
+
+	maintenanceMu      sync.RWMutex
+	maintenanceWindows []Window
+
+	dualAuth *dualAuthStore
 }
 
 // Rule 定義單一 policy 規則。
 type Rule struct {
 	ID          string
 	Description string
-	Condition   func(ctx CommandContext) bool
-	Action      func(ctx CommandContext) PolicyDecision
+	// Priority 越高越先評估；相同優先權時依加入順序評估
+	Priority  int
+	Condition func(ctx CommandContext) bool
+	Action    func(ctx CommandContext) PolicyDecision
 }
 
-// NewEngine 創建新的 policy 引擎。
+// NewEngine 創建使用預設角色定義的 policy 引擎。
 func NewEngine() *Engine {
+	// 不指定路徑時 rbac.NewStore 不會讀檔，因此不會失敗
+	roles, _ := rbac.NewStore("")
+	return NewEngineWithRoles(roles)
+}
+
+// NewEngineWithRoles 創建使用指定角色來源的 policy 引擎。
+func NewEngineWithRoles(roles RoleStore) *Engine {
 	engine := &Engine{
-		rules: []Rule{},
+		rules:    []Rule{},
+		roles:    roles,
+		anomaly:  AnomalyCoupling{Mode: AnomalyModeOff, MinSeverity: "high"},
+		dualAuth: newDualAuthStore(DefaultDualAuthConfig()),
 	}
 	engine.loadDefaultRules()
 	return engine
@@ -46,58 +100,139 @@ func NewEngine() *Engine {
 
 // Evaluate 評估指令是否符合 policy。
 func (e *Engine) Evaluate(ctx CommandContext) PolicyDecision {
-	// 按順序評估所有規則
+	// 衛星覆寫優先於所有規則
+	if decision, ok := e.evaluateOverride(ctx); ok {
+		return e.applyDualAuth(ctx, e.applyAnomaly(ctx, decision))
+	}
+
+	// 依優先權順序評估規則，第一個符合的規則決定結果
 	for _, rule := range e.rules {
 		if rule.Condition(ctx) {
 			decision := rule.Action(ctx)
 			decision.RuleID = rule.ID
-			return decision
+			return e.applyDualAuth(ctx, e.applyAnomaly(ctx, decision))
 		}
 	}
 
 	// 預設允許
-	return PolicyDecision{
+	return e.applyDualAuth(ctx, e.applyAnomaly(ctx, PolicyDecision{
 		Allowed:  true,
 		Reason:   "no matching policy rule, default allow",
 		RuleID:   "default-allow",
 		Severity: "low",
+	}))
+}
+
+// RuleTrace 記錄單一規則在 Explain 中的評估結果。
+type RuleTrace struct {
+	RuleID      string `json:"ruleId"`
+	Description string `json:"description"`
+	Priority    int    `json:"priority"`
+	Matched     bool   `json:"matched"`
+	// Decision 是規則條件成立時此規則會做出的決策（未套用異常耦合）
+	Decision *PolicyDecision `json:"decision,omitempty"`
+	// Winner 表示依第一個符合規則的語意，此規則決定了最終結果
+	Winner bool `json:"winner"`
+}
+
+// Explain 依序評估所有規則（不只第一個符合者）並回傳每條規則的結果，不產生任何副作用。
+// 沒有規則符合時，最後附上預設放行的紀錄並標記為 Winner。
+func (e *Engine) Explain(ctx CommandContext) []RuleTrace {
+	traces := make([]RuleTrace, 0, len(e.rules)+2)
+	winner := false
+	if decision, ok := e.evaluateOverride(ctx); ok {
+		traces = append(traces, RuleTrace{
+			RuleID:      SatelliteOverrideRuleID,
+			Description: "衛星指令覆寫",
+			Matched:     true,
+			Decision:    &decision,
+			Winner:      true,
+		})
+		winner = true
+	}
+	for _, rule := range e.rules {
+		trace := RuleTrace{RuleID: rule.ID, Description: rule.Description, Priority: rule.Priority}
+		if rule.Condition(ctx) {
+			decision := rule.Action(ctx)
+			decision.RuleID = rule.ID
+			trace.Matched = true
+			trace.Decision = &decision
+			trace.Winner = !winner
+			winner = true
+		}
+		traces = append(traces, trace)
 	}
+
+	if !winner {
+		traces = append(traces, RuleTrace{
+			RuleID:      "default-allow",
+			Description: "沒有符合的規則時預設允許",
+			Matched:     true,
+			Decision: &PolicyDecision{
+				Allowed:  true,
+				Reason:   "no matching policy rule, default allow",
+				RuleID:   "default-allow",
+				Severity: "low",
+			},
+			Winner: true,
+		})
+	}
+	return traces
+}
+
+// AddRule 加入規則並維持依優先權（高者先）排序，相同優先權時保留加入順序。
+func (e *Engine) AddRule(rule Rule) {
+	e.rules = append(e.rules, rule)
+	sortRules(e.rules)
+}
+
+// sortRules 依優先權由高至低穩定排序規則。
+func sortRules(rules []Rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
 }
 
+// 預設規則的優先權：危險指令檢查最優先，其次是任務階段限制，最後才是角色指令限制，
+// 因此預設規則的結果不受加入順序影響。
+const (
+	PriorityDangerousCommand = 400
+	PriorityCriticalPhase    = 300
+	PrioritySafeMode         = 200
+	PriorityMaintenance      = 150
+	PriorityRoleCommand      = 100
+)
+
 // loadDefaultRules 載入預設的 policy 規則。
 func (e *Engine) loadDefaultRules() {
-	// 規則 1: 危險指令需要 admin 角色
-	e.rules = append(e.rules, Rule{
+	// 規則 1: 危險指令僅允許 RBAC 中標記為可執行危險指令的角色（預設為 admin）
+	e.AddRule(Rule{
 		ID:          "dangerous-command-admin-only",
-		Description: "危險指令僅允許 admin 角色執行",
+		Priority:    PriorityDangerousCommand,
+		Description: "危險指令僅允許具危險指令權限的角色執行",
 		Condition: func(ctx CommandContext) bool {
-			dangerousCommands := map[string]bool{
-				"deorbit":       true,
-				"disable_power": true,
-				"format_memory": true,
-				"orbit_change":  true,
-			}
-			return dangerousCommands[ctx.Command]
+			return e.roles.IsDangerousCommand(ctx.Command)
 		},
 		Action: func(ctx CommandContext) PolicyDecision {
-			if ctx.OperatorRole != "admin" {
+			if !e.roles.RoleAllowsDangerous(ctx.OperatorRole) {
 				return PolicyDecision{
 					Allowed:  false,
-					Reason:   fmt.Sprintf("command '%s' requires admin role, got '%s'", ctx.Command, ctx.OperatorRole),
+					Reason:   fmt.Sprintf("command '%s' requires a role permitted to run dangerous commands, got '%s'", ctx.Command, ctx.OperatorRole),
 					Severity: "high",
 				}
 			}
 			return PolicyDecision{
 				Allowed:  true,
-				Reason:   fmt.Sprintf("admin role authorized for dangerous command '%s'", ctx.Command),
+				Reason:   fmt.Sprintf("role '%s' authorized for dangerous command '%s'", ctx.OperatorRole, ctx.Command),
 				Severity: "high",
 			}
 		},
 	})
 
 	// 規則 2: 關鍵任務階段限制
-	e.rules = append(e.rules, Rule{
+	e.AddRule(Rule{
 		ID:          "critical-phase-restrictions",
+		Priority:    PriorityCriticalPhase,
 		Description: "關鍵任務階段限制非關鍵指令",
 		Condition: func(ctx CommandContext) bool {
 			return ctx.MissionPhase == "critical"
@@ -124,8 +259,9 @@ func (e *Engine) loadDefaultRules() {
 	})
 
 	// 規則 3: 安全模式限制
-	e.rules = append(e.rules, Rule{
+	e.AddRule(Rule{
 		ID:          "safe-mode-restrictions",
+		Priority:    PrioritySafeMode,
 		Description: "安全模式僅允許基本操作",
 		Condition: func(ctx CommandContext) bool {
 			return ctx.MissionPhase == "safe_mode"
@@ -151,34 +287,62 @@ func (e *Engine) loadDefaultRules() {
 		},
 	})
 
-	// 規則 4: 工程師角色限制
-	e.rules = append(e.rules, Rule{
-		ID:          "engineer-role-restrictions",
-		Description: "工程師角色僅允許維護相關指令",
+	// 規則 4: 維護指令在維護時段外一律拒絕；時段內不做決定，交由後續規則（例如角色指令限制）判斷
+	maintenanceCommands := toSet(MaintenanceCommands)
+	e.AddRule(Rule{
+		ID:          MaintenanceRuleID,
+		Priority:    PriorityMaintenance,
+		Description: "維護指令僅允許在排定的維護時段內執行",
 		Condition: func(ctx CommandContext) bool {
-			return ctx.OperatorRole == "engineer"
+			return maintenanceCommands[ctx.Command] && !e.InMaintenanceWindow(commandTime(ctx))
 		},
 		Action: func(ctx CommandContext) PolicyDecision {
-			engineerCommands := map[string]bool{
-				"health_check":     true,
-				"diagnostics":      true,
-				"system_status":    true,
-				"payload_toggle":   true,
-				"maintenance_mode": true,
+			return PolicyDecision{
+				Allowed:  false,
+				Reason:   fmt.Sprintf("command '%s' only allowed during maintenance windows; %s", ctx.Command, e.describeNextMaintenanceWindow(commandTime(ctx))),
+				Severity: "medium",
 			}
-			if !engineerCommands[ctx.Command] {
+		},
+	})
+
+	// 規則 5: 受限角色（例如 engineer）僅允許 RBAC 中列出的指令
+	e.AddRule(Rule{
+		ID:          "role-command-restrictions",
+		Priority:    PriorityRoleCommand,
+		Description: "受限角色僅允許其 RBAC 定義中的指令",
+		Condition: func(ctx CommandContext) bool {
+			return e.roles.IsRestrictedRole(ctx.OperatorRole)
+		},
+		Action: func(ctx CommandContext) PolicyDecision {
+			if !e.roles.RoleAllowsCommand(ctx.OperatorRole, ctx.Command) {
 				return PolicyDecision{
 					Allowed:  false,
-					Reason:   fmt.Sprintf("engineer role not authorized for command '%s'", ctx.Command),
+					Reason:   fmt.Sprintf("%s role not authorized for command '%s'", ctx.OperatorRole, ctx.Command),
 					Severity: "medium",
 				}
 			}
 			return PolicyDecision{
 				Allowed:  true,
-				Reason:   "engineer role authorized",
+				Reason:   fmt.Sprintf("%s role authorized", ctx.OperatorRole),
 				Severity: "low",
 			}
 		},
 	})
 }
 
+
+// RuleInfo 是規則的唯讀描述，供 status 端點顯示目前載入的規則。
+type RuleInfo struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Priority    int    `json:"priority"`
+}
+
+// Rules 依評估順序回傳目前載入的規則。
+func (e *Engine) Rules() []RuleInfo {
+	rules := make([]RuleInfo, 0, len(e.rules))
+	for _, rule := range e.rules {
+		rules = append(rules, RuleInfo{ID: rule.ID, Description: rule.Description, Priority: rule.Priority})
+	}
+	return rules
+}
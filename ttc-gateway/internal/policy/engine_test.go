@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// permutations 回傳 0..n-1 的所有排列
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+	var result [][]int
+	for _, perm := range permutations(n - 1) {
+		for i := 0; i <= len(perm); i++ {
+			next := make([]int, 0, n)
+			next = append(next, perm[:i]...)
+			next = append(next, n-1)
+			next = append(next, perm[i:]...)
+			result = append(result, next)
+		}
+	}
+	return result
+}
+
+// ruleOrderWindow 是排列測試使用的維護時段，ruleOrderContexts 同時包含時段內與時段外的指令時間
+var ruleOrderWindow = Window{
+	Start: time.Date(2025, 3, 4, 1, 0, 0, 0, time.UTC),
+	End:   time.Date(2025, 3, 4, 3, 0, 0, 0, time.UTC),
+}
+
+// ruleOrderContexts 涵蓋各預設規則彼此重疊的情境（危險指令 × 任務階段 × 受限角色 × 維護指令 × 維護時段）
+func ruleOrderContexts() []CommandContext {
+	var contexts []CommandContext
+	for _, at := range []time.Time{time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC), time.Date(2025, 3, 4, 2, 0, 0, 0, time.UTC)} {
+		for _, command := range []string{"orbit_change", "disable_power", "health_check", "exit_safe_mode", "emergency_safe_mode", "diagnostics", "maintenance_mode", "payload_toggle", "camera_capture"} {
+			for _, role := range []string{"admin", "operator", "engineer", "unknown"} {
+				for _, phase := range []string{"normal", "critical", "safe_mode", "maintenance"} {
+					contexts = append(contexts, CommandContext{
+						Command:      command,
+						OperatorRole: role,
+						MissionPhase: phase,
+						TimeOfDay:    at,
+					})
+				}
+			}
+		}
+	}
+	return contexts
+}
+
+func decisionKey(d PolicyDecision) string {
+	return fmt.Sprintf("%s allowed=%t", d.RuleID, d.Allowed)
+}
+
+func TestDefaultRulesIndependentOfInsertionOrder(t *testing.T) {
+	reference := NewEngine()
+	if err := reference.SetMaintenanceWindows([]Window{ruleOrderWindow}); err != nil {
+		t.Fatalf("SetMaintenanceWindows: %v", err)
+	}
+	contexts := ruleOrderContexts()
+	want := make([]string, len(contexts))
+	for i, ctx := range contexts {
+		want[i] = decisionKey(reference.Evaluate(ctx))
+	}
+
+	for _, perm := range permutations(len(reference.rules)) {
+		engine := NewEngine()
+		if err := engine.SetMaintenanceWindows([]Window{ruleOrderWindow}); err != nil {
+			t.Fatalf("SetMaintenanceWindows: %v", err)
+		}
+		defaults := engine.rules
+		engine.rules = nil
+		order := make([]string, len(perm))
+		for i, index := range perm {
+			engine.AddRule(defaults[index])
+			order[i] = defaults[index].ID
+		}
+
+		for i, ctx := range contexts {
+			if got := decisionKey(engine.Evaluate(ctx)); got != want[i] {
+				t.Fatalf("insertion order %v: %s/%s/%s at %s = %s, want %s", order, ctx.Command, ctx.OperatorRole, ctx.MissionPhase, ctx.TimeOfDay.Format("15:04"), got, want[i])
+			}
+		}
+	}
+}
+
+func TestDefaultRulePrecedence(t *testing.T) {
+	engine := NewEngine()
+	maintenanceAt := time.Date(2025, 3, 4, 2, 0, 0, 0, time.UTC)
+	if err := engine.SetMaintenanceWindows([]Window{{Start: maintenanceAt.Add(-time.Hour), End: maintenanceAt.Add(time.Hour)}}); err != nil {
+		t.Fatalf("SetMaintenanceWindows: %v", err)
+	}
+	tests := []struct {
+		name    string
+		ctx     CommandContext
+		ruleID  string
+		allowed bool
+	}{
+		{
+			name:   "dangerous command outranks safe mode",
+			ctx:    CommandContext{Command: "disable_power", OperatorRole: "operator", MissionPhase: "safe_mode"},
+			ruleID: "dangerous-command-admin-only",
+		},
+		{
+			name:   "critical phase outranks role restrictions",
+			ctx:    CommandContext{Command: "payload_toggle", OperatorRole: "engineer", MissionPhase: "critical"},
+			ruleID: "critical-phase-restrictions",
+		},
+		{
+			name:   "safe mode outranks maintenance",
+			ctx:    CommandContext{Command: "diagnostics", OperatorRole: "engineer", MissionPhase: "safe_mode"},
+			ruleID: "safe-mode-restrictions",
+		},
+		{
+			name:   "maintenance outranks role restrictions outside a window",
+			ctx:    CommandContext{Command: "diagnostics", OperatorRole: "engineer", MissionPhase: "normal"},
+			ruleID: MaintenanceRuleID,
+		},
+		{
+			name:   "role restrictions still apply to maintenance commands inside a window",
+			ctx:    CommandContext{Command: "diagnostics", OperatorRole: "unknown", MissionPhase: "normal", TimeOfDay: maintenanceAt},
+			ruleID: "role-command-restrictions",
+		},
+		{
+			name:    "role allowed the maintenance command inside a window",
+			ctx:     CommandContext{Command: "maintenance_mode", OperatorRole: "engineer", MissionPhase: "normal", TimeOfDay: maintenanceAt},
+			ruleID:  "role-command-restrictions",
+			allowed: true,
+		},
+		{
+			name:    "role restrictions apply last",
+			ctx:     CommandContext{Command: "health_check", OperatorRole: "engineer", MissionPhase: "normal"},
+			ruleID:  "role-command-restrictions",
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ctx.TimeOfDay.IsZero() {
+				tt.ctx.TimeOfDay = time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+			}
+			decision := engine.Evaluate(tt.ctx)
+			if decision.RuleID != tt.ruleID || decision.Allowed != tt.allowed {
+				t.Fatalf("got %s allowed=%t, want %s allowed=%t", decision.RuleID, decision.Allowed, tt.ruleID, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestAddRuleKeepsInsertionOrderForEqualPriority(t *testing.T) {
+	engine := NewEngine()
+	always := func(CommandContext) bool { return true }
+	for _, id := range []string{"first", "second"} {
+		id := id
+		engine.AddRule(Rule{
+			ID:        id,
+			Priority:  PriorityDangerousCommand + 1,
+			Condition: always,
+			Action:    func(CommandContext) PolicyDecision { return PolicyDecision{Allowed: true, Reason: id} },
+		})
+	}
+
+	if got := engine.Evaluate(CommandContext{Command: "health_check", OperatorRole: "admin"}).RuleID; got != "first" {
+		t.Fatalf("rule with equal priority added first should win, got %s", got)
+	}
+	if rules := engine.Rules(); rules[0].ID != "first" || rules[1].ID != "second" {
+		t.Fatalf("unexpected evaluation order: %+v", rules)
+	}
+}
@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaintenanceRuleID 是維護時段規則的 ID。
+const MaintenanceRuleID = "maintenance-window"
+
+// MaintenanceCommands 是僅允許在維護時段內執行的指令。
+var MaintenanceCommands = []string{"maintenance_mode", "diagnostics"}
+
+// Window 是維護時段 [Start, End)。Daily 為 true 時每日重複，只使用 Start / End 的時刻（以 Start 的時區計算），
+// End 的時刻早於 Start 時代表跨越午夜。
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Daily bool      `json:"daily"`
+}
+
+// Validate 檢查維護時段是否有效。
+func (w Window) Validate() error {
+	if w.Start.IsZero() || w.End.IsZero() {
+		return fmt.Errorf("maintenance window requires start and end")
+	}
+	if w.Daily {
+		if w.clock(w.Start) == w.clock(w.End) {
+			return fmt.Errorf("daily maintenance window start and end must differ")
+		}
+		return nil
+	}
+	if !w.End.After(w.Start) {
+		return fmt.Errorf("maintenance window end must be after start")
+	}
+	return nil
+}
+
+// Contains 判斷時間 t 是否在維護時段內。
+func (w Window) Contains(t time.Time) bool {
+	if !w.Daily {
+		return !t.Before(w.Start) && t.Before(w.End)
+	}
+	now, start, end := w.clock(t), w.clock(w.Start), w.clock(w.End)
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// next 回傳 t 之後（不含 t）最近一次時段的起訖時間；一次性時段已開始時回傳 false。
+func (w Window) next(t time.Time) (time.Time, time.Time, bool) {
+	if !w.Daily {
+		if w.Start.After(t) {
+			return w.Start, w.End, true
+		}
+		return time.Time{}, time.Time{}, false
+	}
+
+	local := t.In(w.Start.Location())
+	start := time.Date(local.Year(), local.Month(), local.Day(),
+		w.Start.Hour(), w.Start.Minute(), w.Start.Second(), 0, w.Start.Location())
+	if !start.After(t) {
+		start = start.AddDate(0, 0, 1)
+	}
+	duration := w.clock(w.End) - w.clock(w.Start)
+	if duration <= 0 {
+		duration += 24 * time.Hour
+	}
+	return start, start.Add(duration), true
+}
+
+// clock 回傳 t 在時段時區中距離當日零時的時間。
+func (w Window) clock(t time.Time) time.Duration {
+	local := t.In(w.Start.Location())
+	return time.Duration(local.Hour())*time.Hour +
+		time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+}
+
+// SetMaintenanceWindows 取代目前的維護時段；任一時段無效時回傳錯誤且不變更。
+// 未設定任何時段時，維護指令一律被拒絕。
+func (e *Engine) SetMaintenanceWindows(windows []Window) error {
+	for i, window := range windows {
+		if err := window.Validate(); err != nil {
+			return fmt.Errorf("window %d: %w", i+1, err)
+		}
+	}
+
+	e.maintenanceMu.Lock()
+	defer e.maintenanceMu.Unlock()
+	e.maintenanceWindows = append([]Window(nil), windows...)
+	return nil
+}
+
+// MaintenanceWindows 回傳目前的維護時段。
+func (e *Engine) MaintenanceWindows() []Window {
+	e.maintenanceMu.RLock()
+	defer e.maintenanceMu.RUnlock()
+	return append([]Window{}, e.maintenanceWindows...)
+}
+
+// InMaintenanceWindow 判斷時間 t 是否落在任一維護時段內。
+func (e *Engine) InMaintenanceWindow(t time.Time) bool {
+	for _, window := range e.MaintenanceWindows() {
+		if window.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextMaintenanceWindow 回傳 t 之後最早開始的維護時段；沒有即將到來的時段時回傳 false。
+func (e *Engine) NextMaintenanceWindow(t time.Time) (start, end time.Time, ok bool) {
+	for _, window := range e.MaintenanceWindows() {
+		s, en, found := window.next(t)
+		if found && (!ok || s.Before(start)) {
+			start, end, ok = s, en, true
+		}
+	}
+	return start, end, ok
+}
+
+// describeNextMaintenanceWindow 以文字描述下一個維護時段，用於拒絕理由。
+func (e *Engine) describeNextMaintenanceWindow(t time.Time) string {
+	start, end, ok := e.NextMaintenanceWindow(t)
+	if !ok {
+		return "no upcoming maintenance window scheduled"
+	}
+	return fmt.Sprintf("next window %s - %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+}
+
+// commandTime 回傳評估時使用的指令時間；未設定 TimeOfDay 時使用目前時間。
+func commandTime(ctx CommandContext) time.Time {
+	if ctx.TimeOfDay.IsZero() {
+		return time.Now()
+	}
+	return ctx.TimeOfDay
+}
@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"actinspace.org/ttc-gateway/internal/rbac"
+)
+
+func TestMaintenanceWindowKeepsRoleRestrictions(t *testing.T) {
+	roles, _ := rbac.NewStore("")
+	// 自訂受限角色：可執行 health_check，但沒有任何維護指令
+	if _, err := roles.PutRole(rbac.Role{Name: "observer", AllowedCommands: []string{"health_check"}}); err != nil {
+		t.Fatalf("PutRole: %v", err)
+	}
+	engine := NewEngineWithRoles(roles)
+	inWindow := time.Date(2025, 3, 4, 2, 0, 0, 0, time.UTC)
+	outside := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	if err := engine.SetMaintenanceWindows([]Window{{Start: inWindow.Add(-time.Hour), End: inWindow.Add(time.Hour)}}); err != nil {
+		t.Fatalf("SetMaintenanceWindows: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		role    string
+		command string
+		at      time.Time
+		ruleID  string
+		allowed bool
+	}{
+		{"custom role without the command inside a window", "observer", "diagnostics", inWindow, "role-command-restrictions", false},
+		{"custom role without maintenance_mode inside a window", "observer", "maintenance_mode", inWindow, "role-command-restrictions", false},
+		{"unknown role inside a window", "guest", "diagnostics", inWindow, "role-command-restrictions", false},
+		{"engineer with the command inside a window", "engineer", "diagnostics", inWindow, "role-command-restrictions", true},
+		{"unrestricted role inside a window", "operator", "maintenance_mode", inWindow, "default-allow", true},
+		{"engineer with the command outside a window", "engineer", "diagnostics", outside, MaintenanceRuleID, false},
+		{"unrestricted role outside a window", "operator", "maintenance_mode", outside, MaintenanceRuleID, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(CommandContext{Command: tt.command, OperatorRole: tt.role, MissionPhase: "normal", TimeOfDay: tt.at})
+			if decision.RuleID != tt.ruleID || decision.Allowed != tt.allowed {
+				t.Fatalf("got %s allowed=%t (%s), want %s allowed=%t", decision.RuleID, decision.Allowed, decision.Reason, tt.ruleID, tt.allowed)
+			}
+		})
+	}
+}
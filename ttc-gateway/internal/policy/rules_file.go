@@ -0,0 +1,221 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleFile 是宣告式規則檔（YAML 或 JSON）的格式，規則依 priority 由高至低評估，相同時依列出順序。
+type RuleFile struct {
+	Rules []RuleSpec `json:"rules" yaml:"rules"`
+}
+
+// RuleSpec 以宣告方式描述單一規則：Match 決定規則是否適用，Require 決定放行或拒絕。
+type RuleSpec struct {
+	ID          string      `json:"id" yaml:"id"`
+	Description string      `json:"description" yaml:"description"`
+	Priority    int         `json:"priority" yaml:"priority"` // 越高越先評估，相同時依檔案順序
+	Match       MatchSpec   `json:"match" yaml:"match"`
+	Require     RequireSpec `json:"require" yaml:"require"`
+	// Effect 只在未設定 Require 時使用："allow"（預設）或 "deny"
+	Effect string      `json:"effect" yaml:"effect"`
+	Allow  OutcomeSpec `json:"allow" yaml:"allow"`
+	Deny   OutcomeSpec `json:"deny" yaml:"deny"`
+}
+
+// MatchSpec 的條件全部成立時規則才適用；未設定的條件不限制。
+type MatchSpec struct {
+	Commands        []string `json:"commands" yaml:"commands"`
+	ExceptCommands  []string `json:"exceptCommands" yaml:"exceptCommands"`
+	Dangerous       bool     `json:"dangerous" yaml:"dangerous"`             // 指令屬於 RBAC 危險指令
+	MissionPhases   []string `json:"missionPhases" yaml:"missionPhases"`     // "normal", "critical", "safe_mode", "maintenance"
+	Roles           []string `json:"roles" yaml:"roles"`                     // 操作員角色
+	RestrictedRoles bool     `json:"restrictedRoles" yaml:"restrictedRoles"` // 角色未使用萬用字元（RBAC 受限角色）
+}
+
+// RequireSpec 列出放行條件，任一成立即放行，全部不成立則拒絕。
+type RequireSpec struct {
+	Commands              []string `json:"commands" yaml:"commands"`                           // 指令在清單中
+	Roles                 []string `json:"roles" yaml:"roles"`                                 // 角色在清單中
+	DangerousPermission   bool     `json:"dangerousPermission" yaml:"dangerousPermission"`     // 角色可執行危險指令
+	RoleCommandPermission bool     `json:"roleCommandPermission" yaml:"roleCommandPermission"` // 角色的 RBAC 指令清單包含此指令
+	MaintenanceWindow     bool     `json:"maintenanceWindow" yaml:"maintenanceWindow"`         // 指令時間在維護時段內
+}
+
+// OutcomeSpec 是放行或拒絕時的嚴重性與原因；原因可使用 {command}、{role}、{phase}、{nextWindow} 佔位符。
+type OutcomeSpec struct {
+	Severity string `json:"severity" yaml:"severity"`
+	Reason   string `json:"reason" yaml:"reason"`
+}
+
+var (
+	validMissionPhases = map[string]bool{"normal": true, "critical": true, "safe_mode": true, "maintenance": true}
+	validSeverities    = map[string]bool{"low": true, "medium": true, "high": true, "critical": true}
+)
+
+// IsValidMissionPhase 回傳 phase 是否為支援的任務階段（normal、critical、safe_mode、maintenance）。
+func IsValidMissionPhase(phase string) bool {
+	return validMissionPhases[phase]
+}
+
+func (r RequireSpec) empty() bool {
+	return len(r.Commands) == 0 && len(r.Roles) == 0 && !r.DangerousPermission && !r.RoleCommandPermission &&
+		!r.MaintenanceWindow
+}
+
+// LoadRulesFromFile 讀取宣告式規則檔並取代目前的規則（包含預設規則）。
+// 副檔名為 .json 時以 JSON 解析，其他一律以 YAML 解析；任何規則無效時回傳錯誤且不變更現有規則，
+// 因此也可在 CI 中用來驗證規則檔。
+func (e *Engine) LoadRulesFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read policy rules: %w", err)
+	}
+
+	var file RuleFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&file)
+	} else {
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&file)
+	}
+	if err != nil {
+		return fmt.Errorf("parse policy rules %s: %w", path, err)
+	}
+
+	rules, err := e.compileRules(file.Rules)
+	if err != nil {
+		return fmt.Errorf("policy rules %s: %w", path, err)
+	}
+	e.rules = rules
+	return nil
+}
+
+// compileRules 驗證規則定義並轉換為 Evaluate 使用的 Rule。
+func (e *Engine) compileRules(specs []RuleSpec) ([]Rule, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no rules defined")
+	}
+
+	seen := make(map[string]bool)
+	rules := make([]Rule, 0, len(specs))
+	for i, spec := range specs {
+		if spec.ID == "" {
+			return nil, fmt.Errorf("rule %d: id is required", i+1)
+		}
+		if seen[spec.ID] {
+			return nil, fmt.Errorf("rule %s: duplicate id", spec.ID)
+		}
+		seen[spec.ID] = true
+
+		for _, phase := range spec.Match.MissionPhases {
+			if !validMissionPhases[phase] {
+				return nil, fmt.Errorf("rule %s: unknown mission phase %q", spec.ID, phase)
+			}
+		}
+		for _, outcome := range []OutcomeSpec{spec.Allow, spec.Deny} {
+			if outcome.Severity != "" && !validSeverities[outcome.Severity] {
+				return nil, fmt.Errorf("rule %s: unknown severity %q", spec.ID, outcome.Severity)
+			}
+		}
+		switch spec.Effect {
+		case "", "allow", "deny":
+		default:
+			return nil, fmt.Errorf("rule %s: effect must be allow or deny", spec.ID)
+		}
+		if spec.Effect != "" && !spec.Require.empty() {
+			return nil, fmt.Errorf("rule %s: effect cannot be combined with require", spec.ID)
+		}
+
+		rules = append(rules, e.compileRule(spec))
+	}
+	sortRules(rules)
+	return rules, nil
+}
+
+// compileRule 將單一規則定義轉換為 Rule。
+func (e *Engine) compileRule(spec RuleSpec) Rule {
+	match := spec.Match
+	commands := toSet(match.Commands)
+	exceptCommands := toSet(match.ExceptCommands)
+	phases := toSet(match.MissionPhases)
+	roles := toSet(match.Roles)
+	require := spec.Require
+	requiredCommands := toSet(require.Commands)
+	requiredRoles := toSet(require.Roles)
+
+	return Rule{
+		ID:          spec.ID,
+		Description: spec.Description,
+		Priority:    spec.Priority,
+		Condition: func(ctx CommandContext) bool {
+			if len(commands) > 0 && !commands[ctx.Command] {
+				return false
+			}
+			if exceptCommands[ctx.Command] {
+				return false
+			}
+			if match.Dangerous && !e.roles.IsDangerousCommand(ctx.Command) {
+				return false
+			}
+			if len(phases) > 0 && !phases[ctx.MissionPhase] {
+				return false
+			}
+			if len(roles) > 0 && !roles[ctx.OperatorRole] {
+				return false
+			}
+			if match.RestrictedRoles && !e.roles.IsRestrictedRole(ctx.OperatorRole) {
+				return false
+			}
+			return true
+		},
+		Action: func(ctx CommandContext) PolicyDecision {
+			allowed := spec.Effect != "deny"
+			if !require.empty() {
+				allowed = requiredCommands[ctx.Command] ||
+					requiredRoles[ctx.OperatorRole] ||
+					(require.DangerousPermission && e.roles.RoleAllowsDangerous(ctx.OperatorRole)) ||
+					(require.RoleCommandPermission && e.roles.RoleAllowsCommand(ctx.OperatorRole, ctx.Command)) ||
+					(require.MaintenanceWindow && e.InMaintenanceWindow(commandTime(ctx)))
+			}
+
+			outcome, defaultReason := spec.Allow, fmt.Sprintf("allowed by policy rule '%s'", spec.ID)
+			if !allowed {
+				outcome, defaultReason = spec.Deny, fmt.Sprintf("denied by policy rule '%s'", spec.ID)
+			}
+			reason := defaultReason
+			if outcome.Reason != "" {
+				replacements := []string{
+					"{command}", ctx.Command,
+					"{role}", ctx.OperatorRole,
+					"{phase}", ctx.MissionPhase,
+				}
+				if strings.Contains(outcome.Reason, "{nextWindow}") {
+					replacements = append(replacements, "{nextWindow}", e.describeNextMaintenanceWindow(commandTime(ctx)))
+				}
+				reason = strings.NewReplacer(replacements...).Replace(outcome.Reason)
+			}
+			severity := outcome.Severity
+			if severity == "" {
+				severity = "medium"
+			}
+			return PolicyDecision{Allowed: allowed, Reason: reason, Severity: severity}
+		},
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SatelliteOverrideRuleID 是衛星覆寫產生之決策的 RuleID。
+const SatelliteOverrideRuleID = "satellite-override"
+
+// SatelliteOverride 是單一衛星的指令允許 / 拒絕清單；"*" 代表所有指令，拒絕清單優先於允許清單。
+type SatelliteOverride struct {
+	SatelliteID string   `json:"satelliteId"`
+	Allow       []string `json:"allow"`
+	Deny        []string `json:"deny"`
+}
+
+type satelliteOverride struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// SetSatelliteOverride 設定衛星的指令覆寫，於所有規則之前評估；
+// 例如除役衛星可設定 deny ["*"]，測試衛星可將危險指令加入 allow 讓任何角色執行。
+// allow 與 deny 皆為空時移除該衛星的覆寫。
+func (e *Engine) SetSatelliteOverride(satelliteID string, allow, deny []string) {
+	e.overrideMu.Lock()
+	defer e.overrideMu.Unlock()
+
+	if len(allow) == 0 && len(deny) == 0 {
+		delete(e.overrides, satelliteID)
+		return
+	}
+	if e.overrides == nil {
+		e.overrides = make(map[string]satelliteOverride)
+	}
+	e.overrides[satelliteID] = satelliteOverride{allow: toSet(allow), deny: toSet(deny)}
+}
+
+// SatelliteOverrides 依衛星 ID 排序回傳目前的覆寫設定。
+func (e *Engine) SatelliteOverrides() []SatelliteOverride {
+	e.overrideMu.RLock()
+	defer e.overrideMu.RUnlock()
+
+	overrides := make([]SatelliteOverride, 0, len(e.overrides))
+	for id, override := range e.overrides {
+		overrides = append(overrides, SatelliteOverride{
+			SatelliteID: id,
+			Allow:       sortedKeys(override.allow),
+			Deny:        sortedKeys(override.deny),
+		})
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].SatelliteID < overrides[j].SatelliteID
+	})
+	return overrides
+}
+
+// evaluateOverride 回傳衛星覆寫的決策；該衛星沒有覆寫或指令不在清單中時回傳 false。
+func (e *Engine) evaluateOverride(ctx CommandContext) (PolicyDecision, bool) {
+	e.overrideMu.RLock()
+	override, ok := e.overrides[ctx.SatelliteID]
+	e.overrideMu.RUnlock()
+	if !ok || ctx.SatelliteID == "" {
+		return PolicyDecision{}, false
+	}
+
+	if override.deny["*"] || override.deny[ctx.Command] {
+		return PolicyDecision{
+			Allowed:  false,
+			Reason:   fmt.Sprintf("command '%s' denied for satellite '%s' by override", ctx.Command, ctx.SatelliteID),
+			RuleID:   SatelliteOverrideRuleID,
+			Severity: "high",
+		}, true
+	}
+	if override.allow["*"] || override.allow[ctx.Command] {
+		return PolicyDecision{
+			Allowed:  true,
+			Reason:   fmt.Sprintf("command '%s' allowed for satellite '%s' by override", ctx.Command, ctx.SatelliteID),
+			RuleID:   SatelliteOverrideRuleID,
+			Severity: "medium",
+		}, true
+	}
+	return PolicyDecision{}, false
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Config 設定 token bucket：每秒補充 Rate 個 token，最多累積 Burst 個。
+// Rate <= 0 表示不限制。
+type Config struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Result 是單次請求的限流結果。
+type Result struct {
+	Allowed bool
+	// RetryAfter 是被拒絕時下一個 token 可用前需等待的時間
+	RetryAfter time.Duration
+	// FirstRejection 表示此 key 自上次放行後第一次被拒絕，可用來避免每個被拒絕的請求都發出事件
+	FirstRejection bool
+}
+
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	rejected bool
+}
+
+// Limiter 是以 key（例如角色或衛星 ID）分開計算的 token bucket 限流器。
+type Limiter struct {
+	mu       sync.Mutex
+	config   Config
+	buckets  map[string]*bucket
+	lastGC   time.Time
+	rejected uint64
+	now      func() time.Time
+}
+
+// New 創建限流器；Burst 小於 1 時視為 1。
+func New(config Config) *Limiter {
+	if config.Burst < 1 {
+		config.Burst = 1
+	}
+	return &Limiter{
+		config:  config,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Config 回傳限流設定。
+func (l *Limiter) Config() Config {
+	return l.config
+}
+
+// Enabled 表示是否實際限制請求。
+func (l *Limiter) Enabled() bool {
+	return l.config.Rate > 0
+}
+
+// Rejected 回傳累計被拒絕的請求數。
+func (l *Limiter) Rejected() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rejected
+}
+
+// Allow 嘗試為 key 取得一個 token。
+func (l *Limiter) Allow(key string) Result {
+	if !l.Enabled() {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.gc(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.config.Burst), last: now}
+		l.buckets[key] = b
+	}
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(l.config.Burst), b.tokens+elapsed*l.config.Rate)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.rejected = false
+		return Result{Allowed: true}
+	}
+
+	l.rejected++
+	first := !b.rejected
+	b.rejected = true
+	wait := time.Duration((1 - b.tokens) / l.config.Rate * float64(time.Second))
+	return Result{Allowed: false, RetryAfter: wait, FirstRejection: first}
+}
+
+// Refund 歸還先前 Allow 為 key 取得的 token，用於同一請求的其他限流檢查拒絕時，避免被拒絕的請求消耗此 key 的額度。
+func (l *Limiter) Refund(key string) {
+	if !l.Enabled() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = math.Min(float64(l.config.Burst), b.tokens+1)
+	}
+}
+
+// gc 每分鐘移除已補滿的 bucket（與新建的 bucket 等價），避免 key 無限增長（呼叫端需持有 mu）。
+func (l *Limiter) gc(now time.Time) {
+	if now.Sub(l.lastGC) < time.Minute {
+		return
+	}
+	l.lastGC = now
+
+	fill := time.Duration(float64(l.config.Burst) / l.config.Rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= fill {
+			delete(l.buckets, key)
+		}
+	}
+}
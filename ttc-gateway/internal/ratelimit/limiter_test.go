@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRefundReturnsToken(t *testing.T) {
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	limiter := New(Config{Rate: 1, Burst: 2})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !limiter.Allow("operator").Allowed {
+			t.Fatalf("request %d within burst should be allowed", i+1)
+		}
+	}
+	limiter.Refund("operator")
+	if !limiter.Allow("operator").Allowed {
+		t.Fatal("refunded token should allow one more request")
+	}
+	if limiter.Allow("operator").Allowed {
+		t.Fatal("bucket should be empty again after using the refunded token")
+	}
+
+	// 歸還不會超過 burst，也不會為未使用過的 key 建立額度
+	limiter.Refund("engineer")
+	limiter.Refund("engineer")
+	for i := 0; i < 2; i++ {
+		if !limiter.Allow("engineer").Allowed {
+			t.Fatalf("request %d within burst should be allowed", i+1)
+		}
+	}
+	if limiter.Allow("engineer").Allowed {
+		t.Fatal("refund must not raise a bucket above its burst")
+	}
+}
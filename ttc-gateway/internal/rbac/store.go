@@ -0,0 +1,317 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WildcardCommand 表示角色可執行所有（非危險）指令。
+const WildcardCommand = "*"
+
+// Role 定義操作員角色與其允許的指令集合。
+type Role struct {
+	Name             string    `json:"name"`
+	Description      string    `json:"description,omitempty"`
+	AllowedCommands  []string  `json:"allowedCommands"`  // "*" 表示不限制一般指令
+	DangerousAllowed bool      `json:"dangerousAllowed"` // 是否可執行危險指令
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// allows 判斷角色是否允許執行一般指令。
+func (r *Role) allows(command string) bool {
+	for _, c := range r.AllowedCommands {
+		if c == WildcardCommand || c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// Store 保存角色定義與危險指令清單，可在執行期間修改並（選擇性）持久化到檔案。
+type Store struct {
+	mu                sync.RWMutex
+	roles             map[string]*Role
+	dangerousCommands map[string]bool
+	path              string
+}
+
+// NewStore 創建 RBAC store；path 為空時僅保存在記憶體中。
+func NewStore(path string) (*Store, error) {
+	store := &Store{
+		roles:             make(map[string]*Role),
+		dangerousCommands: make(map[string]bool),
+		path:              path,
+	}
+	store.loadDefaults()
+
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// loadDefaults 載入與既有 policy 相同的預設角色。
+func (s *Store) loadDefaults() {
+	now := time.Now().UTC()
+
+	for _, cmd := range []string{"deorbit", "disable_power", "format_memory", "orbit_change"} {
+		s.dangerousCommands[cmd] = true
+	}
+
+	s.roles["admin"] = &Role{
+		Name:             "admin",
+		Description:      "任務管理者，可執行所有指令",
+		AllowedCommands:  []string{WildcardCommand},
+		DangerousAllowed: true,
+		UpdatedAt:        now,
+	}
+	s.roles["engineer"] = &Role{
+		Name:            "engineer",
+		Description:     "工程師，僅允許維護相關指令",
+		AllowedCommands: []string{"health_check", "diagnostics", "system_status", "payload_toggle", "maintenance_mode"},
+		UpdatedAt:       now,
+	}
+	s.roles["operator"] = &Role{
+		Name:            "operator",
+		Description:     "一般操作員，可執行非危險指令",
+		AllowedCommands: []string{WildcardCommand},
+		UpdatedAt:       now,
+	}
+}
+
+// IsDangerousCommand 判斷指令是否屬於危險指令。
+func (s *Store) IsDangerousCommand(command string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.dangerousCommands[command]
+}
+
+// RoleAllowsDangerous 判斷角色是否可執行危險指令；未知角色一律拒絕。
+func (s *Store) RoleAllowsDangerous(role string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.roles[role]
+	return ok && r.DangerousAllowed
+}
+
+// RoleAllowsCommand 判斷角色是否可執行一般指令；未知角色一律拒絕。
+func (s *Store) RoleAllowsCommand(role, command string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.roles[role]
+	return ok && r.allows(command)
+}
+
+// IsRestrictedRole 判斷角色是否只能執行明確列出的指令（未使用萬用字元）。
+func (s *Store) IsRestrictedRole(role string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.roles[role]
+	if !ok {
+		return true
+	}
+	for _, c := range r.AllowedCommands {
+		if c == WildcardCommand {
+			return false
+		}
+	}
+	return true
+}
+
+// ListRoles 回傳所有角色（依名稱排序）。
+func (s *Store) ListRoles() []Role {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roles := make([]Role, 0, len(s.roles))
+	for _, r := range s.roles {
+		roles = append(roles, copyRole(r))
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles
+}
+
+// GetRole 取得單一角色。
+func (s *Store) GetRole(name string) (Role, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.roles[name]
+	if !ok {
+		return Role{}, false
+	}
+	return copyRole(r), true
+}
+
+// PutRole 新增或更新角色，回傳是否為新建立的角色。
+func (s *Store) PutRole(role Role) (bool, error) {
+	if role.Name == "" {
+		return false, fmt.Errorf("role name is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.roles[role.Name]
+	role.UpdatedAt = time.Now().UTC()
+	stored := copyRole(&role)
+	roles := s.cloneRolesLocked()
+	roles[role.Name] = &stored
+
+	// 先寫入檔案再套用，寫入失敗時角色維持原狀
+	if err := s.save(roles, s.dangerousCommands); err != nil {
+		return false, err
+	}
+	s.roles = roles
+	return !exists, nil
+}
+
+// DeleteRole 刪除角色；admin 角色不可刪除，以免鎖死管理端點。
+func (s *Store) DeleteRole(name string) error {
+	if name == "admin" {
+		return fmt.Errorf("admin role cannot be deleted")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.roles[name]; !ok {
+		return fmt.Errorf("role '%s' not found", name)
+	}
+	roles := s.cloneRolesLocked()
+	delete(roles, name)
+
+	if err := s.save(roles, s.dangerousCommands); err != nil {
+		return err
+	}
+	s.roles = roles
+	return nil
+}
+
+// DangerousCommands 回傳危險指令清單（排序後）。
+func (s *Store) DangerousCommands() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedKeys(s.dangerousCommands)
+}
+
+// SetDangerousCommands 取代危險指令清單。
+func (s *Store) SetDangerousCommands(commands []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dangerous := make(map[string]bool, len(commands))
+	for _, cmd := range commands {
+		if cmd != "" {
+			dangerous[cmd] = true
+		}
+	}
+
+	if err := s.save(s.roles, dangerous); err != nil {
+		return err
+	}
+	s.dangerousCommands = dangerous
+	return nil
+}
+
+// storeFile 是持久化檔案的格式。
+type storeFile struct {
+	Roles             []Role   `json:"roles"`
+	DangerousCommands []string `json:"dangerous_commands"`
+}
+
+// cloneRolesLocked 複製角色 map（不複製 Role 本身，Role 存入後不會再被修改；呼叫端需持有鎖）。
+func (s *Store) cloneRolesLocked() map[string]*Role {
+	roles := make(map[string]*Role, len(s.roles)+1)
+	for name, r := range s.roles {
+		roles[name] = r
+	}
+	return roles
+}
+
+// save 將指定狀態寫入檔案；呼叫端需持有寫鎖，寫入成功後才以此狀態取代目前狀態。
+func (s *Store) save(roles map[string]*Role, dangerousCommands map[string]bool) error {
+	if s.path == "" {
+		return nil
+	}
+
+	data := storeFile{DangerousCommands: sortedKeys(dangerousCommands)}
+	for _, r := range roles {
+		data.Roles = append(data.Roles, copyRole(r))
+	}
+	sort.Slice(data.Roles, func(i, j int) bool { return data.Roles[i].Name < data.Roles[j].Name })
+
+	bytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode rbac store: %w", err)
+	}
+	if err := os.WriteFile(s.path, bytes, 0o600); err != nil {
+		return fmt.Errorf("failed to write rbac store: %w", err)
+	}
+
+	return nil
+}
+
+// load 從檔案載入角色，檔案不存在時保留預設值。
+func (s *Store) load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	bytes, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read rbac store: %w", err)
+	}
+
+	var data storeFile
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return fmt.Errorf("failed to decode rbac store: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(data.Roles) > 0 {
+		s.roles = make(map[string]*Role, len(data.Roles))
+		for _, r := range data.Roles {
+			role := copyRole(&r)
+			s.roles[role.Name] = &role
+		}
+	}
+	if data.DangerousCommands != nil {
+		s.dangerousCommands = make(map[string]bool, len(data.DangerousCommands))
+		for _, cmd := range data.DangerousCommands {
+			s.dangerousCommands[cmd] = true
+		}
+	}
+
+	return nil
+}
+
+func copyRole(r *Role) Role {
+	c := *r
+	c.AllowedCommands = append([]string(nil), r.AllowedCommands...)
+	return c
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
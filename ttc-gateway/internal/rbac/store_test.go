@@ -0,0 +1,94 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newUnwritableStore 建立持久化路徑位於不存在目錄的 store，讓每次寫入都失敗
+func newUnwritableStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "missing", "rbac.json"))
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store
+}
+
+func TestFailedSaveLeavesRolesUnchanged(t *testing.T) {
+	store := newUnwritableStore(t)
+
+	if _, err := store.PutRole(Role{Name: "observer", AllowedCommands: []string{"health_check"}}); err == nil {
+		t.Fatal("PutRole should report the failed save")
+	}
+	if _, ok := store.GetRole("observer"); ok {
+		t.Fatal("role from a failed save must not take effect")
+	}
+	if store.RoleAllowsCommand("observer", "health_check") {
+		t.Fatal("role from a failed save must not grant commands")
+	}
+
+	if _, err := store.PutRole(Role{Name: "engineer", AllowedCommands: []string{WildcardCommand}}); err == nil {
+		t.Fatal("PutRole should report the failed save")
+	}
+	if !store.IsRestrictedRole("engineer") || store.RoleAllowsCommand("engineer", "payload_deploy") {
+		t.Fatal("failed update must keep the previous engineer definition")
+	}
+
+	if err := store.DeleteRole("operator"); err == nil {
+		t.Fatal("DeleteRole should report the failed save")
+	}
+	if _, ok := store.GetRole("operator"); !ok {
+		t.Fatal("role must survive a failed delete")
+	}
+}
+
+func TestFailedSaveLeavesDangerousCommandsUnchanged(t *testing.T) {
+	store := newUnwritableStore(t)
+	before := store.DangerousCommands()
+
+	if err := store.SetDangerousCommands([]string{"health_check"}); err == nil {
+		t.Fatal("SetDangerousCommands should report the failed save")
+	}
+	if store.IsDangerousCommand("health_check") {
+		t.Fatal("dangerous command list from a failed save must not take effect")
+	}
+	after := store.DangerousCommands()
+	if len(after) != len(before) {
+		t.Fatalf("dangerous commands changed from %v to %v", before, after)
+	}
+	for i := range before {
+		if after[i] != before[i] {
+			t.Fatalf("dangerous commands changed from %v to %v", before, after)
+		}
+	}
+}
+
+func TestSuccessfulSavePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rbac.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if _, err := store.PutRole(Role{Name: "observer", AllowedCommands: []string{"health_check"}}); err != nil {
+		t.Fatalf("PutRole: %v", err)
+	}
+	if err := store.SetDangerousCommands([]string{"orbit_change"}); err != nil {
+		t.Fatalf("SetDangerousCommands: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("store file not written: %v", err)
+	}
+
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reloaded.RoleAllowsCommand("observer", "health_check") {
+		t.Fatal("reloaded store lost the new role")
+	}
+	if got := reloaded.DangerousCommands(); len(got) != 1 || got[0] != "orbit_change" {
+		t.Fatalf("reloaded dangerous commands = %v, want [orbit_change]", got)
+	}
+}
@@ -0,0 +1,163 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceStore 記錄已使用過的 nonce；同一 nonce 在 ttl 內第二次出現即視為重放。
+type NonceStore interface {
+	// Remember 嘗試記錄 nonce，若先前已存在（仍在 ttl 內）則回傳 false。
+	Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+	// Name 回傳 store 類型，供 status/log 使用。
+	Name() string
+}
+
+// MemoryStore 是單一 gateway 實例內的 nonce store。
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]time.Time // nonce -> 到期時間
+	lastGC  time.Time
+}
+
+// NewMemoryStore 創建記憶體 nonce store。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]time.Time),
+	}
+}
+
+// Remember 實作 NonceStore。
+func (s *MemoryStore) Remember(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.gc(now, ttl)
+
+	if expiry, ok := s.entries[nonce]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	s.entries[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// Name 實作 NonceStore。
+func (s *MemoryStore) Name() string {
+	return "memory"
+}
+
+// gc 定期清除過期的 nonce（最多每個 ttl 一次）。
+func (s *MemoryStore) gc(now time.Time, ttl time.Duration) {
+	if now.Sub(s.lastGC) < ttl {
+		return
+	}
+	for nonce, expiry := range s.entries {
+		if !now.Before(expiry) {
+			delete(s.entries, nonce)
+		}
+	}
+	s.lastGC = now
+}
+
+// RedisStore 將 nonce 存放在 Redis，讓多個 gateway 實例共享重放保護。
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore 依 redis URL（例如 redis://:password@redis:6379/0）創建共享 nonce store。
+func NewRedisStore(redisURL string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+
+	return &RedisStore{
+		client: redis.NewClient(opts),
+		prefix: "ttc-gateway:nonce:",
+	}, nil
+}
+
+// Remember 使用 SET NX PX，確保跨實例只有第一個請求能記錄成功。
+func (s *RedisStore) Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+nonce, 1, ttl).Result()
+}
+
+// Name 實作 NonceStore。
+func (s *RedisStore) Name() string {
+	return "redis"
+}
+
+// ErrStoreUnavailable 表示 nonce store 無法回應，無法判斷 nonce 是否已使用過。
+var ErrStoreUnavailable = errors.New("nonce store unavailable")
+
+// Guard 以時間偏移視窗加上 nonce store 實作重放保護。
+// 共享 store 發生錯誤時拒絕請求而非退回本機記憶體：各實例各自記錄 nonce 時，同一請求可重放到另一個實例。
+type Guard struct {
+	store      NonceStore
+	skewWindow time.Duration
+	timeout    time.Duration
+}
+
+// NewGuard 創建重放保護；store 為 nil 時僅使用本機記憶體（單實例模式）。
+func NewGuard(store NonceStore, skewWindow time.Duration) *Guard {
+	if skewWindow <= 0 {
+		skewWindow = 30 * time.Second
+	}
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	return &Guard{
+		store:      store,
+		skewWindow: skewWindow,
+		timeout:    500 * time.Millisecond,
+	}
+}
+
+// Check 驗證請求時間戳記位於偏移視窗內，且 nonce 未曾出現過。
+func (g *Guard) Check(nonce string, timestamp, now time.Time) error {
+	if nonce == "" {
+		return fmt.Errorf("missing request nonce")
+	}
+
+	skew := now.Sub(timestamp)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > g.skewWindow {
+		return fmt.Errorf("request timestamp outside allowed skew window (%v)", g.skewWindow)
+	}
+
+	// nonce 只需保存到時間戳記離開視窗為止（前後各一個視窗）
+	ttl := 2 * g.skewWindow
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	fresh, err := g.store.Remember(ctx, nonce, ttl)
+	if err != nil {
+		return fmt.Errorf("%w (%s): %v", ErrStoreUnavailable, g.store.Name(), err)
+	}
+	if !fresh {
+		return fmt.Errorf("nonce already used (replay detected)")
+	}
+
+	return nil
+}
+
+// Backend 回傳目前使用的 nonce store 類型。
+func (g *Guard) Backend() string {
+	return g.store.Name()
+}
+
+// SkewWindow 回傳允許的時間偏移。
+func (g *Guard) SkewWindow() time.Duration {
+	return g.skewWindow
+}
@@ -0,0 +1,50 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingStore 模擬無法連線的共享 store
+type failingStore struct{}
+
+func (failingStore) Remember(context.Context, string, time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func (failingStore) Name() string {
+	return "redis"
+}
+
+func TestGuardRejectsReplay(t *testing.T) {
+	guard := NewGuard(nil, 30*time.Second)
+	now := time.Now()
+
+	if err := guard.Check("nonce-1", now, now); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := guard.Check("nonce-1", now, now); err == nil {
+		t.Fatal("second use of the same nonce should be rejected")
+	}
+	if err := guard.Check("nonce-2", now.Add(-time.Minute), now); err == nil {
+		t.Fatal("timestamp outside the skew window should be rejected")
+	}
+}
+
+func TestGuardFailsClosedWhenSharedStoreErrors(t *testing.T) {
+	guard := NewGuard(failingStore{}, 30*time.Second)
+	now := time.Now()
+
+	// 共享 store 失敗時每次都拒絕，不能退回本機記憶體而讓第一次請求通過
+	for i := 0; i < 2; i++ {
+		err := guard.Check("nonce-1", now, now)
+		if !errors.Is(err, ErrStoreUnavailable) {
+			t.Fatalf("attempt %d: got %v, want ErrStoreUnavailable", i+1, err)
+		}
+	}
+	if guard.Backend() != "redis" {
+		t.Fatalf("backend = %s, want redis", guard.Backend())
+	}
+}
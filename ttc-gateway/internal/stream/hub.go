@@ -0,0 +1,130 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event 是推送給即時訂閱者的決策事件（內容與送往 Space-SOC 的相同）。
+type Event struct {
+	Type         string                 `json:"type"` // "policy_decision", "anomaly_detected"
+	OperatorRole string                 `json:"operatorRole,omitempty"`
+	SatelliteID  string                 `json:"satelliteId,omitempty"`
+	Timestamp    time.Time              `json:"timestamp"`
+	Data         map[string]interface{} `json:"data"`
+}
+
+// Filter 限制訂閱者收到的事件；空欄位代表不過濾。
+type Filter struct {
+	OperatorRole string
+	SatelliteID  string
+}
+
+func (f Filter) matches(ev Event) bool {
+	if f.OperatorRole != "" && f.OperatorRole != ev.OperatorRole {
+		return false
+	}
+	if f.SatelliteID != "" && f.SatelliteID != ev.SatelliteID {
+		return false
+	}
+	return true
+}
+
+// Subscription 是單一訂閱者的事件佇列。
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	filter  Filter
+	dropped atomic.Uint64
+}
+
+// Dropped 回傳因佇列已滿而丟棄的事件數。
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Hub 是行程內的 pub/sub：Publish 從不阻塞，慢速訂閱者的佇列滿了就丟棄事件，
+// 確保指令處理路徑不會因為即時推送而增加延遲。
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub 創建新的 pub/sub hub。
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe 註冊訂閱者；bufferSize 為每個連線最多暫存的事件數。
+func (h *Hub) Subscribe(filter Filter, bufferSize int) *Subscription {
+	if bufferSize <= 0 {
+		bufferSize = 64
+	}
+	ch := make(chan Event, bufferSize)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+
+	return sub
+}
+
+// Unsubscribe 移除訂閱者並關閉其佇列。
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish 將事件推送給所有符合過濾條件的訂閱者。
+func (h *Hub) Publish(ev Event) {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs {
+		if !sub.filter.matches(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Close 關閉所有訂閱者的佇列並拒絕新的訂閱，讓串流連線在服務關閉時結束。
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscribers 回傳目前的訂閱者數量。
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}